@@ -0,0 +1,132 @@
+package fhevm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+//go:generate go run ./cmd/libabi abi
+
+// An entry of a Solidity ABI JSON file.
+type abiFunction struct {
+	Type            string        `json:"type"`
+	Name            string        `json:"name"`
+	Inputs          []abiArgument `json:"inputs"`
+	Outputs         []abiArgument `json:"outputs"`
+	StateMutability string        `json:"stateMutability"`
+}
+
+type abiArgument struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Splits a "(type1,type2...)" list into its types. Methods don't take tuples, so types don't contain commas.
+func splitTypes(types string) []string {
+	types = strings.TrimSuffix(strings.TrimPrefix(types, "("), ")")
+	if types == "" {
+		return nil
+	}
+	return strings.Split(types, ",")
+}
+
+func abiArguments(types string) []abiArgument {
+	arguments := []abiArgument{}
+	for _, t := range splitTypes(types) {
+		arguments = append(arguments, abiArgument{Type: t})
+	}
+	return arguments
+}
+
+func stateMutability(method *FheLibMethod) string {
+	if method.View() {
+		return "view"
+	}
+	return "nonpayable"
+}
+
+// LibABI returns the Solidity ABI JSON of precompile methods, as listed by FheLibMethods or TeeLibMethods.
+func LibABI(methods []*FheLibMethod) ([]byte, error) {
+	functions := make([]abiFunction, 0, len(methods))
+	for _, method := range methods {
+		functions = append(functions, abiFunction{
+			Type:            "function",
+			Name:            method.Name(),
+			Inputs:          abiArguments(method.ArgTypes()),
+			Outputs:         abiArguments(method.ReturnTypes()),
+			StateMutability: stateMutability(method),
+		})
+	}
+	out, err := json.MarshalIndent(functions, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// Solidity needs a data location for the parameters and results of dynamic types.
+func solidityParameters(types string) string {
+	parameters := splitTypes(types)
+	for i, t := range parameters {
+		if t == "bytes" || t == "string" || strings.HasSuffix(t, "]") {
+			parameters[i] = t + " memory"
+		}
+	}
+	return strings.Join(parameters, ", ")
+}
+
+// LibInterface returns a Solidity interface named name that declares precompile methods, as listed by
+// FheLibMethods or TeeLibMethods.
+func LibInterface(name string, methods []*FheLibMethod) []byte {
+	var out bytes.Buffer
+	out.WriteString("// SPDX-License-Identifier: BSD-3-Clause-Clear\n")
+	out.WriteString("// Code generated by fhevm/cmd/libabi. DO NOT EDIT.\n\n")
+	out.WriteString("pragma solidity ^0.8.0;\n\n")
+	fmt.Fprintf(&out, "interface %s {\n", name)
+	for _, method := range methods {
+		fmt.Fprintf(&out, "    function %s(%s) external", method.Name(), solidityParameters(method.ArgTypes()))
+		if method.View() {
+			out.WriteString(" view")
+		}
+		if returns := solidityParameters(method.ReturnTypes()); returns != "" {
+			fmt.Fprintf(&out, " returns (%s)", returns)
+		}
+		out.WriteString(";\n")
+	}
+	out.WriteString("}\n")
+	return out.Bytes()
+}
+
+// Filters out the methods added by RegisterFheLibMethod or RegisterTeeLibMethod.
+func builtinMethods(methods []*FheLibMethod) []*FheLibMethod {
+	builtin := make([]*FheLibMethod, 0, len(methods))
+	for _, method := range methods {
+		if method.IsBuiltin() {
+			builtin = append(builtin, method)
+		}
+	}
+	return builtin
+}
+
+// LibArtifacts returns the generated files that describe the built-in methods of the FheLib and TeeLib
+// precompiles, by file name.
+func LibArtifacts() (map[string][]byte, error) {
+	fheLib := builtinMethods(FheLibMethods())
+	teeLib := builtinMethods(TeeLibMethods())
+	fheABI, err := LibABI(fheLib)
+	if err != nil {
+		return nil, err
+	}
+	teeABI, err := LibABI(teeLib)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{
+		"FheLib.json": fheABI,
+		"IFheLib.sol": LibInterface("IFheLib", fheLib),
+		"TeeLib.json": teeABI,
+		"ITeeLib.sol": LibInterface("ITeeLib", teeLib),
+	}, nil
+}
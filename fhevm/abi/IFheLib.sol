@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: BSD-3-Clause-Clear
+// Code generated by fhevm/cmd/libabi. DO NOT EDIT.
+
+pragma solidity ^0.8.0;
+
+interface IFheLib {
+    function allow(uint256, address) external;
+    function allowTransient(uint256, address) external;
+    function auditorReencrypt(uint256, address, bytes32) external view returns (bytes memory);
+    function cast(uint256, bytes1) external returns (uint256);
+    function decrypt(uint256) external view returns (uint256);
+    function fheAdd(uint256, uint256, bytes1) external returns (uint256);
+    function fheAddBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fheBitAnd(uint256, uint256, bytes1) external returns (uint256);
+    function fheBitAndBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fheBitOr(uint256, uint256, bytes1) external returns (uint256);
+    function fheBitOrBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fheBitTest(uint256, uint256, bytes1) external returns (uint256);
+    function fheBitTestBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fheBitXor(uint256, uint256, bytes1) external returns (uint256);
+    function fheBitXorBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fheClz(uint256) external returns (uint256);
+    function fheCtz(uint256) external returns (uint256);
+    function fheDiv(uint256, uint256, bytes1) external returns (uint256);
+    function fheDivBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fheEq(uint256, uint256, bytes1) external returns (uint256);
+    function fheEqBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fheGe(uint256, uint256, bytes1) external returns (uint256);
+    function fheGeBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fheGt(uint256, uint256, bytes1) external returns (uint256);
+    function fheGtBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fheIfThenElse(uint256, uint256, uint256) external returns (uint256);
+    function fheLe(uint256, uint256, bytes1) external returns (uint256);
+    function fheLeBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fheLt(uint256, uint256, bytes1) external returns (uint256);
+    function fheLtBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fheMax(uint256, uint256, bytes1) external returns (uint256);
+    function fheMaxBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fheMin(uint256, uint256, bytes1) external returns (uint256);
+    function fheMinBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fheMul(uint256, uint256, bytes1) external returns (uint256);
+    function fheMulBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fheNe(uint256, uint256, bytes1) external returns (uint256);
+    function fheNeBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fheNeg(uint256) external returns (uint256);
+    function fheNot(uint256) external returns (uint256);
+    function fhePopcount(uint256) external returns (uint256);
+    function fheProgram(bytes memory, uint256[] memory) external returns (uint256[] memory);
+    function fhePubKey(bytes1) external view returns (bytes memory);
+    function fheRand(bytes1) external returns (uint256);
+    function fheRandBounded(uint256, bytes1) external returns (uint256);
+    function fheRem(uint256, uint256, bytes1) external returns (uint256);
+    function fheRemBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fheRotl(uint256, uint256, bytes1) external returns (uint256);
+    function fheRotlBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fheRotr(uint256, uint256, bytes1) external returns (uint256);
+    function fheRotrBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fheShl(uint256, uint256, bytes1) external returns (uint256);
+    function fheShlBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fheShr(uint256, uint256, bytes1) external returns (uint256);
+    function fheShrBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fheSub(uint256, uint256, bytes1) external returns (uint256);
+    function fheSubBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function fulfillDecryption(uint256, uint256[] memory, bytes memory) external;
+    function getCiphertext(address, uint256) external view;
+    function getUnlockHeight(uint256) external view returns (uint256);
+    function getViewingKey(address, address) external view returns (bytes32);
+    function isAllowed(uint256, address) external view returns (bool);
+    function isPubliclyDecryptable(uint256) external view returns (bool);
+    function makePubliclyDecryptable(uint256) external;
+    function reencrypt(uint256, uint256) external view returns (bytes memory);
+    function registerViewingKey(address, bytes32) external;
+    function requestDecryption(bytes4, uint256[] memory) external returns (uint256);
+    function revokeViewingKey(address) external;
+    function setUnlockHeight(uint256, uint256) external;
+    function trivialEncrypt(uint256, bytes1) external returns (uint256);
+    function verifyCiphertext(bytes memory) external returns (uint256);
+}
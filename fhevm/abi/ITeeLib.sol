@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: BSD-3-Clause-Clear
+// Code generated by fhevm/cmd/libabi. DO NOT EDIT.
+
+pragma solidity ^0.8.0;
+
+interface ITeeLib {
+    function allow(uint256, address) external;
+    function allowTransient(uint256, address) external;
+    function fulfillDecryption(uint256, uint256[] memory, bytes memory) external;
+    function getUnlockHeight(uint256) external view returns (uint256);
+    function isAllowed(uint256, address) external view returns (bool);
+    function isPubliclyDecryptable(uint256) external view returns (bool);
+    function makePubliclyDecryptable(uint256) external;
+    function setUnlockHeight(uint256, uint256) external;
+    function teeAdd(uint256, uint256, bytes1) external returns (uint256);
+    function teeAddBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function teeBitAnd(uint256, uint256, bytes1) external returns (uint256);
+    function teeBitAndBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function teeBitOr(uint256, uint256, bytes1) external returns (uint256);
+    function teeBitOrBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function teeBitTest(uint256, uint256, bytes1) external returns (uint256);
+    function teeBitTestBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function teeBitXor(uint256, uint256, bytes1) external returns (uint256);
+    function teeBitXorBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function teeCast(uint256, bytes1) external returns (uint256);
+    function teeClz(uint256) external returns (uint256);
+    function teeCtz(uint256) external returns (uint256);
+    function teeDecrypt(uint256) external view returns (uint256);
+    function teeDiv(uint256, uint256, bytes1) external returns (uint256);
+    function teeDivBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function teeEncrypt(uint256, bytes1) external returns (uint256);
+    function teeEq(uint256, uint256, bytes1) external returns (uint256);
+    function teeEqBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function teeGe(uint256, uint256, bytes1) external returns (uint256);
+    function teeGeBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function teeGt(uint256, uint256, bytes1) external returns (uint256);
+    function teeGtBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function teeLe(uint256, uint256, bytes1) external returns (uint256);
+    function teeLeBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function teeLt(uint256, uint256, bytes1) external returns (uint256);
+    function teeLtBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function teeMax(uint256, uint256, bytes1) external returns (uint256);
+    function teeMaxBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function teeMin(uint256, uint256, bytes1) external returns (uint256);
+    function teeMinBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function teeMul(uint256, uint256, bytes1) external returns (uint256);
+    function teeMulBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function teeMulDiv(uint256, uint256, uint256, bytes1) external returns (uint256);
+    function teeMulHi(uint256, uint256, bytes1) external returns (uint256);
+    function teeNe(uint256, uint256, bytes1) external returns (uint256);
+    function teeNeBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function teeNeg(uint256) external returns (uint256);
+    function teeNot(uint256) external returns (uint256);
+    function teePopcount(uint256) external returns (uint256);
+    function teePow(uint256, uint256, bytes1) external returns (uint256);
+    function teeProgram(bytes memory, uint256[] memory) external returns (uint256[] memory);
+    function teeRem(uint256, uint256, bytes1) external returns (uint256);
+    function teeRemBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function teeRequestDecryption(bytes4, uint256[] memory) external returns (uint256);
+    function teeRotl(uint256, uint256, bytes1) external returns (uint256);
+    function teeRotlBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function teeRotr(uint256, uint256, bytes1) external returns (uint256);
+    function teeRotrBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function teeSelect(uint256, uint256, uint256) external returns (uint256);
+    function teeShl(uint256, uint256, bytes1) external returns (uint256);
+    function teeShlBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function teeShr(uint256, uint256, bytes1) external returns (uint256);
+    function teeShrBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+    function teeSqrt(uint256) external returns (uint256);
+    function teeSub(uint256, uint256, bytes1) external returns (uint256);
+    function teeSubBatch(uint256[] memory, uint256[] memory, bytes1) external returns (uint256[] memory);
+}
@@ -0,0 +1,286 @@
+package fhevm
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// Precompile inputs are decoded strictly from the argument types of their method: the input must be the
+// canonical ABI encoding of the arguments, with zero padding, offsets that point right after the previous
+// argument and no trailing bytes. Malformed inputs revert with an ABI encoded Error(string).
+
+type abiKind int
+
+const (
+	abiUint abiKind = iota
+	abiBool
+	abiAddress
+	abiFixedBytes
+	abiBytes
+	abiString
+	abiArray
+)
+
+type abiType struct {
+	kind abiKind
+	// bits of uintN, bytes of bytesN
+	size int
+	// element of an array, which must be static
+	elem *abiType
+}
+
+func (t abiType) dynamic() bool {
+	return t.kind == abiBytes || t.kind == abiString || t.kind == abiArray
+}
+
+func parseABIType(name string) (abiType, error) {
+	if element, found := strings.CutSuffix(name, "[]"); found {
+		elem, err := parseABIType(element)
+		if err != nil {
+			return abiType{}, err
+		}
+		if elem.dynamic() {
+			return abiType{}, fmt.Errorf("arrays of dynamic type %s are not supported", element)
+		}
+		return abiType{kind: abiArray, elem: &elem}, nil
+	}
+	switch name {
+	case "bool":
+		return abiType{kind: abiBool}, nil
+	case "address":
+		return abiType{kind: abiAddress}, nil
+	case "bytes":
+		return abiType{kind: abiBytes}, nil
+	case "string":
+		return abiType{kind: abiString}, nil
+	case "uint":
+		return abiType{kind: abiUint, size: 256}, nil
+	}
+	if bits, found := strings.CutPrefix(name, "uint"); found {
+		size, err := strconv.Atoi(bits)
+		if err != nil || size <= 0 || size > 256 || size%8 != 0 || bits != strconv.Itoa(size) {
+			return abiType{}, fmt.Errorf("invalid type %s", name)
+		}
+		return abiType{kind: abiUint, size: size}, nil
+	}
+	if length, found := strings.CutPrefix(name, "bytes"); found {
+		size, err := strconv.Atoi(length)
+		if err != nil || size <= 0 || size > 32 || length != strconv.Itoa(size) {
+			return abiType{}, fmt.Errorf("invalid type %s", name)
+		}
+		return abiType{kind: abiFixedBytes, size: size}, nil
+	}
+	return abiType{}, fmt.Errorf("unsupported type %s", name)
+}
+
+// Parses a "(type1,type2...)" list of argument types.
+func parseABITypes(types string) ([]abiType, error) {
+	if !isTypeList(types) {
+		return nil, fmt.Errorf("invalid type list %q", types)
+	}
+	parsed := make([]abiType, 0)
+	for _, name := range splitTypes(types) {
+		t, err := parseABIType(name)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, t)
+	}
+	return parsed, nil
+}
+
+func mustParseABITypes(types string) []abiType {
+	parsed, err := parseABITypes(types)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// The decoded arguments of a method. Static arguments are 32-byte words, bytes and strings are their data
+// and arrays are their elements, one word each.
+type abiArgs struct {
+	values [][]byte
+	// the EIP-712 authorization that follows the arguments of an authorized method, if any
+	authorization []byte
+}
+
+// Returns the word of a static argument.
+func (a abiArgs) word(i int) common.Hash {
+	return common.BytesToHash(a.values[i])
+}
+
+// Returns the first byte of a bytesN argument, e.g. the scalar byte of an operation.
+func (a abiArgs) byte(i int) byte {
+	return a.values[i][0]
+}
+
+func (a abiArgs) address(i int) common.Address {
+	return common.BytesToAddress(a.values[i])
+}
+
+// Returns the data of a bytes or string argument.
+func (a abiArgs) bytes(i int) []byte {
+	return a.values[i]
+}
+
+// Returns the elements of an array argument.
+func (a abiArgs) words(i int) []common.Hash {
+	words := make([]common.Hash, 0, len(a.values[i])/32)
+	for start := 0; start < len(a.values[i]); start += 32 {
+		words = append(words, common.BytesToHash(a.values[i][start:start+32]))
+	}
+	return words
+}
+
+// Decodes arguments that take the whole input.
+func decodeABIArgs(types []abiType, input []byte) (abiArgs, error) {
+	args, end, err := decodeABIArgsPrefix(types, input)
+	if err != nil {
+		return abiArgs{}, err
+	}
+	if end != len(input) {
+		return abiArgs{}, fmt.Errorf("input has %d unexpected trailing bytes", len(input)-end)
+	}
+	return args, nil
+}
+
+// Decodes arguments at the start of the input and returns where they end.
+func decodeABIArgsPrefix(types []abiType, input []byte) (abiArgs, int, error) {
+	head := 32 * len(types)
+	if len(input) < head {
+		return abiArgs{}, 0, fmt.Errorf("input has %d bytes, expected at least %d", len(input), head)
+	}
+	args := abiArgs{values: make([][]byte, 0, len(types))}
+	// dynamic arguments are encoded after the head, in order
+	end := head
+	for i, t := range types {
+		word := input[32*i : 32*(i+1)]
+		if !t.dynamic() {
+			if err := checkStaticWord(t, word); err != nil {
+				return abiArgs{}, 0, fmt.Errorf("argument %d: %w", i, err)
+			}
+			args.values = append(args.values, word)
+			continue
+		}
+		if offset, err := readLength(word); err != nil || offset != uint64(end) {
+			return abiArgs{}, 0, fmt.Errorf("argument %d: offset must be %d", i, end)
+		}
+		value, size, err := decodeDynamic(t, input[end:])
+		if err != nil {
+			return abiArgs{}, 0, fmt.Errorf("argument %d: %w", i, err)
+		}
+		args.values = append(args.values, value)
+		end += size
+	}
+	return args, end, nil
+}
+
+// Decodes the length word and the data of a dynamic argument, returning its data and encoded size.
+func decodeDynamic(t abiType, input []byte) ([]byte, int, error) {
+	if len(input) < 32 {
+		return nil, 0, errors.New("input is too short for the length")
+	}
+	length, err := readLength(input[0:32])
+	if err != nil {
+		return nil, 0, err
+	}
+	data := input[32:]
+	if t.kind == abiArray {
+		if length > uint64(len(data))/32 {
+			return nil, 0, errors.New("array length is out of bounds")
+		}
+		size := 32 * int(length)
+		for start := 0; start < size; start += 32 {
+			if err := checkStaticWord(*t.elem, data[start:start+32]); err != nil {
+				return nil, 0, fmt.Errorf("element %d: %w", start/32, err)
+			}
+		}
+		return data[:size], 32 + size, nil
+	}
+	if length > uint64(len(data)) {
+		return nil, 0, errors.New("length is out of bounds")
+	}
+	padded := (int(length) + 31) / 32 * 32
+	if padded > len(data) {
+		return nil, 0, errors.New("input is too short for the padding")
+	}
+	if !isZero(data[length:padded]) {
+		return nil, 0, errors.New("padding must be zero")
+	}
+	return data[:length], 32 + padded, nil
+}
+
+// Reads an offset or a length, which must fit in the input.
+func readLength(word []byte) (uint64, error) {
+	length := new(uint256.Int).SetBytes(word)
+	if !length.IsUint64() || length.Uint64() > 1<<32 {
+		return 0, errors.New("length is out of bounds")
+	}
+	return length.Uint64(), nil
+}
+
+// Checks that a static value is canonically padded.
+func checkStaticWord(t abiType, word []byte) error {
+	switch t.kind {
+	case abiUint:
+		if !isZero(word[:32-t.size/8]) {
+			return fmt.Errorf("value overflows uint%d", t.size)
+		}
+	case abiBool:
+		if !isZero(word[:31]) || word[31] > 1 {
+			return errors.New("bool must be 0 or 1")
+		}
+	case abiAddress:
+		if !isZero(word[:12]) {
+			return errors.New("address must be left-padded with zeros")
+		}
+	case abiFixedBytes:
+		if !isZero(word[t.size:]) {
+			return fmt.Errorf("bytes%d must be right-padded with zeros", t.size)
+		}
+	}
+	return nil
+}
+
+func isZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Decodes the input of a method, which may be followed by an authorization if the method takes one.
+func (fheLibMethod *FheLibMethod) decodeInput(input []byte) (abiArgs, error) {
+	args, end, err := decodeABIArgsPrefix(fheLibMethod.args, input)
+	if err != nil {
+		return abiArgs{}, err
+	}
+	if trailing := len(input) - end; trailing != 0 && !(fheLibMethod.authorized && trailing == authorizationLen) {
+		return abiArgs{}, fmt.Errorf("input has %d unexpected trailing bytes", trailing)
+	}
+	args.authorization = input[end:]
+	return args, nil
+}
+
+var errorSelector = crypto.Keccak256([]byte("Error(string)"))[0:4]
+
+// Reverts with the ABI encoding of Error(reason), like a Solidity require. The data only reaches the caller
+// with ErrExecutionReverted, so a plain error is returned if the host didn't register it.
+func revertWithReason(reason string) ([]byte, error) {
+	if ErrExecutionReverted == nil {
+		return nil, errors.New(reason)
+	}
+	data := append([]byte{}, errorSelector...)
+	data = append(data, uint256.NewInt(32).PaddedBytes(32)...)
+	data = append(data, padArrayTo32Multiple(toEVMBytes([]byte(reason)))...)
+	return data, ErrExecutionReverted
+}
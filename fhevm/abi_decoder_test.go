@@ -0,0 +1,135 @@
+package fhevm
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+func word(value uint64) []byte {
+	return uint256.NewInt(value).PaddedBytes(32)
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func TestDecodeABIArgs(t *testing.T) {
+	types := mustParseABITypes("(uint256,address,bytes1,bytes,uint256[])")
+	input := concat(
+		word(7),
+		common.LeftPadBytes(common.HexToAddress("0x1000").Bytes(), 32),
+		common.RightPadBytes([]byte{1}, 32),
+		word(160),
+		word(224),
+		word(3), common.RightPadBytes([]byte{1, 2, 3}, 32),
+		word(2), word(8), word(9),
+	)
+	args, err := decodeABIArgs(types, input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if args.word(0).Big().Uint64() != 7 || args.address(1) != common.HexToAddress("0x1000") || args.byte(2) != 1 {
+		t.Fatalf("unexpected static arguments")
+	}
+	if !bytes.Equal(args.bytes(3), []byte{1, 2, 3}) {
+		t.Fatalf("unexpected bytes argument %x", args.bytes(3))
+	}
+	if words := args.words(4); len(words) != 2 || words[0].Big().Uint64() != 8 || words[1].Big().Uint64() != 9 {
+		t.Fatalf("unexpected array argument %v", words)
+	}
+}
+
+func TestDecodeABIArgsMalformed(t *testing.T) {
+	testcases := []struct {
+		name  string
+		types string
+		input []byte
+	}{
+		{"short", "(uint256,bytes1)", concat(word(7), []byte{1})},
+		{"trailing bytes", "(uint256)", concat(word(7), word(0))},
+		{"bytes1 padding", "(bytes1)", word(1)},
+		{"address padding", "(address)", bytes.Repeat([]byte{0xff}, 32)},
+		{"bool value", "(bool)", word(2)},
+		{"uint8 overflow", "(uint8)", word(256)},
+		{"offset past the head", "(bytes)", concat(word(64), word(0), word(0))},
+		{"offset into the head", "(bytes)", concat(word(0), word(0))},
+		{"huge offset", "(bytes)", concat(bytes.Repeat([]byte{0xff}, 32), word(0))},
+		{"bytes length", "(bytes)", concat(word(32), word(33), word(0))},
+		{"bytes padding", "(bytes)", concat(word(32), word(1), bytes.Repeat([]byte{0xff}, 32))},
+		{"missing bytes padding", "(bytes)", concat(word(32), word(1), []byte{1})},
+		{"array length", "(uint256[])", concat(word(32), word(2), word(1))},
+		{"array element", "(address[])", concat(word(32), word(1), bytes.Repeat([]byte{0xff}, 32))},
+		{"second offset", "(bytes,uint256[])", concat(word(64), word(160), word(0), word(0), word(0))},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := decodeABIArgs(mustParseABITypes(tc.types), tc.input); err == nil {
+				t.Fatalf("expected malformed input to fail")
+			}
+		})
+	}
+}
+
+func TestParseABITypesInvalid(t *testing.T) {
+	for _, types := range []string{"(uint7)", "(uint512)", "(bytes0)", "(bytes33)", "(int256)", "(bytes[])", "(uint256[2])", "uint256"} {
+		if _, err := parseABITypes(types); err == nil {
+			t.Fatalf("expected %s to be rejected", types)
+		}
+	}
+}
+
+func TestLibRunRevertsOnMalformedInput(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	addr := common.Address{}
+	ct := verifyCiphertextInTestMemory(environment, 2, environment.depth, tfhe.FheUint8).GetHash()
+	// the scalar byte isn't padded
+	input := toLibPrecompileInput("fheAdd(uint256,uint256,bytes1)", false, ct, ct)
+	input = input[:len(input)-31]
+	if gas := FheLibRequiredGas(environment, 0, input); gas != 0 {
+		t.Fatalf("expected no gas for malformed input, got %d", gas)
+	}
+	out, err := FheLibRun(environment, addr, addr, input, false)
+	if !errors.Is(err, ErrExecutionReverted) {
+		t.Fatalf("expected a revert, got %v", err)
+	}
+	if !bytes.Equal(out[0:4], errorSelector) {
+		t.Fatalf("expected an Error(string) revert, got %x", out)
+	}
+	reason, err := decodeABIArgs(mustParseABITypes("(string)"), out[4:])
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !strings.HasPrefix(string(reason.bytes(0)), "fheAdd: ") {
+		t.Fatalf("unexpected revert reason %q", reason.bytes(0))
+	}
+
+	_, err = TeeLibRun(environment, addr, addr, toLibPrecompileInput("teeNeg(uint256)", false, ct), false)
+	if !errors.Is(err, ErrExecutionReverted) {
+		t.Fatalf("expected a revert on trailing bytes, got %v", err)
+	}
+}
+
+func TestLibRunAcceptsAuthorization(t *testing.T) {
+	method, found := GetFheLibMethod(makeKeccakSignature("decrypt(uint256)"))
+	if !found {
+		t.Fatalf("decrypt not found")
+	}
+	handle := common.HexToHash("0x1234").Bytes()
+	if _, err := method.decodeInput(concat(handle, make([]byte, authorizationLen))); err != nil {
+		t.Fatalf("expected an authorization to be accepted: %s", err.Error())
+	}
+	if _, err := method.decodeInput(concat(handle, make([]byte, authorizationLen-1))); err == nil {
+		t.Fatalf("expected a truncated authorization to fail")
+	}
+	add, _ := GetFheLibMethod(makeKeccakSignature("fheAdd(uint256,uint256,bytes1)"))
+	if _, err := add.decodeInput(concat(handle, handle, make([]byte, 32+authorizationLen))); err == nil {
+		t.Fatalf("expected an authorization to be rejected by methods that don't take one")
+	}
+}
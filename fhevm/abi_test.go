@@ -0,0 +1,108 @@
+package fhevm
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLibArtifactsUpToDate(t *testing.T) {
+	artifacts, err := LibArtifacts()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for name, generated := range artifacts {
+		committed, err := os.ReadFile(filepath.Join("abi", name))
+		if err != nil {
+			t.Fatalf("%s: %s, run go generate ./fhevm", name, err.Error())
+		}
+		if !bytes.Equal(committed, generated) {
+			t.Errorf("abi/%s is out of date with the registered methods, run go generate ./fhevm", name)
+		}
+	}
+}
+
+func TestLibMethodIntrospection(t *testing.T) {
+	methods := FindFheLibMethods("fheAdd")
+	if len(methods) == 0 {
+		t.Fatalf("expected fheAdd to be found")
+	}
+	var fheAdd *FheLibMethod
+	for _, method := range methods {
+		if method.ArgTypes() == "(uint256,uint256,bytes1)" {
+			fheAdd = method
+		}
+	}
+	if fheAdd == nil {
+		t.Fatalf("expected the built-in fheAdd overload to be found")
+	}
+	if !fheAdd.IsBuiltin() || fheAdd.Backend() != DeferrableFheBackend || fheAdd.WritesState() {
+		t.Fatalf("unexpected fheAdd metadata")
+	}
+	if fheAdd.ReturnTypes() != "(uint256)" || fheAdd.GasCategory() != "FheAddSub" {
+		t.Fatalf("unexpected fheAdd types %s or gas category %s", fheAdd.ReturnTypes(), fheAdd.GasCategory())
+	}
+	if found, ok := GetFheLibMethod(fheAdd.Signature()); !ok || found != fheAdd {
+		t.Fatalf("expected the lookup by selector to return the same method")
+	}
+	all := FheLibMethods()
+	for i := 1; i < len(all); i++ {
+		if all[i-1].Name()+all[i-1].ArgTypes() >= all[i].Name()+all[i].ArgTypes() {
+			t.Fatalf("expected the methods to be sorted by signature")
+		}
+	}
+	allow := FindTeeLibMethods("allow")
+	if len(allow) != 1 || !allow[0].WritesState() || allow[0].ReturnTypes() != "()" {
+		t.Fatalf("unexpected teeLib allow metadata")
+	}
+	if len(FindTeeLibMethods("fheAdd")) != 0 {
+		t.Fatalf("expected fheAdd not to be a teeLib method")
+	}
+	for _, method := range append(FheLibMethods(), TeeLibMethods()...) {
+		if method.IsBuiltin() && method.GasCategory() == "" {
+			t.Errorf("built-in method %s%s has no gas category", method.Name(), method.ArgTypes())
+		}
+	}
+}
+
+func TestLibABIStateMutability(t *testing.T) {
+	expected := map[string]map[string]string{
+		"fheLib": {
+			"fheAdd":                "nonpayable",
+			"fheRand":               "nonpayable",
+			"fheRandBounded":        "nonpayable",
+			"allow":                 "nonpayable",
+			"allowTransient":        "nonpayable",
+			"isAllowed":             "view",
+			"decrypt":               "view",
+			"getCiphertext":         "view",
+			"isPubliclyDecryptable": "view",
+		},
+		"teeLib": {
+			"teeAdd":         "nonpayable",
+			"allowTransient": "nonpayable",
+			"isAllowed":      "view",
+			"teeDecrypt":     "view",
+		},
+	}
+	for lib, methods := range map[string][]*FheLibMethod{"fheLib": FheLibMethods(), "teeLib": TeeLibMethods()} {
+		out, err := LibABI(methods)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		var functions []abiFunction
+		if err := json.Unmarshal(out, &functions); err != nil {
+			t.Fatalf(err.Error())
+		}
+		for _, function := range functions {
+			if mutability, ok := expected[lib][function.Name]; ok && function.StateMutability != mutability {
+				t.Errorf("expected %s %s to be %s, got %s", lib, function.Name, mutability, function.StateMutability)
+			}
+			if function.StateMutability != "view" && function.StateMutability != "nonpayable" {
+				t.Errorf("unexpected state mutability %s of %s %s", function.StateMutability, lib, function.Name)
+			}
+		}
+	}
+}
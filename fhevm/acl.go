@@ -0,0 +1,211 @@
+package fhevm
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	fhevm_crypto "github.com/zama-ai/fhevm-go/fhevm/crypto"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Access-control lists for ciphertext handles.
+//
+// ACL entries live in the protected storage of a well-known ACL address and survive across
+// transactions. An entry for (handle, address) is stored at keccak256(handle || address) and is
+// set to the protected storage `flag` value when the address is allowed to use the handle.
+var aclContractAddress = common.BytesToAddress([]byte{94})
+
+func getAclProtectedStorage() common.Address {
+	return fhevm_crypto.CreateProtectedStorageContractAddress(aclContractAddress)
+}
+
+func getAclKey(handle common.Hash, address common.Address) common.Hash {
+	return crypto.Keccak256Hash(handle.Bytes(), address.Bytes())
+}
+
+// Persistently allow the given address to use the ciphertext handle.
+func allowHandle(environment EVMEnvironment, handle common.Hash, address common.Address) {
+	aclKey := getAclKey(handle, address)
+	protectedStorage := getAclProtectedStorage()
+	if environment.GetState(protectedStorage, aclKey) != flag {
+		environment.SetState(protectedStorage, aclKey, flag)
+	}
+}
+
+// Returns true if the given address is allowed to use the ciphertext handle.
+func isHandleAllowed(environment EVMEnvironment, handle common.Hash, address common.Address) bool {
+	return environment.GetState(getAclProtectedStorage(), getAclKey(handle, address)) == flag
+}
+
+// A transient allowance lets an address use a handle until the end of the current transaction. Allowances
+// aren't journaled: like the handles verified in a call, a grant made in a call that reverts lasts until the end
+// of the transaction.
+type transientAllowance struct {
+	handle  common.Hash
+	address common.Address
+}
+
+// Allow the given address to use the ciphertext handle until the end of the transaction.
+func allowHandleTransiently(environment EVMEnvironment, handle common.Hash, address common.Address) {
+	environment.FhevmData().transientAllowances[transientAllowance{handle, address}] = struct{}{}
+}
+
+// Returns true if the given address is allowed to use the ciphertext handle in the current transaction.
+func isHandleAllowedTransiently(environment EVMEnvironment, handle common.Hash, address common.Address) bool {
+	_, ok := environment.FhevmData().transientAllowances[transientAllowance{handle, address}]
+	return ok
+}
+
+// Returns true if the given address is allowed to use the ciphertext handle, either persistently or transiently.
+func isHandleUsable(environment EVMEnvironment, handle common.Hash, address common.Address) bool {
+	return isHandleAllowedTransiently(environment, handle, address) || isHandleAllowed(environment, handle, address)
+}
+
+// Clear all transient allowances, and the time locks of results that weren't persisted. Must be called at
+// the end of each transaction, unless the host creates a new FhevmData for each transaction.
+func ClearTransientAllowances(environment EVMEnvironment) {
+	environment.FhevmData().transientAllowances = make(map[transientAllowance]struct{})
+	environment.FhevmData().transientUnlockHeights = make(map[common.Hash]*big.Int)
+}
+
+// Import at the current depth the ciphertext handles in a precompile input that the caller is transiently allowed to use.
+// Only 32-byte words are considered, as handles are always ABI-encoded as uint256.
+func importTransientlyAllowedHandles(environment EVMEnvironment, caller common.Address, input []byte) {
+	if len(environment.FhevmData().transientAllowances) == 0 {
+		return
+	}
+	for i := 0; i+32 <= len(input); i += 32 {
+		handle := common.BytesToHash(input[i : i+32])
+		ct, ok := environment.FhevmData().verifiedCiphertexts[handle]
+		if ok && isHandleAllowedTransiently(environment, handle, caller) {
+			ct.verifiedDepths.add(environment.GetDepth())
+		}
+	}
+}
+
+// Allow the caller to use the result of an operation, which inherits the time lock of its operands. Results are
+// only allowed until the end of the transaction and their time lock is kept in memory, so that intermediate values
+// don't write to the state: both are persisted when the result is stored with SSTORE or allowed with allow.
+func allowResult(environment EVMEnvironment, caller common.Address, result common.Hash, operands []common.Hash) {
+	inheritUnlockHeight(environment, result, operands)
+	allowHandleTransiently(environment, result, caller)
+}
+
+// Import at the current depth, while the gas of a precompile input is computed, the handles in the input that are
+// transiently allowed to any address. Gas functions don't know the caller, and operands only need to be verified for
+// their types to be priced: Run fails if the caller isn't allowed. Returns a function that undoes the import.
+func importTransientlyAllowedHandlesForGas(environment EVMEnvironment, input []byte) func() {
+	if len(environment.FhevmData().transientAllowances) == 0 {
+		return func() {}
+	}
+	allowed := make(map[common.Hash]struct{})
+	for allowance := range environment.FhevmData().transientAllowances {
+		allowed[allowance.handle] = struct{}{}
+	}
+	depth := environment.GetDepth()
+	var imported []*verifiedCiphertext
+	for i := 0; i+32 <= len(input); i += 32 {
+		handle := common.BytesToHash(input[i : i+32])
+		ct, ok := environment.FhevmData().verifiedCiphertexts[handle]
+		if _, transient := allowed[handle]; ok && transient && !ct.verifiedDepths.has(depth) {
+			ct.verifiedDepths.add(depth)
+			imported = append(imported, ct)
+		}
+	}
+	return func() {
+		for _, ct := range imported {
+			ct.verifiedDepths.del(depth)
+		}
+	}
+}
+
+// If the output of a precompile is a ciphertext handle verified at the current depth, allow the caller to use it.
+// The handles in the input are its operands.
+func allowResultToCaller(environment EVMEnvironment, caller common.Address, input []byte, ret []byte) {
+	if len(ret) != 32 {
+		return
+	}
+	handle := common.BytesToHash(ret)
+	if getVerifiedCiphertextFromEVM(environment, handle) != nil {
+		allowResult(environment, caller, handle, inputHandles(environment, input))
+	}
+}
+
+func aclAllowRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	logger := environment.GetLogger()
+	if readOnly {
+		return nil, ErrWriteProtection
+	}
+
+	handle := args.word(0)
+	address := args.address(1)
+
+	ct := getVerifiedCiphertextFromEVM(environment, handle)
+	if ct == nil {
+		msg := "allow unverified ciphertext handle"
+		logger.Error(msg, "handle", handle.Hex())
+		return nil, errors.New(msg)
+	}
+	if !isHandleUsable(environment, handle, caller) {
+		msg := "allow caller is not allowed to use the ciphertext handle"
+		logger.Error(msg, "handle", handle.Hex(), "caller", caller.Hex())
+		return nil, errors.New(msg)
+	}
+	otelDescribeOperandsFheTypes(runSpan, ct.fheUintType())
+
+	allowHandle(environment, handle, address)
+	persistUnlockHeight(environment, handle)
+	if environment.IsCommitting() {
+		logger.Info("allow success",
+			"handle", handle.Hex(),
+			"address", address.Hex(),
+		)
+	}
+	return nil, nil
+}
+
+func aclAllowTransientRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	logger := environment.GetLogger()
+	if readOnly {
+		return nil, ErrWriteProtection
+	}
+
+	handle := args.word(0)
+	address := args.address(1)
+
+	ct := getVerifiedCiphertextFromEVM(environment, handle)
+	if ct == nil {
+		msg := "allowTransient unverified ciphertext handle"
+		logger.Error(msg, "handle", handle.Hex())
+		return nil, errors.New(msg)
+	}
+	if !isHandleUsable(environment, handle, caller) {
+		msg := "allowTransient caller is not allowed to use the ciphertext handle"
+		logger.Error(msg, "handle", handle.Hex(), "caller", caller.Hex())
+		return nil, errors.New(msg)
+	}
+	otelDescribeOperandsFheTypes(runSpan, ct.fheUintType())
+
+	allowHandleTransiently(environment, handle, address)
+	if environment.IsCommitting() {
+		logger.Info("allowTransient success",
+			"handle", handle.Hex(),
+			"address", address.Hex(),
+		)
+	}
+	return nil, nil
+}
+
+func aclIsAllowedRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	handle := args.word(0)
+	address := args.address(1)
+
+	// Always return a 32-byte big-endian boolean.
+	ret := make([]byte, 32)
+	if isHandleUsable(environment, handle, address) {
+		ret[31] = 1
+	}
+	return ret, nil
+}
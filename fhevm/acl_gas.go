@@ -0,0 +1,13 @@
+package fhevm
+
+func aclAllowRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
+	return environment.FhevmParams().GasCosts.AclAllow
+}
+
+func aclAllowTransientRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
+	return environment.FhevmParams().GasCosts.AclAllowTransient
+}
+
+func aclIsAllowedRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
+	return environment.FhevmParams().GasCosts.AclIsAllowed
+}
@@ -0,0 +1,378 @@
+package fhevm
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+	fhevm_crypto "github.com/zama-ai/fhevm-go/fhevm/crypto"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+func toAclPrecompileInput(method string, handle common.Hash, address common.Address) []byte {
+	ret := crypto.Keccak256([]byte(method))[0:4]
+	ret = append(ret, handle.Bytes()...)
+	ret = append(ret, common.BytesToHash(address.Bytes()).Bytes()...)
+	return ret
+}
+
+func TestAclResultAllowedToCaller(t *testing.T) {
+	depth := 1
+	environment := newTestEVMEnvironment()
+	environment.depth = depth
+	caller := common.HexToAddress("0x1000")
+	other := common.HexToAddress("0x2000")
+	lhsHash := verifyCiphertextInTestMemory(environment, 2, depth, tfhe.FheUint8).GetHash()
+	rhsHash := verifyCiphertextInTestMemory(environment, 3, depth, tfhe.FheUint8).GetHash()
+	input := toLibPrecompileInput("fheAdd(uint256,uint256,bytes1)", false, lhsHash, rhsHash)
+	out, err := FheLibRun(environment, caller, caller, input, false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	result := common.BytesToHash(out)
+	if !isHandleAllowedTransiently(environment, result, caller) {
+		t.Fatalf("expected result to be allowed to the caller")
+	}
+	if isHandleUsable(environment, result, other) {
+		t.Fatalf("expected result not to be allowed to another address")
+	}
+	if isHandleAllowed(environment, result, caller) {
+		t.Fatalf("expected the result not to be written to the ACL before it is stored")
+	}
+}
+
+func TestAclSstorePersistsResult(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	environment.blockNumber = 50
+	pc := uint64(0)
+	contract := testContractAddress{}.Address()
+	locked := verifyCiphertextInTestMemory(environment, 42, environment.depth, tfhe.FheUint8).GetHash()
+	allowHandle(environment, locked, contract)
+	if _, err := FheLibRun(environment, contract, contract, toSetUnlockHeightInput(locked, 100), false); err != nil {
+		t.Fatalf(err.Error())
+	}
+	out, err := FheLibRun(environment, contract, contract, toLibPrecompileInput("fheAdd(uint256,uint256,bytes1)", true, locked, common.Hash{}), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	result := common.BytesToHash(out)
+	protectedStorage := getAclProtectedStorage()
+	if environment.GetState(protectedStorage, getAclKey(result, contract)) != (common.Hash{}) || environment.GetState(protectedStorage, getUnlockHeightKey(result)) != (common.Hash{}) {
+		t.Fatalf("expected an intermediate result not to write the ACL")
+	}
+
+	scope := newTestScopeConext()
+	scope.pushToStack(uint256FromBig(result.Big()))
+	scope.pushToStack(uint256.NewInt(0))
+	if _, err := OpSstore(&pc, environment, scope); err != nil {
+		t.Fatalf(err.Error())
+	}
+	ClearTransientAllowances(environment)
+	if !isHandleAllowed(environment, result, contract) {
+		t.Fatalf("expected a stored result to be allowed persistently")
+	}
+	if height := getHandleUnlockHeight(environment, result); height.Uint64() != 100 {
+		t.Fatalf("expected the time lock of a stored result to be persisted, got %v", height)
+	}
+}
+
+func TestAclAllowAndIsAllowed(t *testing.T) {
+	depth := 1
+	environment := newTestEVMEnvironment()
+	environment.depth = depth
+	caller := common.HexToAddress("0x1000")
+	other := common.HexToAddress("0x2000")
+	handle := verifyCiphertextInTestMemory(environment, 2, depth, tfhe.FheUint8).GetHash()
+	allowHandle(environment, handle, caller)
+
+	out, err := FheLibRun(environment, caller, caller, toAclPrecompileInput("isAllowed(uint256,address)", handle, other), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if out[31] != 0 {
+		t.Fatalf("expected handle not to be allowed before allow()")
+	}
+
+	_, err = FheLibRun(environment, caller, caller, toAclPrecompileInput("allow(uint256,address)", handle, other), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	out, err = FheLibRun(environment, caller, caller, toAclPrecompileInput("isAllowed(uint256,address)", handle, other), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if out[31] != 1 {
+		t.Fatalf("expected handle to be allowed after allow()")
+	}
+}
+
+func TestAclAllowRequiresAllowedCaller(t *testing.T) {
+	depth := 1
+	environment := newTestEVMEnvironment()
+	environment.depth = depth
+	caller := common.HexToAddress("0x1000")
+	other := common.HexToAddress("0x2000")
+	handle := verifyCiphertextInTestMemory(environment, 2, depth, tfhe.FheUint8).GetHash()
+
+	_, err := FheLibRun(environment, caller, caller, toAclPrecompileInput("allow(uint256,address)", handle, other), false)
+	if err == nil {
+		t.Fatalf("expected allow() to fail for a caller that is not allowed")
+	}
+	if isHandleAllowed(environment, handle, other) {
+		t.Fatalf("expected handle not to be allowed")
+	}
+}
+
+func TestAclAllowRequiresVerifiedHandle(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	caller := common.HexToAddress("0x1000")
+	other := common.HexToAddress("0x2000")
+	handle := verifyCiphertextInTestMemory(environment, 2, 2, tfhe.FheUint8).GetHash()
+	allowHandle(environment, handle, caller)
+
+	_, err := FheLibRun(environment, caller, caller, toAclPrecompileInput("allow(uint256,address)", handle, other), false)
+	if err == nil {
+		t.Fatalf("expected allow() to fail for a handle that is not verified at the current depth")
+	}
+}
+
+func TestAclSloadDoesNotVerifyNotAllowedHandle(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	pc := uint64(0)
+	environment.depth = 1
+	// The ciphertext is only verified at a different depth, e.g. in another contract's frame.
+	ct := verifyCiphertextInTestMemory(environment, 2, 2, tfhe.FheUint8)
+	scope := newTestScopeConext()
+	loc := uint256.NewInt(10)
+	value := uint256FromBig(ct.GetHash().Big())
+
+	scope.pushToStack(value)
+	scope.pushToStack(loc)
+	_, err := OpSstore(&pc, environment, scope)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	scope.pushToStack(loc)
+	_, err = OpSload(&pc, environment, scope)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if getVerifiedCiphertextFromEVM(environment, ct.GetHash()) != nil {
+		t.Fatalf("expected ciphertext not to be verified for a contract that is not allowed")
+	}
+}
+
+func TestAclSloadVerifiesHandlePersistedBeforeAcl(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	pc := uint64(0)
+	contract := testContractAddress{}.Address()
+	ct := verifyCiphertextInTestMemory(environment, 2, environment.depth, tfhe.FheUint8)
+	// Persisted without an ACL entry, like the handles stored before ACLs existed.
+	persistIfVerifiedCiphertext(crypto.Keccak256Hash([]byte{1}), ct.GetHash(), fhevm_crypto.CreateProtectedStorageContractAddress(contract), environment)
+	environment.SetState(contract, common.Hash{}, ct.GetHash())
+
+	fhevmData := NewFhevmData()
+	environment.fhevmData = &fhevmData
+	scope := newTestScopeConext()
+	scope.pushToStack(uint256.NewInt(0))
+	if _, err := OpSload(&pc, environment, scope); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if getVerifiedCiphertextFromEVM(environment, ct.GetHash()) == nil {
+		t.Fatalf("expected a ciphertext persisted in the contract's protected storage to be verified")
+	}
+}
+
+func TestAclAllowTransient(t *testing.T) {
+	depth := 1
+	environment := newTestEVMEnvironment()
+	environment.depth = depth
+	caller := common.HexToAddress("0x1000")
+	other := common.HexToAddress("0x2000")
+	handle := verifyCiphertextInTestMemory(environment, 2, depth, tfhe.FheUint8).GetHash()
+	allowHandle(environment, handle, caller)
+
+	_, err := FheLibRun(environment, caller, caller, toAclPrecompileInput("allowTransient(uint256,address)", handle, other), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if isHandleAllowed(environment, handle, other) {
+		t.Fatalf("expected transient allowance not to be persisted")
+	}
+	out, err := FheLibRun(environment, caller, caller, toAclPrecompileInput("isAllowed(uint256,address)", handle, other), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if out[31] != 1 {
+		t.Fatalf("expected handle to be allowed after allowTransient()")
+	}
+
+	ClearTransientAllowances(environment)
+	if isHandleUsable(environment, handle, other) {
+		t.Fatalf("expected transient allowance to be cleared")
+	}
+}
+
+func TestAclTransientAllowanceMakesHandleAvailable(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	caller := common.HexToAddress("0x1000")
+	// The operands are only verified at a different depth and are not passed through calldata.
+	lhsHash := verifyCiphertextInTestMemory(environment, 2, 2, tfhe.FheUint8).GetHash()
+	rhsHash := verifyCiphertextInTestMemory(environment, 3, 2, tfhe.FheUint8).GetHash()
+	input := toLibPrecompileInput("fheAdd(uint256,uint256,bytes1)", false, lhsHash, rhsHash)
+
+	importTransientlyAllowedHandles(environment, caller, input[4:])
+	if getVerifiedCiphertextFromEVM(environment, lhsHash) != nil {
+		t.Fatalf("expected handle not to be available without a transient allowance")
+	}
+
+	allowHandleTransiently(environment, lhsHash, caller)
+	allowHandleTransiently(environment, rhsHash, caller)
+	out, err := FheLibRun(environment, caller, caller, input, false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	res := getVerifiedCiphertextFromEVM(environment, common.BytesToHash(out))
+	if res == nil {
+		t.Fatalf("output ciphertext is not found in verifiedCiphertexts")
+	}
+	decrypted, err := res.ciphertext.Decrypt()
+	if err != nil || decrypted.Uint64() != 5 {
+		t.Fatalf("invalid decrypted result, decrypted %v != expected %v", decrypted.Uint64(), 5)
+	}
+}
+
+func TestAclTransientAllowanceGas(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	caller := common.HexToAddress("0x1000")
+	lhs := verifyCiphertextInTestMemory(environment, 2, environment.depth, tfhe.FheUint8).GetHash()
+	rhs := verifyCiphertextInTestMemory(environment, 3, environment.depth, tfhe.FheUint8).GetHash()
+	input := toLibPrecompileInput("fheAdd(uint256,uint256,bytes1)", false, lhs, rhs)
+	expected := FheLibRequiredGas(environment, 0, input)
+	if expected == 0 {
+		t.Fatalf("expected fheAdd to cost gas")
+	}
+
+	// The same operands, only granted with allowTransient, are priced the same.
+	transientLhs := verifyCiphertextInTestMemory(environment, 4, 2, tfhe.FheUint8).GetHash()
+	transientRhs := verifyCiphertextInTestMemory(environment, 5, 2, tfhe.FheUint8).GetHash()
+	allowHandleTransiently(environment, transientLhs, caller)
+	allowHandleTransiently(environment, transientRhs, caller)
+	input = toLibPrecompileInput("fheAdd(uint256,uint256,bytes1)", false, transientLhs, transientRhs)
+	if gas := FheLibRequiredGas(environment, 0, input); gas != expected {
+		t.Fatalf("expected gas %d for transiently allowed operands, got %d", expected, gas)
+	}
+	if getVerifiedCiphertextFromEVM(environment, transientLhs) != nil {
+		t.Fatalf("expected the gas function not to import the operands")
+	}
+	if _, err := FheLibRun(environment, caller, caller, input, false); err != nil {
+		t.Fatalf(err.Error())
+	}
+}
+
+func TestAclReadOnlyResultIsAllowedTransiently(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	environment.blockNumber = 50
+	contract := common.HexToAddress("0x1000")
+	locked := verifyCiphertextInTestMemory(environment, 42, environment.depth, tfhe.FheUint8).GetHash()
+	allowHandle(environment, locked, contract)
+	if _, err := FheLibRun(environment, contract, contract, toSetUnlockHeightInput(locked, 100), false); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	out, err := FheLibRun(environment, contract, contract, toLibPrecompileInput("fheAdd(uint256,uint256,bytes1)", true, locked, common.Hash{}), true)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	result := common.BytesToHash(out)
+	protectedStorage := getAclProtectedStorage()
+	if environment.GetState(protectedStorage, getAclKey(result, contract)) != (common.Hash{}) || environment.GetState(protectedStorage, getUnlockHeightKey(result)) != (common.Hash{}) {
+		t.Fatalf("expected a read-only call not to write the ACL")
+	}
+	if !isHandleAllowedTransiently(environment, result, contract) {
+		t.Fatalf("expected the result of a read-only call to be allowed transiently to the caller")
+	}
+	if _, err := FheLibRun(environment, contract, contract, toDecryptInput("decrypt(uint256)", result, nil), true); err == nil {
+		t.Fatalf("expected the result of a read-only call to inherit the time lock of its operand")
+	}
+}
+
+func TestAclSstoreAllowsOnlyHandlesProducedInFrame(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	pc := uint64(0)
+	contract := testContractAddress{}.Address()
+	// The caller's handle, passed to the contract as a call argument.
+	delegated := verifyCiphertextInTestMemory(environment, 2, environment.depth, tfhe.FheUint8).GetHash()
+	DelegateCiphertextHandlesInArgs(environment, delegated.Bytes())
+	environment.depth = 2
+	produced := verifyCiphertextInTestMemory(environment, 3, environment.depth, tfhe.FheUint8).GetHash()
+
+	scope := newTestScopeConext()
+	for i, handle := range []common.Hash{delegated, produced} {
+		scope.pushToStack(uint256FromBig(handle.Big()))
+		scope.pushToStack(uint256.NewInt(uint64(i)))
+		if _, err := OpSstore(&pc, environment, scope); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	if isHandleAllowed(environment, delegated, contract) {
+		t.Fatalf("expected a handle received from the caller not to be allowed by SSTORE")
+	}
+	if getCiphertextFromProtectedStoage(environment, contract, delegated) != nil {
+		t.Fatalf("expected a handle the contract isn't allowed to use not to be persisted")
+	}
+	if !isHandleAllowed(environment, produced, contract) {
+		t.Fatalf("expected a handle produced in the frame to be allowed by SSTORE")
+	}
+
+	// The handle is returned to the caller, which passes it to another frame at the same depth. That frame didn't
+	// produce it.
+	delegateCiphertextHandlesToCaller(environment, produced.Bytes())
+	RemoveVerifiedCipherextsAtCurrentDepth(environment)
+	environment.depth = 1
+	DelegateCiphertextHandlesInArgs(environment, produced.Bytes())
+	environment.depth = 2
+	if ct := getVerifiedCiphertextFromEVM(environment, produced); ct == nil || ct.producerDepth == environment.depth {
+		t.Fatalf("expected the handle to be verified in the new frame without being produced there")
+	}
+}
+
+func TestAclGetCiphertextRequiresAllowance(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	pc := uint64(0)
+	contract := testContractAddress{}.Address()
+	handle := verifyCiphertextInTestMemory(environment, 2, environment.depth, tfhe.FheUint8).GetHash()
+	scope := newTestScopeConext()
+	scope.pushToStack(uint256FromBig(handle.Big()))
+	scope.pushToStack(uint256.NewInt(10))
+	if _, err := OpSstore(&pc, environment, scope); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	environment.ethCall = true
+	input := append(crypto.Keccak256([]byte("getCiphertext(address,uint256)"))[0:4], common.BytesToHash(contract.Bytes()).Bytes()...)
+	input = append(input, handle.Bytes()...)
+	other := common.HexToAddress("0x2000")
+	if _, err := FheLibRun(environment, other, other, input, true); err == nil {
+		t.Fatalf("expected getCiphertext to fail for a caller that is not allowed to use the handle")
+	}
+	if out, err := FheLibRun(environment, contract, contract, input, true); err != nil || len(out) == 0 {
+		t.Fatalf("expected getCiphertext to return the ciphertext to the contract, got %v", err)
+	}
+	allowHandle(environment, handle, other)
+	if out, err := FheLibRun(environment, other, other, input, true); err != nil || len(out) == 0 {
+		t.Fatalf("expected getCiphertext to return the ciphertext to an allowed caller, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package fhevm
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Decryptions and re-encryptions on eth_call must be authorized by the user with an EIP-712 signature.
+//
+// The authorization is appended to the precompile input, right after the regular arguments:
+//
+//	expiry (32 bytes, unix seconds) || signature (65 bytes, r || s || v)
+//
+// The signed message is the EIP-712 typed data below, where `contract` is the caller of the precompile
+// and `publicKey` is zero for plain decryptions. The domain's chain ID is the ID of the chain and its verifying
+// contract is the precompile address. The expiry is compared with the timestamp of the block. Both the signer and
+// the contract must be allowed to use the handle.
+const authorizationLen = 32 + crypto.SignatureLength
+
+var authorizationDomainTypeHash = crypto.Keccak256Hash([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+var authorizationTypeHash = crypto.Keccak256Hash([]byte("Authorization(uint256 handle,address contract,bytes32 publicKey,uint256 expiry)"))
+var authorizationDomainName = crypto.Keccak256Hash([]byte("fhEVM"))
+var authorizationDomainVersion = crypto.Keccak256Hash([]byte("1"))
+
+// Returns the EIP-712 digest of a struct hash in the fhEVM domain of the given chain and verifying contract.
+func eip712Digest(chainID *big.Int, verifyingContract common.Address, structHash common.Hash) common.Hash {
+	domainSeparator := crypto.Keccak256Hash(
+		authorizationDomainTypeHash.Bytes(),
+		authorizationDomainName.Bytes(),
+		authorizationDomainVersion.Bytes(),
+		common.BigToHash(chainID).Bytes(),
+		common.BytesToHash(verifyingContract.Bytes()).Bytes(),
+	)
+	return crypto.Keccak256Hash([]byte{0x19, 0x01}, domainSeparator.Bytes(), structHash.Bytes())
+}
+
+// Returns the EIP-712 digest that the user signs to authorize a decryption or re-encryption.
+func authorizationDigest(chainID *big.Int, precompile common.Address, handle common.Hash, contract common.Address, publicKey common.Hash, expiry *big.Int) common.Hash {
+	structHash := crypto.Keccak256Hash(
+		authorizationTypeHash.Bytes(),
+		handle.Bytes(),
+		common.BytesToHash(contract.Bytes()).Bytes(),
+		publicKey.Bytes(),
+		common.BigToHash(expiry).Bytes(),
+	)
+	return eip712Digest(chainID, precompile, structHash)
+}
+
+// Verify the authorization appended to a decryption or re-encryption input on eth_call.
+// Returns the address of the user that signed it.
+func verifyAuthorization(environment EVMEnvironment, caller common.Address, precompile common.Address, handle common.Hash, publicKey common.Hash, authorization []byte) (common.Address, error) {
+	signer, err := recoverAuthorizationSigner(environment, precompile, handle, caller, publicKey, authorization)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	logger := environment.GetLogger()
+	if !isHandleUsable(environment, handle, signer) {
+		msg := "authorization signer is not allowed to use the ciphertext handle"
+		logger.Error(msg, "handle", handle.Hex(), "signer", signer.Hex())
+		return common.Address{}, errors.New(msg)
+	}
+	if !isHandleUsable(environment, handle, caller) {
+		msg := "authorization contract is not allowed to use the ciphertext handle"
+		logger.Error(msg, "handle", handle.Hex(), "contract", caller.Hex())
+		return common.Address{}, errors.New(msg)
+	}
+	return signer, nil
+}
+
+// Check the expiry of an authorization and return the address that signed it for the given contract.
+func recoverAuthorizationSigner(environment EVMEnvironment, precompile common.Address, handle common.Hash, contract common.Address, publicKey common.Hash, authorization []byte) (common.Address, error) {
+	authorization = authorization[:minInt(authorizationLen, len(authorization))]
+
+	logger := environment.GetLogger()
+	if len(authorization) != authorizationLen {
+		msg := "authorization must contain a 32-byte expiry and a 65-byte signature"
+		logger.Error(msg, "len", len(authorization))
+		return common.Address{}, errors.New(msg)
+	}
+	expiry := new(big.Int).SetBytes(authorization[0:32])
+	if !expiry.IsUint64() || expiry.Uint64() <= environment.BlockTimestamp() {
+		msg := "authorization expired"
+		logger.Error(msg, "expiry", expiry, "blockTimestamp", environment.BlockTimestamp())
+		return common.Address{}, errors.New(msg)
+	}
+
+	digest := authorizationDigest(environment.ChainID(), precompile, handle, contract, publicKey, expiry)
+	signer, err := recoverSigner(digest, authorization[32:])
+	if err != nil {
+		msg := "authorization signature is invalid"
+		logger.Error(msg, "err", err)
+		return common.Address{}, errors.New(msg)
+	}
+	return signer, nil
+}
+
+// Recover the address that signed the digest. Accepts both the Ethereum (27/28) and the raw (0/1) recovery id.
+func recoverSigner(digest common.Hash, signature []byte) (common.Address, error) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, errors.New("signature must be 65 bytes")
+	}
+	sig := make([]byte, crypto.SignatureLength)
+	copy(sig, signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pubKey, err := crypto.SigToPub(digest.Bytes(), sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
@@ -0,0 +1,204 @@
+package fhevm
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+	"golang.org/x/crypto/nacl/box"
+)
+
+func signAuthorization(t *testing.T, chainID *big.Int, key *ecdsa.PrivateKey, precompile common.Address, handle common.Hash, contract common.Address, publicKey common.Hash, expiry int64) []byte {
+	expiryInt := big.NewInt(expiry)
+	digest := authorizationDigest(chainID, precompile, handle, contract, publicKey, expiryInt)
+	signature, err := crypto.Sign(digest.Bytes(), key)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	// Use the Ethereum recovery id, as wallets do.
+	signature[crypto.RecoveryIDOffset] += 27
+	return append(common.BigToHash(expiryInt).Bytes(), signature...)
+}
+
+func toDecryptInput(method string, handle common.Hash, authorization []byte) []byte {
+	input := crypto.Keccak256([]byte(method))[0:4]
+	input = append(input, handle.Bytes()...)
+	return append(input, authorization...)
+}
+
+func TestDecryptEthCallAuthorization(t *testing.T) {
+	user, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	userAddr := crypto.PubkeyToAddress(user.PublicKey)
+	contract := common.HexToAddress("0x1000")
+	precompile := common.HexToAddress("0x5d")
+	expiry := int64(testBlockTimestamp) + 3600
+
+	testcases := []struct {
+		description   string
+		allowUser     bool
+		allowContract bool
+		expiry        int64
+		signed        bool
+		chainID       int64
+		succeeds      bool
+	}{
+		{"Authorized", true, true, expiry, true, 9000, true},
+		{"NoAuthorization", true, true, expiry, false, 9000, false},
+		{"UserNotAllowed", false, true, expiry, true, 9000, false},
+		{"ContractNotAllowed", true, false, expiry, true, 9000, false},
+		// Expired at block time, even though the wall clock is earlier.
+		{"Expired", true, true, int64(testBlockTimestamp) - 3600, true, 9000, false},
+		{"OtherChain", true, true, expiry, true, 1, false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			depth := 1
+			environment := newTestEVMEnvironment()
+			environment.depth = depth
+			environment.ethCall = true
+			environment.chainID = 9000
+			handle := verifyCiphertextInTestMemory(environment, 42, depth, tfhe.FheUint8).GetHash()
+			if tc.allowUser {
+				allowHandle(environment, handle, userAddr)
+			}
+			if tc.allowContract {
+				allowHandle(environment, handle, contract)
+			}
+			var authorization []byte
+			if tc.signed {
+				authorization = signAuthorization(t, big.NewInt(tc.chainID), user, precompile, handle, contract, common.Hash{}, tc.expiry)
+			}
+
+			out, err := FheLibRun(environment, contract, precompile, toDecryptInput("decrypt(uint256)", handle, authorization), false)
+			if !tc.succeeds {
+				if err == nil {
+					t.Fatalf("expected decrypt to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			if new(big.Int).SetBytes(out).Uint64() != 42 {
+				t.Fatalf("decrypt result not equal to value, result %v != value %v", new(big.Int).SetBytes(out).Uint64(), 42)
+			}
+		})
+	}
+}
+
+func TestDecryptEthCallAuthorizationForAnotherHandle(t *testing.T) {
+	user, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	userAddr := crypto.PubkeyToAddress(user.PublicKey)
+	contract := common.HexToAddress("0x1000")
+	precompile := common.HexToAddress("0x5d")
+	depth := 1
+	environment := newTestEVMEnvironment()
+	environment.depth = depth
+	environment.ethCall = true
+	handle := verifyCiphertextInTestMemory(environment, 42, depth, tfhe.FheUint8).GetHash()
+	otherHandle := verifyCiphertextInTestMemory(environment, 43, depth, tfhe.FheUint8).GetHash()
+	for _, h := range []common.Hash{handle, otherHandle} {
+		allowHandle(environment, h, userAddr)
+		allowHandle(environment, h, contract)
+	}
+
+	authorization := signAuthorization(t, environment.ChainID(), user, precompile, otherHandle, contract, common.Hash{}, int64(testBlockTimestamp)+3600)
+	_, err = FheLibRun(environment, contract, precompile, toDecryptInput("decrypt(uint256)", handle, authorization), false)
+	if err == nil {
+		t.Fatalf("expected decrypt to fail with an authorization for another handle")
+	}
+}
+
+func TestTeeDecryptEthCallAuthorization(t *testing.T) {
+	user, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	userAddr := crypto.PubkeyToAddress(user.PublicKey)
+	contract := common.HexToAddress("0x1000")
+	precompile := common.HexToAddress("0x5e")
+	depth := 1
+	environment := newTestEVMEnvironment()
+	environment.depth = depth
+	environment.ethCall = true
+	ct, err := importTeePlaintextToEVM(environment, depth, uint64(42), tfhe.FheUint32)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	handle := ct.GetHash()
+
+	_, err = TeeLibRun(environment, contract, precompile, toDecryptInput("teeDecrypt(uint256)", handle, nil), false)
+	if err == nil {
+		t.Fatalf("expected teeDecrypt to fail without authorization")
+	}
+
+	allowHandle(environment, handle, userAddr)
+	allowHandle(environment, handle, contract)
+	authorization := signAuthorization(t, environment.ChainID(), user, precompile, handle, contract, common.Hash{}, int64(testBlockTimestamp)+3600)
+	out, err := TeeLibRun(environment, contract, precompile, toDecryptInput("teeDecrypt(uint256)", handle, authorization), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if new(big.Int).SetBytes(out).Uint64() != 42 {
+		t.Fatalf("teeDecrypt result not equal to value, result %v != value %v", new(big.Int).SetBytes(out).Uint64(), 42)
+	}
+}
+
+func TestReencryptEthCallAuthorization(t *testing.T) {
+	user, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	userAddr := crypto.PubkeyToAddress(user.PublicKey)
+	contract := common.HexToAddress("0x1000")
+	precompile := common.HexToAddress("0x5d")
+	boxPublicKey, boxPrivateKey, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	depth := 1
+	environment := newTestEVMEnvironment()
+	environment.depth = depth
+	environment.ethCall = true
+	handle := verifyCiphertextInTestMemory(environment, 42, depth, tfhe.FheUint8).GetHash()
+	allowHandle(environment, handle, userAddr)
+	allowHandle(environment, handle, contract)
+
+	input := toDecryptInput("reencrypt(uint256,uint256)", handle, boxPublicKey[:])
+	_, err = FheLibRun(environment, contract, precompile, input, false)
+	if err == nil {
+		t.Fatalf("expected reencrypt to fail without authorization")
+	}
+
+	// An authorization for another public key must not be accepted.
+	authorization := signAuthorization(t, environment.ChainID(), user, precompile, handle, contract, common.Hash{}, int64(testBlockTimestamp)+3600)
+	_, err = FheLibRun(environment, contract, precompile, append(input, authorization...), false)
+	if err == nil {
+		t.Fatalf("expected reencrypt to fail with an authorization for another public key")
+	}
+
+	authorization = signAuthorization(t, environment.ChainID(), user, precompile, handle, contract, common.Hash(*boxPublicKey), int64(testBlockTimestamp)+3600)
+	out, err := FheLibRun(environment, contract, precompile, append(input, authorization...), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	// The output is ABI-encoded bytes: offset, length and the sealed box.
+	length := new(big.Int).SetBytes(out[32:64]).Uint64()
+	plaintext, ok := box.OpenAnonymous(nil, out[64:64+length], boxPublicKey, boxPrivateKey)
+	if !ok {
+		t.Fatalf("failed to open the re-encrypted value")
+	}
+	if new(big.Int).SetBytes(plaintext).Uint64() != 42 {
+		t.Fatalf("reencrypt result not equal to value, result %v != value %v", new(big.Int).SetBytes(plaintext).Uint64(), 42)
+	}
+}
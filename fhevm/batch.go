@@ -0,0 +1,220 @@
+package fhevm
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Batches apply a binary operation element-wise to arrays of handles in one precompile call.
+//
+// The batch variant of a binary operation, e.g. fheAddBatch or teeAddBatch, takes (uint256[] lhs,
+// uint256[] rhs, bytes1 scalarByte) and returns the uint256[] handles of the results, which are allowed to the
+// caller. The arrays have the same length, unless scalarByte is 0x01, in which case rhs holds a single scalar
+// applied to every element. Operands are validated once: all lhs handles have the same type, as do all rhs
+// handles. The TEE backend decrypts the operands and encrypts the results in a single round trip each.
+const maxBatchLength = 1024
+
+var batchOperators = []struct {
+	name   string
+	opcode byte
+}{
+	{"Add", programAdd},
+	{"Sub", programSub},
+	{"Mul", programMul},
+	{"Div", programDiv},
+	{"Rem", programRem},
+	{"Min", programMin},
+	{"Max", programMax},
+	{"BitAnd", programAnd},
+	{"BitOr", programOr},
+	{"BitXor", programXor},
+	{"Shl", programShl},
+	{"Shr", programShr},
+	{"Rotl", programRotl},
+	{"Rotr", programRotr},
+	{"Eq", programEq},
+	{"Ne", programNe},
+	{"Ge", programGe},
+	{"Gt", programGt},
+	{"Le", programLe},
+	{"Lt", programLt},
+	{"BitTest", programBitTest},
+}
+
+// Returns the batch variants of the binary operations, named with the given prefix, e.g. fheAddBatch.
+func batchMethods(prefix string, isTee bool) []*FheLibMethod {
+	methods := make([]*FheLibMethod, 0, len(batchOperators))
+	for _, operator := range batchOperators {
+		methods = append(methods, &FheLibMethod{
+			name:                prefix + operator.name + "Batch",
+			argTypes:            "(uint256[],uint256[],bytes1)",
+			returnTypes:         "(uint256[])",
+			requiredGasFunction: batchRequiredGas(operator.opcode, isTee),
+			gasCategory:         batchGasCategory(operator.opcode, isTee),
+			runFunction:         batchRun(operator.opcode, isTee),
+		})
+	}
+	return methods
+}
+
+type batch struct {
+	instruction programInstruction
+	lhs         []common.Hash
+	rhs         []common.Hash
+	// The types of the lhs operands, the rhs operands and the results.
+	registers []programRegister
+}
+
+func (b *batch) isScalar() bool {
+	return b.registers[1].scalar
+}
+
+var batchArgs = mustParseABITypes("(uint256[],uint256[],bytes1)")
+
+// Decodes the (uint256[] lhs, uint256[] rhs, bytes1 scalarByte) input of a batch operation.
+func decodeBatchInput(input []byte) (lhs []common.Hash, rhs []common.Hash, isScalar bool, err error) {
+	args, err := decodeABIArgs(batchArgs, input)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return args.words(0), args.words(1), args.byte(2) == 1, nil
+}
+
+// Decode a batch operation and check that its operands are verified ciphertexts of matching types.
+func parseBatch(environment EVMEnvironment, opcode byte, input []byte, isTee bool) (*batch, error) {
+	lhs, rhs, isScalar, err := decodeBatchInput(input)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(lhs) == 0:
+		return nil, errors.New("batch is empty")
+	case len(lhs) > maxBatchLength:
+		return nil, fmt.Errorf("batch has more than %d elements", maxBatchLength)
+	case isScalar && len(rhs) != 1:
+		return nil, errors.New("scalar batch takes a single rhs scalar")
+	case !isScalar && len(rhs) != len(lhs):
+		return nil, fmt.Errorf("batch operands have different lengths %d and %d", len(lhs), len(rhs))
+	}
+	lhsType, err := batchOperandsType(environment, lhs)
+	if err != nil {
+		return nil, fmt.Errorf("lhs %w", err)
+	}
+	rhsRegister := programRegister{scalar: true}
+	if !isScalar {
+		rhsType, err := batchOperandsType(environment, rhs)
+		if err != nil {
+			return nil, fmt.Errorf("rhs %w", err)
+		}
+		rhsRegister = programRegister{fheUintType: rhsType}
+	}
+
+	// Check the operation once, like a program instruction over the first elements.
+	instruction := programInstruction{opcode: opcode, operands: []int{0, 1}}
+	p := &program{inputs: []common.Hash{lhs[0], rhs[0]}, registers: []programRegister{{fheUintType: lhsType}, rhsRegister}}
+	if err := p.check(instruction, isTee); err != nil {
+		return nil, err
+	}
+	return &batch{instruction: instruction, lhs: lhs, rhs: rhs, registers: p.registers}, nil
+}
+
+// Returns the type of handles, failing unless they all are verified ciphertexts of the same type.
+func batchOperandsType(environment EVMEnvironment, handles []common.Hash) (tfhe.FheUintType, error) {
+	var t tfhe.FheUintType
+	for i, handle := range handles {
+		ct := getVerifiedCiphertext(environment, handle)
+		if ct == nil {
+			return 0, fmt.Errorf("operand %d is not a verified ciphertext handle", i)
+		}
+		if i == 0 {
+			t = ct.fheUintType()
+		} else if ct.fheUintType() != t {
+			return 0, fmt.Errorf("operand %d has type %s instead of %s", i, ct.fheUintType(), t)
+		}
+	}
+	return t, nil
+}
+
+// Compute the results of a batch.
+func (b *batch) run(environment EVMEnvironment, backend programBackend) ([]*tfhe.TfheCiphertext, error) {
+	cts := make([]*tfhe.TfheCiphertext, 0, 2*len(b.lhs))
+	for _, handle := range b.lhs {
+		cts = append(cts, getVerifiedCiphertext(environment, handle).ciphertext)
+	}
+	if !b.isScalar() {
+		for _, handle := range b.rhs {
+			cts = append(cts, getVerifiedCiphertext(environment, handle).ciphertext)
+		}
+	}
+	operands, err := backend.load(cts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]programValue, 0, len(b.lhs))
+	types := make([]tfhe.FheUintType, 0, len(b.lhs))
+	for i := range b.lhs {
+		rhs := programValue{plaintext: b.rhs[0].Big()}
+		if !b.isScalar() {
+			rhs = operands[len(b.lhs)+i]
+		}
+		result, err := backend.apply(b.instruction, []programValue{operands[i], rhs}, b.registers[:2])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+		types = append(types, b.registers[2].fheUintType)
+	}
+	return backend.store(results, types)
+}
+
+func batchRun(opcode byte, isTee bool) func(EVMEnvironment, common.Address, common.Address, []byte, bool, trace.Span) ([]byte, error) {
+	return func(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
+		logger := environment.GetLogger()
+		b, err := parseBatch(environment, opcode, input, isTee)
+		if err != nil {
+			logger.Error("batch is invalid", "err", err, "input", hex.EncodeToString(input))
+			return nil, err
+		}
+		otelDescribeOperandsFheTypes(runSpan, b.registers[0].fheUintType)
+
+		handles := make([]common.Hash, 0, len(b.lhs))
+		// If we are doing gas estimation, skip execution and insert random ciphertexts as results.
+		if !environment.IsCommitting() && !environment.IsEthCall() {
+			for range b.lhs {
+				handles = append(handles, common.BytesToHash(importRandomCiphertext(environment, b.registers[2].fheUintType)))
+			}
+		} else {
+			var backend programBackend = fheProgramBackend{}
+			if isTee {
+				backend = teeProgramBackend{caller: caller}
+			}
+			results, err := b.run(environment, backend)
+			if err != nil {
+				logger.Error("batch failed", "err", err)
+				return nil, err
+			}
+			for _, ct := range results {
+				importCiphertext(environment, ct)
+				handles = append(handles, ct.GetHash())
+			}
+		}
+
+		for i, handle := range handles {
+			operands := []common.Hash{b.lhs[i]}
+			if !b.isScalar() {
+				operands = append(operands, b.rhs[i])
+			}
+			allowResult(environment, caller, handle, operands)
+		}
+		if environment.IsCommitting() {
+			logger.Info("batch success", "opcode", opcode, "elements", len(handles))
+		}
+		return encodeWordArray(handles), nil
+	}
+}
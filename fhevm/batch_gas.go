@@ -0,0 +1,65 @@
+package fhevm
+
+import (
+	"encoding/hex"
+)
+
+func batchRequiredGas(opcode byte, isTee bool) func(EVMEnvironment, uint64, []byte) uint64 {
+	return func(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
+		logger := environment.GetLogger()
+		b, err := parseBatch(environment, opcode, input, isTee)
+		if err != nil {
+			logger.Error("batch RequiredGas() batch is invalid", "err", err, "input", hex.EncodeToString(input))
+			return 0
+		}
+		return b.requiredGas(&environment.FhevmParams().GasCosts, isTee)
+	}
+}
+
+// Returns the gas of the operation for every element, minus the dispatch overhead of all but the first one.
+func (b *batch) requiredGas(costs *GasCosts, isTee bool) uint64 {
+	t := b.registers[0].fheUintType
+	var cost, dispatch uint64
+	if isTee {
+		cost, dispatch = teeProgramInstructionGas(costs, b.instruction, t), costs.TeeBatchDispatch
+	} else {
+		cost, dispatch = fheProgramInstructionGas(costs, b.instruction, t, b.isScalar()), costs.FheBatchDispatch
+	}
+	if dispatch > cost {
+		dispatch = cost
+	}
+	n := uint64(len(b.lhs))
+	return n*cost - (n-1)*dispatch
+}
+
+// The GasCosts fields that price the batch variant of a binary operation.
+var batchGasCategories = map[byte][2]string{
+	programAdd:     {"FheAddSub", "TeeAddSub"},
+	programSub:     {"FheAddSub", "TeeAddSub"},
+	programMul:     {"FheMul,FheScalarMul", "TeeMul"},
+	programDiv:     {"FheScalarDiv", "TeeDiv"},
+	programRem:     {"FheScalarRem", "TeeRem"},
+	programMin:     {"FheMinMax,FheScalarMinMax", "TeeComparison"},
+	programMax:     {"FheMinMax,FheScalarMinMax", "TeeComparison"},
+	programAnd:     {"FheBitwiseOp", "TeeBitwiseOp"},
+	programOr:      {"FheBitwiseOp", "TeeBitwiseOp"},
+	programXor:     {"FheBitwiseOp", "TeeBitwiseOp"},
+	programShl:     {"FheShift,FheScalarShift", "TeeShift"},
+	programShr:     {"FheShift,FheScalarShift", "TeeShift"},
+	programRotl:    {"FheShift,FheScalarShift", "TeeShift"},
+	programRotr:    {"FheShift,FheScalarShift", "TeeShift"},
+	programEq:      {"FheEq", "TeeComparison"},
+	programNe:      {"FheEq", "TeeComparison"},
+	programGe:      {"FheLe", "TeeComparison"},
+	programGt:      {"FheLe", "TeeComparison"},
+	programLe:      {"FheLe", "TeeComparison"},
+	programLt:      {"FheLe", "TeeComparison"},
+	programBitTest: {"FheBitTest,FheScalarBitTest", "TeeBitTest"},
+}
+
+func batchGasCategory(opcode byte, isTee bool) string {
+	if isTee {
+		return batchGasCategories[opcode][1] + ",TeeBatchDispatch"
+	}
+	return batchGasCategories[opcode][0] + ",FheBatchDispatch"
+}
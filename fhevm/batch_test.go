@@ -0,0 +1,174 @@
+package fhevm
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+	"github.com/zama-ai/fhevm-go/tee"
+)
+
+func toBatchInput(method string, lhs []common.Hash, rhs []common.Hash, isScalar bool) []byte {
+	input := crypto.Keccak256([]byte(method))[0:4]
+	input = append(input, uint256.NewInt(96).PaddedBytes(32)...)
+	input = append(input, uint256.NewInt(uint64(128+32*len(lhs))).PaddedBytes(32)...)
+	scalarByte := byte(0)
+	if isScalar {
+		scalarByte = 1
+	}
+	input = append(input, common.RightPadBytes([]byte{scalarByte}, 32)...)
+	for _, handles := range [][]common.Hash{lhs, rhs} {
+		input = append(input, uint256.NewInt(uint64(len(handles))).PaddedBytes(32)...)
+		for _, handle := range handles {
+			input = append(input, handle.Bytes()...)
+		}
+	}
+	return input
+}
+
+func verifyBatchInTestMemory(environment *MockEVMEnvironment, values []uint64, t tfhe.FheUintType) []common.Hash {
+	handles := make([]common.Hash, 0, len(values))
+	for _, value := range values {
+		ct := new(tfhe.TfheCiphertext).TrivialEncrypt(*new(big.Int).SetUint64(value), t)
+		handles = append(handles, verifyTfheCiphertextInTestMemory(environment, ct, environment.depth).GetHash())
+	}
+	return handles
+}
+
+func TestFheBatchRun(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	contract := common.HexToAddress("0x1000")
+	balances := verifyBatchInTestMemory(environment, []uint64{100, 20, 3}, tfhe.FheUint16)
+	salaries := verifyBatchInTestMemory(environment, []uint64{10, 20, 30}, tfhe.FheUint16)
+
+	input := toBatchInput("fheAddBatch(uint256[],uint256[],bytes1)", balances, salaries, false)
+	costs := environment.FhevmParams().GasCosts
+	expectedGas := 3*costs.FheAddSub[tfhe.FheUint16] - 2*costs.FheBatchDispatch
+	if gas := FheLibRequiredGas(environment, 0, input); gas != expectedGas {
+		t.Fatalf("incorrect gas, expected=%d, got=%d", expectedGas, gas)
+	}
+	out, err := FheLibRun(environment, contract, contract, input, false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for i, expected := range []uint64{110, 40, 33} {
+		if value := decryptInTest(t, environment, contract, decodeHandlesInTest(t, out)[i]); value != expected {
+			t.Fatalf("decrypt result not equal to value, result %v != value %v", value, expected)
+		}
+	}
+
+	// A scalar rhs applies to every element.
+	input = toBatchInput("fheMulBatch(uint256[],uint256[],bytes1)", balances, []common.Hash{common.BigToHash(big.NewInt(2))}, true)
+	expectedGas = 3*costs.FheScalarMul[tfhe.FheUint16] - 2*costs.FheBatchDispatch
+	if gas := FheLibRequiredGas(environment, 0, input); gas != expectedGas {
+		t.Fatalf("incorrect gas, expected=%d, got=%d", expectedGas, gas)
+	}
+	out, err = FheLibRun(environment, contract, contract, input, false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for i, expected := range []uint64{200, 40, 6} {
+		if value := decryptInTest(t, environment, contract, decodeHandlesInTest(t, out)[i]); value != expected {
+			t.Fatalf("decrypt result not equal to value, result %v != value %v", value, expected)
+		}
+	}
+}
+
+func TestTeeBatchRun(t *testing.T) {
+	testcases := []struct {
+		method   string
+		isScalar bool
+		expected []uint64
+	}{
+		{"teeSubBatch", false, []uint64{250, 0, 27}},
+		{"teeLtBatch", false, []uint64{1, 0, 0}},
+		{"teeShlBatch", true, []uint64{16, 80, 120}},
+	}
+	for _, tc := range testcases {
+		t.Run(fmt.Sprintf("%s with scalar %t", tc.method, tc.isScalar), func(t *testing.T) {
+			environment := newTestEVMEnvironment()
+			environment.depth = 1
+			contract := common.HexToAddress("0x1000")
+			var lhs, rhs []common.Hash
+			for i, values := range [][2]uint64{{4, 10}, {20, 20}, {30, 3}} {
+				for j, hashes := range []*[]common.Hash{&lhs, &rhs} {
+					ct, err := importTeePlaintextToEVM(environment, environment.depth, values[j], tfhe.FheUint8)
+					if err != nil {
+						t.Fatalf("element %d: %s", i, err.Error())
+					}
+					*hashes = append(*hashes, ct.GetHash())
+				}
+			}
+			if tc.isScalar {
+				rhs = []common.Hash{common.BigToHash(big.NewInt(2))}
+			}
+
+			roundTrips := tee.RoundTrips()
+			out, err := TeeLibRun(environment, contract, contract, toBatchInput(tc.method+"(uint256[],uint256[],bytes1)", lhs, rhs, tc.isScalar), false)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			// One round trip decrypts the operands and another encrypts the results.
+			if n := tee.RoundTrips() - roundTrips; n != 2 {
+				t.Fatalf("expected 2 round trips to the TEE, got %d", n)
+			}
+			outputs := decodeHandlesInTest(t, out)
+			if len(outputs) != len(tc.expected) {
+				t.Fatalf("expected %d results, got %d", len(tc.expected), len(outputs))
+			}
+			for i, expected := range tc.expected {
+				plaintext, err := tee.Decrypt(getVerifiedCiphertextFromEVM(environment, outputs[i]).ciphertext)
+				if err != nil {
+					t.Fatalf(err.Error())
+				}
+				if result := new(big.Int).SetBytes(plaintext.Value); result.Uint64() != expected {
+					t.Fatalf("incorrect result %d, expected=%d, got=%d", i, expected, result)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchInvalid(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	contract := common.HexToAddress("0x1000")
+	uint8s := verifyBatchInTestMemory(environment, []uint64{1, 2}, tfhe.FheUint8)
+	uint16s := verifyBatchInTestMemory(environment, []uint64{1, 2}, tfhe.FheUint16)
+	mixed := []common.Hash{uint8s[0], uint16s[0]}
+	scalar := []common.Hash{common.BigToHash(big.NewInt(2))}
+
+	testcases := []struct {
+		name     string
+		method   string
+		lhs      []common.Hash
+		rhs      []common.Hash
+		isScalar bool
+	}{
+		{"empty", "fheAddBatch", nil, nil, false},
+		{"different lengths", "fheAddBatch", uint8s, uint8s[:1], false},
+		{"mixed lhs types", "fheAddBatch", mixed, uint8s, false},
+		{"type mismatch", "fheAddBatch", uint8s, uint16s, false},
+		{"unverified operand", "fheAddBatch", uint8s, []common.Hash{uint8s[0], common.HexToHash("0xabcd")}, false},
+		{"several scalars", "fheAddBatch", uint8s, uint8s, true},
+		{"encrypted divisor", "fheDivBatch", uint8s, uint8s, false},
+		{"scalar bitwise operand", "fheBitAndBatch", uint8s, scalar, true},
+		{"division by zero", "fheDivBatch", uint8s, []common.Hash{{}}, true},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			input := toBatchInput(tc.method+"(uint256[],uint256[],bytes1)", tc.lhs, tc.rhs, tc.isScalar)
+			if gas := FheLibRequiredGas(environment, 0, input); gas != 0 {
+				t.Fatalf("expected no gas for an invalid batch, got %d", gas)
+			}
+			if _, err := FheLibRun(environment, contract, contract, input, false); err == nil {
+				t.Fatalf("expected an invalid batch to fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package fhevm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	fhevm_crypto "github.com/zama-ai/fhevm-go/fhevm/crypto"
+	"github.com/zama-ai/fhevm-go/fhevm/kms"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+// A Merkle-Patricia proof that a ciphertext is persisted in the protected storage of a contract, in the
+// state with the given root. It lets a KMS check that the ciphertext it is asked to decrypt is on chain.
+//
+// AccountProof proves the protected storage account in the state trie. StorageProof contains the nodes
+// proving the metadata slot and every ciphertext slot in the account's storage trie.
+type CiphertextProof struct {
+	StateRoot    common.Hash
+	Contract     common.Address
+	Handle       common.Hash
+	AccountProof [][]byte
+	StorageProof [][]byte
+}
+
+// ProofTrie is a Merkle-Patricia trie that can prove its keys, like go-ethereum's state.Trie.
+// Keys are given hashed, as they are stored in secure tries.
+type ProofTrie interface {
+	Prove(key []byte, fromLevel uint, proofDb ethdb.KeyValueWriter) error
+}
+
+// Collects proof nodes, dropping duplicates. The nodes are also kept in a database keyed by their hash,
+// as trie.VerifyProof expects.
+type proofNodes struct {
+	nodes [][]byte
+	db    *memorydb.Database
+}
+
+func newProofNodes(nodes [][]byte) *proofNodes {
+	p := &proofNodes{db: memorydb.New()}
+	for _, node := range nodes {
+		p.Put(crypto.Keccak256(node), node)
+	}
+	return p
+}
+
+func (p *proofNodes) Put(key []byte, value []byte) error {
+	if ok, _ := p.db.Has(key); ok {
+		return nil
+	}
+	p.nodes = append(p.nodes, common.CopyBytes(value))
+	return p.db.Put(key, value)
+}
+
+func (p *proofNodes) Delete(key []byte) error {
+	return errors.New("proof nodes can't be deleted")
+}
+
+// Verifies the proof of the protected storage account and returns its storage root.
+func verifyProtectedStorageAccount(stateRoot common.Hash, protectedStorage common.Address, accountProof [][]byte) (common.Hash, error) {
+	value, err := trie.VerifyProof(stateRoot, crypto.Keccak256(protectedStorage.Bytes()), newProofNodes(accountProof).db)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if value == nil {
+		return common.Hash{}, fmt.Errorf("protected storage account %s is not in the state", protectedStorage.Hex())
+	}
+	account := new(types.StateAccount)
+	if err := rlp.DecodeBytes(value, account); err != nil {
+		return common.Hash{}, err
+	}
+	return account.Root, nil
+}
+
+// Returns the value of a storage slot proven by the given nodes. Absent slots are zero.
+func verifyStorageSlot(storageRoot common.Hash, key common.Hash, db ethdb.KeyValueReader) (common.Hash, error) {
+	value, err := trie.VerifyProof(storageRoot, crypto.Keccak256(key.Bytes()), db)
+	if err != nil || value == nil {
+		return common.Hash{}, err
+	}
+	_, content, _, err := rlp.Split(value)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(content), nil
+}
+
+// A StateReader over proven storage slots, so that ciphertexts are read with the same layout as
+// getCiphertextFromProtectedStoage. If prove is set, the slots are proven from the storage trie first.
+// Reads after the first error return zero.
+type provenStorageReader struct {
+	protectedStorage common.Address
+	storageRoot      common.Hash
+	prove            ProofTrie
+	nodes            *proofNodes
+	err              error
+}
+
+func (r *provenStorageReader) GetState(addr common.Address, key common.Hash) common.Hash {
+	if r.err != nil {
+		return common.Hash{}
+	}
+	if addr != r.protectedStorage {
+		r.err = fmt.Errorf("unexpected read of %s outside of protected storage", addr.Hex())
+		return common.Hash{}
+	}
+	if r.prove != nil {
+		if err := r.prove.Prove(crypto.Keccak256(key.Bytes()), 0, r.nodes); err != nil {
+			r.err = err
+			return common.Hash{}
+		}
+	}
+	value, err := verifyStorageSlot(r.storageRoot, key, r.nodes.db)
+	if err != nil {
+		r.err = err
+		return common.Hash{}
+	}
+	return value
+}
+
+// StateProver opens the tries of the state an environment executes on, so that KmsDecryptionBackend can prove the
+// ciphertexts it sends to the KMS. With go-ethereum, the tries come from state.Database, at the state root of the
+// block the environment executes on.
+type StateProver interface {
+	OpenTries(environment EVMEnvironment) (stateRoot common.Hash, stateTrie ProofTrie, openStorageTrie func(addrHash common.Hash, root common.Hash) (ProofTrie, error), err error)
+}
+
+// Records the slots read through a StateReader.
+type slotRecorder struct {
+	state StateReader
+	slots []common.Hash
+}
+
+func (r *slotRecorder) GetState(addr common.Address, key common.Hash) common.Hash {
+	r.slots = append(r.slots, key)
+	return r.state.GetState(addr, key)
+}
+
+// CiphertextSlots returns the protected storage slots that hold the ciphertext of handle persisted by contract, as
+// read from state: the metadata slot, then the ciphertext slots. Provers that take storage keys, like eth_getProof,
+// prove these slots.
+func CiphertextSlots(state StateReader, contract common.Address, handle common.Hash) ([]common.Hash, error) {
+	recorder := &slotRecorder{state: state}
+	if getCiphertextFromProtectedStoage(recorder, contract, handle) == nil {
+		return nil, fmt.Errorf("no ciphertext for handle %s in the protected storage of %s", handle.Hex(), contract.Hex())
+	}
+	return recorder.slots, nil
+}
+
+// BuildCiphertextProof proves the ciphertext of handle persisted by contract. stateTrie is the account
+// trie with the given root, and openStorageTrie opens the storage trie of an account by the hash of its
+// address and its storage root.
+func BuildCiphertextProof(stateRoot common.Hash, stateTrie ProofTrie, openStorageTrie func(addrHash common.Hash, root common.Hash) (ProofTrie, error), contract common.Address, handle common.Hash) (*CiphertextProof, error) {
+	protectedStorage := fhevm_crypto.CreateProtectedStorageContractAddress(contract)
+	accountNodes := newProofNodes(nil)
+	if err := stateTrie.Prove(crypto.Keccak256(protectedStorage.Bytes()), 0, accountNodes); err != nil {
+		return nil, err
+	}
+	storageRoot, err := verifyProtectedStorageAccount(stateRoot, protectedStorage, accountNodes.nodes)
+	if err != nil {
+		return nil, err
+	}
+	storageTrie, err := openStorageTrie(crypto.Keccak256Hash(protectedStorage.Bytes()), storageRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &provenStorageReader{protectedStorage: protectedStorage, storageRoot: storageRoot, prove: storageTrie, nodes: newProofNodes(nil)}
+	ciphertext := getCiphertextFromProtectedStoage(reader, contract, handle)
+	if reader.err != nil {
+		return nil, reader.err
+	}
+	if ciphertext == nil {
+		return nil, fmt.Errorf("no ciphertext for handle %s in the protected storage of %s", handle.Hex(), contract.Hex())
+	}
+	return &CiphertextProof{
+		StateRoot:    stateRoot,
+		Contract:     contract,
+		Handle:       handle,
+		AccountProof: accountNodes.nodes,
+		StorageProof: reader.nodes.nodes,
+	}, nil
+}
+
+// VerifyCiphertextProof checks the proof against its state root and returns the proven ciphertext and
+// its type. The caller must check that the state root is the one of a trusted block.
+func VerifyCiphertextProof(proof *CiphertextProof) ([]byte, tfhe.FheUintType, error) {
+	protectedStorage := fhevm_crypto.CreateProtectedStorageContractAddress(proof.Contract)
+	storageRoot, err := verifyProtectedStorageAccount(proof.StateRoot, protectedStorage, proof.AccountProof)
+	if err != nil {
+		return nil, 0, err
+	}
+	reader := &provenStorageReader{protectedStorage: protectedStorage, storageRoot: storageRoot, nodes: newProofNodes(proof.StorageProof)}
+	ciphertext := getCiphertextFromProtectedStoage(reader, proof.Contract, proof.Handle)
+	if reader.err != nil {
+		return nil, 0, reader.err
+	}
+	if ciphertext == nil {
+		return nil, 0, errors.New("proof doesn't contain a ciphertext")
+	}
+	return ciphertext.bytes, ciphertext.metadata.fheUintType, nil
+}
+
+// Returns the proof as sent to the KMS, for the block at the given height.
+func (proof *CiphertextProof) ToKmsProof(height uint32) (*kms.Proof, error) {
+	encoded, err := rlp.EncodeToBytes(proof)
+	if err != nil {
+		return nil, err
+	}
+	return &kms.Proof{Height: height, MerklePatriciaProof: encoded}, nil
+}
+
+// Decodes a proof received by the KMS.
+func CiphertextProofFromKms(proof *kms.Proof) (*CiphertextProof, error) {
+	if proof == nil {
+		return nil, errors.New("no proof")
+	}
+	decoded := new(CiphertextProof)
+	if err := rlp.DecodeBytes(proof.MerklePatriciaProof, decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
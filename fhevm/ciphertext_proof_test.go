@@ -0,0 +1,105 @@
+package fhevm
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	fhevm_crypto "github.com/zama-ai/fhevm-go/fhevm/crypto"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+// Persists a ciphertext to the protected storage of the contract, commits the state and proves it.
+func newTestCiphertextProof(t *testing.T, contract common.Address, handle common.Hash) (*CiphertextProof, *tfhe.TfheCiphertext, error) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	ct := verifyCiphertextInTestMemory(environment, 42, environment.depth, tfhe.FheUint32)
+	other := verifyCiphertextInTestMemory(environment, 43, environment.depth, tfhe.FheUint8)
+	protectedStorage := fhevm_crypto.CreateProtectedStorageContractAddress(contract)
+	for i, h := range []common.Hash{ct.GetHash(), other.GetHash()} {
+		persistIfVerifiedCiphertext(crypto.Keccak256Hash([]byte{byte(i)}), h, protectedStorage, environment)
+	}
+	root, err := environment.stateDb.Commit(false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	db := environment.stateDb.Database()
+	stateTrie, err := db.OpenTrie(root)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	openStorageTrie := func(addrHash common.Hash, storageRoot common.Hash) (ProofTrie, error) {
+		return db.OpenStorageTrie(root, addrHash, storageRoot)
+	}
+	if handle == (common.Hash{}) {
+		handle = ct.GetHash()
+	}
+	proof, err := BuildCiphertextProof(root, stateTrie, openStorageTrie, contract, handle)
+	return proof, ct, err
+}
+
+func TestCiphertextProof(t *testing.T) {
+	contract := common.HexToAddress("0x1000")
+	proof, ct, err := newTestCiphertextProof(t, contract, common.Hash{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// The proof goes through the KMS request unchanged.
+	kmsProof, err := proof.ToKmsProof(7)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	decoded, err := CiphertextProofFromKms(kmsProof)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if kmsProof.Height != 7 || decoded.StateRoot != proof.StateRoot || decoded.Handle != ct.GetHash() {
+		t.Fatalf("unexpected decoded proof")
+	}
+
+	bytes, typ, err := VerifyCiphertextProof(decoded)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if typ != tfhe.FheUint32 || string(bytes) != string(ct.Serialize()) {
+		t.Fatalf("expected the proof to contain the ciphertext")
+	}
+}
+
+func TestCiphertextProofForMissingHandle(t *testing.T) {
+	_, _, err := newTestCiphertextProof(t, common.HexToAddress("0x1000"), common.HexToHash("0x1234"))
+	if err == nil {
+		t.Fatalf("expected no proof for a handle that isn't persisted")
+	}
+}
+
+func TestCiphertextProofTampered(t *testing.T) {
+	contract := common.HexToAddress("0x1000")
+
+	testcases := []struct {
+		description string
+		tamper      func(proof *CiphertextProof)
+	}{
+		{"WrongStateRoot", func(proof *CiphertextProof) { proof.StateRoot = common.HexToHash("0x01") }},
+		{"WrongContract", func(proof *CiphertextProof) { proof.Contract = common.HexToAddress("0x2000") }},
+		{"WrongHandle", func(proof *CiphertextProof) { proof.Handle = common.HexToHash("0x1234") }},
+		{"MissingSlot", func(proof *CiphertextProof) { proof.StorageProof = proof.StorageProof[:len(proof.StorageProof)-1] }},
+		{"ModifiedNode", func(proof *CiphertextProof) {
+			node := proof.StorageProof[len(proof.StorageProof)-1]
+			node[len(node)-1] ^= 1
+		}},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			proof, _, err := newTestCiphertextProof(t, contract, common.Hash{})
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			tc.tamper(proof)
+			if _, _, err := VerifyCiphertextProof(proof); err == nil {
+				t.Fatalf("expected the tampered proof to be rejected")
+			}
+		})
+	}
+}
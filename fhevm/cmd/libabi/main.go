@@ -0,0 +1,45 @@
+// Command libabi writes the Solidity ABI JSON and interface of the FheLib and TeeLib precompiles to a directory.
+//
+// Usage: libabi <directory>
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/zama-ai/fhevm-go/fhevm"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: libabi <directory>")
+		os.Exit(2)
+	}
+	if err := run(os.Args[1]); err != nil {
+		fmt.Fprintln(os.Stderr, "libabi:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string) error {
+	artifacts, err := fhevm.LibArtifacts()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(artifacts))
+	for name := range artifacts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), artifacts[name], 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,11 +2,11 @@ package fhevm
 
 import (
 	"bytes"
-	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/big"
+	"math/bits"
 	"os"
 	"strings"
 	"testing"
@@ -66,6 +66,7 @@ func toPrecompileInput(isScalar bool, hashes ...common.Hash) []byte {
 		isScalarByte = 0
 	}
 	ret = append(ret, isScalarByte)
+	ret = append(ret, scalarBytePadding...)
 	return ret
 }
 
@@ -149,12 +150,11 @@ func toLibPrecompileInputNoScalar(method string, hashes ...common.Hash) []byte {
 	return ret
 }
 
-// verifyCiphertext expect a certain format: mainly some padding and the size of the buffer
+// verifyCiphertext takes the ABI encoding of bytes: the offset of the buffer, its size and its padded data
 func prepareInputForVerifyCiphertext(input []byte) []byte {
-	padding := make([]byte, 60)
-	size := make([]byte, 4)
-	binary.BigEndian.PutUint32(size, uint32(len(input)))
-	return append(append(padding, size...), input...)
+	ret := uint256.NewInt(32).PaddedBytes(32)
+	ret = append(ret, uint256.NewInt(uint64(len(input))).PaddedBytes(32)...)
+	return append(ret, common.RightPadBytes(input, (len(input)+31)/32*32)...)
 }
 
 func VerifyCiphertext(t *testing.T, fheUintType tfhe.FheUintType) {
@@ -249,7 +249,7 @@ func TrivialEncrypt(t *testing.T, fheUintType tfhe.FheUintType) {
 	addr := common.Address{}
 	readOnly := false
 	valueBytes := make([]byte, 32)
-	input := append(value.FillBytes(valueBytes), byte(fheUintType))
+	input := append(value.FillBytes(valueBytes), common.RightPadBytes([]byte{byte(fheUintType)}, 32)...)
 	out, err := trivialEncryptRun(environment, addr, addr, input, readOnly, nil)
 	if err != nil {
 		t.Fatalf(err.Error())
@@ -1145,6 +1145,186 @@ func FheLibNeg(t *testing.T, fheUintType tfhe.FheUintType) {
 	}
 }
 
+func FheLibPopcount(t *testing.T, fheUintType tfhe.FheUintType) {
+	var pt, expected uint64
+	switch fheUintType {
+	case tfhe.FheUint4:
+		pt = 11
+		expected = uint64(bits.OnesCount8(uint8(pt)))
+	case tfhe.FheUint8:
+		pt = 173
+		expected = uint64(bits.OnesCount8(uint8(pt)))
+	case tfhe.FheUint16:
+		pt = 4283
+		expected = uint64(bits.OnesCount16(uint16(pt)))
+	case tfhe.FheUint32:
+		pt = 1333337
+		expected = uint64(bits.OnesCount32(uint32(pt)))
+	case tfhe.FheUint64:
+		pt = 13333377777777777
+		expected = uint64(bits.OnesCount64(pt))
+	}
+
+	signature := "fhePopcount(uint256)"
+	depth := 1
+	environment := newTestEVMEnvironment()
+	environment.depth = depth
+	addr := common.Address{}
+	readOnly := false
+	ptHash := verifyCiphertextInTestMemory(environment, pt, depth, fheUintType).GetHash()
+
+	input := toLibPrecompileInputNoScalar(signature, ptHash)
+	out, err := FheLibRun(environment, addr, addr, input, readOnly)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	res := getVerifiedCiphertextFromEVM(environment, common.BytesToHash(out))
+	if res == nil {
+		t.Fatalf("output ciphertext is not found in verifiedCiphertexts")
+	}
+	decrypted, err := res.ciphertext.Decrypt()
+	if err != nil || decrypted.Uint64() != expected {
+		t.Fatalf("invalid decrypted result, decrypted %v != expected %v", decrypted.Uint64(), expected)
+	}
+}
+
+func FheLibClz(t *testing.T, fheUintType tfhe.FheUintType) {
+	var pt, expected uint64
+	switch fheUintType {
+	case tfhe.FheUint4:
+		pt = 3
+		expected = uint64(bits.LeadingZeros8(uint8(pt)) - 4)
+	case tfhe.FheUint8:
+		pt = 17
+		expected = uint64(bits.LeadingZeros8(uint8(pt)))
+	case tfhe.FheUint16:
+		pt = 283
+		expected = uint64(bits.LeadingZeros16(uint16(pt)))
+	case tfhe.FheUint32:
+		pt = 1333337
+		expected = uint64(bits.LeadingZeros32(uint32(pt)))
+	case tfhe.FheUint64:
+		pt = 13333377777777
+		expected = uint64(bits.LeadingZeros64(pt))
+	}
+
+	signature := "fheClz(uint256)"
+	depth := 1
+	environment := newTestEVMEnvironment()
+	environment.depth = depth
+	addr := common.Address{}
+	readOnly := false
+	ptHash := verifyCiphertextInTestMemory(environment, pt, depth, fheUintType).GetHash()
+
+	input := toLibPrecompileInputNoScalar(signature, ptHash)
+	out, err := FheLibRun(environment, addr, addr, input, readOnly)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	res := getVerifiedCiphertextFromEVM(environment, common.BytesToHash(out))
+	if res == nil {
+		t.Fatalf("output ciphertext is not found in verifiedCiphertexts")
+	}
+	decrypted, err := res.ciphertext.Decrypt()
+	if err != nil || decrypted.Uint64() != expected {
+		t.Fatalf("invalid decrypted result, decrypted %v != expected %v", decrypted.Uint64(), expected)
+	}
+}
+
+func FheLibCtz(t *testing.T, fheUintType tfhe.FheUintType) {
+	var pt, expected uint64
+	switch fheUintType {
+	case tfhe.FheUint4:
+		pt = 4
+		expected = uint64(bits.TrailingZeros8(uint8(pt)))
+	case tfhe.FheUint8:
+		pt = 48
+		expected = uint64(bits.TrailingZeros8(uint8(pt)))
+	case tfhe.FheUint16:
+		pt = 4288
+		expected = uint64(bits.TrailingZeros16(uint16(pt)))
+	case tfhe.FheUint32:
+		pt = 1333376
+		expected = uint64(bits.TrailingZeros32(uint32(pt)))
+	case tfhe.FheUint64:
+		pt = 13333377777778176
+		expected = uint64(bits.TrailingZeros64(pt))
+	}
+
+	signature := "fheCtz(uint256)"
+	depth := 1
+	environment := newTestEVMEnvironment()
+	environment.depth = depth
+	addr := common.Address{}
+	readOnly := false
+	ptHash := verifyCiphertextInTestMemory(environment, pt, depth, fheUintType).GetHash()
+
+	input := toLibPrecompileInputNoScalar(signature, ptHash)
+	out, err := FheLibRun(environment, addr, addr, input, readOnly)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	res := getVerifiedCiphertextFromEVM(environment, common.BytesToHash(out))
+	if res == nil {
+		t.Fatalf("output ciphertext is not found in verifiedCiphertexts")
+	}
+	decrypted, err := res.ciphertext.Decrypt()
+	if err != nil || decrypted.Uint64() != expected {
+		t.Fatalf("invalid decrypted result, decrypted %v != expected %v", decrypted.Uint64(), expected)
+	}
+}
+
+func FheLibBitTest(t *testing.T, fheUintType tfhe.FheUintType, scalar bool) {
+	var lhs, rhs uint64
+	switch fheUintType {
+	case tfhe.FheUint4:
+		lhs = 10
+		rhs = 1
+	case tfhe.FheUint8:
+		lhs = 173
+		rhs = 2
+	case tfhe.FheUint16:
+		lhs = 4283
+		rhs = 12
+	case tfhe.FheUint32:
+		lhs = 1333337
+		rhs = 17
+	case tfhe.FheUint64:
+		lhs = 13333377777777777
+		rhs = 42
+	}
+	expected := (lhs >> rhs) & 1
+	signature := "fheBitTest(uint256,uint256,bytes1)"
+	depth := 1
+	environment := newTestEVMEnvironment()
+	environment.depth = depth
+	addr := common.Address{}
+	readOnly := false
+	lhsHash := verifyCiphertextInTestMemory(environment, lhs, depth, fheUintType).GetHash()
+	var rhsHash common.Hash
+	if scalar {
+		rhsHash = common.BytesToHash(big.NewInt(int64(rhs)).Bytes())
+	} else {
+		rhsHash = verifyCiphertextInTestMemory(environment, rhs, depth, fheUintType).GetHash()
+	}
+	input := toLibPrecompileInput(signature, scalar, lhsHash, rhsHash)
+	out, err := FheLibRun(environment, addr, addr, input, readOnly)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	res := getVerifiedCiphertextFromEVM(environment, common.BytesToHash(out))
+	if res == nil {
+		t.Fatalf("output ciphertext is not found in verifiedCiphertexts")
+	}
+	if res.fheUintType() != tfhe.FheBool {
+		t.Fatalf("incorrect result type, expected %v, got %v", tfhe.FheBool, res.fheUintType())
+	}
+	decrypted, err := res.ciphertext.Decrypt()
+	if err != nil || decrypted.Uint64() != expected {
+		t.Fatalf("invalid decrypted result, decrypted %v != expected %v", decrypted.Uint64(), expected)
+	}
+}
+
 func FheLibNot(t *testing.T, fheUintType tfhe.FheUintType) {
 	var pt, expected uint64
 	switch fheUintType {
@@ -1482,7 +1662,7 @@ func FheLibRand(t *testing.T, fheUintType tfhe.FheUintType) {
 	signatureBytes := hashRes[0:4]
 	input := make([]byte, 0)
 	input = append(input, signatureBytes...)
-	input = append(input, byte(fheUintType))
+	input = append(input, common.RightPadBytes([]byte{byte(fheUintType)}, 32)...)
 	out, err := FheLibRun(environment, addr, addr, input, readOnly)
 	if err != nil {
 		t.Fatalf(err.Error())
@@ -1538,7 +1718,7 @@ func FheLibRandBounded(t *testing.T, fheUintType tfhe.FheUintType, upperBound64
 	input := make([]byte, 0)
 	input = append(input, signatureBytes...)
 	input = append(input, upperBound[:]...)
-	input = append(input, byte(fheUintType))
+	input = append(input, common.RightPadBytes([]byte{byte(fheUintType)}, 32)...)
 	out, err := FheLibRun(environment, addr, addr, input, readOnly)
 	if err != nil {
 		t.Fatalf(err.Error())
@@ -1632,7 +1812,7 @@ func LibTrivialEncrypt(t *testing.T, fheUintType tfhe.FheUintType) {
 	input := make([]byte, 0)
 	input = append(input, signatureBytes...)
 	input = append(input, value.FillBytes(valueBytes)...)
-	input = append(input, byte(fheUintType))
+	input = append(input, common.RightPadBytes([]byte{byte(fheUintType)}, 32)...)
 	out, err := FheLibRun(environment, addr, addr, input, readOnly)
 	if err != nil {
 		t.Fatalf(err.Error())
@@ -1699,8 +1879,7 @@ func TestLibVerifyCiphertextInvalidType(t *testing.T) {
 	input := make([]byte, 0)
 	input = append(input, signatureBytes...)
 	compact := tfhe.EncryptAndSerializeCompact(0, tfhe.FheUint32)
-	input = append(input, compact...)
-	input = append(input, byte(invalidType))
+	input = append(input, prepareInputForVerifyCiphertext(append(compact, byte(invalidType)))...)
 	_, err := FheLibRun(environment, addr, addr, input, readOnly)
 	if err == nil {
 		t.Fatalf("verifyCiphertext must have failed on invalid ciphertext type")
@@ -1752,7 +1931,7 @@ func TestLibCast(t *testing.T) {
 	input := make([]byte, 0)
 	input = append(input, signatureBytes...)
 	input = append(input, encCiphertext.Bytes()...)
-	input = append(input, byte(tfhe.FheUint32))
+	input = append(input, common.RightPadBytes([]byte{byte(tfhe.FheUint32)}, 32)...)
 	_, err := FheLibRun(environment, addr, addr, input, readOnly)
 	if err != nil {
 		t.Fatalf("Cast error: %s", err.Error())
@@ -2925,7 +3104,7 @@ func FheRand(t *testing.T, fheUintType tfhe.FheUintType) {
 	environment.depth = depth
 	addr := common.Address{}
 	readOnly := false
-	out, err := fheRandRun(environment, addr, addr, []byte{byte(fheUintType)}, readOnly, nil)
+	out, err := fheRandRun(environment, addr, addr, common.RightPadBytes([]byte{byte(fheUintType)}, 32), readOnly, nil)
 	if err != nil {
 		t.Fatalf(err.Error())
 	} else if len(out) != 32 {
@@ -2958,12 +3137,6 @@ func TestVerifyCiphertextInvalidType(t *testing.T) {
 }
 
 func TestTrivialEncryptInvalidType(t *testing.T) {
-	// TODO: maybe trivialEncryptRun shouldn't panic but return an error?
-	defer func() {
-		if r := recover(); r == nil {
-			t.Fatalf("trivialEncrypt must have failed (panic) on invalid ciphertext type")
-		}
-	}()
 	depth := 1
 	environment := newTestEVMEnvironment()
 	environment.depth = depth
@@ -2971,8 +3144,11 @@ func TestTrivialEncryptInvalidType(t *testing.T) {
 	readOnly := false
 	invalidType := tfhe.FheUintType(255)
 	input := make([]byte, 32)
-	input = append(input, byte(invalidType))
-	trivialEncryptRun(environment, addr, addr, input, readOnly, nil)
+	input = append(input, common.RightPadBytes([]byte{byte(invalidType)}, 32)...)
+	_, err := trivialEncryptRun(environment, addr, addr, input, readOnly, nil)
+	if err == nil {
+		t.Fatalf("trivialEncrypt must have failed on invalid ciphertext type")
+	}
 }
 
 func TestCastInvalidType(t *testing.T) {
@@ -2985,7 +3161,7 @@ func TestCastInvalidType(t *testing.T) {
 	hash := verifyCiphertextInTestMemory(environment, 1, depth, tfhe.FheUint8).GetHash()
 	input := make([]byte, 0)
 	input = append(input, hash.Bytes()...)
-	input = append(input, byte(invalidType))
+	input = append(input, common.RightPadBytes([]byte{byte(invalidType)}, 32)...)
 	_, err := castRun(environment, addr, addr, input, readOnly, nil)
 	if err == nil {
 		t.Fatalf("cast must have failed on invalid ciphertext type")
@@ -3174,6 +3350,44 @@ func TestFheLibNot4(t *testing.T) {
 	FheLibNot(t, tfhe.FheUint4)
 }
 
+func TestFheLibPopcount4(t *testing.T) {
+	FheLibPopcount(t, tfhe.FheUint4)
+}
+
+func TestFheLibClz4(t *testing.T) {
+	FheLibClz(t, tfhe.FheUint4)
+}
+
+func TestFheLibCtz4(t *testing.T) {
+	FheLibCtz(t, tfhe.FheUint4)
+}
+
+func TestFheLibBitTest4(t *testing.T) {
+	FheLibBitTest(t, tfhe.FheUint4, false)
+}
+
+func TestFheLibScalarBitTest4(t *testing.T) {
+	FheLibBitTest(t, tfhe.FheUint4, true)
+}
+
+func TestFheLibBitTestUnverifiedOperands(t *testing.T) {
+	signature := "fheBitTest(uint256,uint256,bytes1)"
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	addr := common.Address{}
+	verified := verifyCiphertextInTestMemory(environment, 10, environment.depth, tfhe.FheUint8).GetHash()
+	unverified := common.HexToHash("0xabcd")
+	for _, input := range [][]byte{
+		toLibPrecompileInput(signature, false, unverified, verified),
+		toLibPrecompileInput(signature, false, verified, unverified),
+		toLibPrecompileInput(signature, true, unverified, common.BigToHash(big.NewInt(1))),
+	} {
+		if _, err := FheLibRun(environment, addr, addr, input, false); err == nil {
+			t.Fatalf("expected fheBitTest on an unverified operand to fail")
+		}
+	}
+}
+
 func TestFheLibDiv4(t *testing.T) {
 	FheLibDiv(t, tfhe.FheUint4, true)
 }
@@ -3258,6 +3472,26 @@ func TestFheLibNot8(t *testing.T) {
 	FheLibNot(t, tfhe.FheUint8)
 }
 
+func TestFheLibPopcount8(t *testing.T) {
+	FheLibPopcount(t, tfhe.FheUint8)
+}
+
+func TestFheLibClz8(t *testing.T) {
+	FheLibClz(t, tfhe.FheUint8)
+}
+
+func TestFheLibCtz8(t *testing.T) {
+	FheLibCtz(t, tfhe.FheUint8)
+}
+
+func TestFheLibBitTest8(t *testing.T) {
+	FheLibBitTest(t, tfhe.FheUint8, false)
+}
+
+func TestFheLibScalarBitTest8(t *testing.T) {
+	FheLibBitTest(t, tfhe.FheUint8, true)
+}
+
 func TestFheLibDiv8(t *testing.T) {
 	FheLibDiv(t, tfhe.FheUint8, true)
 }
@@ -4116,7 +4350,7 @@ func TestFheRandInvalidType(t *testing.T) {
 	environment.depth = depth
 	addr := common.Address{}
 	readOnly := false
-	_, err := fheRandRun(environment, addr, addr, []byte{byte(254)}, readOnly, nil)
+	_, err := fheRandRun(environment, addr, addr, common.RightPadBytes([]byte{byte(254)}, 32), readOnly, nil)
 	if err == nil {
 		t.Fatalf("fheRand expected failure on invalid type")
 	}
@@ -4134,7 +4368,7 @@ func TestFheRandBoundedInvalidType(t *testing.T) {
 	input := make([]byte, 0)
 	upperBound := uint256.NewInt(8).Bytes32()
 	input = append(input, upperBound[:]...)
-	input = append(input, byte(254))
+	input = append(input, common.RightPadBytes([]byte{byte(254)}, 32)...)
 	_, err := fheRandBoundedRun(environment, addr, addr, input, readOnly, nil)
 	if err == nil {
 		t.Fatalf("fheRandBounded expected failure on invalid type")
@@ -4153,7 +4387,7 @@ func FheRandBoundedInvalidBound(t *testing.T, fheUintType tfhe.FheUintType, boun
 	input := make([]byte, 0)
 	upperBound := bound.Bytes32()
 	input = append(input, upperBound[:]...)
-	input = append(input, byte(fheUintType))
+	input = append(input, common.RightPadBytes([]byte{byte(fheUintType)}, 32)...)
 	_, err := fheRandBoundedRun(environment, addr, addr, input, readOnly, nil)
 	if err == nil {
 		t.Fatalf("fheRandBounded expected failure on invalid bound")
@@ -4210,7 +4444,7 @@ func TestFheRandEthCall(t *testing.T) {
 	environment.ethCall = true
 	addr := common.Address{}
 	readOnly := true
-	_, err := fheRandRun(environment, addr, addr, []byte{byte(tfhe.FheUint8)}, readOnly, nil)
+	_, err := fheRandRun(environment, addr, addr, common.RightPadBytes([]byte{byte(tfhe.FheUint8)}, 32), readOnly, nil)
 	if err == nil {
 		t.Fatalf("fheRand expected failure on EthCall")
 	}
@@ -4229,7 +4463,7 @@ func TestFheRandBoundedEthCall(t *testing.T) {
 	input := make([]byte, 0)
 	upperBound := uint256.NewInt(4).Bytes32()
 	input = append(input, upperBound[:]...)
-	input = append(input, byte(tfhe.FheUint8))
+	input = append(input, common.RightPadBytes([]byte{byte(tfhe.FheUint8)}, 32)...)
 	_, err := fheRandBoundedRun(environment, addr, addr, input, readOnly, nil)
 	if err == nil {
 		t.Fatalf("fheRandBounded expected failure on EthCall")
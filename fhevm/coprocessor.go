@@ -0,0 +1,599 @@
+package fhevm
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Coprocessor mode.
+//
+// With FhevmParams.Coprocessor, FHE operations in transactions don't compute their result. As results have
+// symbolic handles, the EVM only checks the inputs, records the operation and returns the handle of its
+// result, as it does during gas estimation. The coprocessor computes the recorded operations on a pool of
+// workers, in the order of their dependencies, and stores the resulting ciphertexts.
+//
+// Ciphertexts that are still pending when they are stored in a contract are persisted without their bytes:
+// their metadata is flagged with ciphertextFlagComputedByCoprocessor and the ciphertext is fetched from the
+// coprocessor when it is loaded.
+// Decryptions and decryption requests block until their inputs are computed. Once their block is final,
+// results are written to a CiphertextStore and evicted from memory, so they are loaded from the store
+// afterwards, including after a restart.
+//
+// Only fhelib operations are deferred. TEE operations and operations whose result depends on the state, such
+// as fheRand, are always computed in the EVM: TEE encryption is randomized, so a TEE result can't be
+// recomputed outside the EVM with the same handle.
+
+// DefaultCoprocessorTimeout is how long the EVM waits for a ciphertext computed by a coprocessor by default.
+const DefaultCoprocessorTimeout = time.Minute
+
+type CoprocessorConfig struct {
+	// Number of workers computing ciphertexts. One if zero.
+	Workers int
+	// Number of blocks after which the computations of a block must be done, see Coprocessor.FinalizeBlock.
+	FinalityDepth uint64
+	// How long the EVM waits for a pending ciphertext. DefaultCoprocessorTimeout if zero.
+	Timeout time.Duration
+}
+
+// A Computation is an operation recorded by the EVM. Running the fhelib method of the selector on the
+// input, with the same contract, chain and symbolic handle counter, gives the ciphertext of the handle.
+type Computation struct {
+	Handle   common.Hash
+	Block    uint64
+	Contract common.Address
+	Selector uint32
+	Input    []byte
+	Counter  uint64
+	ChainID  *big.Int
+	// Input ciphertexts that were computed when the operation was recorded.
+	Operands []*tfhe.TfheCiphertext
+	// Handles of input ciphertexts computed by earlier computations.
+	Dependencies []common.Hash
+}
+
+// ComputationQueue holds the computations whose dependencies are computed until a worker picks them.
+type ComputationQueue interface {
+	Push(computation *Computation) error
+	// Pop blocks until a computation is available or the context is done.
+	Pop(ctx context.Context) (*Computation, error)
+}
+
+type memoryComputationQueue struct {
+	mu           sync.Mutex
+	computations []*Computation
+	notify       chan struct{}
+}
+
+// NewMemoryComputationQueue returns an unbounded in-process queue.
+func NewMemoryComputationQueue() ComputationQueue {
+	return &memoryComputationQueue{notify: make(chan struct{}, 1)}
+}
+
+func (q *memoryComputationQueue) Push(computation *Computation) error {
+	q.mu.Lock()
+	q.computations = append(q.computations, computation)
+	q.mu.Unlock()
+	q.signal()
+	return nil
+}
+
+func (q *memoryComputationQueue) Pop(ctx context.Context) (*Computation, error) {
+	for {
+		q.mu.Lock()
+		if len(q.computations) > 0 {
+			computation := q.computations[0]
+			q.computations = q.computations[1:]
+			left := len(q.computations)
+			q.mu.Unlock()
+			// Wake up another worker for the computations left.
+			if left > 0 {
+				q.signal()
+			}
+			return computation, nil
+		}
+		q.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-q.notify:
+		}
+	}
+}
+
+func (q *memoryComputationQueue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+type computationResult struct {
+	ciphertext *tfhe.TfheCiphertext
+	err        error
+	completed  bool
+	done       chan struct{}
+	// The computation, while it waits for its dependencies.
+	computation *Computation
+	remaining   int
+	// Handles of the computations waiting for this one.
+	dependents []common.Hash
+}
+
+// Coprocessor computes the operations recorded by the EVM. Results are kept in memory until their block is
+// final, and are then persisted in its store.
+type Coprocessor struct {
+	config CoprocessorConfig
+	queue  ComputationQueue
+	store  CiphertextStore
+	logger Logger
+
+	mu      sync.Mutex
+	results map[common.Hash]*computationResult
+	blocks  map[uint64][]common.Hash
+}
+
+// NewCoprocessor returns a coprocessor that takes its computations from the given queue and persists their
+// results in the given store. Workers are started with Start. An in-memory store is used if store is nil, and
+// the default logger if logger is nil.
+func NewCoprocessor(config CoprocessorConfig, queue ComputationQueue, store CiphertextStore, logger Logger) *Coprocessor {
+	if config.Workers == 0 {
+		config.Workers = 1
+	}
+	if config.Timeout == 0 {
+		config.Timeout = DefaultCoprocessorTimeout
+	}
+	if store == nil {
+		store = NewMemoryCiphertextStore()
+	}
+	if logger == nil {
+		logger = NewDefaultLogger()
+	}
+	return &Coprocessor{
+		config:  config,
+		queue:   queue,
+		store:   store,
+		logger:  logger,
+		results: make(map[common.Hash]*computationResult),
+		blocks:  make(map[uint64][]common.Hash),
+	}
+}
+
+// Start the workers. They stop when the context is done.
+func (c *Coprocessor) Start(ctx context.Context) {
+	for i := 0; i < c.config.Workers; i++ {
+		go c.work(ctx)
+	}
+}
+
+// Submit a computation. It is queued once its dependencies are computed.
+func (c *Coprocessor) Submit(computation *Computation) error {
+	c.mu.Lock()
+	if _, ok := c.results[computation.Handle]; ok {
+		// The transaction is executed again, e.g. after a reorg.
+		c.blocks[computation.Block] = append(c.blocks[computation.Block], computation.Handle)
+		c.mu.Unlock()
+		return nil
+	}
+	for _, dependency := range computation.Dependencies {
+		if _, ok := c.results[dependency]; ok {
+			continue
+		}
+		// Finalized dependencies are evicted from memory and loaded from the store.
+		if ct, err := c.store.Get(dependency); err != nil || ct == nil {
+			c.mu.Unlock()
+			return fmt.Errorf("no computation of the input ciphertext %s", dependency.Hex())
+		}
+	}
+	result := &computationResult{done: make(chan struct{}), computation: computation}
+	for _, dependency := range computation.Dependencies {
+		if d, ok := c.results[dependency]; ok && !d.completed {
+			d.dependents = append(d.dependents, computation.Handle)
+			result.remaining++
+		}
+	}
+	c.results[computation.Handle] = result
+	c.blocks[computation.Block] = append(c.blocks[computation.Block], computation.Handle)
+	ready := result.remaining == 0
+	c.mu.Unlock()
+
+	if ready {
+		return c.push(computation)
+	}
+	return nil
+}
+
+func (c *Coprocessor) push(computation *Computation) error {
+	if err := c.queue.Push(computation); err != nil {
+		c.complete(computation.Handle, nil, err)
+		return err
+	}
+	return nil
+}
+
+// Store the result of a computation and queue the computations that were waiting for it.
+func (c *Coprocessor) complete(handle common.Hash, ct *tfhe.TfheCiphertext, err error) {
+	c.mu.Lock()
+	result, ok := c.results[handle]
+	if !ok {
+		// A computation submitted before a restart, popped from a durable queue.
+		c.mu.Unlock()
+		if err == nil {
+			if err := c.store.Put(handle, ct); err != nil {
+				c.logger.Error("coprocessor failed to store a ciphertext", "handle", handle.Hex(), "err", err)
+			}
+		}
+		return
+	}
+	result.ciphertext = ct
+	result.err = err
+	result.completed = true
+	result.computation = nil
+	close(result.done)
+	ready := make([]*Computation, 0)
+	for _, dependent := range result.dependents {
+		d := c.results[dependent]
+		d.remaining--
+		if d.remaining == 0 {
+			ready = append(ready, d.computation)
+		}
+	}
+	result.dependents = nil
+	c.mu.Unlock()
+
+	for _, computation := range ready {
+		c.push(computation)
+	}
+}
+
+// Ciphertext blocks until the ciphertext of the handle is computed or the context is done. Ciphertexts of
+// finalized blocks are loaded from the store.
+func (c *Coprocessor) Ciphertext(ctx context.Context, handle common.Hash) (*tfhe.TfheCiphertext, error) {
+	c.mu.Lock()
+	result, ok := c.results[handle]
+	c.mu.Unlock()
+	if !ok {
+		ct, err := c.store.Get(handle)
+		if err != nil {
+			return nil, err
+		}
+		if ct == nil {
+			return nil, fmt.Errorf("no computation of ciphertext %s", handle.Hex())
+		}
+		return ct, nil
+	}
+	select {
+	case <-result.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+	// Callers may set the handle of their copy.
+	ct := *result.ciphertext
+	return &ct, nil
+}
+
+// Returns a function that waits for the ciphertext of the handle, at most for the configured timeout.
+func (c *Coprocessor) await(handle common.Hash) func() (*tfhe.TfheCiphertext, error) {
+	return func() (*tfhe.TfheCiphertext, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout)
+		defer cancel()
+		return c.Ciphertext(ctx, handle)
+	}
+}
+
+// FinalizeBlock must be called when a block is imported. It blocks until the computations of the blocks
+// that are FinalityDepth blocks older are done, and returns an error if one of them failed. Their ciphertexts
+// are then written to the store and evicted from memory. If writing fails, they are kept and written again by
+// the next call.
+func (c *Coprocessor) FinalizeBlock(ctx context.Context, block uint64) error {
+	if block < c.config.FinalityDepth {
+		return nil
+	}
+	final := block - c.config.FinalityDepth
+	c.mu.Lock()
+	finalized := make([]uint64, 0)
+	handles := make([]common.Hash, 0)
+	for b, blockHandles := range c.blocks {
+		if b <= final {
+			finalized = append(finalized, b)
+			handles = append(handles, blockHandles...)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, handle := range handles {
+		ct, err := c.Ciphertext(ctx, handle)
+		if err != nil {
+			return fmt.Errorf("computation of ciphertext %s failed: %w", handle.Hex(), err)
+		}
+		if err := c.store.Put(handle, ct); err != nil {
+			return fmt.Errorf("failed to store ciphertext %s: %w", handle.Hex(), err)
+		}
+	}
+
+	c.mu.Lock()
+	for _, b := range finalized {
+		delete(c.blocks, b)
+	}
+	// Handles submitted again in a block that isn't final yet, e.g. after a reorg, stay in memory.
+	pending := make(map[common.Hash]bool)
+	for _, blockHandles := range c.blocks {
+		for _, handle := range blockHandles {
+			pending[handle] = true
+		}
+	}
+	for _, handle := range handles {
+		if !pending[handle] {
+			delete(c.results, handle)
+		}
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Coprocessor) work(ctx context.Context) {
+	for {
+		computation, err := c.queue.Pop(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.logger.Error("coprocessor failed to get a computation", "err", err)
+			continue
+		}
+		ct, err := c.compute(computation)
+		if err != nil {
+			c.logger.Error("coprocessor computation failed", "handle", computation.Handle.Hex(), "err", err)
+		}
+		c.complete(computation.Handle, ct, err)
+	}
+}
+
+// Run the fhelib method of a computation on its input ciphertexts.
+func (c *Coprocessor) compute(computation *Computation) (*tfhe.TfheCiphertext, error) {
+	inputs := append([]*tfhe.TfheCiphertext{}, computation.Operands...)
+	for _, dependency := range computation.Dependencies {
+		ct, err := c.Ciphertext(context.Background(), dependency)
+		if err != nil {
+			return nil, fmt.Errorf("input ciphertext %s: %w", dependency.Hex(), err)
+		}
+		inputs = append(inputs, ct)
+	}
+	return runComputation(c.logger, computation, inputs)
+}
+
+// Returns a run function that records the operation of a deferrable method in the coprocessor instead of
+// computing it.
+func (c *Coprocessor) recorder(method *FheLibMethod, selector uint32) func(EVMEnvironment, common.Address, common.Address, []byte, bool, trace.Span) ([]byte, error) {
+	return func(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
+		computation, result, err := recordComputation(environment, method, selector, caller, addr, input, readOnly, runSpan)
+		if err != nil {
+			return nil, err
+		}
+		logger := environment.GetLogger()
+		if err := c.Submit(computation); err != nil {
+			logger.Error("failed to submit a computation to the coprocessor", "method", method.name, "handle", computation.Handle.Hex(), "err", err)
+			return nil, err
+		}
+		result.compute = c.await(computation.Handle)
+		logger.Info("computation submitted to the coprocessor", "method", method.name, "handle", computation.Handle.Hex())
+		return computation.Handle.Bytes(), nil
+	}
+}
+
+// Run a deferrable method symbolically and return the computation of its result, which is pending.
+func recordComputation(environment EVMEnvironment, method *FheLibMethod, selector uint32, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) (*Computation, *verifiedCiphertext, error) {
+	counter := environment.FhevmData().handleCounter
+	ret, err := method.Run(&symbolicEnvironment{environment}, caller, addr, input, readOnly, runSpan)
+	if err != nil {
+		return nil, nil, err
+	}
+	handle := common.BytesToHash(ret)
+	result := getVerifiedCiphertextFromEVM(environment, handle)
+	if result == nil {
+		return nil, nil, errors.New("recorded operation has no result")
+	}
+
+	computation := &Computation{
+		Handle:   handle,
+		Block:    environment.BlockNumber().Uint64(),
+		Contract: caller,
+		Selector: selector,
+		Input:    common.CopyBytes(input),
+		Counter:  counter,
+		ChainID:  environment.ChainID(),
+	}
+	// Only 32-byte words are considered, as handles are always ABI-encoded as uint256.
+	for i := 0; i+32 <= len(input); i += 32 {
+		operand := getVerifiedCiphertextFromEVM(environment, common.BytesToHash(input[i:i+32]))
+		if operand == nil || operand == result {
+			continue
+		}
+		if operand.isPending() {
+			computation.Dependencies = append(computation.Dependencies, operand.hash())
+		} else {
+			computation.Operands = append(computation.Operands, operand.ciphertext)
+		}
+	}
+	return computation, result, nil
+}
+
+// Run the fhelib method of a computation in an environment where only its inputs are verified, and return
+// the ciphertext of its result. It runs outside of the EVM, in worker goroutines, so a panic of the method is
+// returned as a PanicError.
+func runComputation(logger Logger, computation *Computation, inputs []*tfhe.TfheCiphertext) (ct *tfhe.TfheCiphertext, err error) {
+	method, found := GetFheLibMethod(computation.Selector)
+	if !found || !method.deferrable {
+		return nil, fmt.Errorf("method %#08x can't be computed asynchronously", computation.Selector)
+	}
+	environment := newComputationEnvironment(logger, computation)
+	defer func() {
+		if value := recover(); value != nil {
+			ct, err = nil, newPanicError(environment, fheLibRegistry, append(binary.BigEndian.AppendUint32(nil, computation.Selector), computation.Input...), value)
+		}
+	}()
+	for _, ct := range inputs {
+		importCiphertextToEVM(environment, ct)
+	}
+	environment.data.handleContext = &handleContext{computation.Selector, computation.Input, computation.Contract}
+	environment.data.handleCounter = computation.Counter
+
+	ret, err := method.Run(environment, computation.Contract, common.Address{}, computation.Input, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	if handle := common.BytesToHash(ret); handle != computation.Handle {
+		return nil, fmt.Errorf("computed ciphertext %s instead of %s", handle.Hex(), computation.Handle.Hex())
+	}
+	return environment.data.verifiedCiphertexts[computation.Handle].ciphertext, nil
+}
+
+// Returns true if a deferrable method must be recorded in the coprocessor instead of being computed.
+func shouldRecordComputation(environment EVMEnvironment, method *FheLibMethod) bool {
+	return environment.FhevmParams().Coprocessor != nil && method.deferrable && environment.IsCommitting() && !environment.IsEthCall()
+}
+
+// Returns a function that waits for the ciphertext of a handle whose metadata was persisted without the
+// ciphertext, as the coprocessor computes it.
+func awaitCoprocessorCiphertext(environment EVMEnvironment, handle common.Hash) func() (*tfhe.TfheCiphertext, error) {
+	coprocessor := environment.FhevmParams().Coprocessor
+	if coprocessor == nil {
+		return func() (*tfhe.TfheCiphertext, error) {
+			return nil, fmt.Errorf("ciphertext %s is computed by a coprocessor, which isn't configured", handle.Hex())
+		}
+	}
+	return coprocessor.await(handle)
+}
+
+// An environment in which fhelib methods only check their inputs and return the handle of their result, as
+// during gas estimation, and in which pending ciphertexts aren't computed.
+type symbolicEnvironment struct {
+	EVMEnvironment
+}
+
+func (*symbolicEnvironment) IsCommitting() bool {
+	return false
+}
+
+func (*symbolicEnvironment) IsEthCall() bool {
+	return false
+}
+
+func isSymbolicExecution(environment EVMEnvironment) bool {
+	_, ok := environment.(*symbolicEnvironment)
+	return ok
+}
+
+// The environment in which a computation runs. There is no state: the inputs are imported at
+// depth 1 and the result is read from the verified ciphertexts.
+type computationEnvironment struct {
+	data    FhevmData
+	params  FhevmParams
+	logger  Logger
+	block   *big.Int
+	chainID *big.Int
+}
+
+func newComputationEnvironment(logger Logger, computation *Computation) *computationEnvironment {
+	params := DefaultFhevmParams()
+	params.SymbolicHandles = true
+	return &computationEnvironment{
+		data:    NewFhevmData(),
+		params:  params,
+		logger:  logger,
+		block:   new(big.Int).SetUint64(computation.Block),
+		chainID: computation.ChainID,
+	}
+}
+
+func (*computationEnvironment) GetState(common.Address, common.Hash) common.Hash {
+	return common.Hash{}
+}
+
+func (*computationEnvironment) SetState(common.Address, common.Hash, common.Hash) {}
+
+func (*computationEnvironment) GetNonce(common.Address) uint64 {
+	return 0
+}
+
+func (*computationEnvironment) AddBalance(common.Address, *big.Int) {}
+
+func (*computationEnvironment) GetBalance(common.Address) *big.Int {
+	return big.NewInt(0)
+}
+
+func (*computationEnvironment) Suicide(common.Address) bool {
+	return false
+}
+
+func (*computationEnvironment) GetDepth() int {
+	return 1
+}
+
+func (e *computationEnvironment) GetLogger() Logger {
+	return e.logger
+}
+
+func (*computationEnvironment) IsCommitting() bool {
+	return true
+}
+
+func (*computationEnvironment) IsEthCall() bool {
+	return false
+}
+
+func (*computationEnvironment) IsReadOnly() bool {
+	return false
+}
+
+func (*computationEnvironment) CreateContract(caller common.Address, code []byte, gas uint64, value *big.Int, address common.Address) ([]byte, common.Address, uint64, error) {
+	return nil, common.Address{}, 0, errors.New("coprocessor computations can't create contracts")
+}
+
+func (*computationEnvironment) CreateContract2(caller common.Address, code []byte, codeHash common.Hash, gas uint64, value *big.Int, address common.Address) ([]byte, common.Address, uint64, error) {
+	return nil, common.Address{}, 0, errors.New("coprocessor computations can't create contracts")
+}
+
+func (*computationEnvironment) Call(caller common.Address, addr common.Address, input []byte, gas uint64, value *big.Int) ([]byte, uint64, error) {
+	return nil, 0, errors.New("coprocessor computations can't call contracts")
+}
+
+func (e *computationEnvironment) FhevmData() *FhevmData {
+	return &e.data
+}
+
+func (e *computationEnvironment) FhevmParams() *FhevmParams {
+	return &e.params
+}
+
+func (*computationEnvironment) GasLimit() uint64 {
+	return 0
+}
+
+func (e *computationEnvironment) BlockNumber() *big.Int {
+	return e.block
+}
+
+func (e *computationEnvironment) ChainID() *big.Int {
+	return e.chainID
+}
+
+// Computations don't check authorizations.
+func (*computationEnvironment) BlockTimestamp() uint64 {
+	return 0
+}
+
+func (*computationEnvironment) OtelContext() context.Context {
+	return nil
+}
@@ -0,0 +1,116 @@
+package fhevm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+// CiphertextStore persists the ciphertexts computed by a coprocessor once their block is final, see
+// Coprocessor.FinalizeBlock. Ciphertexts persisted without their bytes in protected storage are loaded from it
+// after they are evicted from memory, including after a restart. A store shared by several nodes, e.g. a
+// database, lets them load ciphertexts that they didn't compute.
+type CiphertextStore interface {
+	Put(handle common.Hash, ct *tfhe.TfheCiphertext) error
+	// Get returns nil if the store doesn't have the ciphertext of the handle.
+	Get(handle common.Hash) (*tfhe.TfheCiphertext, error)
+}
+
+type memoryCiphertextStore struct {
+	mu          sync.RWMutex
+	ciphertexts map[common.Hash][]byte
+}
+
+// NewMemoryCiphertextStore returns an in-process store. Its ciphertexts are lost when the process stops.
+func NewMemoryCiphertextStore() CiphertextStore {
+	return &memoryCiphertextStore{ciphertexts: make(map[common.Hash][]byte)}
+}
+
+func (s *memoryCiphertextStore) Put(handle common.Hash, ct *tfhe.TfheCiphertext) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ciphertexts[handle] = encodeStoredCiphertext(ct)
+	return nil
+}
+
+func (s *memoryCiphertextStore) Get(handle common.Hash) (*tfhe.TfheCiphertext, error) {
+	s.mu.RLock()
+	data, ok := s.ciphertexts[handle]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+	return decodeStoredCiphertext(handle, data)
+}
+
+type fileCiphertextStore struct {
+	dir string
+}
+
+// NewFileCiphertextStore returns a store that writes each ciphertext to a file of the directory, named after its
+// handle. The directory is created if it doesn't exist.
+func NewFileCiphertextStore(dir string) (CiphertextStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &fileCiphertextStore{dir: dir}, nil
+}
+
+func (s *fileCiphertextStore) path(handle common.Hash) string {
+	return filepath.Join(s.dir, handle.Hex()[2:])
+}
+
+// Writes the ciphertext to a temporary file and renames it, so that a crash never leaves a partial file behind.
+func (s *fileCiphertextStore) Put(handle common.Hash, ct *tfhe.TfheCiphertext) error {
+	tmp, err := os.CreateTemp(s.dir, handle.Hex()[2:]+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(encodeStoredCiphertext(ct)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(handle))
+}
+
+func (s *fileCiphertextStore) Get(handle common.Hash) (*tfhe.TfheCiphertext, error) {
+	data, err := os.ReadFile(s.path(handle))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeStoredCiphertext(handle, data)
+}
+
+// A stored ciphertext is its type byte followed by its serialization.
+func encodeStoredCiphertext(ct *tfhe.TfheCiphertext) []byte {
+	return append([]byte{byte(ct.FheUintType)}, ct.Serialize()...)
+}
+
+func decodeStoredCiphertext(handle common.Hash, data []byte) (*tfhe.TfheCiphertext, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("stored ciphertext %s is empty", handle.Hex())
+	}
+	ct := new(tfhe.TfheCiphertext)
+	if err := ct.Deserialize(data[1:], tfhe.FheUintType(data[0])); err != nil {
+		return nil, fmt.Errorf("stored ciphertext %s: %w", handle.Hex(), err)
+	}
+	// Symbolic handles aren't the hash of the ciphertext.
+	ct.Hash = &handle
+	return ct, nil
+}
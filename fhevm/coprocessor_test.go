@@ -0,0 +1,214 @@
+package fhevm
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	fhevm_crypto "github.com/zama-ai/fhevm-go/fhevm/crypto"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+func newCoprocessorTestEnvironment(finalityDepth uint64) (*MockEVMEnvironment, *Coprocessor) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	environment.chainID = 9000
+	environment.blockNumber = 10
+	coprocessor := NewCoprocessor(CoprocessorConfig{Workers: 2, FinalityDepth: finalityDepth, Timeout: 5 * time.Second}, NewMemoryComputationQueue(), nil, nil)
+	environment.fhevmParams.Coprocessor = coprocessor
+	return environment, coprocessor
+}
+
+// Computes (2 + 3) + 3 and returns the two result handles.
+func runChainedAdds(t *testing.T, environment *MockEVMEnvironment, contract common.Address) (common.Hash, common.Hash) {
+	lhs := verifyTfheCiphertextInTestMemory(environment, new(tfhe.TfheCiphertext).TrivialEncrypt(*big.NewInt(2), tfhe.FheUint8), environment.depth).GetHash()
+	rhs := verifyTfheCiphertextInTestMemory(environment, new(tfhe.TfheCiphertext).TrivialEncrypt(*big.NewInt(3), tfhe.FheUint8), environment.depth).GetHash()
+	allowHandle(environment, lhs, contract)
+	allowHandle(environment, rhs, contract)
+	out, err := FheLibRun(environment, contract, contract, toBinaryOpInput("fheAdd(uint256,uint256,bytes1)", lhs, rhs), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	first := common.BytesToHash(out)
+	out, err = FheLibRun(environment, contract, contract, toBinaryOpInput("fheAdd(uint256,uint256,bytes1)", first, rhs), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return first, common.BytesToHash(out)
+}
+
+func decryptInTest(t *testing.T, environment *MockEVMEnvironment, contract common.Address, handle common.Hash) uint64 {
+	out, err := FheLibRun(environment, contract, contract, toDecryptInput("decrypt(uint256)", handle, nil), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return new(big.Int).SetBytes(out).Uint64()
+}
+
+func TestCoprocessorComputesRecordedOperations(t *testing.T) {
+	environment, coprocessor := newCoprocessorTestEnvironment(0)
+	contract := common.HexToAddress("0x1000")
+	first, second := runChainedAdds(t, environment, contract)
+
+	// Results are the same as with synchronous symbolic handles.
+	expectedFirst, expectedSecond := runChainedAdds(t, newSymbolicHandlesTestEnvironment(), contract)
+	if first != expectedFirst || second != expectedSecond {
+		t.Fatalf("expected the handles of the coprocessor mode to be the symbolic handles")
+	}
+
+	// Nothing is computed before the workers start, and gas doesn't depend on it.
+	for _, handle := range []common.Hash{first, second} {
+		if !environment.FhevmData().verifiedCiphertexts[handle].isPending() {
+			t.Fatalf("expected the result %s to be pending", handle.Hex())
+		}
+	}
+	gas := FheLibRequiredGas(environment, 0, toBinaryOpInput("fheAdd(uint256,uint256,bytes1)", first, second))
+	if gas != environment.FhevmParams().GasCosts.FheAddSub[tfhe.FheUint8] {
+		t.Fatalf("expected the gas of an addition of pending ciphertexts, got %d", gas)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	coprocessor.Start(ctx)
+	if value := decryptInTest(t, environment, contract, second); value != 8 {
+		t.Fatalf("decrypt result not equal to value, result %v != value %v", value, 8)
+	}
+	if value := decryptInTest(t, environment, contract, first); value != 5 {
+		t.Fatalf("decrypt result not equal to value, result %v != value %v", value, 5)
+	}
+}
+
+func TestCoprocessorPersistsMetadataOnly(t *testing.T) {
+	environment, coprocessor := newCoprocessorTestEnvironment(0)
+	contract := common.HexToAddress("0x1000")
+	_, handle := runChainedAdds(t, environment, contract)
+	protectedStorage := fhevm_crypto.CreateProtectedStorageContractAddress(contract)
+	persistIfVerifiedCiphertext(crypto.Keccak256Hash([]byte{1}), handle, protectedStorage, environment)
+
+	ciphertext := getCiphertextFromProtectedStoage(environment, contract, handle)
+	if ciphertext == nil || !ciphertext.isComputedByCoprocessor() || ciphertext.metadata.fheUintType != tfhe.FheUint8 {
+		t.Fatalf("expected only the metadata of the pending ciphertext to be persisted")
+	}
+
+	// Load the ciphertext back in another transaction.
+	fhevmData := NewFhevmData()
+	environment.fhevmData = &fhevmData
+	if err := verifyIfCiphertextHandle(handle, environment, contract); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !environment.FhevmData().verifiedCiphertexts[handle].isPending() {
+		t.Fatalf("expected the loaded ciphertext to be pending")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	coprocessor.Start(ctx)
+	if value := decryptInTest(t, environment, contract, handle); value != 8 {
+		t.Fatalf("decrypt result not equal to value, result %v != value %v", value, 8)
+	}
+
+	environment.ethCall = true
+	input := append(crypto.Keccak256([]byte("getCiphertext(address,uint256)"))[0:4], common.BytesToHash(contract.Bytes()).Bytes()...)
+	out, err := FheLibRun(environment, contract, contract, append(input, handle.Bytes()...), true)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	ct, err := coprocessor.Ciphertext(ctx, handle)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if common.Bytes2Hex(out) != common.Bytes2Hex(ct.Serialize()) {
+		t.Fatalf("expected getCiphertext to return the ciphertext computed by the coprocessor")
+	}
+}
+
+func TestCoprocessorFinalizeBlock(t *testing.T) {
+	environment, coprocessor := newCoprocessorTestEnvironment(2)
+	contract := common.HexToAddress("0x1000")
+	runChainedAdds(t, environment, contract)
+
+	// Block 10 isn't final yet, so there is nothing to wait for.
+	if err := coprocessor.FinalizeBlock(context.Background(), 11); err != nil {
+		t.Fatalf(err.Error())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := coprocessor.FinalizeBlock(ctx, 12); err == nil {
+		t.Fatalf("expected finalization to wait for the computations of block 10")
+	}
+
+	workers, stop := context.WithCancel(context.Background())
+	defer stop()
+	coprocessor.Start(workers)
+	if err := coprocessor.FinalizeBlock(context.Background(), 12); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(coprocessor.blocks) != 0 || len(coprocessor.results) != 0 {
+		t.Fatalf("expected finalized blocks and their results to be pruned")
+	}
+}
+
+func TestCoprocessorRestart(t *testing.T) {
+	store, err := NewFileCiphertextStore(t.TempDir())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	environment, coprocessor := newCoprocessorTestEnvironment(0)
+	coprocessor.store = store
+	contract := common.HexToAddress("0x1000")
+	_, handle := runChainedAdds(t, environment, contract)
+	protectedStorage := fhevm_crypto.CreateProtectedStorageContractAddress(contract)
+	persistIfVerifiedCiphertext(crypto.Keccak256Hash([]byte{1}), handle, protectedStorage, environment)
+
+	workers, stop := context.WithCancel(context.Background())
+	coprocessor.Start(workers)
+	if err := coprocessor.FinalizeBlock(context.Background(), environment.blockNumber); err != nil {
+		t.Fatalf(err.Error())
+	}
+	stop()
+	if len(coprocessor.results) != 0 {
+		t.Fatalf("expected the results of the finalized block to be evicted")
+	}
+
+	// A new coprocessor, e.g. after a restart, loads the ciphertext from the store.
+	restarted := NewCoprocessor(CoprocessorConfig{Timeout: time.Second}, NewMemoryComputationQueue(), store, nil)
+	environment.fhevmParams.Coprocessor = restarted
+	fhevmData := NewFhevmData()
+	environment.fhevmData = &fhevmData
+	if err := verifyIfCiphertextHandle(handle, environment, contract); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if value := decryptInTest(t, environment, contract, handle); value != 8 {
+		t.Fatalf("decrypt result not equal to value, result %v != value %v", value, 8)
+	}
+
+	// Operations on the loaded ciphertext depend on the stored one.
+	environment.blockNumber++
+	out, err := FheLibRun(environment, contract, contract, toBinaryOpInput("fheAdd(uint256,uint256,bytes1)", handle, handle), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	restarted.Start(ctx)
+	if value := decryptInTest(t, environment, contract, common.BytesToHash(out)); value != 16 {
+		t.Fatalf("decrypt result not equal to value, result %v != value %v", value, 16)
+	}
+}
+
+func TestCoprocessorUnknownDependency(t *testing.T) {
+	environment, _ := newCoprocessorTestEnvironment(0)
+	contract := common.HexToAddress("0x1000")
+	pending := common.HexToHash("0xabcd")
+	importPendingCiphertext(environment, pending, tfhe.FheUint8, func() (*tfhe.TfheCiphertext, error) {
+		return new(tfhe.TfheCiphertext).TrivialEncrypt(*big.NewInt(7), tfhe.FheUint8), nil
+	})
+	allowHandle(environment, pending, contract)
+	_, err := FheLibRun(environment, contract, contract, toBinaryOpInput("fheAdd(uint256,uint256,bytes1)", pending, pending), false)
+	if err == nil {
+		t.Fatalf("expected an operation on a ciphertext unknown to the coprocessor to fail")
+	}
+}
@@ -0,0 +1,182 @@
+package fhevm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/zama-ai/fhevm-go/fhevm/kms"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+// DecryptionBackend decrypts FHE ciphertexts for decrypt and the mock verification of inputs, and re-encrypts them
+// for reencrypt and auditorReencrypt. It is set in FhevmParams. The client key loaded in the process is used if it
+// is nil.
+//
+// contract is the contract whose protected storage holds ct, or the zero address if ct isn't read from storage.
+type DecryptionBackend interface {
+	Decrypt(ctx context.Context, environment EVMEnvironment, contract common.Address, ct *tfhe.TfheCiphertext) (*big.Int, error)
+	// Reencrypt returns the plaintext of ct sealed to a user's 32-byte Curve25519 public key, as by
+	// box.SealAnonymous. The node only sees the sealed value.
+	Reencrypt(ctx context.Context, environment EVMEnvironment, contract common.Address, ct *tfhe.TfheCiphertext, publicKey []byte) ([]byte, error)
+}
+
+// LocalDecryptionBackend decrypts with the client key loaded in the process.
+type LocalDecryptionBackend struct{}
+
+func (LocalDecryptionBackend) Decrypt(ctx context.Context, environment EVMEnvironment, contract common.Address, ct *tfhe.TfheCiphertext) (*big.Int, error) {
+	plaintext, err := ct.Decrypt()
+	if err != nil {
+		return nil, err
+	}
+	return &plaintext, nil
+}
+
+func (b LocalDecryptionBackend) Reencrypt(ctx context.Context, environment EVMEnvironment, contract common.Address, ct *tfhe.TfheCiphertext, publicKey []byte) ([]byte, error) {
+	plaintext, err := b.Decrypt(ctx, environment, contract, ct)
+	if err != nil {
+		return nil, err
+	}
+	return encryptToUserKey(plaintext, publicKey)
+}
+
+// KmsDecryptionBackend decrypts with a remote KMS through the ValidateAndDecrypt method of the KmsEndpoint gRPC
+// service. The request field is the ABI encoding of (uint256 chainId, bytes32 handle), and the KMS must sign the
+// plaintext with one of the trusted keys, over KmsDecryptionDigest.
+//
+// It re-encrypts through the ValidateAndReencrypt method, whose request field is the ABI encoding of
+// (uint256 chainId, bytes32 handle, bytes32 publicKey). The KMS returns the plaintext sealed to the public key, so
+// the node never sees it.
+//
+// With a StateProver, both requests carry a Merkle-Patricia proof that the ciphertext is persisted in the protected
+// storage of its contract, built by BuildCiphertextProof for the block the environment executes on. Ciphertexts
+// that aren't persisted, e.g. computed in the same call, can't be proven and are sent without a proof.
+//
+// A remote KMS can't take part in consensus, so the backend only decrypts on eth_call and fails in transactions.
+type KmsDecryptionBackend struct {
+	client  kms.KmsEndpointClient
+	timeout time.Duration
+	signers []common.Address
+	prover  StateProver
+}
+
+// NewKmsDecryptionBackend returns a backend that accepts plaintexts signed by one of signers and whose requests
+// time out after the given duration, or kms.DefaultTimeout if it is zero. The connection is usually made with
+// kms.Dial. Requests carry no proof if prover is nil.
+func NewKmsDecryptionBackend(client kms.KmsEndpointClient, timeout time.Duration, signers []common.Address, prover StateProver) *KmsDecryptionBackend {
+	if timeout == 0 {
+		timeout = kms.DefaultTimeout
+	}
+	return &KmsDecryptionBackend{client: client, timeout: timeout, signers: signers, prover: prover}
+}
+
+var kmsDecryptionTypeHash = crypto.Keccak256Hash([]byte("Decryption(uint256 handle,uint256 plaintext)"))
+
+// KmsDecryptionDigest returns the EIP-712 digest that the KMS signs over the plaintext of a handle decrypted by
+// KmsDecryptionBackend.
+func KmsDecryptionDigest(chainID *big.Int, handle common.Hash, plaintext *big.Int) common.Hash {
+	structHash := crypto.Keccak256Hash(
+		kmsDecryptionTypeHash.Bytes(),
+		handle.Bytes(),
+		common.BigToHash(plaintext).Bytes(),
+	)
+	return eip712Digest(chainID, decryptionOracleAddress, structHash)
+}
+
+// Returns the proof of a ciphertext persisted by contract, or nil if the backend has no prover or the ciphertext
+// isn't persisted.
+func (b *KmsDecryptionBackend) proveCiphertext(environment EVMEnvironment, contract common.Address, handle common.Hash) (*kms.Proof, error) {
+	if b.prover == nil || contract == (common.Address{}) || getCiphertextFromProtectedStoage(environment, contract, handle) == nil {
+		return nil, nil
+	}
+	stateRoot, stateTrie, openStorageTrie, err := b.prover.OpenTries(environment)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := BuildCiphertextProof(stateRoot, stateTrie, openStorageTrie, contract, handle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prove the ciphertext of %s: %w", handle.Hex(), err)
+	}
+	return proof.ToKmsProof(uint32(environment.BlockNumber().Uint64()))
+}
+
+func (b *KmsDecryptionBackend) Decrypt(ctx context.Context, environment EVMEnvironment, contract common.Address, ct *tfhe.TfheCiphertext) (*big.Int, error) {
+	if !environment.IsEthCall() {
+		return nil, errors.New("KMS decryptions are only supported on eth_call")
+	}
+	fheType, err := kms.ToFheType(ct.Type())
+	if err != nil {
+		return nil, err
+	}
+	handle := ct.GetHash()
+	proof, err := b.proveCiphertext(environment, contract, handle)
+	if err != nil {
+		return nil, err
+	}
+	request := append(common.BigToHash(environment.ChainID()).Bytes(), handle.Bytes()...)
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+	res, err := b.client.ValidateAndDecrypt(ctx, &kms.DecryptionRequest{FheType: fheType, Ciphertext: ct.Serialize(), Request: request, Proof: proof})
+	if err != nil {
+		return nil, err
+	}
+	if res.FheType != fheType {
+		return nil, fmt.Errorf("KMS returned type %s, expected %s", res.FheType, fheType)
+	}
+	plaintext := new(big.Int).SetBytes(res.Plaintext)
+	if err := verifyDecryptionOracleSignature(b.signers, KmsDecryptionDigest(environment.ChainID(), handle, plaintext), res.Signature); err != nil {
+		return nil, fmt.Errorf("KMS signature of %s is invalid: %w", handle.Hex(), err)
+	}
+	return plaintext, nil
+}
+
+func (b *KmsDecryptionBackend) Reencrypt(ctx context.Context, environment EVMEnvironment, contract common.Address, ct *tfhe.TfheCiphertext, publicKey []byte) ([]byte, error) {
+	if !environment.IsEthCall() {
+		return nil, errors.New("KMS reencryptions are only supported on eth_call")
+	}
+	if len(publicKey) != 32 {
+		return nil, fmt.Errorf("public key must be 32 bytes, got %d", len(publicKey))
+	}
+	fheType, err := kms.ToFheType(ct.Type())
+	if err != nil {
+		return nil, err
+	}
+	handle := ct.GetHash()
+	proof, err := b.proveCiphertext(environment, contract, handle)
+	if err != nil {
+		return nil, err
+	}
+	request := append(common.BigToHash(environment.ChainID()).Bytes(), handle.Bytes()...)
+	request = append(request, publicKey...)
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+	res, err := b.client.ValidateAndReencrypt(ctx, &kms.ReencryptionRequest{FheType: fheType, Ciphertext: ct.Serialize(), Request: request, Proof: proof})
+	if err != nil {
+		return nil, err
+	}
+	if res.FheType != fheType {
+		return nil, fmt.Errorf("KMS returned type %s, expected %s", res.FheType, fheType)
+	}
+	if len(res.ReencryptedCiphertext) == 0 {
+		return nil, fmt.Errorf("KMS returned an empty reencryption of %s", handle.Hex())
+	}
+	return res.ReencryptedCiphertext, nil
+}
+
+func getDecryptionBackend(environment EVMEnvironment) DecryptionBackend {
+	if backend := environment.FhevmParams().DecryptionBackend; backend != nil {
+		return backend
+	}
+	return LocalDecryptionBackend{}
+}
+
+func decryptionContext(environment EVMEnvironment) context.Context {
+	if ctx := environment.OtelContext(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
@@ -0,0 +1,326 @@
+package fhevm
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	fhevm_crypto "github.com/zama-ai/fhevm-go/fhevm/crypto"
+	"github.com/zama-ai/fhevm-go/fhevm/kms"
+	"github.com/zama-ai/fhevm-go/fhevm/kms/kmstest"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+	"golang.org/x/crypto/nacl/box"
+	"google.golang.org/grpc/credentials"
+)
+
+// Returns a KMS that decrypts with the local client key and signs the plaintexts with key, for the chain and
+// handle of the request.
+func newSigningKmsServer(key *ecdsa.PrivateKey) *kmstest.Server {
+	return &kmstest.Server{DecryptFunc: func(ctx context.Context, req *kms.DecryptionRequest) (*kms.DecryptionResponse, error) {
+		res, err := kmstest.DecryptLocally(req)
+		if err != nil {
+			return nil, err
+		}
+		chainID := new(big.Int).SetBytes(req.Request[:32])
+		handle := common.BytesToHash(req.Request[32:64])
+		signature, err := crypto.Sign(KmsDecryptionDigest(chainID, handle, new(big.Int).SetBytes(res.Plaintext)).Bytes(), key)
+		if err != nil {
+			return nil, err
+		}
+		res.Signature = signature
+		return res, nil
+	}}
+}
+
+func newKmsTestEnvironment(t *testing.T, server *kmstest.Server, signers ...common.Address) *MockEVMEnvironment {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	environment.ethCall = true
+	environment.chainID = 9000
+	environment.fhevmParams.DecryptionBackend = NewKmsDecryptionBackend(kms.NewKmsEndpointClient(server.Start(t)), 0, signers, nil)
+	return environment
+}
+
+func TestKmsDecryptionBackend(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	server := newSigningKmsServer(key)
+	environment := newKmsTestEnvironment(t, server, crypto.PubkeyToAddress(key.PublicKey))
+	ct := verifyCiphertextInTestMemory(environment, 42, environment.depth, tfhe.FheUint16)
+
+	plaintext, err := DecryptCiphertext(environment, ct.GetHash())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if plaintext.Uint64() != 42 {
+		t.Fatalf("decrypt result not equal to value, result %v != value %v", plaintext.Uint64(), 42)
+	}
+	requests := server.Requests()
+	if len(requests) != 1 || requests[0].FheType != kms.FheType_Euint16 || string(requests[0].Ciphertext) != string(ct.Serialize()) {
+		t.Fatalf("expected the ciphertext to be sent to the KMS")
+	}
+	expectedRequest := append(common.BigToHash(big.NewInt(9000)).Bytes(), ct.GetHash().Bytes()...)
+	if string(requests[0].Request) != string(expectedRequest) {
+		t.Fatalf("expected the request to carry the chain ID and the handle")
+	}
+}
+
+func TestKmsDecryptionBackendReencrypt(t *testing.T) {
+	user, _ := crypto.GenerateKey()
+	contract := common.HexToAddress("0x1000")
+	precompile := common.HexToAddress("0x5d")
+	boxPublicKey, boxPrivateKey, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	// The node must not see the plaintext, so the KMS must not be asked to decrypt.
+	server := &kmstest.Server{DecryptFunc: func(ctx context.Context, req *kms.DecryptionRequest) (*kms.DecryptionResponse, error) {
+		t.Errorf("expected reencrypt not to decrypt through the KMS")
+		return kmstest.DecryptLocally(req)
+	}}
+	environment := newKmsTestEnvironment(t, server)
+	ct := verifyCiphertextInTestMemory(environment, 42, environment.depth, tfhe.FheUint16)
+	handle := ct.GetHash()
+	allowHandle(environment, handle, crypto.PubkeyToAddress(user.PublicKey))
+	allowHandle(environment, handle, contract)
+
+	input := toDecryptInput("reencrypt(uint256,uint256)", handle, boxPublicKey[:])
+	authorization := signAuthorization(t, environment.ChainID(), user, precompile, handle, contract, common.Hash(*boxPublicKey), int64(testBlockTimestamp)+3600)
+	out, err := FheLibRun(environment, contract, precompile, append(input, authorization...), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	length := new(big.Int).SetBytes(out[32:64]).Uint64()
+	plaintext, ok := box.OpenAnonymous(nil, out[64:64+length], boxPublicKey, boxPrivateKey)
+	if !ok {
+		t.Fatalf("failed to open the re-encrypted value")
+	}
+	if new(big.Int).SetBytes(plaintext).Uint64() != 42 {
+		t.Fatalf("reencrypt result not equal to value, result %v != value %v", new(big.Int).SetBytes(plaintext).Uint64(), 42)
+	}
+
+	requests := server.ReencryptionRequests()
+	if len(requests) != 1 || requests[0].FheType != kms.FheType_Euint16 || string(requests[0].Ciphertext) != string(ct.Serialize()) {
+		t.Fatalf("expected the ciphertext to be sent to the KMS")
+	}
+	expectedRequest := append(common.BigToHash(big.NewInt(9000)).Bytes(), handle.Bytes()...)
+	expectedRequest = append(expectedRequest, boxPublicKey[:]...)
+	if string(requests[0].Request) != string(expectedRequest) {
+		t.Fatalf("expected the request to carry the chain ID, the handle and the public key")
+	}
+
+	// Like decryptions, reencryptions through the KMS are only supported on eth_call.
+	environment.ethCall = false
+	if _, err := environment.fhevmParams.DecryptionBackend.Reencrypt(context.Background(), environment, common.Address{}, ct, boxPublicKey[:]); err == nil {
+		t.Fatalf("expected a KMS reencryption in a transaction to fail")
+	}
+	if len(server.ReencryptionRequests()) != 1 {
+		t.Fatalf("expected no request to be sent to the KMS in a transaction")
+	}
+}
+
+func TestKmsDecryptionBackendOnlyOnEthCall(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	server := newSigningKmsServer(key)
+	environment := newKmsTestEnvironment(t, server, crypto.PubkeyToAddress(key.PublicKey))
+	environment.ethCall = false
+	ct := verifyCiphertextInTestMemory(environment, 42, environment.depth, tfhe.FheUint16)
+	addr := common.Address{}
+
+	if _, err := FheLibRun(environment, addr, addr, toDecryptInput("decrypt(uint256)", ct.GetHash(), nil), false); err == nil {
+		t.Fatalf("expected a KMS decryption in a transaction to fail")
+	}
+	if len(server.Requests()) != 0 {
+		t.Fatalf("expected no request to be sent to the KMS in a transaction")
+	}
+}
+
+func TestKmsDecryptionBackendRejectsUnknownSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	environment := newKmsTestEnvironment(t, newSigningKmsServer(other), crypto.PubkeyToAddress(key.PublicKey))
+	ct := verifyCiphertextInTestMemory(environment, 42, environment.depth, tfhe.FheUint16)
+	if _, err := DecryptCiphertext(environment, ct.GetHash()); err == nil {
+		t.Fatalf("expected a plaintext signed by an unknown key to be rejected")
+	}
+
+	// The default server doesn't sign its plaintexts.
+	environment = newKmsTestEnvironment(t, &kmstest.Server{}, crypto.PubkeyToAddress(key.PublicKey))
+	ct = verifyCiphertextInTestMemory(environment, 42, environment.depth, tfhe.FheUint16)
+	if _, err := DecryptCiphertext(environment, ct.GetHash()); err == nil {
+		t.Fatalf("expected an unsigned plaintext to be rejected")
+	}
+}
+
+func TestKmsDecryptionBackendRejectsOtherHandle(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	server := &kmstest.Server{DecryptFunc: func(ctx context.Context, req *kms.DecryptionRequest) (*kms.DecryptionResponse, error) {
+		res, err := kmstest.DecryptLocally(req)
+		if err != nil {
+			return nil, err
+		}
+		digest := KmsDecryptionDigest(big.NewInt(9000), common.HexToHash("0x01"), new(big.Int).SetBytes(res.Plaintext))
+		res.Signature, err = crypto.Sign(digest.Bytes(), key)
+		return res, err
+	}}
+	environment := newKmsTestEnvironment(t, server, crypto.PubkeyToAddress(key.PublicKey))
+	ct := verifyCiphertextInTestMemory(environment, 42, environment.depth, tfhe.FheUint16)
+	if _, err := DecryptCiphertext(environment, ct.GetHash()); err == nil {
+		t.Fatalf("expected a signature over another handle to be rejected")
+	}
+}
+
+// Proves ciphertexts against the committed state of the test environment.
+type testStateProver struct {
+	root common.Hash
+}
+
+func (p testStateProver) OpenTries(environment EVMEnvironment) (common.Hash, ProofTrie, func(common.Hash, common.Hash) (ProofTrie, error), error) {
+	db := environment.(*MockEVMEnvironment).stateDb.Database()
+	stateTrie, err := db.OpenTrie(p.root)
+	if err != nil {
+		return common.Hash{}, nil, nil, err
+	}
+	openStorageTrie := func(addrHash common.Hash, storageRoot common.Hash) (ProofTrie, error) {
+		return db.OpenStorageTrie(p.root, addrHash, storageRoot)
+	}
+	return p.root, stateTrie, openStorageTrie, nil
+}
+
+func TestKmsDecryptionBackendProof(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	server := newSigningKmsServer(key)
+	environment := newKmsTestEnvironment(t, server, crypto.PubkeyToAddress(key.PublicKey))
+	contract := common.HexToAddress("0x1000")
+	ct := verifyCiphertextInTestMemory(environment, 42, environment.depth, tfhe.FheUint16)
+	persistIfVerifiedCiphertext(common.HexToHash("0x01"), ct.GetHash(), fhevm_crypto.CreateProtectedStorageContractAddress(contract), environment)
+	root, err := environment.stateDb.Commit(false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	backend := NewKmsDecryptionBackend(kms.NewKmsEndpointClient(server.Start(t)), 0, []common.Address{crypto.PubkeyToAddress(key.PublicKey)}, testStateProver{root})
+
+	plaintext, err := backend.Decrypt(context.Background(), environment, contract, ct)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if plaintext.Uint64() != 42 {
+		t.Fatalf("decrypt result not equal to value, result %v != value %v", plaintext.Uint64(), 42)
+	}
+	requests := server.Requests()
+	proof, err := CiphertextProofFromKms(requests[len(requests)-1].Proof)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	bytes, typ, err := VerifyCiphertextProof(proof)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if proof.StateRoot != root || proof.Contract != contract || typ != tfhe.FheUint16 || string(bytes) != string(requests[len(requests)-1].Ciphertext) {
+		t.Fatalf("expected the request to carry a proof of its ciphertext")
+	}
+
+	// A ciphertext that isn't persisted is sent without a proof.
+	other := verifyCiphertextInTestMemory(environment, 43, environment.depth, tfhe.FheUint16)
+	if _, err := backend.Decrypt(context.Background(), environment, contract, other); err != nil {
+		t.Fatalf(err.Error())
+	}
+	requests = server.Requests()
+	if requests[len(requests)-1].Proof != nil {
+		t.Fatalf("expected no proof for a ciphertext that isn't persisted")
+	}
+}
+
+func TestKmsDecryptionBackendTimeout(t *testing.T) {
+	server := &kmstest.Server{DecryptFunc: func(ctx context.Context, req *kms.DecryptionRequest) (*kms.DecryptionResponse, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+	backend := NewKmsDecryptionBackend(kms.NewKmsEndpointClient(server.Start(t)), 50*time.Millisecond, nil, nil)
+	environment := newTestEVMEnvironment()
+	environment.ethCall = true
+	ct := new(tfhe.TfheCiphertext).TrivialEncrypt(*big.NewInt(1), tfhe.FheUint8)
+
+	start := time.Now()
+	if _, err := backend.Decrypt(context.Background(), environment, common.Address{}, ct); err == nil {
+		t.Fatalf("expected the KMS request to time out")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("KMS request didn't time out in time")
+	}
+}
+
+func TestKmsDecryptionBackendTLS(t *testing.T) {
+	dir := t.TempDir()
+	serverCert, caFile := newTestCertificate(t, dir)
+	key, _ := crypto.GenerateKey()
+	signers := []common.Address{crypto.PubkeyToAddress(key.PublicKey)}
+	server := newSigningKmsServer(key)
+	address := server.Listen(t, credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{serverCert}}))
+	environment := newTestEVMEnvironment()
+	environment.ethCall = true
+	ct := new(tfhe.TfheCiphertext).TrivialEncrypt(*big.NewInt(7), tfhe.FheUint8)
+
+	conn, err := kms.Dial(kms.Config{Address: address, CACertFile: caFile})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conn.Close()
+	plaintext, err := NewKmsDecryptionBackend(kms.NewKmsEndpointClient(conn), 0, signers, nil).Decrypt(context.Background(), environment, common.Address{}, ct)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if plaintext.Uint64() != 7 {
+		t.Fatalf("decrypt result not equal to value, result %v != value %v", plaintext.Uint64(), 7)
+	}
+
+	// Without the CA, the KMS certificate must be rejected.
+	conn, err = kms.Dial(kms.Config{Address: address})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conn.Close()
+	if _, err := NewKmsDecryptionBackend(kms.NewKmsEndpointClient(conn), time.Second, signers, nil).Decrypt(context.Background(), environment, common.Address{}, ct); err == nil {
+		t.Fatalf("expected an untrusted KMS certificate to be rejected")
+	}
+}
+
+// Returns a self-signed certificate for 127.0.0.1 and the path of its PEM file.
+func newTestCertificate(t *testing.T, dir string) (tls.Certificate, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kms"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	path := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf(err.Error())
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, path
+}
@@ -0,0 +1,385 @@
+package fhevm
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+	fhevm_crypto "github.com/zama-ai/fhevm-go/fhevm/crypto"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Asynchronous decryption oracle.
+//
+// A contract calls requestDecryption (or teeRequestDecryption) with a list of handles and the selector of
+// its callback function. The request is recorded in the protected storage of a well-known oracle address
+// and its ciphertexts are persisted there, so that an off-chain relayer can read them with getCiphertext.
+// The relayer obtains the plaintexts from the KMS and calls fulfillDecryption with
+// (uint256 requestId, uint256[] plaintexts, bytes signature). fulfillDecryption checks the request and the KMS
+// signature, marks the request as fulfilled and only then calls the contract's callback with
+// (uint256 requestId, uint256[] plaintexts), from the oracle address. The callback must check that the caller
+// is DecryptionOracleAddress(), so that only plaintexts signed by the KMS reach it. If the callback reverts,
+// so does fulfillDecryption, and the request stays pending. The relayer also checks the signature, with
+// VerifyDecryptionResult, before it submits the plaintexts.
+//
+// The signature is either a single 65-byte signature over DecryptionResultDigest of the whole request,
+// or one signature per handle, concatenated, each over DecryptionResultDigest of that handle alone.
+var decryptionOracleAddress = common.BytesToAddress([]byte{95})
+
+// Layout of a decryption request record, relative to its base slot. The handles are followed by their types.
+const (
+	decryptionRequestContractSlot = iota
+	decryptionRequestSelectorSlot
+	decryptionRequestStatusSlot
+	decryptionRequestTeeSlot
+	decryptionRequestLengthSlot
+	decryptionRequestHandlesSlot
+)
+
+// Slot of the next request id in the oracle's protected storage.
+var decryptionRequestCounterSlot = common.Hash{}
+
+var decryptionResultTypeHash = crypto.Keccak256Hash([]byte("DecryptionResult(uint256 requestId,bytes32[] handles,uint256[] plaintexts)"))
+
+// StateReader gives read access to the blockchain state, e.g. for an off-chain relayer.
+type StateReader interface {
+	GetState(common.Address, common.Hash) common.Hash
+}
+
+// A decryption request recorded by requestDecryption.
+type DecryptionRequest struct {
+	ID               uint64
+	Contract         common.Address
+	CallbackSelector [4]byte
+	Handles          []common.Hash
+	Types            []tfhe.FheUintType
+	// Tee is true if the handles point to TEE ciphertexts.
+	Tee       bool
+	Fulfilled bool
+}
+
+// Returns the address whose protected storage holds the ciphertexts of pending decryption requests.
+// The relayer passes it to getCiphertext.
+func DecryptionOracleAddress() common.Address {
+	return decryptionOracleAddress
+}
+
+func getDecryptionOracleProtectedStorage() common.Address {
+	return fhevm_crypto.CreateProtectedStorageContractAddress(decryptionOracleAddress)
+}
+
+func getDecryptionRequestSlot(id uint64, offset uint64) common.Hash {
+	base := newInt(crypto.Keccak256([]byte("decryptionRequest"), uint256.NewInt(id).PaddedBytes(32)))
+	return common.Hash(base.AddUint64(base, offset).Bytes32())
+}
+
+// Returns the id that the next decryption request will get. Requests have ids from 0 to this value, exclusive.
+func NextDecryptionRequestID(state StateReader) uint64 {
+	return newInt(state.GetState(getDecryptionOracleProtectedStorage(), decryptionRequestCounterSlot).Bytes()).Uint64()
+}
+
+// Returns the decryption request with the given id or nil if it doesn't exist.
+func GetDecryptionRequest(state StateReader, id uint64) *DecryptionRequest {
+	if id >= NextDecryptionRequestID(state) {
+		return nil
+	}
+	protectedStorage := getDecryptionOracleProtectedStorage()
+	get := func(offset uint64) common.Hash {
+		return state.GetState(protectedStorage, getDecryptionRequestSlot(id, offset))
+	}
+	request := &DecryptionRequest{
+		ID:        id,
+		Contract:  common.BytesToAddress(get(decryptionRequestContractSlot).Bytes()),
+		Tee:       get(decryptionRequestTeeSlot) == flag,
+		Fulfilled: get(decryptionRequestStatusSlot) == flag,
+	}
+	copy(request.CallbackSelector[:], get(decryptionRequestSelectorSlot).Bytes())
+	length := newInt(get(decryptionRequestLengthSlot).Bytes()).Uint64()
+	for i := uint64(0); i < length; i++ {
+		request.Handles = append(request.Handles, get(decryptionRequestHandlesSlot+i))
+		request.Types = append(request.Types, tfhe.FheUintType(get(decryptionRequestHandlesSlot + length + i)[31]))
+	}
+	return request
+}
+
+// Returns the ciphertext of a handle in a pending decryption request and its type, or nil if it isn't stored.
+func GetDecryptionRequestCiphertext(state StateReader, handle common.Hash) ([]byte, tfhe.FheUintType) {
+	ciphertext := getCiphertextFromProtectedStoage(state, decryptionOracleAddress, handle)
+	if ciphertext == nil {
+		return nil, 0
+	}
+	return ciphertext.bytes, ciphertext.metadata.fheUintType
+}
+
+// Returns the EIP-712 digest that the KMS signs over the plaintexts of a decryption request on the given chain.
+func DecryptionResultDigest(chainID *big.Int, requestID uint64, handles []common.Hash, plaintexts []*big.Int) common.Hash {
+	encodedHandles := make([]byte, 0, 32*len(handles))
+	for _, handle := range handles {
+		encodedHandles = append(encodedHandles, handle.Bytes()...)
+	}
+	encodedPlaintexts := make([]byte, 0, 32*len(plaintexts))
+	for _, plaintext := range plaintexts {
+		encodedPlaintexts = append(encodedPlaintexts, common.BigToHash(plaintext).Bytes()...)
+	}
+	structHash := crypto.Keccak256Hash(
+		decryptionResultTypeHash.Bytes(),
+		uint256.NewInt(requestID).PaddedBytes(32),
+		crypto.Keccak256(encodedHandles),
+		crypto.Keccak256(encodedPlaintexts),
+	)
+	return eip712Digest(chainID, decryptionOracleAddress, structHash)
+}
+
+var fulfillDecryptionSelector = crypto.Keccak256([]byte("fulfillDecryption(uint256,uint256[],bytes)"))[0:4]
+
+// Returns the calldata of the fulfillDecryption call that delivers the plaintexts of a request: its selector
+// followed by the ABI encoding of (uint256 requestId, uint256[] plaintexts, bytes signature).
+func EncodeFulfillDecryption(request *DecryptionRequest, plaintexts []*big.Int, signature []byte) []byte {
+	ret := append([]byte{}, fulfillDecryptionSelector...)
+	ret = append(ret, uint256.NewInt(request.ID).PaddedBytes(32)...)
+	// Offsets of the dynamic arguments are relative to the start of the arguments.
+	plaintextsOffset := 3 * 32
+	signatureOffset := plaintextsOffset + 32 + 32*len(plaintexts)
+	ret = append(ret, uint256.NewInt(uint64(plaintextsOffset)).PaddedBytes(32)...)
+	ret = append(ret, uint256.NewInt(uint64(signatureOffset)).PaddedBytes(32)...)
+	ret = append(ret, encodePlaintexts(plaintexts)...)
+	// The padding of the signature ends the arguments at a multiple of 32 bytes, after the selector.
+	return append(ret, padArrayTo32Multiple(toEVMBytes(signature))...)
+}
+
+// Returns the calldata of the callback to the requesting contract: the callback selector followed by
+// the ABI encoding of (uint256 requestId, uint256[] plaintexts).
+func encodeDecryptionCallback(request *DecryptionRequest, plaintexts []*big.Int) []byte {
+	ret := append([]byte{}, request.CallbackSelector[:]...)
+	ret = append(ret, uint256.NewInt(request.ID).PaddedBytes(32)...)
+	ret = append(ret, uint256.NewInt(2*32).PaddedBytes(32)...)
+	return append(ret, encodePlaintexts(plaintexts)...)
+}
+
+// Encodes the length and the elements of an ABI uint256[] of plaintexts.
+func encodePlaintexts(plaintexts []*big.Int) []byte {
+	ret := make([]byte, 0, 32+32*len(plaintexts))
+	ret = append(ret, uint256.NewInt(uint64(len(plaintexts))).PaddedBytes(32)...)
+	for _, plaintext := range plaintexts {
+		ret = append(ret, common.BigToHash(plaintext).Bytes()...)
+	}
+	return ret
+}
+
+// Encodes handles as an ABI uint256[].
+func encodeWordArray(handles []common.Hash) []byte {
+	ret := make([]byte, 0, 64+32*len(handles))
+	ret = append(ret, uint256.NewInt(32).PaddedBytes(32)...)
+	ret = append(ret, uint256.NewInt(uint64(len(handles))).PaddedBytes(32)...)
+	for _, handle := range handles {
+		ret = append(ret, handle.Bytes()...)
+	}
+	return ret
+}
+
+var (
+	decryptionRequestArgs     = mustParseABITypes("(bytes4,uint256[])")
+	decryptionFulfillmentArgs = mustParseABITypes("(uint256,uint256[],bytes)")
+)
+
+// Decodes the (bytes4 callbackSelector, uint256[] handles) input of requestDecryption.
+func decodeDecryptionRequestInput(input []byte) (selector [4]byte, handles []common.Hash, err error) {
+	args, err := decodeABIArgs(decryptionRequestArgs, input)
+	if err != nil {
+		return selector, nil, err
+	}
+	copy(selector[:], args.bytes(0))
+	handles = args.words(1)
+	if len(handles) == 0 {
+		return selector, nil, errors.New("at least one handle is required")
+	}
+	return selector, handles, nil
+}
+
+func requestDecryptionRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return doRequestDecryption(environment, caller, input, readOnly, runSpan, false)
+}
+
+func teeRequestDecryptionRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return doRequestDecryption(environment, caller, input, readOnly, runSpan, true)
+}
+
+func doRequestDecryption(environment EVMEnvironment, caller common.Address, input []byte, readOnly bool, runSpan trace.Span, tee bool) ([]byte, error) {
+	logger := environment.GetLogger()
+	if environment.IsEthCall() {
+		msg := "requestDecryption not supported on EthCall"
+		logger.Error(msg)
+		return nil, errors.New(msg)
+	}
+	if readOnly {
+		return nil, ErrWriteProtection
+	}
+	selector, handles, err := decodeDecryptionRequestInput(input)
+	if err != nil {
+		logger.Error("requestDecryption failed to decode input", "err", err, "input", hex.EncodeToString(input))
+		return nil, err
+	}
+
+	types := make([]tfhe.FheUintType, 0, len(handles))
+	for _, handle := range handles {
+		ct := getVerifiedCiphertext(environment, handle)
+		if ct == nil {
+			msg := "requestDecryption unverified ciphertext handle"
+			logger.Error(msg, "handle", handle.Hex())
+			return nil, errors.New(msg)
+		}
+		if !isHandleUsable(environment, handle, caller) {
+			msg := "requestDecryption caller is not allowed to use the ciphertext handle"
+			logger.Error(msg, "handle", handle.Hex(), "caller", caller.Hex())
+			return nil, errors.New(msg)
+		}
+		if err := verifyUnlocked(environment, handle); err != nil {
+			return nil, err
+		}
+		types = append(types, ct.fheUintType())
+	}
+	otelDescribeOperandsFheTypes(runSpan, types...)
+
+	protectedStorage := getDecryptionOracleProtectedStorage()
+	id := NextDecryptionRequestID(environment)
+	environment.SetState(protectedStorage, decryptionRequestCounterSlot, uint256.NewInt(id+1).Bytes32())
+
+	set := func(offset uint64, value common.Hash) {
+		environment.SetState(protectedStorage, getDecryptionRequestSlot(id, offset), value)
+	}
+	set(decryptionRequestContractSlot, common.BytesToHash(caller.Bytes()))
+	set(decryptionRequestSelectorSlot, common.BytesToHash(common.RightPadBytes(selector[:], 32)))
+	if tee {
+		set(decryptionRequestTeeSlot, flag)
+	}
+	set(decryptionRequestLengthSlot, uint256.NewInt(uint64(len(handles))).Bytes32())
+	for i, handle := range handles {
+		handleSlot := getDecryptionRequestSlot(id, decryptionRequestHandlesSlot+uint64(i))
+		environment.SetState(protectedStorage, handleSlot, handle)
+		// Persist the ciphertext for the relayer, flagging the handle's slot like SSTORE does.
+		flagHandleLocation := crypto.Keccak256Hash(crypto.Keccak256Hash(handleSlot.Bytes()).Bytes())
+		persistIfVerifiedCiphertext(flagHandleLocation, handle, protectedStorage, environment)
+		set(decryptionRequestHandlesSlot+uint64(len(handles)+i), uint256.NewInt(uint64(types[i])).Bytes32())
+	}
+
+	if environment.IsCommitting() {
+		logger.Info("requestDecryption success",
+			"id", id,
+			"contract", caller.Hex(),
+			"handles", len(handles),
+			"tee", tee)
+	}
+	return uint256.NewInt(id).PaddedBytes(32), nil
+}
+
+func fulfillDecryptionRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	logger := environment.GetLogger()
+	if readOnly {
+		return nil, ErrWriteProtection
+	}
+	args, err := decodeABIArgs(decryptionFulfillmentArgs, input)
+	if err != nil {
+		logger.Error("fulfillDecryption failed to decode input", "err", err)
+		return nil, err
+	}
+	idInt := newInt(args.word(0).Bytes())
+	plaintextWords := args.words(1)
+	signature := args.bytes(2)
+
+	if !idInt.IsUint64() {
+		msg := "fulfillDecryption unknown request"
+		logger.Error(msg, "id", idInt)
+		return nil, errors.New(msg)
+	}
+	request := GetDecryptionRequest(environment, idInt.Uint64())
+	if request == nil {
+		msg := "fulfillDecryption unknown request"
+		logger.Error(msg, "id", idInt)
+		return nil, errors.New(msg)
+	}
+	if request.Fulfilled {
+		msg := "fulfillDecryption request already fulfilled"
+		logger.Error(msg, "id", request.ID)
+		return nil, errors.New(msg)
+	}
+	if len(plaintextWords) != len(request.Handles) {
+		msg := "fulfillDecryption plaintext count doesn't match the request"
+		logger.Error(msg, "id", request.ID, "plaintexts", len(plaintextWords), "handles", len(request.Handles))
+		return nil, errors.New(msg)
+	}
+
+	plaintexts := make([]*big.Int, 0, len(plaintextWords))
+	for _, word := range plaintextWords {
+		plaintexts = append(plaintexts, word.Big())
+	}
+	if err := VerifyDecryptionResult(environment.ChainID(), environment.FhevmParams().DecryptionOracleSigners, request, plaintexts, signature); err != nil {
+		msg := "fulfillDecryption invalid KMS signature"
+		logger.Error(msg, "id", request.ID, "err", err)
+		return nil, errors.New(msg)
+	}
+
+	protectedStorage := getDecryptionOracleProtectedStorage()
+	environment.SetState(protectedStorage, getDecryptionRequestSlot(request.ID, decryptionRequestStatusSlot), flag)
+	// The relayer doesn't need the ciphertexts anymore.
+	for i, handle := range request.Handles {
+		handleSlot := getDecryptionRequestSlot(request.ID, decryptionRequestHandlesSlot+uint64(i))
+		flagHandleLocation := crypto.Keccak256Hash(crypto.Keccak256Hash(handleSlot.Bytes()).Bytes())
+		garbageCollectProtectedStorage(flagHandleLocation, handle, protectedStorage, environment)
+	}
+
+	// The request is fulfilled before the callback runs, so that the callback can't fulfill it again.
+	callbackGas := environment.FhevmParams().GasCosts.DecryptionCallback
+	if _, _, err := environment.Call(decryptionOracleAddress, request.Contract, encodeDecryptionCallback(request, plaintexts), callbackGas, big.NewInt(0)); err != nil {
+		msg := "fulfillDecryption callback failed"
+		logger.Error(msg, "id", request.ID, "contract", request.Contract.Hex(), "err", err)
+		return nil, errors.New(msg)
+	}
+
+	if environment.IsCommitting() {
+		logger.Info("fulfillDecryption success",
+			"id", request.ID,
+			"contract", request.Contract.Hex(),
+			"relayer", caller.Hex())
+	}
+	return nil, nil
+}
+
+// VerifyDecryptionResult checks that one of signers, the KMS keys trusted by the chain, signed the plaintexts of a
+// request on the chain with the given ID, either with one signature for the whole request or with one per handle. fulfillDecryption checks it
+// against FhevmParams.DecryptionOracleSigners, and a relayer should check it before submitting the plaintexts.
+func VerifyDecryptionResult(chainID *big.Int, signers []common.Address, request *DecryptionRequest, plaintexts []*big.Int, signature []byte) error {
+	if len(plaintexts) != len(request.Handles) {
+		return errors.New("plaintext count doesn't match the request")
+	}
+	if len(signature) == crypto.SignatureLength {
+		return verifyDecryptionOracleSignature(signers, DecryptionResultDigest(chainID, request.ID, request.Handles, plaintexts), signature)
+	}
+	if len(signature) != crypto.SignatureLength*len(request.Handles) {
+		return errors.New("signature must be 65 bytes or 65 bytes per handle")
+	}
+	for i := range request.Handles {
+		digest := DecryptionResultDigest(chainID, request.ID, request.Handles[i:i+1], plaintexts[i:i+1])
+		if err := verifyDecryptionOracleSignature(signers, digest, signature[crypto.SignatureLength*i:crypto.SignatureLength*(i+1)]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyDecryptionOracleSignature(signers []common.Address, digest common.Hash, signature []byte) error {
+	signer, err := recoverSigner(digest, signature)
+	if err != nil {
+		return err
+	}
+	for _, s := range signers {
+		if s == signer {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not a decryption oracle signer", signer.Hex())
+}
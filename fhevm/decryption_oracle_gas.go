@@ -0,0 +1,27 @@
+package fhevm
+
+import "encoding/hex"
+
+func requestDecryptionRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
+	_, handles, err := decodeDecryptionRequestInput(input)
+	if err != nil {
+		environment.GetLogger().Error("requestDecryption RequiredGas() failed to decode input", "err", err, "input", hex.EncodeToString(input))
+		return 0
+	}
+	gasCosts := environment.FhevmParams().GasCosts
+	gas := gasCosts.DecryptionRequest
+	for _, handle := range handles {
+		ct := getVerifiedCiphertext(environment, handle)
+		if ct == nil {
+			environment.GetLogger().Error("requestDecryption RequiredGas() input doesn't point to verified ciphertext", "handle", handle.Hex())
+			return 0
+		}
+		gas += gasCosts.DecryptionRequestPerHandle[ct.fheUintType()]
+	}
+	return gas
+}
+
+func fulfillDecryptionRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
+	gasCosts := environment.FhevmParams().GasCosts
+	return gasCosts.DecryptionFulfill + gasCosts.DecryptionCallback
+}
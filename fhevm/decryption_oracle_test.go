@@ -0,0 +1,280 @@
+package fhevm
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+var testCallbackSelector = [4]byte{0xde, 0xad, 0xbe, 0xef}
+
+func toRequestDecryptionInput(method string, handles ...common.Hash) []byte {
+	input := crypto.Keccak256([]byte(method))[0:4]
+	input = append(input, common.RightPadBytes(testCallbackSelector[:], 32)...)
+	input = append(input, uint256.NewInt(64).PaddedBytes(32)...)
+	input = append(input, uint256.NewInt(uint64(len(handles))).PaddedBytes(32)...)
+	for _, handle := range handles {
+		input = append(input, handle.Bytes()...)
+	}
+	return input
+}
+
+// A test contract that records the decryption callbacks it receives, from any caller.
+type testCallbackContract struct {
+	callers []common.Address
+	inputs  [][]byte
+	revert  bool
+}
+
+func (c *testCallbackContract) run(caller common.Address, input []byte) ([]byte, error) {
+	if c.revert {
+		return nil, errors.New("callback reverted")
+	}
+	c.callers = append(c.callers, caller)
+	c.inputs = append(c.inputs, input)
+	return nil, nil
+}
+
+func deployTestCallbackContract(environment *MockEVMEnvironment, address common.Address) *testCallbackContract {
+	contract := &testCallbackContract{}
+	if environment.contracts == nil {
+		environment.contracts = make(map[common.Address]func(common.Address, []byte) ([]byte, error))
+	}
+	environment.contracts[address] = contract.run
+	return contract
+}
+
+func signDecryptionResult(t *testing.T, key *ecdsa.PrivateKey, chainID *big.Int, request *DecryptionRequest, plaintexts []*big.Int) []byte {
+	signature, err := crypto.Sign(DecryptionResultDigest(chainID, request.ID, request.Handles, plaintexts).Bytes(), key)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return signature
+}
+
+func newDecryptionOracleTestEnvironment(t *testing.T) (*MockEVMEnvironment, *ecdsa.PrivateKey) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	environment.chainID = 9000
+	environment.fhevmParams.DecryptionOracleSigners = []common.Address{crypto.PubkeyToAddress(key.PublicKey)}
+	return environment, key
+}
+
+func TestRequestDecryption(t *testing.T) {
+	environment, _ := newDecryptionOracleTestEnvironment(t)
+	contract := common.HexToAddress("0x1000")
+	ct1 := verifyCiphertextInTestMemory(environment, 7, environment.depth, tfhe.FheUint8)
+	ct2 := verifyCiphertextInTestMemory(environment, 1, environment.depth, tfhe.FheBool)
+	allowHandle(environment, ct1.GetHash(), contract)
+	allowHandle(environment, ct2.GetHash(), contract)
+
+	for expectedID := uint64(0); expectedID < 2; expectedID++ {
+		out, err := FheLibRun(environment, contract, contract, toRequestDecryptionInput("requestDecryption(bytes4,uint256[])", ct1.GetHash(), ct2.GetHash()), false)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if new(big.Int).SetBytes(out).Uint64() != expectedID {
+			t.Fatalf("expected request id %d, got %d", expectedID, new(big.Int).SetBytes(out).Uint64())
+		}
+	}
+	if NextDecryptionRequestID(environment) != 2 {
+		t.Fatalf("expected next request id 2, got %d", NextDecryptionRequestID(environment))
+	}
+
+	request := GetDecryptionRequest(environment, 1)
+	if request == nil {
+		t.Fatalf("expected request to be recorded")
+	}
+	if request.Contract != contract || request.CallbackSelector != testCallbackSelector || request.Tee || request.Fulfilled {
+		t.Fatalf("unexpected request record %+v", request)
+	}
+	if len(request.Handles) != 2 || request.Handles[0] != ct1.GetHash() || request.Handles[1] != ct2.GetHash() {
+		t.Fatalf("unexpected request handles %v", request.Handles)
+	}
+	if len(request.Types) != 2 || request.Types[0] != tfhe.FheUint8 || request.Types[1] != tfhe.FheBool {
+		t.Fatalf("unexpected request types %v", request.Types)
+	}
+	bytes, typ := GetDecryptionRequestCiphertext(environment, ct1.GetHash())
+	if typ != tfhe.FheUint8 || string(bytes) != string(ct1.Serialize()) {
+		t.Fatalf("expected the ciphertext to be persisted for the relayer")
+	}
+	if GetDecryptionRequest(environment, 2) != nil {
+		t.Fatalf("expected no request with id 2")
+	}
+}
+
+func TestRequestDecryptionGasDependsOnTypes(t *testing.T) {
+	environment, _ := newDecryptionOracleTestEnvironment(t)
+	small := verifyCiphertextInTestMemory(environment, 1, environment.depth, tfhe.FheBool).GetHash()
+	large := verifyCiphertextInTestMemory(environment, 1, environment.depth, tfhe.FheUint64).GetHash()
+	gasCosts := environment.FhevmParams().GasCosts
+
+	gas := FheLibRequiredGas(environment, 0, toRequestDecryptionInput("requestDecryption(bytes4,uint256[])", small, large))
+	expected := gasCosts.DecryptionRequest + gasCosts.DecryptionRequestPerHandle[tfhe.FheBool] + gasCosts.DecryptionRequestPerHandle[tfhe.FheUint64]
+	if gas != expected {
+		t.Fatalf("expected gas %d, got %d", expected, gas)
+	}
+	if gasCosts.DecryptionRequestPerHandle[tfhe.FheBool] >= gasCosts.DecryptionRequestPerHandle[tfhe.FheUint64] {
+		t.Fatalf("expected larger ciphertexts to cost more to persist")
+	}
+	if gas := FheLibRequiredGas(environment, 0, toRequestDecryptionInput("requestDecryption(bytes4,uint256[])", common.HexToHash("0xabcd"))); gas != 0 {
+		t.Fatalf("expected no gas for an unverified handle, got %d", gas)
+	}
+}
+
+func TestRequestDecryptionNotAllowed(t *testing.T) {
+	environment, _ := newDecryptionOracleTestEnvironment(t)
+	contract := common.HexToAddress("0x1000")
+	ct := verifyCiphertextInTestMemory(environment, 7, environment.depth, tfhe.FheUint8)
+
+	_, err := FheLibRun(environment, contract, contract, toRequestDecryptionInput("requestDecryption(bytes4,uint256[])", ct.GetHash()), false)
+	if err == nil {
+		t.Fatalf("expected requestDecryption to fail for a handle the caller is not allowed to use")
+	}
+	if NextDecryptionRequestID(environment) != 0 {
+		t.Fatalf("expected no request to be recorded")
+	}
+}
+
+func TestFulfillDecryption(t *testing.T) {
+	contract := common.HexToAddress("0x1000")
+	relayer := common.HexToAddress("0x2000")
+	otherKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	testcases := []struct {
+		description   string
+		wrongSigner   bool
+		wrongValue    bool
+		wrongChain    bool
+		callbackFails bool
+		succeeds      bool
+	}{
+		{"Valid", false, false, false, false, true},
+		{"WrongSigner", true, false, false, false, false},
+		{"WrongPlaintext", false, true, false, false, false},
+		{"WrongChain", false, false, true, false, false},
+		{"CallbackReverts", false, false, false, true, false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			environment, key := newDecryptionOracleTestEnvironment(t)
+			callbackContract := deployTestCallbackContract(environment, contract)
+			callbackContract.revert = tc.callbackFails
+			ct := verifyCiphertextInTestMemory(environment, 7, environment.depth, tfhe.FheUint8)
+			allowHandle(environment, ct.GetHash(), contract)
+			_, err := FheLibRun(environment, contract, contract, toRequestDecryptionInput("requestDecryption(bytes4,uint256[])", ct.GetHash()), false)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			request := GetDecryptionRequest(environment, 0)
+
+			plaintexts := []*big.Int{big.NewInt(7)}
+			signingKey := key
+			if tc.wrongSigner {
+				signingKey = otherKey
+			}
+			chainID := environment.ChainID()
+			if tc.wrongChain {
+				chainID = big.NewInt(1)
+			}
+			signature := signDecryptionResult(t, signingKey, chainID, request, plaintexts)
+			if tc.wrongValue {
+				plaintexts = []*big.Int{big.NewInt(8)}
+			}
+			input := EncodeFulfillDecryption(request, plaintexts, signature)
+			_, err = FheLibRun(environment, relayer, relayer, input, false)
+			if !tc.succeeds {
+				if err == nil {
+					t.Fatalf("expected fulfillDecryption to fail")
+				}
+				if len(callbackContract.inputs) != 0 {
+					t.Fatalf("expected the callback not to be called")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			if !GetDecryptionRequest(environment, 0).Fulfilled {
+				t.Fatalf("expected request to be fulfilled")
+			}
+			if bytes, _ := GetDecryptionRequestCiphertext(environment, ct.GetHash()); bytes != nil {
+				t.Fatalf("expected the ciphertext to be garbage collected after fulfillment")
+			}
+
+			// The callback is called once, by the oracle, with the request id and the plaintexts.
+			if len(callbackContract.inputs) != 1 || callbackContract.callers[0] != DecryptionOracleAddress() {
+				t.Fatalf("expected one callback from the oracle, got callers %v", callbackContract.callers)
+			}
+			callback := callbackContract.inputs[0]
+			if !bytes.Equal(callback[:4], testCallbackSelector[:]) {
+				t.Fatalf("unexpected callback selector %x", callback[:4])
+			}
+			if id := new(big.Int).SetBytes(callback[4:36]); id.Uint64() != request.ID {
+				t.Fatalf("unexpected request id %v in the callback", id)
+			}
+			if len(callback) != 4+4*32 || new(big.Int).SetBytes(callback[4+3*32:]).Uint64() != 7 {
+				t.Fatalf("unexpected plaintexts in the callback %x", callback[4:])
+			}
+
+			// Fulfilling the same request twice must fail.
+			_, err = FheLibRun(environment, relayer, relayer, input, false)
+			if err == nil {
+				t.Fatalf("expected fulfillDecryption to fail on replay")
+			}
+			if len(callbackContract.inputs) != 1 {
+				t.Fatalf("expected no callback on replay")
+			}
+		})
+	}
+}
+
+func TestFulfillDecryptionWithPerHandleSignatures(t *testing.T) {
+	environment, key := newDecryptionOracleTestEnvironment(t)
+	contract := common.HexToAddress("0x1000")
+	ct1 := verifyCiphertextInTestMemory(environment, 7, environment.depth, tfhe.FheUint8)
+	ct2 := verifyCiphertextInTestMemory(environment, 1, environment.depth, tfhe.FheBool)
+	allowHandle(environment, ct1.GetHash(), contract)
+	allowHandle(environment, ct2.GetHash(), contract)
+	_, err := FheLibRun(environment, contract, contract, toRequestDecryptionInput("requestDecryption(bytes4,uint256[])", ct1.GetHash(), ct2.GetHash()), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	request := GetDecryptionRequest(environment, 0)
+
+	plaintexts := []*big.Int{big.NewInt(7), big.NewInt(1)}
+	signature := make([]byte, 0)
+	for i := range request.Handles {
+		single := &DecryptionRequest{ID: request.ID, Handles: request.Handles[i : i+1]}
+		signature = append(signature, signDecryptionResult(t, key, environment.ChainID(), single, plaintexts[i:i+1])...)
+	}
+
+	// Signatures over the wrong plaintext must be rejected.
+	swapped := append(append([]byte{}, signature[65:]...), signature[:65]...)
+	_, err = FheLibRun(environment, contract, contract, EncodeFulfillDecryption(request, plaintexts, swapped), false)
+	if err == nil {
+		t.Fatalf("expected fulfillDecryption to fail with swapped signatures")
+	}
+
+	_, err = FheLibRun(environment, contract, contract, EncodeFulfillDecryption(request, plaintexts, signature), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !GetDecryptionRequest(environment, 0).Fulfilled {
+		t.Fatalf("expected request to be fulfilled")
+	}
+}
@@ -53,8 +53,17 @@ func (l *DefaultLogger) Error(msg string, keyvals ...interface{}) {
 	l.slogger.Error(msg, keyvals...)
 }
 
+// Returns the verified ciphertext of a handle, computing it first if it is pending.
 func getVerifiedCiphertext(environment EVMEnvironment, ciphertextHash common.Hash) *verifiedCiphertext {
-	return getVerifiedCiphertextFromEVM(environment, ciphertextHash)
+	ct := getVerifiedCiphertextFromEVM(environment, ciphertextHash)
+	if ct == nil || isSymbolicExecution(environment) {
+		return ct
+	}
+	if err := ct.resolve(); err != nil {
+		environment.GetLogger().Error("failed to compute a pending ciphertext", "handle", ciphertextHash.Hex(), "err", err)
+		return nil
+	}
+	return ct
 }
 
 func importCiphertextToEVMAtDepth(environment EVMEnvironment, ct *tfhe.TfheCiphertext, depth int) *verifiedCiphertext {
@@ -66,8 +75,9 @@ func importCiphertextToEVMAtDepth(environment EVMEnvironment, ct *tfhe.TfheCiphe
 		verifiedDepths := newDepthSet()
 		verifiedDepths.add(depth)
 		new := &verifiedCiphertext{
-			verifiedDepths,
-			ct,
+			verifiedDepths: verifiedDepths,
+			ciphertext:     ct,
+			producerDepth:  depth,
 		}
 		environment.FhevmData().verifiedCiphertexts[ct.GetHash()] = new
 		return new
@@ -78,10 +88,22 @@ func importCiphertextToEVM(environment EVMEnvironment, ct *tfhe.TfheCiphertext)
 	return importCiphertextToEVMAtDepth(environment, ct, environment.GetDepth())
 }
 
+// Import the result of an operation, under a symbolic handle if they are enabled.
 func importCiphertext(environment EVMEnvironment, ct *tfhe.TfheCiphertext) *verifiedCiphertext {
+	assignSymbolicHandle(environment, ct)
 	return importCiphertextToEVM(environment, ct)
 }
 
+// Import at the current depth a ciphertext that isn't computed yet. It is computed the first time it is used.
+func importPendingCiphertext(environment EVMEnvironment, handle common.Hash, t tfhe.FheUintType, compute func() (*tfhe.TfheCiphertext, error)) *verifiedCiphertext {
+	ct := &tfhe.TfheCiphertext{FheUintType: t, Hash: &handle}
+	vc := importCiphertextToEVM(environment, ct)
+	if vc.ciphertext == ct {
+		vc.compute = compute
+	}
+	return vc
+}
+
 func importRandomCiphertext(environment EVMEnvironment, t tfhe.FheUintType) []byte {
 	nextCtHash := &environment.FhevmData().nextCiphertextHashOnGasEst
 	ctHashBytes := crypto.Keccak256(nextCtHash.Bytes())
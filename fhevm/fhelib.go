@@ -2,6 +2,7 @@ package fhevm
 
 import (
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"math/big"
 
@@ -22,6 +23,26 @@ type FheLibMethod struct {
 	argTypes            string
 	requiredGasFunction func(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64
 	runFunction         func(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error)
+	// set instead of requiredGasFunction and runFunction by methods that read their arguments as decoded by the
+	// precompile, so that they don't parse the input again
+	requiredGasArgsFunction func(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64
+	runArgsFunction         func(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error)
+	// true if the result only depends on the input ciphertexts, so it can be computed by a coprocessor
+	deferrable bool
+	// types of the values that the fhelib function returns, in the same format. Empty means "(uint256)", a handle
+	returnTypes string
+	// the GasCosts fields that price the method, comma-separated, or "instructions" for programs
+	gasCategory string
+	// true if the method writes state, so the precompile fails in a static call before running it
+	writesState bool
+	// true if the method has no side effects, so it is declared view in the generated ABI
+	view bool
+	// true if the method was added with RegisterFheLibMethod or RegisterTeeLibMethod
+	plugin bool
+	// true if the arguments can be followed by an EIP-712 authorization, see recoverAuthorizationSigner
+	authorized bool
+	// parsed argTypes, set when the method is registered
+	args []abiType
 }
 
 func (fheLibMethod *FheLibMethod) Name() string {
@@ -32,25 +53,92 @@ func makeKeccakSignature(input string) uint32 {
 	return binary.BigEndian.Uint32(crypto.Keccak256([]byte(input))[0:4])
 }
 
+func (fheLibMethod *FheLibMethod) ArgTypes() string {
+	return fheLibMethod.argTypes
+}
+
+func (fheLibMethod *FheLibMethod) ReturnTypes() string {
+	if fheLibMethod.returnTypes == "" {
+		return "(uint256)"
+	}
+	return fheLibMethod.returnTypes
+}
+
+func (fheLibMethod *FheLibMethod) GasCategory() string {
+	return fheLibMethod.gasCategory
+}
+
+func (fheLibMethod *FheLibMethod) Backend() MethodBackend {
+	if fheLibMethod.deferrable {
+		return DeferrableFheBackend
+	}
+	return EVMBackend
+}
+
+func (fheLibMethod *FheLibMethod) WritesState() bool {
+	return fheLibMethod.writesState
+}
+
+// Returns true if the method has no side effects. Other methods, such as operations that allow their result to
+// the caller, are declared nonpayable in the generated ABI.
+func (fheLibMethod *FheLibMethod) View() bool {
+	return fheLibMethod.view && !fheLibMethod.writesState
+}
+
+// Returns true if the method is part of fhevm-go, and not registered by a plugin.
+func (fheLibMethod *FheLibMethod) IsBuiltin() bool {
+	return !fheLibMethod.plugin
+}
+
 // Return the computed signature by concatenating the name and the arg types of the method
 func (fheLibMethod *FheLibMethod) Signature() uint32 {
 	return makeKeccakSignature(fheLibMethod.name + fheLibMethod.argTypes)
 }
 
+// RequiredGas returns the gas of the method for an input without the selector, or 0 if the input is malformed.
 func (fheLibMethod *FheLibMethod) RequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	return fheLibMethod.requiredGasFunction(environment, suppliedGas, input)
+	if fheLibMethod.requiredGasArgsFunction == nil {
+		return fheLibMethod.requiredGasFunction(environment, suppliedGas, input)
+	}
+	args, err := fheLibMethod.decodeInput(input)
+	if err != nil {
+		environment.GetLogger().Error(fheLibMethod.name+" RequiredGas() input is malformed", "err", err, "input", hex.EncodeToString(input))
+		return 0
+	}
+	return fheLibMethod.requiredGas(environment, suppliedGas, input, args)
 }
 
+// Run runs the method on an input without the selector.
 func (fheLibMethod *FheLibMethod) Run(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return fheLibMethod.runFunction(environment, caller, addr, input, readOnly, runSpan)
+	if fheLibMethod.runArgsFunction == nil {
+		return fheLibMethod.runFunction(environment, caller, addr, input, readOnly, runSpan)
+	}
+	args, err := fheLibMethod.decodeInput(input)
+	if err != nil {
+		environment.GetLogger().Error(fheLibMethod.name+" input is malformed", "err", err, "input", hex.EncodeToString(input))
+		return nil, err
+	}
+	return fheLibMethod.run(environment, caller, addr, input, args, readOnly, runSpan)
 }
 
-// Mapping between function signatures and the functions to call
-var signatureToFheLibMethod = map[uint32]*FheLibMethod{}
+// Like RequiredGas, with the arguments already decoded from the input.
+func (fheLibMethod *FheLibMethod) requiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte, args abiArgs) uint64 {
+	if fheLibMethod.requiredGasArgsFunction != nil {
+		return fheLibMethod.requiredGasArgsFunction(environment, suppliedGas, args)
+	}
+	return fheLibMethod.requiredGasFunction(environment, suppliedGas, input)
+}
+
+// Like Run, with the arguments already decoded from the input.
+func (fheLibMethod *FheLibMethod) run(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	if fheLibMethod.runArgsFunction != nil {
+		return fheLibMethod.runArgsFunction(environment, caller, addr, args, readOnly, runSpan)
+	}
+	return fheLibMethod.runFunction(environment, caller, addr, input, readOnly, runSpan)
+}
 
 func GetFheLibMethod(signature uint32) (fheLibMethod *FheLibMethod, found bool) {
-	fheLibMethod, found = signatureToFheLibMethod[signature]
-	return
+	return fheLibRegistry.get(signature)
 }
 
 // All methods available in the fhelib precompile
@@ -60,202 +148,423 @@ var fhelibMethods = []*FheLibMethod{
 		name:                "fheAdd",
 		argTypes:            "(uint256,uint256,bytes1)",
 		requiredGasFunction: fheAddSubRequiredGas,
+		gasCategory:         "FheAddSub",
 		runFunction:         fheAddRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheSub",
 		argTypes:            "(uint256,uint256,bytes1)",
 		requiredGasFunction: fheAddSubRequiredGas,
+		gasCategory:         "FheAddSub",
 		runFunction:         fheSubRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheMul",
 		argTypes:            "(uint256,uint256,bytes1)",
 		requiredGasFunction: fheMulRequiredGas,
+		gasCategory:         "FheMul,FheScalarMul",
 		runFunction:         fheMulRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheDiv",
 		argTypes:            "(uint256,uint256,bytes1)",
 		requiredGasFunction: fheDivRequiredGas,
+		gasCategory:         "FheScalarDiv",
 		runFunction:         fheDivRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheRem",
 		argTypes:            "(uint256,uint256,bytes1)",
 		requiredGasFunction: fheRemRequiredGas,
+		gasCategory:         "FheScalarRem",
 		runFunction:         fheRemRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheMin",
 		argTypes:            "(uint256,uint256,bytes1)",
 		requiredGasFunction: fheMinRequiredGas,
+		gasCategory:         "FheMinMax,FheScalarMinMax",
 		runFunction:         fheMinRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheMax",
 		argTypes:            "(uint256,uint256,bytes1)",
 		requiredGasFunction: fheMaxRequiredGas,
+		gasCategory:         "FheMinMax,FheScalarMinMax",
 		runFunction:         fheMaxRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheRand",
+		writesState:         true,
 		argTypes:            "(bytes1)",
 		requiredGasFunction: fheRandRequiredGas,
+		gasCategory:         "FheRand",
 		runFunction:         fheRandRun,
 	},
 	{
 		name:                "fheRandBounded",
+		writesState:         true,
 		argTypes:            "(uint256,bytes1)",
 		requiredGasFunction: fheRandBoundedRequiredGas,
+		gasCategory:         "FheRand",
 		runFunction:         fheRandBoundedRun,
 	},
 	{
 		name:                "cast",
 		argTypes:            "(uint256,bytes1)",
 		requiredGasFunction: castRequiredGas,
+		gasCategory:         "FheCast",
 		runFunction:         castRun,
+		deferrable:          true,
+	},
+	{
+		name:                "fheProgram",
+		argTypes:            "(bytes,uint256[])",
+		returnTypes:         "(uint256[])",
+		requiredGasFunction: fheProgramRequiredGas,
+		gasCategory:         "instructions",
+		runFunction:         fheProgramRun,
 	},
 	{
 		name:                "fheLe",
 		argTypes:            "(uint256,uint256,bytes1)",
 		requiredGasFunction: fheLeRequiredGas,
+		gasCategory:         "FheLe",
 		runFunction:         fheLeRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheLt",
 		argTypes:            "(uint256,uint256,bytes1)",
 		requiredGasFunction: fheLtRequiredGas,
+		gasCategory:         "FheLe",
 		runFunction:         fheLtRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheEq",
 		argTypes:            "(uint256,uint256,bytes1)",
 		requiredGasFunction: fheEqRequiredGas,
+		gasCategory:         "FheEq",
 		runFunction:         fheEqRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheGe",
 		argTypes:            "(uint256,uint256,bytes1)",
 		requiredGasFunction: fheGeRequiredGas,
+		gasCategory:         "FheLe",
 		runFunction:         fheGeRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheGt",
 		argTypes:            "(uint256,uint256,bytes1)",
 		requiredGasFunction: fheGtRequiredGas,
+		gasCategory:         "FheLe",
 		runFunction:         fheGtRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheShl",
 		argTypes:            "(uint256,uint256,bytes1)",
 		requiredGasFunction: fheShlRequiredGas,
+		gasCategory:         "FheShift,FheScalarShift",
 		runFunction:         fheShlRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheShr",
 		argTypes:            "(uint256,uint256,bytes1)",
 		requiredGasFunction: fheShrRequiredGas,
+		gasCategory:         "FheShift,FheScalarShift",
 		runFunction:         fheShrRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheRotl",
 		argTypes:            "(uint256,uint256,bytes1)",
 		requiredGasFunction: fheRotlRequiredGas,
+		gasCategory:         "FheShift,FheScalarShift",
 		runFunction:         fheRotlRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheRotr",
 		argTypes:            "(uint256,uint256,bytes1)",
 		requiredGasFunction: fheRotrRequiredGas,
+		gasCategory:         "FheShift,FheScalarShift",
 		runFunction:         fheRotrRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheNe",
 		argTypes:            "(uint256,uint256,bytes1)",
 		requiredGasFunction: fheNeRequiredGas,
+		gasCategory:         "FheEq",
 		runFunction:         fheNeRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheNeg",
 		argTypes:            "(uint256)",
 		requiredGasFunction: fheNegRequiredGas,
+		gasCategory:         "FheNeg",
 		runFunction:         fheNegRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheNot",
 		argTypes:            "(uint256)",
 		requiredGasFunction: fheNotRequiredGas,
+		gasCategory:         "FheNot",
 		runFunction:         fheNotRun,
+		deferrable:          true,
+	},
+	{
+		name:                "fhePopcount",
+		argTypes:            "(uint256)",
+		requiredGasFunction: fheBitCountRequiredGas,
+		gasCategory:         "FheBitCount",
+		runFunction:         fhePopcountRun,
+		deferrable:          true,
+	},
+	{
+		name:                "fheClz",
+		argTypes:            "(uint256)",
+		requiredGasFunction: fheBitCountRequiredGas,
+		gasCategory:         "FheBitCount",
+		runFunction:         fheClzRun,
+		deferrable:          true,
+	},
+	{
+		name:                "fheCtz",
+		argTypes:            "(uint256)",
+		requiredGasFunction: fheBitCountRequiredGas,
+		gasCategory:         "FheBitCount",
+		runFunction:         fheCtzRun,
+		deferrable:          true,
+	},
+	{
+		name:                "fheBitTest",
+		argTypes:            "(uint256,uint256,bytes1)",
+		requiredGasFunction: fheBitTestRequiredGas,
+		gasCategory:         "FheBitTest,FheScalarBitTest",
+		runFunction:         fheBitTestRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheBitAnd",
 		argTypes:            "(uint256,uint256,bytes1)",
 		requiredGasFunction: fheBitAndRequiredGas,
+		gasCategory:         "FheBitwiseOp",
 		runFunction:         fheBitAndRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheBitOr",
 		argTypes:            "(uint256,uint256,bytes1)",
 		requiredGasFunction: fheBitOrRequiredGas,
+		gasCategory:         "FheBitwiseOp",
 		runFunction:         fheBitOrRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheBitXor",
 		argTypes:            "(uint256,uint256,bytes1)",
 		requiredGasFunction: fheBitXorRequiredGas,
+		gasCategory:         "FheBitwiseOp",
 		runFunction:         fheBitXorRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fheIfThenElse",
 		argTypes:            "(uint256,uint256,uint256)",
 		requiredGasFunction: fheIfThenElseRequiredGas,
+		gasCategory:         "FheIfThenElse",
 		runFunction:         fheIfThenElseRun,
+		deferrable:          true,
 	},
 	{
 		name:                "fhePubKey",
+		view:                true,
 		argTypes:            "(bytes1)",
+		returnTypes:         "(bytes)",
 		requiredGasFunction: fhePubKeyRequiredGas,
-		runFunction:         fhePubKeyRun,
+		gasCategory:         "FhePubKey",
+		runArgsFunction:     fhePubKeyRun,
 	},
 	{
 		name:                "trivialEncrypt",
 		argTypes:            "(uint256,bytes1)",
 		requiredGasFunction: trivialEncryptRequiredGas,
+		gasCategory:         "FheTrivialEncrypt",
 		runFunction:         trivialEncryptRun,
 	},
 	{
-		name:                "decrypt",
-		argTypes:            "(uint256)",
-		requiredGasFunction: decryptRequiredGas,
-		runFunction:         decryptRun,
+		name:                    "decrypt",
+		view:                    true,
+		argTypes:                "(uint256)",
+		requiredGasArgsFunction: decryptRequiredGas,
+		gasCategory:             "FheDecrypt",
+		runArgsFunction:         decryptRun,
+		authorized:              true,
 	},
 	{
-		name:                "reencrypt",
-		argTypes:            "(uint256,uint256)",
-		requiredGasFunction: reencryptRequiredGas,
-		runFunction:         reencryptRun,
+		name:                    "reencrypt",
+		view:                    true,
+		argTypes:                "(uint256,uint256)",
+		returnTypes:             "(bytes)",
+		requiredGasArgsFunction: reencryptRequiredGas,
+		gasCategory:             "FheReencrypt",
+		runArgsFunction:         reencryptRun,
+		authorized:              true,
 	},
 	{
 		name:                "verifyCiphertext",
 		argTypes:            "(bytes)",
 		requiredGasFunction: verifyCiphertextRequiredGas,
+		gasCategory:         "FheVerify",
 		runFunction:         verifyCiphertextRun,
 	},
 	{
-		name:                "getCiphertext",
-		argTypes:            "(address,uint256)",
-		requiredGasFunction: getCiphertextRequiredGas,
-		runFunction:         getCiphertextRun,
+		name:                    "getCiphertext",
+		view:                    true,
+		argTypes:                "(address,uint256)",
+		returnTypes:             "()", // the serialized ciphertext is returned as is, without ABI encoding
+		requiredGasArgsFunction: getCiphertextRequiredGas,
+		gasCategory:             "FheGetCiphertext",
+		runArgsFunction:         getCiphertextRun,
+	},
+	{
+		name:                "allow",
+		argTypes:            "(uint256,address)",
+		returnTypes:         "()",
+		writesState:         true,
+		requiredGasFunction: aclAllowRequiredGas,
+		gasCategory:         "AclAllow",
+		runArgsFunction:     aclAllowRun,
+	},
+	{
+		name:                "allowTransient",
+		writesState:         true,
+		argTypes:            "(uint256,address)",
+		returnTypes:         "()",
+		requiredGasFunction: aclAllowTransientRequiredGas,
+		gasCategory:         "AclAllowTransient",
+		runArgsFunction:     aclAllowTransientRun,
+	},
+	{
+		name:                "isAllowed",
+		view:                true,
+		argTypes:            "(uint256,address)",
+		returnTypes:         "(bool)",
+		requiredGasFunction: aclIsAllowedRequiredGas,
+		gasCategory:         "AclIsAllowed",
+		runArgsFunction:     aclIsAllowedRun,
+	},
+	{
+		name:                    "makePubliclyDecryptable",
+		argTypes:                "(uint256)",
+		returnTypes:             "()",
+		writesState:             true,
+		requiredGasArgsFunction: makePubliclyDecryptableRequiredGas,
+		gasCategory:             "MakePubliclyDecryptable",
+		runArgsFunction:         makePubliclyDecryptableRun,
+	},
+	{
+		name:                "isPubliclyDecryptable",
+		view:                true,
+		argTypes:            "(uint256)",
+		returnTypes:         "(bool)",
+		requiredGasFunction: isPubliclyDecryptableRequiredGas,
+		gasCategory:         "IsPubliclyDecryptable",
+		runArgsFunction:     isPubliclyDecryptableRun,
+	},
+	{
+		name:                "setUnlockHeight",
+		argTypes:            "(uint256,uint256)",
+		returnTypes:         "()",
+		writesState:         true,
+		requiredGasFunction: setUnlockHeightRequiredGas,
+		gasCategory:         "SetUnlockHeight",
+		runArgsFunction:     setUnlockHeightRun,
+	},
+	{
+		name:                "getUnlockHeight",
+		view:                true,
+		argTypes:            "(uint256)",
+		requiredGasFunction: getUnlockHeightRequiredGas,
+		gasCategory:         "GetUnlockHeight",
+		runArgsFunction:     getUnlockHeightRun,
+	},
+	{
+		name:                "registerViewingKey",
+		argTypes:            "(address,bytes32)",
+		returnTypes:         "()",
+		writesState:         true,
+		requiredGasFunction: registerViewingKeyRequiredGas,
+		gasCategory:         "RegisterViewingKey",
+		runArgsFunction:     registerViewingKeyRun,
+	},
+	{
+		name:                "revokeViewingKey",
+		argTypes:            "(address)",
+		returnTypes:         "()",
+		writesState:         true,
+		requiredGasFunction: revokeViewingKeyRequiredGas,
+		gasCategory:         "RevokeViewingKey",
+		runArgsFunction:     revokeViewingKeyRun,
+	},
+	{
+		name:                "getViewingKey",
+		view:                true,
+		argTypes:            "(address,address)",
+		returnTypes:         "(bytes32)",
+		requiredGasFunction: getViewingKeyRequiredGas,
+		gasCategory:         "GetViewingKey",
+		runArgsFunction:     getViewingKeyRun,
+	},
+	{
+		name:                    "auditorReencrypt",
+		view:                    true,
+		argTypes:                "(uint256,address,bytes32)",
+		returnTypes:             "(bytes)",
+		requiredGasArgsFunction: auditorReencryptRequiredGas,
+		gasCategory:             "FheReencrypt",
+		runArgsFunction:         auditorReencryptRun,
+		authorized:              true,
+	},
+	{
+		name:                "requestDecryption",
+		argTypes:            "(bytes4,uint256[])",
+		writesState:         true,
+		requiredGasFunction: requestDecryptionRequiredGas,
+		gasCategory:         "DecryptionRequest,DecryptionRequestPerHandle",
+		runFunction:         requestDecryptionRun,
+	},
+	{
+		name:                "fulfillDecryption",
+		argTypes:            "(uint256,uint256[],bytes)",
+		returnTypes:         "()",
+		writesState:         true,
+		requiredGasFunction: fulfillDecryptionRequiredGas,
+		gasCategory:         "DecryptionFulfill",
+		runFunction:         fulfillDecryptionRun,
 	},
 }
 
 func init() {
 	// create the mapping for every available fhelib method
-	for _, method := range fhelibMethods {
-		signatureToFheLibMethod[method.Signature()] = method
-	}
-
+	fhelibMethods = append(fhelibMethods, batchMethods("fhe", false)...)
+	fheLibRegistry.mustRegister(fhelibMethods)
 }
 
 func minInt(a int, b int) int {
@@ -291,15 +600,12 @@ func toEVMBytes(input []byte) []byte {
 	return ret
 }
 
-func get2VerifiedOperands(environment EVMEnvironment, input []byte) (lhs *verifiedCiphertext, rhs *verifiedCiphertext, err error) {
-	if len(input) != 65 {
-		return nil, nil, errors.New("input needs to contain two 256-bit sized values and 1 8-bit value")
-	}
-	lhs = getVerifiedCiphertext(environment, common.BytesToHash(input[0:32]))
+func get2VerifiedOperands(environment EVMEnvironment, args abiArgs) (lhs *verifiedCiphertext, rhs *verifiedCiphertext, err error) {
+	lhs = getVerifiedCiphertext(environment, args.word(0))
 	if lhs == nil {
 		return nil, nil, errors.New("unverified ciphertext handle")
 	}
-	rhs = getVerifiedCiphertext(environment, common.BytesToHash(input[32:64]))
+	rhs = getVerifiedCiphertext(environment, args.word(1))
 	if rhs == nil {
 		return nil, nil, errors.New("unverified ciphertext handle")
 	}
@@ -307,27 +613,21 @@ func get2VerifiedOperands(environment EVMEnvironment, input []byte) (lhs *verifi
 	return
 }
 
-func isScalarOp(input []byte) (bool, error) {
-	if len(input) != 65 {
-		return false, errors.New("input needs to contain two 256-bit sized values and 1 8-bit value")
-	}
-	isScalar := (input[64] == 1)
-	return isScalar, nil
+// Returns true if the rhs of a binary operation, whose arguments are (uint256,uint256,bytes1), is a plaintext scalar.
+func isScalarOp(args abiArgs) bool {
+	return args.byte(2) == 1
 }
 
-func get3VerifiedOperands(environment EVMEnvironment, input []byte) (first *verifiedCiphertext, second *verifiedCiphertext, third *verifiedCiphertext, err error) {
-	if len(input) != 96 {
-		return nil, nil, nil, errors.New("input needs to contain three 256-bit sized values")
-	}
-	first = getVerifiedCiphertext(environment, common.BytesToHash(input[0:32]))
+func get3VerifiedOperands(environment EVMEnvironment, args abiArgs) (first *verifiedCiphertext, second *verifiedCiphertext, third *verifiedCiphertext, err error) {
+	first = getVerifiedCiphertext(environment, args.word(0))
 	if first == nil {
 		return nil, nil, nil, errors.New("unverified ciphertext handle")
 	}
-	second = getVerifiedCiphertext(environment, common.BytesToHash(input[32:64]))
+	second = getVerifiedCiphertext(environment, args.word(1))
 	if second == nil {
 		return nil, nil, nil, errors.New("unverified ciphertext handle")
 	}
-	third = getVerifiedCiphertext(environment, common.BytesToHash(input[64:96]))
+	third = getVerifiedCiphertext(environment, args.word(2))
 	if third == nil {
 		return nil, nil, nil, errors.New("unverified ciphertext handle")
 	}
@@ -335,15 +635,11 @@ func get3VerifiedOperands(environment EVMEnvironment, input []byte) (first *veri
 	return
 }
 
-func getScalarOperands(environment EVMEnvironment, input []byte) (lhs *verifiedCiphertext, rhs *big.Int, err error) {
-	if len(input) != 65 {
-		return nil, nil, errors.New("input needs to contain two 256-bit sized values and 1 8-bit value")
-	}
-	lhs = getVerifiedCiphertext(environment, common.BytesToHash(input[0:32]))
+func getScalarOperands(environment EVMEnvironment, args abiArgs) (lhs *verifiedCiphertext, rhs *big.Int, err error) {
+	lhs = getVerifiedCiphertext(environment, args.word(0))
 	if lhs == nil {
 		return nil, nil, errors.New("unverified ciphertext handle")
 	}
-	rhs = &big.Int{}
-	rhs.SetBytes(input[32:64])
+	rhs = args.word(1).Big()
 	return
 }
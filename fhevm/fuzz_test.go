@@ -0,0 +1,152 @@
+package fhevm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+// The types of the ciphertexts that fuzzed inputs can refer to.
+var fuzzTypes = []tfhe.FheUintType{tfhe.FheBool, tfhe.FheUint4, tfhe.FheUint8, tfhe.FheUint16, tfhe.FheUint32, tfhe.FheUint64}
+
+// TEE operations also take the wide types, which go through doWideOp, doMulDivOp and the decimal operations.
+var teeFuzzTypes = append(fuzzTypes[:len(fuzzTypes):len(fuzzTypes)], tfhe.FheUint128, tfhe.FheDecimal)
+
+// A word of 0xfe bytes followed by an index in fuzzTypes is replaced by the handle of a verified ciphertext of
+// that type, so that fuzzed inputs reach the methods past the operand checks.
+var fuzzPlaceholderPrefix = bytes.Repeat([]byte{0xfe}, 31)
+
+func fuzzPlaceholder(typeIndex int) []byte {
+	return append(append([]byte{}, fuzzPlaceholderPrefix...), byte(typeIndex))
+}
+
+// Ciphertexts are only created for the types that args refer to, by verify, as creating TEE ciphertexts is slow.
+func replaceFuzzPlaceholders(args []byte, types []tfhe.FheUintType, verify func(typ tfhe.FheUintType) common.Hash) []byte {
+	args = append([]byte{}, args...)
+	handles := make(map[tfhe.FheUintType]common.Hash)
+	for start := 0; start+32 <= len(args); start += 32 {
+		if bytes.Equal(args[start:start+31], fuzzPlaceholderPrefix) {
+			typ := types[int(args[start+31])%len(types)]
+			if _, ok := handles[typ]; !ok {
+				handles[typ] = verify(typ)
+			}
+			copy(args[start:start+32], handles[typ].Bytes())
+		}
+	}
+	return args
+}
+
+// Returns the canonical encoding of arguments of the given types, with uint256 arguments set to handle.
+func fuzzArgs(types []abiType, handle []byte, scalar byte) []byte {
+	head := make([]byte, 0, 32*len(types))
+	var tail []byte
+	for _, t := range types {
+		switch {
+		case t.kind == abiUint && t.size == 256:
+			head = append(head, handle...)
+		case t.kind == abiFixedBytes && t.size == 1:
+			head = append(head, common.RightPadBytes([]byte{scalar}, 32)...)
+		case t.kind == abiArray:
+			head = append(head, word(uint64(32*len(types)+len(tail)))...)
+			tail = concat(tail, word(2), handle, handle)
+		case t.dynamic():
+			head = append(head, word(uint64(32*len(types)+len(tail)))...)
+			tail = concat(tail, word(0))
+		default:
+			head = append(head, word(0)...)
+		}
+	}
+	return concat(head, tail)
+}
+
+// Reports whether the bytes1 argument of a method is a type, as in cast, rather than the scalar flag of a binary
+// operation, which follows two operands.
+func takesTypeArgument(method *FheLibMethod) bool {
+	operands := 0
+	for _, t := range method.args {
+		switch {
+		case t.kind == abiUint && t.size == 256:
+			operands++
+		case t.kind == abiFixedBytes && t.size == 1:
+			return operands <= 1
+		}
+	}
+	return false
+}
+
+// Seeds a fuzz target with, for each method: its arguments referring to ciphertexts of each type, with a type
+// argument set to each type too, e.g. the target type of a cast, all zero arguments, which refer to no
+// ciphertext, and a zero scalar operand, e.g. a division by zero.
+func addLibMethodSeeds(f *testing.F, methods []*FheLibMethod, types []tfhe.FheUintType) {
+	for i, method := range methods {
+		for typeIndex := range types {
+			f.Add(uint16(i), fuzzArgs(method.args, fuzzPlaceholder(typeIndex), 0), true)
+			if !takesTypeArgument(method) {
+				continue
+			}
+			for _, typ := range types {
+				f.Add(uint16(i), fuzzArgs(method.args, fuzzPlaceholder(typeIndex), byte(typ)), true)
+			}
+		}
+		f.Add(uint16(i), fuzzArgs(method.args, word(0), 0), true)
+		zeroScalar := fuzzArgs(method.args, fuzzPlaceholder(2), 1)
+		if len(method.args) > 1 && method.args[0].kind == abiUint && method.args[1].kind == abiUint {
+			copy(zeroScalar[32:64], word(0))
+		}
+		f.Add(uint16(i), zeroScalar, true)
+		f.Add(uint16(i), zeroScalar, false)
+	}
+}
+
+// Decodes the input of a method, prices and runs it. Gas functions are called directly, as FheLibRequiredGas and
+// TeeLibRequiredGas recover panics without returning an error.
+func fuzzLibMethod(t *testing.T, method *FheLibMethod, environment *MockEVMEnvironment, args []byte, run func(EVMEnvironment, common.Address, common.Address, []byte, bool) ([]byte, error)) {
+	if _, err := method.decodeInput(args); err == nil {
+		method.RequiredGas(&symbolicEnvironment{environment}, 0, args)
+	}
+	addr := common.Address{}
+	input := concat(binary.BigEndian.AppendUint32(nil, method.Signature()), args)
+	if _, err := run(environment, addr, addr, input, false); errors.Is(err, ErrPrecompilePanic) {
+		var panicErr *PanicError
+		errors.As(err, &panicErr)
+		t.Fatalf("%s\n%s", err.Error(), panicErr.Stack)
+	}
+}
+
+func FuzzFheLibMethods(f *testing.F) {
+	methods := builtinMethods(FheLibMethods())
+	addLibMethodSeeds(f, methods, fuzzTypes)
+	f.Fuzz(func(t *testing.T, index uint16, args []byte, committing bool) {
+		environment := newTestEVMEnvironment()
+		environment.depth = 1
+		environment.commit = committing
+		args = replaceFuzzPlaceholders(args, fuzzTypes, func(typ tfhe.FheUintType) common.Hash {
+			return verifyCiphertextInTestMemory(environment, 1, environment.depth, typ).GetHash()
+		})
+		method := methods[int(index)%len(methods)]
+		fuzzLibMethod(t, method, environment, args, FheLibRun)
+	})
+}
+
+func FuzzTeeLibMethods(f *testing.F) {
+	methods := builtinMethods(TeeLibMethods())
+	addLibMethodSeeds(f, methods, teeFuzzTypes)
+	f.Fuzz(func(t *testing.T, index uint16, args []byte, committing bool) {
+		environment := newTestEVMEnvironment()
+		environment.depth = 1
+		environment.commit = committing
+		args = replaceFuzzPlaceholders(args, teeFuzzTypes, func(typ tfhe.FheUintType) common.Hash {
+			ct, err := importTeePlaintextToEVM(environment, environment.depth, uint64(1), typ)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			return ct.GetHash()
+		})
+		method := methods[int(index)%len(methods)]
+		fuzzLibMethod(t, method, environment, args, TeeLibRun)
+	})
+}
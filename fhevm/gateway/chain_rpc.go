@@ -0,0 +1,163 @@
+package gateway
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/zama-ai/fhevm-go/fhevm"
+	fhevm_crypto "github.com/zama-ai/fhevm-go/fhevm/crypto"
+	"github.com/zama-ai/fhevm-go/fhevm/kms"
+)
+
+// Address of the FheLib precompile, which serves getCiphertext and fulfillDecryption.
+var fheLibAddress = common.BytesToAddress([]byte{93})
+
+var getCiphertextSelector = crypto.Keccak256([]byte("getCiphertext(address,uint256)"))[0:4]
+
+// RPCChain is a Chain backed by a node's JSON-RPC API. Results are sent as transactions signed with the
+// relayer's key, which needs funds to pay for gas.
+type RPCChain struct {
+	client  *ethclient.Client
+	key     *ecdsa.PrivateKey
+	chainID *big.Int
+}
+
+func NewRPCChain(ctx context.Context, client *ethclient.Client, key *ecdsa.PrivateKey) (*RPCChain, error) {
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &RPCChain{client: client, key: key, chainID: chainID}, nil
+}
+
+func (c *RPCChain) ChainID() *big.Int {
+	return c.chainID
+}
+
+func (c *RPCChain) StorageAt(ctx context.Context, addr common.Address, key common.Hash) (common.Hash, error) {
+	value, err := c.client.StorageAt(ctx, addr, key, nil)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(value), nil
+}
+
+func (c *RPCChain) GetCiphertext(ctx context.Context, contract common.Address, handle common.Hash) ([]byte, error) {
+	input := append([]byte{}, getCiphertextSelector...)
+	input = append(input, common.BytesToHash(contract.Bytes()).Bytes()...)
+	input = append(input, handle.Bytes()...)
+	return c.client.CallContract(ctx, ethereum.CallMsg{To: &fheLibAddress, Data: input}, nil)
+}
+
+// Reads the state at a given block.
+type blockStateReader struct {
+	ctx    context.Context
+	client *ethclient.Client
+	number *big.Int
+	err    error
+}
+
+func (s *blockStateReader) GetState(addr common.Address, key common.Hash) common.Hash {
+	if s.err != nil {
+		return common.Hash{}
+	}
+	value, err := s.client.StorageAt(s.ctx, addr, key, s.number)
+	if err != nil {
+		s.err = err
+		return common.Hash{}
+	}
+	return common.BytesToHash(value)
+}
+
+// ProveCiphertext proves the slots of the ciphertext with eth_getProof, at the latest block.
+func (c *RPCChain) ProveCiphertext(ctx context.Context, contract common.Address, handle common.Hash) (*kms.Proof, error) {
+	header, err := c.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	state := &blockStateReader{ctx: ctx, client: c.client, number: header.Number}
+	slots, err := fhevm.CiphertextSlots(state, contract, handle)
+	if state.err != nil {
+		return nil, state.err
+	}
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(slots))
+	for _, slot := range slots {
+		keys = append(keys, slot.Hex())
+	}
+	var res struct {
+		AccountProof []string `json:"accountProof"`
+		StorageProof []struct {
+			Proof []string `json:"proof"`
+		} `json:"storageProof"`
+	}
+	protectedStorage := fhevm_crypto.CreateProtectedStorageContractAddress(contract)
+	if err := c.client.Client().CallContext(ctx, &res, "eth_getProof", protectedStorage, keys, hexutil.EncodeBig(header.Number)); err != nil {
+		return nil, err
+	}
+	proof := &fhevm.CiphertextProof{StateRoot: header.Root, Contract: contract, Handle: handle}
+	if proof.AccountProof, err = decodeProofNodes(res.AccountProof); err != nil {
+		return nil, err
+	}
+	for _, storage := range res.StorageProof {
+		nodes, err := decodeProofNodes(storage.Proof)
+		if err != nil {
+			return nil, err
+		}
+		proof.StorageProof = append(proof.StorageProof, nodes...)
+	}
+	// Don't send the KMS a proof it would reject.
+	if _, _, err := fhevm.VerifyCiphertextProof(proof); err != nil {
+		return nil, fmt.Errorf("node returned an invalid proof: %w", err)
+	}
+	return proof.ToKmsProof(uint32(header.Number.Uint64()))
+}
+
+func decodeProofNodes(nodes []string) ([][]byte, error) {
+	ret := make([][]byte, 0, len(nodes))
+	for _, node := range nodes {
+		bytes, err := hexutil.Decode(node)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, bytes)
+	}
+	return ret, nil
+}
+
+func (c *RPCChain) SubmitFulfillment(ctx context.Context, calldata []byte) error {
+	from := crypto.PubkeyToAddress(c.key.PublicKey)
+	nonce, err := c.client.PendingNonceAt(ctx, from)
+	if err != nil {
+		return err
+	}
+	gasPrice, err := c.client.SuggestGasPrice(ctx)
+	if err != nil {
+		return err
+	}
+	gas, err := c.client.EstimateGas(ctx, ethereum.CallMsg{From: from, To: &fheLibAddress, Data: calldata})
+	if err != nil {
+		return err
+	}
+	tx, err := types.SignNewTx(c.key, types.LatestSignerForChainID(c.chainID), &types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+		Gas:      gas,
+		To:       &fheLibAddress,
+		Data:     calldata,
+	})
+	if err != nil {
+		return err
+	}
+	return c.client.SendTransaction(ctx, tx)
+}
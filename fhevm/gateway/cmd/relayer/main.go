@@ -0,0 +1,88 @@
+// Command relayer fulfills the asynchronous decryption requests of an fhEVM chain with a remote KMS.
+//
+// The relayer's private key is read from the RELAYER_PRIVATE_KEY environment variable, as a hex string.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/zama-ai/fhevm-go/fhevm"
+	"github.com/zama-ai/fhevm-go/fhevm/gateway"
+	"github.com/zama-ai/fhevm-go/fhevm/kms"
+)
+
+func main() {
+	config := gateway.DefaultConfig()
+	rpcURL := flag.String("rpc", "http://localhost:8545", "JSON-RPC endpoint of the fhEVM node")
+	var kmsConfig kms.Config
+	flag.StringVar(&kmsConfig.Address, "kms", kms.KmsEndpointAddr, "address of the KMS gRPC endpoint")
+	flag.BoolVar(&kmsConfig.Insecure, "kms-insecure", false, "connect to the KMS without TLS")
+	flag.StringVar(&kmsConfig.CACertFile, "kms-ca", "", "PEM file of the CA that issued the KMS certificate")
+	flag.StringVar(&kmsConfig.ClientCertFile, "kms-cert", "", "PEM file of the client certificate, for mutual TLS")
+	flag.StringVar(&kmsConfig.ClientKeyFile, "kms-key", "", "PEM file of the client key, for mutual TLS")
+	kmsTimeout := flag.Duration("kms-timeout", kms.DefaultTimeout, "deadline of a KMS request")
+	queuePath := flag.String("queue", "relayer-queue.json", "file in which pending requests are persisted")
+	flag.DurationVar(&config.PollInterval, "poll", config.PollInterval, "interval between polls for new requests")
+	flag.DurationVar(&config.MinBackoff, "min-backoff", config.MinBackoff, "delay before the first retry of a failed request")
+	flag.DurationVar(&config.MaxBackoff, "max-backoff", config.MaxBackoff, "maximum delay between retries of a failed request")
+	flag.DurationVar(&config.ResubmitDelay, "resubmit-delay", config.ResubmitDelay, "delay before submitting a result again if it wasn't included")
+	signers := flag.String("kms-signers", "", "comma-separated addresses of the KMS keys trusted by the chain")
+	flag.Parse()
+	for _, signer := range strings.Split(*signers, ",") {
+		if signer = strings.TrimSpace(signer); signer != "" {
+			config.Signers = append(config.Signers, common.HexToAddress(signer))
+		}
+	}
+
+	logger := fhevm.NewDefaultLogger()
+	if err := run(*rpcURL, kmsConfig, *kmsTimeout, *queuePath, config, logger); err != nil && !errors.Is(err, context.Canceled) {
+		logger.Error("relayer stopped", "err", err)
+		os.Exit(1)
+	}
+}
+
+func run(rpcURL string, kmsConfig kms.Config, kmsTimeout time.Duration, queuePath string, config gateway.Config, logger fhevm.Logger) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if len(config.Signers) == 0 {
+		return errors.New("at least one KMS signer is required, see -kms-signers")
+	}
+
+	key, err := crypto.HexToECDSA(os.Getenv("RELAYER_PRIVATE_KEY"))
+	if err != nil {
+		return err
+	}
+	client, err := ethclient.DialContext(ctx, rpcURL)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	chain, err := gateway.NewRPCChain(ctx, client, key)
+	if err != nil {
+		return err
+	}
+
+	conn, err := kms.Dial(kmsConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	queue, err := gateway.OpenQueue(queuePath)
+	if err != nil {
+		return err
+	}
+	logger.Info("relayer started", "rpc", rpcURL, "kms", kmsConfig.Address, "address", crypto.PubkeyToAddress(key.PublicKey).Hex(), "nextRequestId", queue.NextRequestID())
+	return gateway.NewRelayer(chain, gateway.NewGrpcKMS(kms.NewKmsEndpointClient(conn), kmsTimeout), queue, config, logger).Run(ctx)
+}
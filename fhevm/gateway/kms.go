@@ -0,0 +1,85 @@
+// Package gateway contains the off-chain side of asynchronous decryptions: a relayer that fulfills the
+// decryption requests recorded by the fhEVM precompiles, and the KMS it obtains plaintexts from.
+package gateway
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/zama-ai/fhevm-go/fhevm"
+	"github.com/zama-ai/fhevm-go/fhevm/kms"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+	"github.com/zama-ai/fhevm-go/tee"
+)
+
+// A ciphertext of a decryption request, as persisted by the decryption oracle, and the proof that it is.
+type Ciphertext struct {
+	Handle common.Hash
+	Type   tfhe.FheUintType
+	Bytes  []byte
+	Proof  *kms.Proof
+}
+
+// KMS decrypts the ciphertexts of a decryption request and signs the plaintexts with a key trusted by the chain.
+// The signature is over fhevm.DecryptionResultDigest, for the chain with the given ID.
+type KMS interface {
+	Decrypt(ctx context.Context, chainID *big.Int, request *fhevm.DecryptionRequest, ciphertexts []Ciphertext) (plaintexts []*big.Int, signature []byte, err error)
+}
+
+// InMemoryKMS is a stand-in KMS for tests and local networks. It decrypts with the local client key and
+// the TEE key, and signs with the given private key.
+type InMemoryKMS struct {
+	key *ecdsa.PrivateKey
+}
+
+func NewInMemoryKMS(key *ecdsa.PrivateKey) *InMemoryKMS {
+	return &InMemoryKMS{key: key}
+}
+
+// Address of the signing key, to be added to fhevm.FhevmParams.DecryptionOracleSigners.
+func (kms *InMemoryKMS) Address() common.Address {
+	return crypto.PubkeyToAddress(kms.key.PublicKey)
+}
+
+func (kms *InMemoryKMS) Decrypt(ctx context.Context, chainID *big.Int, request *fhevm.DecryptionRequest, ciphertexts []Ciphertext) ([]*big.Int, []byte, error) {
+	if len(ciphertexts) != len(request.Handles) {
+		return nil, nil, errors.New("ciphertext count doesn't match the request")
+	}
+	plaintexts := make([]*big.Int, 0, len(ciphertexts))
+	for _, ct := range ciphertexts {
+		plaintext, err := decryptCiphertext(ct, request.Tee)
+		if err != nil {
+			return nil, nil, err
+		}
+		plaintexts = append(plaintexts, plaintext)
+	}
+	digest := fhevm.DecryptionResultDigest(chainID, request.ID, request.Handles, plaintexts)
+	signature, err := crypto.Sign(digest.Bytes(), kms.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return plaintexts, signature, nil
+}
+
+func decryptCiphertext(ct Ciphertext, isTee bool) (*big.Int, error) {
+	if isTee {
+		plaintext, err := tee.Decrypt(&tfhe.TfheCiphertext{FheUintType: ct.Type, Serialization: ct.Bytes})
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(plaintext.Value), nil
+	}
+	fheCt := new(tfhe.TfheCiphertext)
+	if err := fheCt.Deserialize(ct.Bytes, ct.Type); err != nil {
+		return nil, err
+	}
+	plaintext, err := fheCt.Decrypt()
+	if err != nil {
+		return nil, err
+	}
+	return &plaintext, nil
+}
@@ -0,0 +1,84 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+	"github.com/zama-ai/fhevm-go/fhevm"
+	"github.com/zama-ai/fhevm-go/fhevm/kms"
+)
+
+// GrpcKMS obtains plaintexts from a remote KMS through the kms.KmsEndpoint gRPC service.
+//
+// Each ciphertext is decrypted by a separate Decrypt call whose request field is the ABI encoding of
+// (uint256 chainId, uint256 requestId, bytes32 handle, bool tee). The KMS returns the big-endian plaintext and a signature
+// over fhevm.DecryptionResultDigest of that handle alone, and the per-handle signatures are concatenated.
+type GrpcKMS struct {
+	client  kms.KmsEndpointClient
+	timeout time.Duration
+}
+
+// NewGrpcKMS returns a KMS whose requests time out after the given duration, or kms.DefaultTimeout if it
+// is zero. The connection is usually made with kms.Dial.
+func NewGrpcKMS(client kms.KmsEndpointClient, timeout time.Duration) *GrpcKMS {
+	if timeout == 0 {
+		timeout = kms.DefaultTimeout
+	}
+	return &GrpcKMS{client: client, timeout: timeout}
+}
+
+func (k *GrpcKMS) Decrypt(ctx context.Context, chainID *big.Int, request *fhevm.DecryptionRequest, ciphertexts []Ciphertext) ([]*big.Int, []byte, error) {
+	if len(ciphertexts) != len(request.Handles) {
+		return nil, nil, errors.New("ciphertext count doesn't match the request")
+	}
+	plaintexts := make([]*big.Int, 0, len(ciphertexts))
+	signature := make([]byte, 0, crypto.SignatureLength*len(ciphertexts))
+	for _, ct := range ciphertexts {
+		fheType, err := kms.ToFheType(ct.Type)
+		if err != nil {
+			return nil, nil, err
+		}
+		res, err := k.decrypt(ctx, &kms.DecryptionRequest{
+			FheType:    fheType,
+			Ciphertext: ct.Bytes,
+			Request:    encodeKmsDecryptionRequest(chainID, request.ID, ct.Handle, request.Tee),
+			Proof:      ct.Proof,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("KMS failed to decrypt %s: %w", ct.Handle.Hex(), err)
+		}
+		if res.FheType != fheType {
+			return nil, nil, fmt.Errorf("KMS returned type %s for %s, expected %s", res.FheType, ct.Handle.Hex(), fheType)
+		}
+		if len(res.Signature) != crypto.SignatureLength {
+			return nil, nil, fmt.Errorf("KMS returned a %d-byte signature for %s", len(res.Signature), ct.Handle.Hex())
+		}
+		plaintexts = append(plaintexts, new(big.Int).SetBytes(res.Plaintext))
+		signature = append(signature, res.Signature...)
+	}
+	return plaintexts, signature, nil
+}
+
+func (k *GrpcKMS) decrypt(ctx context.Context, req *kms.DecryptionRequest) (*kms.DecryptionResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, k.timeout)
+	defer cancel()
+	return k.client.Decrypt(ctx, req)
+}
+
+// Encodes the request field of a kms.DecryptionRequest: (uint256 chainId, uint256 requestId, bytes32 handle, bool tee).
+func encodeKmsDecryptionRequest(chainID *big.Int, id uint64, handle common.Hash, tee bool) []byte {
+	ret := common.BigToHash(chainID).Bytes()
+	ret = append(ret, uint256.NewInt(id).PaddedBytes(32)...)
+	ret = append(ret, handle.Bytes()...)
+	teeWord := make([]byte, 32)
+	if tee {
+		teeWord[31] = 1
+	}
+	return append(ret, teeWord...)
+}
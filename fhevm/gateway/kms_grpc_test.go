@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+	"github.com/zama-ai/fhevm-go/fhevm"
+	"github.com/zama-ai/fhevm-go/fhevm/kms"
+	"github.com/zama-ai/fhevm-go/fhevm/kms/kmstest"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+// Starts a fake KMS that decrypts with the local keys and signs each plaintext like the real KMS does.
+func newFakeKms(t *testing.T) (*kmstest.Server, *ecdsa.PrivateKey, *GrpcKMS) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	server := &kmstest.Server{DecryptFunc: func(ctx context.Context, req *kms.DecryptionRequest) (*kms.DecryptionResponse, error) {
+		chainID, id, handle, isTee, err := decodeKmsDecryptionRequest(req.Request)
+		if err != nil {
+			return nil, err
+		}
+		typ, err := kms.ToFheUintType(req.FheType)
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := decryptCiphertext(Ciphertext{Handle: handle, Type: typ, Bytes: req.Ciphertext}, isTee)
+		if err != nil {
+			return nil, err
+		}
+		digest := fhevm.DecryptionResultDigest(chainID, id, []common.Hash{handle}, []*big.Int{plaintext})
+		signature, err := crypto.Sign(digest.Bytes(), key)
+		if err != nil {
+			return nil, err
+		}
+		return &kms.DecryptionResponse{Signature: signature, FheType: req.FheType, Plaintext: plaintext.Bytes()}, nil
+	}}
+	return server, key, NewGrpcKMS(kms.NewKmsEndpointClient(server.Start(t)), 0)
+}
+
+func decodeKmsDecryptionRequest(request []byte) (chainID *big.Int, id uint64, handle common.Hash, tee bool, err error) {
+	if len(request) != 4*32 {
+		return nil, 0, common.Hash{}, false, errors.New("KMS decryption request must be 128 bytes")
+	}
+	idInt := new(uint256.Int).SetBytes(request[32:64])
+	if !idInt.IsUint64() {
+		return nil, 0, common.Hash{}, false, errors.New("KMS decryption request id is too large")
+	}
+	return new(big.Int).SetBytes(request[0:32]), idInt.Uint64(), common.BytesToHash(request[64:96]), request[127] == 1, nil
+}
+
+func TestRelayerWithGrpcKMS(t *testing.T) {
+	server, key, grpcKms := newFakeKms(t)
+	chain := newFakeChain(crypto.PubkeyToAddress(key.PublicKey))
+	relayer := NewRelayer(chain, grpcKms, NewMemoryQueue(), Config{Signers: chain.params.DecryptionOracleSigners}, fhevm.NewDefaultLogger())
+	contract := common.HexToAddress("0x1000")
+
+	teeRequestDecryption(t, chain, contract, 42, 7)
+	out, err := fhevm.FheLibRun(chain, contract, contract, append(append(selector("trivialEncrypt(uint256,bytes1)"), common.BigToHash(big.NewInt(13)).Bytes()...), common.RightPadBytes([]byte{byte(tfhe.FheUint8)}, 32)...), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if _, err := fhevm.FheLibRun(chain, contract, contract, requestDecryptionInput("requestDecryption(bytes4,uint256[])", selector("callback(uint256,uint256[])"), common.BytesToHash(out)), false); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := relayer.ProcessPending(context.Background()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(chain.fulfillments) != 2 || len(server.Requests()) != 3 {
+		t.Fatalf("expected 2 submissions and 3 KMS requests, got %d and %d", len(chain.fulfillments), len(server.Requests()))
+	}
+	// Each request carries a proof of its ciphertext in the decryption oracle's protected storage.
+	for _, req := range server.Requests() {
+		proof, err := fhevm.CiphertextProofFromKms(req.Proof)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		bytes, _, err := fhevm.VerifyCiphertextProof(proof)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if proof.Contract != fhevm.DecryptionOracleAddress() || string(bytes) != string(req.Ciphertext) {
+			t.Fatalf("expected the request to carry a proof of its ciphertext")
+		}
+	}
+	plaintexts := executeFulfillment(t, chain, chain.fulfillments[0], fhevm.TeeLibRun)
+	if len(plaintexts) != 2 || plaintexts[0].Uint64() != 42 || plaintexts[1].Uint64() != 7 {
+		t.Fatalf("unexpected plaintexts %v", plaintexts)
+	}
+	plaintexts = executeFulfillment(t, chain, chain.fulfillments[1], fhevm.FheLibRun)
+	if len(plaintexts) != 1 || plaintexts[0].Uint64() != 13 {
+		t.Fatalf("unexpected plaintexts %v", plaintexts)
+	}
+}
+
+func TestGrpcKMSRejectsUnknownSigner(t *testing.T) {
+	_, key, grpcKms := newFakeKms(t)
+	chain := newFakeChain(common.HexToAddress("0x2000"))
+	relayer := NewRelayer(chain, grpcKms, NewMemoryQueue(), Config{Signers: chain.params.DecryptionOracleSigners}, fhevm.NewDefaultLogger())
+	contract := common.HexToAddress("0x1000")
+	teeRequestDecryption(t, chain, contract, 5)
+
+	// The relayer doesn't submit plaintexts that the chain would reject.
+	if err := relayer.ProcessPending(context.Background()); err == nil {
+		t.Fatalf("expected the relayer to reject a signature from an unknown KMS key")
+	}
+	if len(chain.fulfillments) != 0 {
+		t.Fatalf("expected no submission, got %d", len(chain.fulfillments))
+	}
+
+	// Neither does fulfillDecryption, e.g. if the relayer trusts the wrong key.
+	relayer = NewRelayer(chain, grpcKms, NewMemoryQueue(), Config{Signers: []common.Address{crypto.PubkeyToAddress(key.PublicKey)}}, fhevm.NewDefaultLogger())
+	if err := relayer.ProcessPending(context.Background()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if _, err := fhevm.TeeLibRun(chain, contract, contract, chain.fulfillments[0], false); err == nil {
+		t.Fatalf("expected fulfillDecryption to reject a signature from an unknown KMS key")
+	}
+}
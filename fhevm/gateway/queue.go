@@ -0,0 +1,136 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Queue keeps track of the decryption requests the relayer has discovered but not yet seen fulfilled.
+// A queue opened with OpenQueue is saved to a file after every change, so that the relayer resumes where
+// it left off after a restart.
+type Queue struct {
+	path  string
+	state queueState
+}
+
+type queueState struct {
+	// Id of the next request to discover.
+	NextRequestID uint64        `json:"nextRequestId"`
+	Pending       []*queueEntry `json:"pending"`
+}
+
+type queueEntry struct {
+	ID       uint64 `json:"id"`
+	Attempts int    `json:"attempts"`
+	// The entry is not processed before this time.
+	NextAttempt time.Time `json:"nextAttempt"`
+	// True if a callback has been submitted and the relayer is waiting for it to be included.
+	Submitted bool `json:"submitted"`
+}
+
+// NewMemoryQueue returns a queue that is not persisted.
+func NewMemoryQueue() *Queue {
+	return &Queue{}
+}
+
+// OpenQueue loads the queue saved at path, or creates an empty one if the file doesn't exist.
+func OpenQueue(path string) (*Queue, error) {
+	q := &Queue{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &q.state); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// Returns the id of the next request to discover.
+func (q *Queue) NextRequestID() uint64 {
+	return q.state.NextRequestID
+}
+
+// Returns the ids of the pending requests, in order.
+func (q *Queue) Pending() []uint64 {
+	ids := make([]uint64, 0, len(q.state.Pending))
+	for _, entry := range q.state.Pending {
+		ids = append(ids, entry.ID)
+	}
+	return ids
+}
+
+// Adds the requests up to next, exclusive, that haven't been discovered yet.
+func (q *Queue) discover(next uint64, now time.Time) error {
+	if next <= q.state.NextRequestID {
+		return nil
+	}
+	for id := q.state.NextRequestID; id < next; id++ {
+		q.state.Pending = append(q.state.Pending, &queueEntry{ID: id, NextAttempt: now})
+	}
+	q.state.NextRequestID = next
+	return q.save()
+}
+
+// Returns the pending entries whose next attempt is due, in request order.
+func (q *Queue) due(now time.Time) []*queueEntry {
+	entries := make([]*queueEntry, 0)
+	for _, entry := range q.state.Pending {
+		if !entry.NextAttempt.After(now) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries
+}
+
+func (q *Queue) remove(id uint64) error {
+	for i, entry := range q.state.Pending {
+		if entry.ID == id {
+			q.state.Pending = append(q.state.Pending[:i], q.state.Pending[i+1:]...)
+			break
+		}
+	}
+	return q.save()
+}
+
+func (q *Queue) update(entry *queueEntry, nextAttempt time.Time, submitted bool) error {
+	entry.NextAttempt = nextAttempt
+	entry.Submitted = submitted
+	return q.save()
+}
+
+// Writes the queue to a temporary file and renames it, so that a crash never leaves a partial file behind.
+func (q *Queue) save() error {
+	if q.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(&q.state)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(q.path), filepath.Base(q.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), q.path)
+}
@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/zama-ai/fhevm-go/fhevm"
+)
+
+func TestRelayerResumesFromQueueFile(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	kms := NewInMemoryKMS(key)
+	chain := newFakeChain(kms.Address())
+	path := filepath.Join(t.TempDir(), "queue.json")
+	contract := common.HexToAddress("0x1000")
+	teeRequestDecryption(t, chain, contract, 1)
+	teeRequestDecryption(t, chain, contract, 2)
+
+	queue, err := OpenQueue(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	relayer := NewRelayer(chain, kms, queue, Config{Signers: chain.params.DecryptionOracleSigners}, fhevm.NewDefaultLogger())
+	if err := relayer.ProcessPending(context.Background()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	// Only the first result is included before the restart.
+	executeFulfillment(t, chain, chain.fulfillments[0], fhevm.TeeLibRun)
+	teeRequestDecryption(t, chain, contract, 3)
+
+	queue, err = OpenQueue(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if queue.NextRequestID() != 2 || len(queue.Pending()) != 2 {
+		t.Fatalf("unexpected queue after restart: next %d, pending %v", queue.NextRequestID(), queue.Pending())
+	}
+	relayer = NewRelayer(chain, kms, queue, Config{Signers: chain.params.DecryptionOracleSigners}, fhevm.NewDefaultLogger())
+	if err := relayer.ProcessPending(context.Background()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	// Request 0 is fulfilled and skipped; request 1 is resubmitted and request 2 is new.
+	if len(chain.fulfillments) != 4 {
+		t.Fatalf("expected 4 submissions, got %d", len(chain.fulfillments))
+	}
+	if pending := queue.Pending(); len(pending) != 2 || pending[0] != 1 || pending[1] != 2 {
+		t.Fatalf("unexpected pending requests %v", pending)
+	}
+}
@@ -0,0 +1,194 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zama-ai/fhevm-go/fhevm"
+	"github.com/zama-ai/fhevm-go/fhevm/kms"
+)
+
+// Chain is the relayer's view of the blockchain.
+type Chain interface {
+	// StorageAt returns the value of a storage slot in the latest state.
+	StorageAt(ctx context.Context, addr common.Address, key common.Hash) (common.Hash, error)
+	// GetCiphertext returns the bytes of the ciphertext persisted for handle in the protected storage of
+	// contract, as returned by the getCiphertext precompile on eth_call.
+	GetCiphertext(ctx context.Context, contract common.Address, handle common.Hash) ([]byte, error)
+	// ProveCiphertext returns the Merkle-Patricia proof of the ciphertext persisted for handle in the protected
+	// storage of contract, in the latest state, as built by fhevm.BuildCiphertextProof.
+	ProveCiphertext(ctx context.Context, contract common.Address, handle common.Hash) (*kms.Proof, error)
+	// SubmitFulfillment sends a transaction with the given fulfillDecryption calldata to the FheLib precompile,
+	// which calls back the requesting contract once it has checked the signature.
+	SubmitFulfillment(ctx context.Context, calldata []byte) error
+	// ChainID returns the ID of the chain, which is part of the EIP-712 domain of the signed plaintexts.
+	ChainID() *big.Int
+}
+
+// Config of the relayer.
+type Config struct {
+	// How often Run looks for new requests.
+	PollInterval time.Duration
+	// Delay before retrying a failed request. It doubles with every failed attempt, up to MaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// How long to wait for a submitted result to be included before submitting it again.
+	ResubmitDelay time.Duration
+	// Addresses of the KMS keys trusted by the chain, i.e. fhevm.FhevmParams.DecryptionOracleSigners. Results
+	// are only submitted with plaintexts signed by one of them, so none are submitted if it is empty.
+	Signers []common.Address
+}
+
+func DefaultConfig() Config {
+	return Config{
+		PollInterval:  2 * time.Second,
+		MinBackoff:    time.Second,
+		MaxBackoff:    5 * time.Minute,
+		ResubmitDelay: time.Minute,
+	}
+}
+
+// Relayer fulfills decryption requests: it discovers them on the chain, has the KMS decrypt their
+// ciphertexts and submits the signed plaintexts to fulfillDecryption, which calls back the requesting contracts.
+//
+// Requests stay in the queue until the chain reports them as fulfilled, so a result that was dropped
+// is submitted again after Config.ResubmitDelay, and a request fulfilled by someone else is never submitted.
+type Relayer struct {
+	chain  Chain
+	kms    KMS
+	queue  *Queue
+	config Config
+	logger fhevm.Logger
+	now    func() time.Time
+}
+
+func NewRelayer(chain Chain, kms KMS, queue *Queue, config Config, logger fhevm.Logger) *Relayer {
+	return &Relayer{chain: chain, kms: kms, queue: queue, config: config, logger: logger, now: time.Now}
+}
+
+// Run processes requests every Config.PollInterval until the context is done.
+func (r *Relayer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := r.ProcessPending(ctx); err != nil {
+			r.logger.Error("relayer failed to process decryption requests", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessPending discovers the requests recorded since the last call and processes the queued requests
+// that are due. A request that fails is retried with exponential backoff; the errors are returned.
+func (r *Relayer) ProcessPending(ctx context.Context) error {
+	state := &stateReader{ctx: ctx, chain: r.chain}
+	next := fhevm.NextDecryptionRequestID(state)
+	if state.err != nil {
+		return state.err
+	}
+	if err := r.queue.discover(next, r.now()); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, entry := range r.queue.due(r.now()) {
+		if err := r.process(ctx, entry); err != nil {
+			r.logger.Error("relayer failed to fulfill decryption request", "id", entry.ID, "attempts", entry.Attempts, "err", err)
+			errs = append(errs, fmt.Errorf("decryption request %d: %w", entry.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Relayer) process(ctx context.Context, entry *queueEntry) error {
+	state := &stateReader{ctx: ctx, chain: r.chain}
+	request := fhevm.GetDecryptionRequest(state, entry.ID)
+	if state.err != nil {
+		return r.retry(entry, state.err)
+	}
+	if request == nil {
+		return r.retry(entry, errors.New("not found"))
+	}
+	if request.Fulfilled {
+		return r.queue.remove(entry.ID)
+	}
+	if entry.Submitted {
+		r.logger.Info("relayer resubmitting decryption request", "id", entry.ID)
+	}
+
+	ciphertexts := make([]Ciphertext, 0, len(request.Handles))
+	for i, handle := range request.Handles {
+		bytes, err := r.chain.GetCiphertext(ctx, fhevm.DecryptionOracleAddress(), handle)
+		if err != nil {
+			return r.retry(entry, err)
+		}
+		if len(bytes) == 0 {
+			return r.retry(entry, fmt.Errorf("ciphertext %s not found", handle.Hex()))
+		}
+		proof, err := r.chain.ProveCiphertext(ctx, fhevm.DecryptionOracleAddress(), handle)
+		if err != nil {
+			return r.retry(entry, fmt.Errorf("failed to prove ciphertext %s: %w", handle.Hex(), err))
+		}
+		ciphertexts = append(ciphertexts, Ciphertext{Handle: handle, Type: request.Types[i], Bytes: bytes, Proof: proof})
+	}
+	plaintexts, signature, err := r.kms.Decrypt(ctx, r.chain.ChainID(), request, ciphertexts)
+	if err != nil {
+		return r.retry(entry, err)
+	}
+	if err := fhevm.VerifyDecryptionResult(r.chain.ChainID(), r.config.Signers, request, plaintexts, signature); err != nil {
+		return r.retry(entry, fmt.Errorf("invalid KMS signature: %w", err))
+	}
+	if err := r.chain.SubmitFulfillment(ctx, fhevm.EncodeFulfillDecryption(request, plaintexts, signature)); err != nil {
+		return r.retry(entry, err)
+	}
+	entry.Attempts = 0
+	r.logger.Info("relayer submitted decryption result", "id", entry.ID, "contract", request.Contract.Hex())
+	return r.queue.update(entry, r.now().Add(r.config.ResubmitDelay), true)
+}
+
+// Schedules the next attempt of a failed request and returns the error.
+func (r *Relayer) retry(entry *queueEntry, err error) error {
+	entry.Attempts++
+	if saveErr := r.queue.update(entry, r.now().Add(r.backoff(entry.Attempts)), entry.Submitted); saveErr != nil {
+		return errors.Join(err, saveErr)
+	}
+	return err
+}
+
+func (r *Relayer) backoff(attempts int) time.Duration {
+	delay := r.config.MinBackoff
+	for i := 1; i < attempts && delay < r.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.config.MaxBackoff {
+		delay = r.config.MaxBackoff
+	}
+	return delay
+}
+
+// stateReader adapts Chain to fhevm.StateReader. Reads after the first error return zero.
+type stateReader struct {
+	ctx   context.Context
+	chain Chain
+	err   error
+}
+
+func (s *stateReader) GetState(addr common.Address, key common.Hash) common.Hash {
+	if s.err != nil {
+		return common.Hash{}
+	}
+	value, err := s.chain.StorageAt(s.ctx, addr, key)
+	if err != nil {
+		s.err = err
+		return common.Hash{}
+	}
+	return value
+}
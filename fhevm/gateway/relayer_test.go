@@ -0,0 +1,365 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/zama-ai/fhevm-go/fhevm"
+	"github.com/zama-ai/fhevm-go/fhevm/kms"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+func TestMain(m *testing.M) {
+	if !tfhe.AllGlobalKeysPresent() {
+		tfhe.InitGlobalKeysWithNewKeys()
+	}
+	os.Exit(m.Run())
+}
+
+// A fake chain with in-memory state. It is also the EVM environment in which precompiles run. It records
+// the fulfillDecryption calldata submitted by the relayer instead of executing it, and the callbacks that
+// fulfillDecryption makes to contracts.
+type fakeChain struct {
+	state        map[common.Address]map[common.Hash]common.Hash
+	fhevmData    fhevm.FhevmData
+	params       fhevm.FhevmParams
+	ethCall      bool
+	fulfillments [][]byte
+	callbacks    []fakeCallback
+	fail         bool
+}
+
+type fakeCallback struct {
+	caller   common.Address
+	contract common.Address
+	calldata []byte
+}
+
+func newFakeChain(signer common.Address) *fakeChain {
+	params := fhevm.DefaultFhevmParams()
+	params.DecryptionOracleSigners = []common.Address{signer}
+	return &fakeChain{
+		state:     make(map[common.Address]map[common.Hash]common.Hash),
+		fhevmData: fhevm.NewFhevmData(),
+		params:    params,
+	}
+}
+
+func (c *fakeChain) GetState(addr common.Address, key common.Hash) common.Hash {
+	return c.state[addr][key]
+}
+
+func (c *fakeChain) SetState(addr common.Address, key common.Hash, value common.Hash) {
+	if c.state[addr] == nil {
+		c.state[addr] = make(map[common.Hash]common.Hash)
+	}
+	c.state[addr][key] = value
+}
+
+func (c *fakeChain) GetNonce(common.Address) uint64      { return 0 }
+func (c *fakeChain) AddBalance(common.Address, *big.Int) {}
+func (c *fakeChain) GetBalance(common.Address) *big.Int  { return big.NewInt(0) }
+func (c *fakeChain) Suicide(common.Address) bool         { return false }
+func (c *fakeChain) GetDepth() int                       { return 1 }
+func (c *fakeChain) GetLogger() fhevm.Logger             { return fhevm.NewDefaultLogger() }
+func (c *fakeChain) IsCommitting() bool                  { return true }
+func (c *fakeChain) IsEthCall() bool                     { return c.ethCall }
+func (c *fakeChain) IsReadOnly() bool                    { return false }
+func (c *fakeChain) FhevmData() *fhevm.FhevmData         { return &c.fhevmData }
+func (c *fakeChain) FhevmParams() *fhevm.FhevmParams     { return &c.params }
+func (c *fakeChain) GasLimit() uint64                    { return 0 }
+func (c *fakeChain) BlockNumber() *big.Int               { return big.NewInt(0) }
+func (c *fakeChain) ChainID() *big.Int                   { return big.NewInt(9000) }
+func (c *fakeChain) BlockTimestamp() uint64              { return 0 }
+func (c *fakeChain) OtelContext() context.Context        { return nil }
+func (c *fakeChain) CreateContract(common.Address, []byte, uint64, *big.Int, common.Address) ([]byte, common.Address, uint64, error) {
+	return nil, common.Address{}, 0, nil
+}
+func (c *fakeChain) CreateContract2(common.Address, []byte, common.Hash, uint64, *big.Int, common.Address) ([]byte, common.Address, uint64, error) {
+	return nil, common.Address{}, 0, nil
+}
+
+func (c *fakeChain) Call(caller common.Address, addr common.Address, input []byte, gas uint64, value *big.Int) ([]byte, uint64, error) {
+	c.callbacks = append(c.callbacks, fakeCallback{caller, addr, input})
+	return nil, gas, nil
+}
+
+func (c *fakeChain) StorageAt(ctx context.Context, addr common.Address, key common.Hash) (common.Hash, error) {
+	return c.GetState(addr, key), nil
+}
+
+func (c *fakeChain) GetCiphertext(ctx context.Context, contract common.Address, handle common.Hash) ([]byte, error) {
+	c.ethCall = true
+	defer func() { c.ethCall = false }()
+	input := append(selector("getCiphertext(address,uint256)"), common.BytesToHash(contract.Bytes()).Bytes()...)
+	return fhevm.FheLibRun(c, contract, contract, append(input, handle.Bytes()...), true)
+}
+
+// Proves the ciphertext against a state trie built from the fake state.
+func (c *fakeChain) ProveCiphertext(ctx context.Context, contract common.Address, handle common.Hash) (*kms.Proof, error) {
+	db := state.NewDatabase(rawdb.NewMemoryDatabase())
+	statedb, err := state.New(common.Hash{}, db, nil)
+	if err != nil {
+		return nil, err
+	}
+	for addr, slots := range c.state {
+		for key, value := range slots {
+			statedb.SetState(addr, key, value)
+		}
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		return nil, err
+	}
+	stateTrie, err := db.OpenTrie(root)
+	if err != nil {
+		return nil, err
+	}
+	openStorageTrie := func(addrHash common.Hash, storageRoot common.Hash) (fhevm.ProofTrie, error) {
+		return db.OpenStorageTrie(root, addrHash, storageRoot)
+	}
+	proof, err := fhevm.BuildCiphertextProof(root, stateTrie, openStorageTrie, contract, handle)
+	if err != nil {
+		return nil, err
+	}
+	return proof.ToKmsProof(0)
+}
+
+func (c *fakeChain) SubmitFulfillment(ctx context.Context, calldata []byte) error {
+	if c.fail {
+		return errors.New("submission failed")
+	}
+	c.fulfillments = append(c.fulfillments, calldata)
+	return nil
+}
+
+func selector(method string) []byte {
+	return crypto.Keccak256([]byte(method))[0:4]
+}
+
+func requestDecryptionInput(method string, callbackSelector []byte, handles ...common.Hash) []byte {
+	input := selector(method)
+	input = append(input, common.RightPadBytes(callbackSelector, 32)...)
+	input = append(input, common.BigToHash(big.NewInt(64)).Bytes()...)
+	input = append(input, common.BigToHash(big.NewInt(int64(len(handles)))).Bytes()...)
+	for _, handle := range handles {
+		input = append(input, handle.Bytes()...)
+	}
+	return input
+}
+
+// Include a submitted fulfillDecryption call in a block, from the relayer, and return the plaintexts of the
+// callback it makes.
+func executeFulfillment(t *testing.T, chain *fakeChain, calldata []byte, run func(fhevm.EVMEnvironment, common.Address, common.Address, []byte, bool) ([]byte, error)) []*big.Int {
+	relayer := common.HexToAddress("0x3000")
+	if _, err := run(chain, relayer, relayer, calldata, false); err != nil {
+		t.Fatalf(err.Error())
+	}
+	callback := chain.callbacks[len(chain.callbacks)-1]
+	if callback.caller != fhevm.DecryptionOracleAddress() {
+		t.Fatalf("expected the callback to come from the oracle, got %s", callback.caller.Hex())
+	}
+	args := callback.calldata[4:]
+	offset := new(big.Int).SetBytes(args[32:64]).Uint64()
+	length := new(big.Int).SetBytes(args[offset : offset+32]).Uint64()
+	plaintexts := make([]*big.Int, 0, length)
+	for i := uint64(0); i < length; i++ {
+		start := offset + 32 + 32*i
+		plaintexts = append(plaintexts, new(big.Int).SetBytes(args[start:start+32]))
+	}
+	return plaintexts
+}
+
+// Retries and resubmissions are immediate unless a test changes the config.
+func newTestRelayer(t *testing.T) (*Relayer, *fakeChain) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	kms := NewInMemoryKMS(key)
+	chain := newFakeChain(kms.Address())
+	return NewRelayer(chain, kms, NewMemoryQueue(), Config{Signers: chain.params.DecryptionOracleSigners}, fhevm.NewDefaultLogger()), chain
+}
+
+func teeRequestDecryption(t *testing.T, chain *fakeChain, contract common.Address, values ...byte) {
+	handles := make([]common.Hash, 0)
+	for _, value := range values {
+		out, err := fhevm.TeeLibRun(chain, contract, contract, append(append(selector("teeEncrypt(uint256,bytes1)"), common.BigToHash(big.NewInt(int64(value))).Bytes()...), common.RightPadBytes([]byte{byte(tfhe.FheUint32)}, 32)...), false)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		handles = append(handles, common.BytesToHash(out))
+	}
+	if _, err := fhevm.TeeLibRun(chain, contract, contract, requestDecryptionInput("teeRequestDecryption(bytes4,uint256[])", selector("callback(uint256,uint256[])"), handles...), false); err != nil {
+		t.Fatalf(err.Error())
+	}
+}
+
+func TestRelayerFulfillsTeeDecryption(t *testing.T) {
+	relayer, chain := newTestRelayer(t)
+	contract := common.HexToAddress("0x1000")
+	callbackSelector := selector("callback(uint256,uint256[])")
+
+	handles := make([]common.Hash, 0)
+	for _, value := range []byte{42, 7} {
+		out, err := fhevm.TeeLibRun(chain, contract, contract, append(append(selector("teeEncrypt(uint256,bytes1)"), common.BigToHash(big.NewInt(int64(value))).Bytes()...), common.RightPadBytes([]byte{byte(tfhe.FheUint32)}, 32)...), false)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		handles = append(handles, common.BytesToHash(out))
+	}
+	if _, err := fhevm.TeeLibRun(chain, contract, contract, requestDecryptionInput("teeRequestDecryption(bytes4,uint256[])", callbackSelector, handles...), false); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := relayer.ProcessPending(context.Background()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(chain.fulfillments) != 1 {
+		t.Fatalf("expected 1 submission, got %d", len(chain.fulfillments))
+	}
+	plaintexts := executeFulfillment(t, chain, chain.fulfillments[0], fhevm.TeeLibRun)
+	callback := chain.callbacks[0]
+	if callback.contract != contract || string(callback.calldata[0:4]) != string(callbackSelector) {
+		t.Fatalf("unexpected callback to %s", callback.contract.Hex())
+	}
+	if len(plaintexts) != 2 || plaintexts[0].Uint64() != 42 || plaintexts[1].Uint64() != 7 {
+		t.Fatalf("unexpected plaintexts %v", plaintexts)
+	}
+	if !fhevm.GetDecryptionRequest(chain, 0).Fulfilled {
+		t.Fatalf("expected request to be fulfilled")
+	}
+
+	// Nothing left to process.
+	if err := relayer.ProcessPending(context.Background()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(chain.fulfillments) != 1 {
+		t.Fatalf("expected no new submission, got %d", len(chain.fulfillments))
+	}
+	if len(relayer.queue.Pending()) != 0 {
+		t.Fatalf("expected the fulfilled request to leave the queue")
+	}
+}
+
+func TestRelayerFulfillsFheDecryption(t *testing.T) {
+	relayer, chain := newTestRelayer(t)
+	contract := common.HexToAddress("0x1000")
+	callbackSelector := selector("callback(uint256,uint256[])")
+
+	out, err := fhevm.FheLibRun(chain, contract, contract, append(append(selector("trivialEncrypt(uint256,bytes1)"), common.BigToHash(big.NewInt(13)).Bytes()...), common.RightPadBytes([]byte{byte(tfhe.FheUint8)}, 32)...), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if _, err := fhevm.FheLibRun(chain, contract, contract, requestDecryptionInput("requestDecryption(bytes4,uint256[])", callbackSelector, common.BytesToHash(out)), false); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := relayer.ProcessPending(context.Background()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(chain.fulfillments) != 1 {
+		t.Fatalf("expected 1 submission, got %d", len(chain.fulfillments))
+	}
+	plaintexts := executeFulfillment(t, chain, chain.fulfillments[0], fhevm.FheLibRun)
+	if len(plaintexts) != 1 || plaintexts[0].Uint64() != 13 {
+		t.Fatalf("unexpected plaintexts %v", plaintexts)
+	}
+}
+
+func TestRelayerRetriesFailedRequest(t *testing.T) {
+	relayer, chain := newTestRelayer(t)
+	teeRequestDecryption(t, chain, common.HexToAddress("0x1000"), 5)
+
+	chain.fail = true
+	if err := relayer.ProcessPending(context.Background()); err == nil {
+		t.Fatalf("expected processing to fail")
+	}
+	chain.fail = false
+	if err := relayer.ProcessPending(context.Background()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(chain.fulfillments) != 1 {
+		t.Fatalf("expected the failed request to be retried, got %d submissions", len(chain.fulfillments))
+	}
+}
+
+func TestRelayerBacksOff(t *testing.T) {
+	relayer, chain := newTestRelayer(t)
+	relayer.config.MinBackoff = time.Minute
+	relayer.config.MaxBackoff = 3 * time.Minute
+	now := time.Unix(1700000000, 0)
+	relayer.now = func() time.Time { return now }
+	teeRequestDecryption(t, chain, common.HexToAddress("0x1000"), 5)
+
+	chain.fail = true
+	if err := relayer.ProcessPending(context.Background()); err == nil {
+		t.Fatalf("expected processing to fail")
+	}
+	// Failed attempts are retried after 1, 2, 3 and 3 minutes.
+	for _, delay := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		now = now.Add(delay - time.Second)
+		if err := relayer.ProcessPending(context.Background()); err != nil {
+			t.Fatalf("expected no attempt before %s", delay)
+		}
+		now = now.Add(time.Second)
+		if err := relayer.ProcessPending(context.Background()); err == nil {
+			t.Fatalf("expected an attempt after %s", delay)
+		}
+	}
+	chain.fail = false
+	now = now.Add(3 * time.Minute)
+	if err := relayer.ProcessPending(context.Background()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(chain.fulfillments) != 1 {
+		t.Fatalf("expected 1 submission, got %d", len(chain.fulfillments))
+	}
+}
+
+func TestRelayerResubmitsUntilFulfilled(t *testing.T) {
+	relayer, chain := newTestRelayer(t)
+	relayer.config.ResubmitDelay = time.Minute
+	now := time.Unix(1700000000, 0)
+	relayer.now = func() time.Time { return now }
+	teeRequestDecryption(t, chain, common.HexToAddress("0x1000"), 5)
+
+	if err := relayer.ProcessPending(context.Background()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	// The result isn't included yet: don't submit it again before the delay.
+	if err := relayer.ProcessPending(context.Background()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(chain.fulfillments) != 1 {
+		t.Fatalf("expected 1 submission, got %d", len(chain.fulfillments))
+	}
+	now = now.Add(time.Minute)
+	if err := relayer.ProcessPending(context.Background()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(chain.fulfillments) != 2 {
+		t.Fatalf("expected the dropped result to be resubmitted, got %d submissions", len(chain.fulfillments))
+	}
+
+	// Once the request is fulfilled it is never submitted again.
+	executeFulfillment(t, chain, chain.fulfillments[1], fhevm.TeeLibRun)
+	now = now.Add(time.Minute)
+	if err := relayer.ProcessPending(context.Background()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(chain.fulfillments) != 2 {
+		t.Fatalf("expected no submission for a fulfilled request, got %d submissions", len(chain.fulfillments))
+	}
+	if len(relayer.queue.Pending()) != 0 {
+		t.Fatalf("expected the fulfilled request to leave the queue")
+	}
+}
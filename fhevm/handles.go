@@ -0,0 +1,77 @@
+package fhevm
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+// Symbolic handles.
+//
+// By default, the handle of a ciphertext is the keccak256 hash of its serialization, so the ciphertext must be
+// computed before its handle exists. With FhevmParams.SymbolicHandles, the handle of a result is derived from
+// the operation that produces it instead:
+//
+//	keccak256(selector || input || chainID (32 bytes) || contract || counter (8 bytes))
+//
+// where selector and input are the precompile method and its arguments (operand handles and scalar), contract
+// is the caller and counter is the number of symbolic handles created so far in the transaction. fheRand and
+// fheRandBounded have no operands, so the RNG nonce of the caller is appended to their input: otherwise the same
+// contract would get the same handle for different random values in two transactions. The last two
+// bytes are then replaced by the type of the result and the version of the scheme. Handles are the same on
+// every node, even if encryption is randomized, and can be known before the ciphertext is computed.
+const symbolicHandleVersion = 1
+
+// The precompile call whose results get symbolic handles.
+type handleContext struct {
+	selector uint32
+	input    []byte
+	contract common.Address
+}
+
+// Set the precompile call whose results get symbolic handles. The returned function clears it.
+func setHandleContext(environment EVMEnvironment, selector uint32, contract common.Address, input []byte) func() {
+	params := environment.FhevmParams()
+	if !params.SymbolicHandles && params.Coprocessor == nil {
+		return func() {}
+	}
+	environment.FhevmData().handleContext = &handleContext{selector, input, contract}
+	return func() { environment.FhevmData().handleContext = nil }
+}
+
+// Append entropy to the input of the current precompile call, for results that don't only depend on their operands.
+func addHandleEntropy(environment EVMEnvironment, entropy []byte) {
+	hc := environment.FhevmData().handleContext
+	if hc == nil {
+		return
+	}
+	// The input is the caller's buffer, so it is copied rather than appended to.
+	input := make([]byte, 0, len(hc.input)+len(entropy))
+	hc.input = append(append(input, hc.input...), entropy...)
+}
+
+func computeSymbolicHandle(selector uint32, input []byte, chainID *big.Int, contract common.Address, counter uint64, t tfhe.FheUintType) common.Hash {
+	var selectorBytes [4]byte
+	binary.BigEndian.PutUint32(selectorBytes[:], selector)
+	var counterBytes [8]byte
+	binary.BigEndian.PutUint64(counterBytes[:], counter)
+	handle := crypto.Keccak256Hash(selectorBytes[:], input, common.BigToHash(chainID).Bytes(), contract.Bytes(), counterBytes[:])
+	handle[30] = byte(t)
+	handle[31] = symbolicHandleVersion
+	return handle
+}
+
+// Give the result of an operation the next symbolic handle of the current precompile call, if any.
+func assignSymbolicHandle(environment EVMEnvironment, ct *tfhe.TfheCiphertext) {
+	hc := environment.FhevmData().handleContext
+	if hc == nil {
+		return
+	}
+	counter := environment.FhevmData().handleCounter
+	environment.FhevmData().handleCounter++
+	handle := computeSymbolicHandle(hc.selector, hc.input, environment.ChainID(), hc.contract, counter, ct.FheUintType)
+	ct.Hash = &handle
+}
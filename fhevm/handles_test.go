@@ -0,0 +1,158 @@
+package fhevm
+
+import (
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	fhevm_crypto "github.com/zama-ai/fhevm-go/fhevm/crypto"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+func toBinaryOpInput(method string, lhs common.Hash, rhs common.Hash) []byte {
+	input := crypto.Keccak256([]byte(method))[0:4]
+	input = append(input, lhs.Bytes()...)
+	input = append(input, rhs.Bytes()...)
+	return append(input, make([]byte, 32)...)
+}
+
+func newSymbolicHandlesTestEnvironment() *MockEVMEnvironment {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	environment.chainID = 9000
+	environment.fhevmParams.SymbolicHandles = true
+	return environment
+}
+
+// Adds 2 and 3 twice and returns the two result handles.
+func runSymbolicAdds(t *testing.T, environment *MockEVMEnvironment, contract common.Address) []common.Hash {
+	lhs := verifyTfheCiphertextInTestMemory(environment, new(tfhe.TfheCiphertext).TrivialEncrypt(*big.NewInt(2), tfhe.FheUint8), environment.depth).GetHash()
+	rhs := verifyTfheCiphertextInTestMemory(environment, new(tfhe.TfheCiphertext).TrivialEncrypt(*big.NewInt(3), tfhe.FheUint8), environment.depth).GetHash()
+	allowHandle(environment, lhs, contract)
+	allowHandle(environment, rhs, contract)
+	var results []common.Hash
+	for i := 0; i < 2; i++ {
+		out, err := FheLibRun(environment, contract, contract, toBinaryOpInput("fheAdd(uint256,uint256,bytes1)", lhs, rhs), false)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		results = append(results, common.BytesToHash(out))
+	}
+	return results
+}
+
+func TestSymbolicHandles(t *testing.T) {
+	environment := newSymbolicHandlesTestEnvironment()
+	contract := common.HexToAddress("0x1000")
+	results := runSymbolicAdds(t, environment, contract)
+
+	if results[0] == results[1] {
+		t.Fatalf("expected results of two operations to have different handles")
+	}
+	lhs := new(tfhe.TfheCiphertext).TrivialEncrypt(*big.NewInt(2), tfhe.FheUint8).GetHash()
+	rhs := new(tfhe.TfheCiphertext).TrivialEncrypt(*big.NewInt(3), tfhe.FheUint8).GetHash()
+	input := toBinaryOpInput("fheAdd(uint256,uint256,bytes1)", lhs, rhs)
+	for i, result := range results {
+		expected := computeSymbolicHandle(binary.BigEndian.Uint32(input[0:4]), input[4:], big.NewInt(9000), contract, uint64(i), tfhe.FheUint8)
+		if result != expected {
+			t.Fatalf("expected handle %s, got %s", expected.Hex(), result.Hex())
+		}
+		if result[30] != byte(tfhe.FheUint8) || result[31] != symbolicHandleVersion {
+			t.Fatalf("expected the type and version in the last bytes of the handle, got %s", result.Hex())
+		}
+		out, err := FheLibRun(environment, contract, contract, toDecryptInput("decrypt(uint256)", result, nil), false)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if new(big.Int).SetBytes(out).Uint64() != 5 {
+			t.Fatalf("decrypt result not equal to value, result %v != value %v", new(big.Int).SetBytes(out).Uint64(), 5)
+		}
+	}
+
+	// Handles are deterministic, including during gas estimation.
+	estimation := newSymbolicHandlesTestEnvironment()
+	estimation.commit = false
+	if estimated := runSymbolicAdds(t, estimation, contract); estimated[0] != results[0] || estimated[1] != results[1] {
+		t.Fatalf("expected the same handles during gas estimation")
+	}
+}
+
+func TestSymbolicHandlePersisted(t *testing.T) {
+	environment := newSymbolicHandlesTestEnvironment()
+	contract := common.HexToAddress("0x1000")
+	handle := runSymbolicAdds(t, environment, contract)[0]
+	persistIfVerifiedCiphertext(crypto.Keccak256Hash([]byte{1}), handle, fhevm_crypto.CreateProtectedStorageContractAddress(contract), environment)
+
+	// Load the ciphertext back in another transaction.
+	fhevmData := NewFhevmData()
+	environment.fhevmData = &fhevmData
+	if err := verifyIfCiphertextHandle(handle, environment, contract); err != nil {
+		t.Fatalf(err.Error())
+	}
+	ct := getVerifiedCiphertext(environment, handle)
+	if ct == nil {
+		t.Fatalf("expected the persisted ciphertext to be verified under its symbolic handle")
+	}
+	if ct.hash() != handle {
+		t.Fatalf("expected the loaded ciphertext to keep its handle %s, got %s", handle.Hex(), ct.hash().Hex())
+	}
+}
+
+func TestSymbolicRandHandlesDifferAcrossTransactions(t *testing.T) {
+	environment := newSymbolicHandlesTestEnvironment()
+	contract := common.HexToAddress("0x1000")
+	input := append(crypto.Keccak256([]byte("fheRand(bytes1)"))[0:4], common.RightPadBytes([]byte{byte(tfhe.FheUint8)}, 32)...)
+	var handles []common.Hash
+	for i := 0; i < 2; i++ {
+		// Each transaction has its own FhevmData, so the handle counter restarts at 0.
+		fhevmData := NewFhevmData()
+		environment.fhevmData = &fhevmData
+		out, err := FheLibRun(environment, contract, contract, input, false)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		handles = append(handles, common.BytesToHash(out))
+	}
+	if handles[0] == handles[1] {
+		t.Fatalf("expected fheRand to return different handles in two transactions, got %s twice", handles[0].Hex())
+	}
+}
+
+func TestPendingCiphertext(t *testing.T) {
+	environment := newSymbolicHandlesTestEnvironment()
+	contract := common.HexToAddress("0x1000")
+	pending := common.HexToHash("0xabcd")
+	computed := 0
+	importPendingCiphertext(environment, pending, tfhe.FheUint8, func() (*tfhe.TfheCiphertext, error) {
+		computed++
+		return new(tfhe.TfheCiphertext).TrivialEncrypt(*big.NewInt(7), tfhe.FheUint8), nil
+	})
+	allowHandle(environment, pending, contract)
+	rhs := verifyTfheCiphertextInTestMemory(environment, new(tfhe.TfheCiphertext).TrivialEncrypt(*big.NewInt(1), tfhe.FheUint8), environment.depth).GetHash()
+	allowHandle(environment, rhs, contract)
+
+	if !environment.FhevmData().verifiedCiphertexts[pending].isPending() {
+		t.Fatalf("expected the ciphertext to be pending")
+	}
+	for i := 0; i < 2; i++ {
+		out, err := FheLibRun(environment, contract, contract, toBinaryOpInput("fheAdd(uint256,uint256,bytes1)", pending, rhs), false)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		out, err = FheLibRun(environment, contract, contract, toDecryptInput("decrypt(uint256)", common.BytesToHash(out), nil), false)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if new(big.Int).SetBytes(out).Uint64() != 8 {
+			t.Fatalf("decrypt result not equal to value, result %v != value %v", new(big.Int).SetBytes(out).Uint64(), 8)
+		}
+	}
+	if computed != 1 {
+		t.Fatalf("expected the pending ciphertext to be computed once, got %d", computed)
+	}
+	if ct := getVerifiedCiphertext(environment, pending); ct.isPending() || ct.hash() != pending {
+		t.Fatalf("expected the computed ciphertext to keep its handle")
+	}
+}
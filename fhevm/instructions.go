@@ -29,7 +29,7 @@ func isVerifiedAtCurrentDepth(environment EVMEnvironment, ct *verifiedCiphertext
 }
 
 // Returns a pointer to the ciphertext if the given hash points to a verified ciphertext.
-// Else, it returns nil.
+// Else, it returns nil. Pending ciphertexts aren't computed: only their type and handle can be used.
 func getVerifiedCiphertextFromEVM(environment EVMEnvironment, ciphertextHash common.Hash) *verifiedCiphertext {
 	ct, ok := environment.FhevmData().verifiedCiphertexts[ciphertextHash]
 	if ok && isVerifiedAtCurrentDepth(environment, ct) {
@@ -39,6 +39,16 @@ func getVerifiedCiphertextFromEVM(environment EVMEnvironment, ciphertextHash com
 }
 
 func verifyIfCiphertextHandle(handle common.Hash, env EVMEnvironment, contractAddress common.Address) error {
+	// Contracts that are not in the handle's ACL must not be able to use it. A ciphertext persisted in the
+	// contract's own protected storage is allowed to it: SSTORE only persists the handles that a contract is
+	// allowed to use, and handles stored before ACLs existed have no ACL entry.
+	var ciphertext *ciphertextData
+	if !isHandleUsable(env, handle, contractAddress) {
+		if ciphertext = getCiphertextFromProtectedStoage(env, contractAddress, handle); ciphertext == nil {
+			return nil
+		}
+	}
+
 	ct, ok := env.FhevmData().verifiedCiphertexts[handle]
 	if ok {
 		// If already existing in memory, skip storage and import the same ciphertext at the current depth.
@@ -46,12 +56,18 @@ func verifyIfCiphertextHandle(handle common.Hash, env EVMEnvironment, contractAd
 		// Also works for gas estimation - we don't persist anything to protected storage during gas estimation.
 		// However, ciphertexts remain in memory for the duration of the call, allowing for this lookup to find it.
 		// Note that even if a ciphertext has an empty verification depth set, it still remains in memory.
-		importCiphertextToEVM(env, ct.ciphertext)
+		ct.verifiedDepths.add(env.GetDepth())
 		return nil
 	}
 
-	ciphertext := getCiphertextFromProtectedStoage(env, contractAddress, handle)
+	if ciphertext == nil {
+		ciphertext = getCiphertextFromProtectedStoage(env, contractAddress, handle)
+	}
 	if ciphertext != nil {
+		if ciphertext.isComputedByCoprocessor() {
+			importPendingCiphertext(env, handle, ciphertext.metadata.fheUintType, awaitCoprocessorCiphertext(env, handle))
+			return nil
+		}
 		ct := new(tfhe.TfheCiphertext)
 		err := ct.Deserialize(ciphertext.bytes, ciphertext.metadata.fheUintType)
 		if err != nil {
@@ -59,6 +75,8 @@ func verifyIfCiphertextHandle(handle common.Hash, env EVMEnvironment, contractAd
 			env.GetLogger().Error(msg, "err", err)
 			return errors.New(msg)
 		}
+		// Symbolic handles aren't the hash of the ciphertext.
+		ct.Hash = &handle
 		importCiphertextToEVM(env, ct)
 	}
 	return nil
@@ -94,8 +112,17 @@ func OpSstore(pc *uint64, env EVMEnvironment, scope ScopeContext) ([]byte, error
 	newVal := scope.GetStack().Pop()
 	newValHash := common.BytesToHash(newVal.Bytes())
 	oldValHash := env.GetState(scope.GetContract().Address(), common.Hash(loc.Bytes32()))
+	// A contract storing a handle produced in its frame is allowed to load and use it later. Handles received
+	// from other contracts, e.g. as call arguments, must be allowed to it explicitly, or they aren't persisted.
+	ct := getVerifiedCiphertextFromEVM(env, newValHash)
+	produced := ct != nil && ct.producerDepth == env.GetDepth()
 	// If the value is the same or if we are not going to commit, don't do anything to protected storage.
 	if newValHash != oldValHash && env.IsCommitting() {
+		// Results of scheduled operations are materialized when they are stored.
+		if err := materializeCiphertext(env, newValHash); err != nil {
+			env.GetLogger().Error("opSstore failed to materialize a ciphertext", "handle", newValHash.Hex(), "err", err)
+			return nil, err
+		}
 		protectedStorage := fhevm_crypto.CreateProtectedStorageContractAddress(scope.GetContract().Address())
 
 		// Define flag location as keccak256(keccak256(loc)) in protected storage. Used to mark the location as containing a handle.
@@ -108,8 +135,16 @@ func OpSstore(pc *uint64, env EVMEnvironment, scope ScopeContext) ([]byte, error
 		// Since the old value is no longer stored in actual contract storage, run garbage collection on protected storage.
 		garbageCollectProtectedStorage(flagHandleLocation, oldValHash, protectedStorage, env)
 
-		// If a verified ciphertext, persist to protected storage.
-		persistIfVerifiedCiphertext(flagHandleLocation, newValHash, protectedStorage, env)
+		// If a verified ciphertext the contract is allowed to use, persist to protected storage.
+		if produced || isHandleAllowed(env, newValHash, scope.GetContract().Address()) {
+			persistIfVerifiedCiphertext(flagHandleLocation, newValHash, protectedStorage, env)
+		}
+	}
+	if produced {
+		allowHandle(env, newValHash, scope.GetContract().Address())
+	}
+	if ct != nil {
+		persistUnlockHeight(env, newValHash)
 	}
 	// Set the SSTORE's value in the actual contract.
 	env.SetState(scope.GetContract().Address(), loc.Bytes32(), newValHash)
@@ -124,7 +159,7 @@ func DelegateCiphertextHandlesInArgs(env EVMEnvironment, args []byte) (verified
 		if contains(args, key.Bytes()) && isVerifiedAtCurrentDepth(env, verifiedCiphertext) {
 			if env.IsCommitting() {
 				env.GetLogger().Info("delegateCiphertextHandlesInArgs",
-					"handle", verifiedCiphertext.ciphertext.GetHash().Hex(),
+					"handle", key.Hex(),
 					"fromDepth", env.GetDepth(),
 					"toDepth", env.GetDepth()+1)
 			}
@@ -146,7 +181,7 @@ func delegateCiphertextHandlesToCaller(env EVMEnvironment, ret []byte) {
 		if contains(ret, key.Bytes()) && isVerifiedAtCurrentDepth(env, verifiedCiphertext) {
 			if env.IsCommitting() {
 				env.GetLogger().Info("opReturn making ciphertext available to caller",
-					"handle", verifiedCiphertext.ciphertext.GetHash().Hex(),
+					"handle", key.Hex(),
 					"fromDepth", env.GetDepth(),
 					"toDepth", env.GetDepth()-1)
 			}
@@ -157,14 +192,18 @@ func delegateCiphertextHandlesToCaller(env EVMEnvironment, ret []byte) {
 }
 
 func RemoveVerifiedCipherextsAtCurrentDepth(env EVMEnvironment) {
-	for _, verifiedCiphertext := range env.FhevmData().verifiedCiphertexts {
+	for key, verifiedCiphertext := range env.FhevmData().verifiedCiphertexts {
 		if env.IsCommitting() {
 			env.GetLogger().Info("Run removing ciphertext from depth",
-				"handle", verifiedCiphertext.ciphertext.GetHash().Hex(),
+				"handle", key.Hex(),
 				"depth", env.GetDepth())
 		}
 		// Delete the current EVM depth from the set of verified depths.
 		verifiedCiphertext.verifiedDepths.del(env.GetDepth())
+		// Later frames at the same depth didn't produce the ciphertext.
+		if verifiedCiphertext.producerDepth == env.GetDepth() {
+			verifiedCiphertext.producerDepth = -1
+		}
 	}
 }
 
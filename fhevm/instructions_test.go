@@ -161,6 +161,12 @@ type MockEVMEnvironment struct {
 	readOnly    bool
 	fhevmParams FhevmParams
 	gasLimit    uint64
+	blockNumber uint64
+	chainID     uint64
+	// Block time, in unix seconds
+	blockTimestamp uint64
+	// Contracts run by Call. Calls to other addresses succeed, like calls to accounts without code.
+	contracts map[common.Address]func(caller common.Address, input []byte) ([]byte, error)
 }
 
 func (*MockEVMEnvironment) OtelContext() context.Context {
@@ -220,6 +226,15 @@ func (environment *MockEVMEnvironment) CreateContract2(caller common.Address, co
 	return make([]byte, 0), common.Address{}, 0, nil
 }
 
+func (environment *MockEVMEnvironment) Call(caller common.Address, addr common.Address, input []byte, gas uint64, value *big.Int) ([]byte, uint64, error) {
+	contract, ok := environment.contracts[addr]
+	if !ok {
+		return nil, gas, nil
+	}
+	ret, err := contract(caller, input)
+	return ret, gas, err
+}
+
 func (environment *MockEVMEnvironment) FhevmData() *FhevmData {
 	return environment.fhevmData
 }
@@ -236,11 +251,26 @@ func (environment *MockEVMEnvironment) GasLimit() uint64 {
 	return environment.gasLimit
 }
 
+func (environment *MockEVMEnvironment) BlockNumber() *big.Int {
+	return new(big.Int).SetUint64(environment.blockNumber)
+}
+
+func (environment *MockEVMEnvironment) ChainID() *big.Int {
+	return new(big.Int).SetUint64(environment.chainID)
+}
+
+func (environment *MockEVMEnvironment) BlockTimestamp() uint64 {
+	return environment.blockTimestamp
+}
+
+// Block time of test environments. It is far from the wall clock, so that tests catch uses of the wall clock.
+const testBlockTimestamp uint64 = 4102444800
+
 func newTestEVMEnvironment() *MockEVMEnvironment {
 	fhevmData := NewFhevmData()
 	db := rawdb.NewMemoryDatabase()
 	state, _ := state.New(common.Hash{}, state.NewDatabase(db), nil)
-	return &MockEVMEnvironment{fhevmData: &fhevmData, stateDb: state, commit: true, fhevmParams: DefaultFhevmParams()}
+	return &MockEVMEnvironment{fhevmData: &fhevmData, stateDb: state, commit: true, fhevmParams: DefaultFhevmParams(), blockTimestamp: testBlockTimestamp}
 }
 
 func TestProtectedStorageSstoreSload(t *testing.T) {
@@ -31,12 +31,22 @@ type EVMEnvironment interface {
 
 	CreateContract(caller common.Address, code []byte, gas uint64, value *big.Int, address common.Address) ([]byte, common.Address, uint64, error)
 	CreateContract2(caller common.Address, code []byte, codeHash common.Hash, gas uint64, value *big.Int, address common.Address) ([]byte, common.Address, uint64, error)
+	// Call runs a message call from caller to addr, e.g. to deliver a decryption callback, and returns
+	// its output and the gas left.
+	Call(caller common.Address, addr common.Address, input []byte, gas uint64, value *big.Int) ([]byte, uint64, error)
 
 	FhevmData() *FhevmData
 	FhevmParams() *FhevmParams
 
 	// GasLimit returns the gas limit of the transaction specified by the sender.
 	GasLimit() uint64
+	// BlockNumber returns the number of the block being executed, or of the block an eth_call runs on.
+	BlockNumber() *big.Int
+	// ChainID returns the ID of the chain, used to derive symbolic handles and in the EIP-712 domain.
+	ChainID() *big.Int
+	// BlockTimestamp returns the timestamp of the block being executed, or of the block an eth_call runs on,
+	// in unix seconds. Authorizations expire at block time, so that every node agrees on their validity.
+	BlockTimestamp() uint64
 	// This should return the context used for OpenTelemetry in the current EVM.
 	// It should be considered the root context for every op that runs in the EVM, and all spans created from this context
 	// would be child spans for what has been already created using the context.
@@ -45,14 +55,30 @@ type EVMEnvironment interface {
 }
 
 type FhevmData struct {
-	// A map from a ciphertext hash to itself and stack depth at which it is verified
+	// A map from a ciphertext handle to its ciphertext, computed or pending, and the stack depths at which it is verified
 	verifiedCiphertexts map[common.Hash]*verifiedCiphertext
 
+	// The precompile call whose results get symbolic handles, and the number of symbolic handles created in the transaction
+	handleContext *handleContext
+	handleCounter uint64
+
+	// Handles of the results of the operations scheduled in the transaction, see MaterializeCiphertexts
+	scheduledOperations []common.Hash
+
 	nextCiphertextHashOnGasEst uint256.Int
+
+	// A set of (handle, address) pairs granted with allowTransient or to the callers of operations, cleared at the
+	// end of the transaction
+	transientAllowances map[transientAllowance]struct{}
+
+	// Unlock heights inherited by the results of operations, persisted when a result is stored or allowed
+	transientUnlockHeights map[common.Hash]*big.Int
 }
 
 func NewFhevmData() FhevmData {
 	return FhevmData{
-		verifiedCiphertexts: make(map[common.Hash]*verifiedCiphertext),
+		verifiedCiphertexts:    make(map[common.Hash]*verifiedCiphertext),
+		transientAllowances:    make(map[transientAllowance]struct{}),
+		transientUnlockHeights: make(map[common.Hash]*big.Int),
 	}
 }
@@ -49,6 +49,32 @@ func (from *depthSet) clone() (to *depthSet) {
 type verifiedCiphertext struct {
 	verifiedDepths *depthSet
 	ciphertext     *tfhe.TfheCiphertext
+	// The depth of the frame in which the ciphertext was first imported, or -1 once that frame has returned.
+	producerDepth int
+	// Computes the ciphertext while it is pending, nil once it is computed.
+	compute func() (*tfhe.TfheCiphertext, error)
+}
+
+// Computes the ciphertext if it is pending.
+func (vc *verifiedCiphertext) resolve() error {
+	if vc.compute == nil {
+		return nil
+	}
+	ct, err := vc.compute()
+	if err != nil {
+		return err
+	}
+	// Keep the handle the ciphertext was registered under.
+	handle := vc.ciphertext.GetHash()
+	ct.Hash = &handle
+	vc.ciphertext = ct
+	vc.compute = nil
+	return nil
+}
+
+// Returns true if the ciphertext hasn't been computed yet.
+func (vc *verifiedCiphertext) isPending() bool {
+	return vc.compute != nil
 }
 
 // Returns the type of the verified ciphertext
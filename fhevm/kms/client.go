@@ -0,0 +1,76 @@
+package kms
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Default deadline of a KMS request.
+const DefaultTimeout = 10 * time.Second
+
+// Config of a connection to the KMS endpoint.
+type Config struct {
+	// Address of the endpoint. KmsEndpointAddr is used if empty.
+	Address string
+	// Insecure disables TLS. Only use it on local networks.
+	Insecure bool
+	// PEM file of the CA that issued the KMS certificate. The system roots are used if empty.
+	CACertFile string
+	// PEM files of the client certificate and key, for mutual TLS. Both or none must be set.
+	ClientCertFile string
+	ClientKeyFile  string
+	// Overrides the name checked against the KMS certificate. The host of Address is used if empty.
+	ServerName string
+}
+
+// Returns the credentials of connections to the KMS.
+func (config Config) TransportCredentials() (credentials.TransportCredentials, error) {
+	if config.Insecure {
+		return insecure.NewCredentials(), nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: config.ServerName}
+	if config.CACertFile != "" {
+		pem, err := os.ReadFile(config.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificate found in the KMS CA file")
+		}
+	}
+	if (config.ClientCertFile == "") != (config.ClientKeyFile == "") {
+		return nil, errors.New("both the client certificate and key must be set for mutual TLS")
+	}
+	if config.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.ClientCertFile, config.ClientKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// Dial connects to the KMS endpoint. The connection is established lazily, on the first request.
+func Dial(config Config, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	address := config.Address
+	if address == "" {
+		address = KmsEndpointAddr
+	}
+	if address == "" {
+		return nil, errors.New("KMS endpoint address is not set")
+	}
+	creds, err := config.TransportCredentials()
+	if err != nil {
+		return nil, err
+	}
+	return grpc.Dial(address, append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, opts...)...)
+}
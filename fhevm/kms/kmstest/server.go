@@ -0,0 +1,142 @@
+// Package kmstest provides an in-process KMS endpoint for tests.
+package kmstest
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/zama-ai/fhevm-go/fhevm/kms"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+	"golang.org/x/crypto/nacl/box"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Server is a fake KMS. By default, Decrypt and ValidateAndDecrypt decrypt FHE ciphertexts with the client
+// key loaded in the process and return an empty signature. Reencrypt and ValidateAndReencrypt decrypt them the
+// same way and seal the plaintext to the public key at the end of the request field.
+type Server struct {
+	kms.UnimplementedKmsEndpointServer
+	// DecryptFunc, if set, answers the decryption requests instead.
+	DecryptFunc func(ctx context.Context, req *kms.DecryptionRequest) (*kms.DecryptionResponse, error)
+	// ReencryptFunc, if set, answers the reencryption requests instead.
+	ReencryptFunc func(ctx context.Context, req *kms.ReencryptionRequest) (*kms.ReencryptionResponse, error)
+
+	mu                   sync.Mutex
+	requests             []*kms.DecryptionRequest
+	reencryptionRequests []*kms.ReencryptionRequest
+}
+
+func (s *Server) Decrypt(ctx context.Context, req *kms.DecryptionRequest) (*kms.DecryptionResponse, error) {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+	if s.DecryptFunc != nil {
+		return s.DecryptFunc(ctx, req)
+	}
+	return DecryptLocally(req)
+}
+
+func (s *Server) ValidateAndDecrypt(ctx context.Context, req *kms.DecryptionRequest) (*kms.DecryptionResponse, error) {
+	return s.Decrypt(ctx, req)
+}
+
+func (s *Server) Reencrypt(ctx context.Context, req *kms.ReencryptionRequest) (*kms.ReencryptionResponse, error) {
+	s.mu.Lock()
+	s.reencryptionRequests = append(s.reencryptionRequests, req)
+	s.mu.Unlock()
+	if s.ReencryptFunc != nil {
+		return s.ReencryptFunc(ctx, req)
+	}
+	return ReencryptLocally(req)
+}
+
+func (s *Server) ValidateAndReencrypt(ctx context.Context, req *kms.ReencryptionRequest) (*kms.ReencryptionResponse, error) {
+	return s.Reencrypt(ctx, req)
+}
+
+// Returns the decryption requests received so far.
+func (s *Server) Requests() []*kms.DecryptionRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*kms.DecryptionRequest{}, s.requests...)
+}
+
+// Returns the reencryption requests received so far.
+func (s *Server) ReencryptionRequests() []*kms.ReencryptionRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*kms.ReencryptionRequest{}, s.reencryptionRequests...)
+}
+
+// Start serves on an in-memory listener until the test ends and returns a connection to the server.
+func (s *Server) Start(t testing.TB) *grpc.ClientConn {
+	listener := bufconn.Listen(1024 * 1024)
+	s.serve(t, listener)
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// Listen serves on a local TCP port with the given credentials until the test ends and returns its address.
+func (s *Server) Listen(t testing.TB, creds credentials.TransportCredentials) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	s.serve(t, listener, grpc.Creds(creds))
+	return listener.Addr().String()
+}
+
+func (s *Server) serve(t testing.TB, listener net.Listener, opts ...grpc.ServerOption) {
+	server := grpc.NewServer(opts...)
+	kms.RegisterKmsEndpointServer(server, s)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+}
+
+// DecryptLocally answers a decryption request with the client key loaded in the process.
+func DecryptLocally(req *kms.DecryptionRequest) (*kms.DecryptionResponse, error) {
+	typ, err := kms.ToFheUintType(req.FheType)
+	if err != nil {
+		return nil, err
+	}
+	ct := new(tfhe.TfheCiphertext)
+	if err := ct.Deserialize(req.Ciphertext, typ); err != nil {
+		return nil, err
+	}
+	plaintext, err := ct.Decrypt()
+	if err != nil {
+		return nil, err
+	}
+	return &kms.DecryptionResponse{FheType: req.FheType, Plaintext: plaintext.Bytes()}, nil
+}
+
+// ReencryptLocally answers a reencryption request with the client key loaded in the process, sealing the plaintext
+// to the 32-byte public key at the end of the request field.
+func ReencryptLocally(req *kms.ReencryptionRequest) (*kms.ReencryptionResponse, error) {
+	if len(req.Request) < 32 {
+		return nil, errors.New("reencryption request doesn't end with a public key")
+	}
+	res, err := DecryptLocally(&kms.DecryptionRequest{FheType: req.FheType, Ciphertext: req.Ciphertext})
+	if err != nil {
+		return nil, err
+	}
+	publicKey := req.Request[len(req.Request)-32:]
+	sealed, err := box.SealAnonymous(nil, res.Plaintext, (*[32]byte)(publicKey), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &kms.ReencryptionResponse{FheType: req.FheType, ReencryptedCiphertext: sealed}, nil
+}
@@ -0,0 +1,55 @@
+package kms
+
+import (
+	"fmt"
+
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+// Returns the KMS type of an FHE ciphertext type.
+func ToFheType(t tfhe.FheUintType) (FheType, error) {
+	switch t {
+	case tfhe.FheBool:
+		return FheType_Bool, nil
+	case tfhe.FheUint4:
+		return FheType_Euint4, nil
+	case tfhe.FheUint8:
+		return FheType_Euint8, nil
+	case tfhe.FheUint16:
+		return FheType_Euint16, nil
+	case tfhe.FheUint32:
+		return FheType_Euint32, nil
+	case tfhe.FheUint64:
+		return FheType_Euint64, nil
+	case tfhe.FheUint128:
+		return FheType_Euint128, nil
+	case tfhe.FheUint160:
+		return FheType_Euint160, nil
+	default:
+		return 0, fmt.Errorf("no KMS type for FheUintType %d", t)
+	}
+}
+
+// Returns the FHE ciphertext type of a KMS type.
+func ToFheUintType(t FheType) (tfhe.FheUintType, error) {
+	switch t {
+	case FheType_Bool:
+		return tfhe.FheBool, nil
+	case FheType_Euint4:
+		return tfhe.FheUint4, nil
+	case FheType_Euint8:
+		return tfhe.FheUint8, nil
+	case FheType_Euint16:
+		return tfhe.FheUint16, nil
+	case FheType_Euint32:
+		return tfhe.FheUint32, nil
+	case FheType_Euint64:
+		return tfhe.FheUint64, nil
+	case FheType_Euint128:
+		return tfhe.FheUint128, nil
+	case FheType_Euint160:
+		return tfhe.FheUint160, nil
+	default:
+		return 0, fmt.Errorf("unknown KMS type %d", t)
+	}
+}
@@ -0,0 +1,318 @@
+package fhevm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Operations are the typed form of the FheLib operations, for Go code that runs them without an ABI encoded
+// input. The FheLib precompiles decode their input into an Operation and share its validation, gas and
+// computation. An operation is a single instruction of a program, so it follows the rules of programs.
+type Operator byte
+
+const (
+	OpAdd      = Operator(programAdd)
+	OpSub      = Operator(programSub)
+	OpMul      = Operator(programMul)
+	OpDiv      = Operator(programDiv)
+	OpRem      = Operator(programRem)
+	OpMin      = Operator(programMin)
+	OpMax      = Operator(programMax)
+	OpBitAnd   = Operator(programAnd)
+	OpBitOr    = Operator(programOr)
+	OpBitXor   = Operator(programXor)
+	OpShl      = Operator(programShl)
+	OpShr      = Operator(programShr)
+	OpRotl     = Operator(programRotl)
+	OpRotr     = Operator(programRotr)
+	OpEq       = Operator(programEq)
+	OpNe       = Operator(programNe)
+	OpGe       = Operator(programGe)
+	OpGt       = Operator(programGt)
+	OpLe       = Operator(programLe)
+	OpLt       = Operator(programLt)
+	OpBitTest  = Operator(programBitTest)
+	OpNeg      = Operator(programNeg)
+	OpNot      = Operator(programNot)
+	OpPopcount = Operator(programPopcount)
+	OpClz      = Operator(programClz)
+	OpCtz      = Operator(programCtz)
+	OpSelect   = Operator(programSelect)
+	OpCast     = Operator(programCast)
+	OpTrivial  = Operator(programTrivial)
+)
+
+// An Operand is a ciphertext handle, or a plaintext scalar if Scalar is set.
+type Operand struct {
+	Handle common.Hash
+	Scalar *big.Int
+}
+
+type Operation struct {
+	Operator Operator
+	Operands []Operand
+	// The type to cast or trivially encrypt to.
+	Type tfhe.FheUintType
+}
+
+// Decode an operation into a single instruction program, checking that ciphertext operands are verified at
+// the current depth and have valid types.
+func parseOperation(environment EVMEnvironment, operation Operation) (*program, error) {
+	opcode := byte(operation.Operator)
+	count := programOperandCount(opcode)
+	if count < 0 || opcode == programOutput {
+		return nil, fmt.Errorf("unknown operator %d", opcode)
+	}
+	// The type of cast and trivial is an immediate operand of programs.
+	if opcode == programCast || opcode == programTrivial {
+		count--
+	}
+	if len(operation.Operands) != count {
+		return nil, fmt.Errorf("operator %d takes %d operands, got %d", opcode, count, len(operation.Operands))
+	}
+	p := &program{}
+	instruction := programInstruction{opcode: opcode, castType: operation.Type}
+	for i, operand := range operation.Operands {
+		if operand.Scalar != nil {
+			if operand.Scalar.Sign() < 0 || operand.Scalar.BitLen() > 256 {
+				return nil, fmt.Errorf("operand %d is not a 256-bit unsigned scalar", i)
+			}
+			p.inputs = append(p.inputs, common.BigToHash(operand.Scalar))
+			p.registers = append(p.registers, programRegister{scalar: true})
+		} else {
+			ct := getVerifiedCiphertext(environment, operand.Handle)
+			if ct == nil {
+				return nil, fmt.Errorf("operand %d is not a verified ciphertext handle", i)
+			}
+			p.inputs = append(p.inputs, operand.Handle)
+			p.registers = append(p.registers, programRegister{fheUintType: ct.fheUintType()})
+		}
+		instruction.operands = append(instruction.operands, i)
+	}
+	if err := p.check(instruction, false); err != nil {
+		return nil, err
+	}
+	p.instructions = []programInstruction{instruction}
+	p.outputs = []int{len(p.registers) - 1}
+	return p, nil
+}
+
+// Returns the type of the result of an operation.
+func (p *program) resultType() tfhe.FheUintType {
+	return p.registers[p.outputs[0]].fheUintType
+}
+
+// RunOperation computes an operation and imports its result at the current depth. During gas estimation, it
+// imports a random ciphertext of the result type instead. The result isn't allowed to anyone, and it inherits the
+// time lock of its operands.
+func RunOperation(environment EVMEnvironment, operation Operation) (common.Hash, error) {
+	p, err := parseOperation(environment, operation)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	result, err := p.runOperation(environment)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	inheritUnlockHeight(environment, result, p.ciphertextInputs())
+	return result, nil
+}
+
+func (p *program) runOperation(environment EVMEnvironment) (common.Hash, error) {
+	// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
+	if !environment.IsCommitting() && !environment.IsEthCall() {
+		return common.BytesToHash(importRandomCiphertext(environment, p.resultType())), nil
+	}
+	outputs, err := p.run(environment, fheProgramBackend{})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	importCiphertext(environment, outputs[0])
+	return outputs[0].GetHash(), nil
+}
+
+// OperationRequiredGas returns the gas of an operation, failing if the operation is invalid.
+func OperationRequiredGas(environment EVMEnvironment, operation Operation) (uint64, error) {
+	p, err := parseOperation(environment, operation)
+	if err != nil {
+		return 0, err
+	}
+	return p.requiredGas(&environment.FhevmParams().GasCosts, false), nil
+}
+
+// DecryptRequiredGas returns the gas of decrypting a verified handle.
+func DecryptRequiredGas(environment EVMEnvironment, handle common.Hash) (uint64, error) {
+	ct := getVerifiedCiphertext(environment, handle)
+	if ct == nil {
+		return 0, errors.New("unverified handle")
+	}
+	return environment.FhevmParams().GasCosts.FheDecrypt[ct.fheUintType()], nil
+}
+
+// DecryptCiphertext returns the plaintext of a verified or publicly decryptable handle. Decryptions follow the same rules
+// as the decrypt precompile, except that the caller doesn't have to be authorized on eth_call. During gas
+// estimation, it returns the maximum 256-bit value.
+func DecryptCiphertext(environment EVMEnvironment, handle common.Hash) (*big.Int, error) {
+	return decryptHandle(environment, common.Address{}, handle, nil)
+}
+
+// Decrypt a handle used by contract. authorize is called on eth_call, unless the handle is publicly decryptable.
+func decryptHandle(environment EVMEnvironment, contract common.Address, handle common.Hash, authorize func(ct *tfhe.TfheCiphertext) error) (*big.Int, error) {
+	// if not gas estimation and not view function fail if decryptions are disabled in transactions
+	if environment.IsCommitting() && !environment.IsEthCall() && environment.FhevmParams().DisableDecryptionsInTransaction {
+		return nil, errors.New("decryptions during transaction are disabled")
+	}
+	ct, public, err := getDecryptableCiphertext(environment, handle, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load a publicly decryptable ciphertext: %w", err)
+	}
+	if ct == nil {
+		return nil, errors.New("unverified handle")
+	}
+	if err := verifyUnlocked(environment, ct.GetHash()); err != nil {
+		return nil, err
+	}
+
+	// If we are doing gas estimation, skip decryption and make sure we return the maximum possible value.
+	// We need that, because non-zero bytes cost more than zero bytes in some contexts (e.g. SSTORE or memory operations).
+	if !environment.IsCommitting() && !environment.IsEthCall() {
+		return new(big.Int).SetBytes(bytes.Repeat([]byte{0xFF}, 32)), nil
+	}
+
+	if environment.IsEthCall() && !public && authorize != nil {
+		if err := authorize(ct); err != nil {
+			return nil, err
+		}
+	}
+
+	plaintext, err := decryptValue(environment, ciphertextContract(contract, public), ct)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetUint64(plaintext), nil
+}
+
+// The FheLib precompiles are ABI adapters over operations: they decode their input into an Operation,
+// then run it or compute its gas.
+type operationDecoder func(input []byte) (Operation, error)
+
+var (
+	binaryOperationArgs = mustParseABITypes("(uint256,uint256,bytes1)")
+	unaryOperationArgs  = mustParseABITypes("(uint256)")
+	selectOperationArgs = mustParseABITypes("(uint256,uint256,uint256)")
+	typedOperationArgs  = mustParseABITypes("(uint256,bytes1)")
+)
+
+// Decodes the (uint256 lhs, uint256 rhs, bytes1 scalarByte) input of a binary operation.
+func binaryOperation(operator Operator) operationDecoder {
+	return func(input []byte) (Operation, error) {
+		args, err := decodeABIArgs(binaryOperationArgs, input)
+		if err != nil {
+			return Operation{}, err
+		}
+		rhs := Operand{Handle: args.word(1)}
+		if args.byte(2) == 1 {
+			rhs = Operand{Scalar: args.word(1).Big()}
+		}
+		return Operation{Operator: operator, Operands: []Operand{{Handle: args.word(0)}, rhs}}, nil
+	}
+}
+
+// Decodes the uint256 ct input of a unary operation.
+func unaryOperation(operator Operator) operationDecoder {
+	return func(input []byte) (Operation, error) {
+		args, err := decodeABIArgs(unaryOperationArgs, input)
+		if err != nil {
+			return Operation{}, err
+		}
+		return Operation{Operator: operator, Operands: []Operand{{Handle: args.word(0)}}}, nil
+	}
+}
+
+// Decodes the (uint256 control, uint256 ifTrue, uint256 ifFalse) input of fheIfThenElse.
+func selectOperation(input []byte) (Operation, error) {
+	args, err := decodeABIArgs(selectOperationArgs, input)
+	if err != nil {
+		return Operation{}, err
+	}
+	operands := []Operand{{Handle: args.word(0)}, {Handle: args.word(1)}, {Handle: args.word(2)}}
+	return Operation{Operator: OpSelect, Operands: operands}, nil
+}
+
+// Decodes the (uint256 ct, bytes1 toType) input of cast.
+func castOperation(input []byte) (Operation, error) {
+	args, err := decodeABIArgs(typedOperationArgs, input)
+	if err != nil {
+		return Operation{}, err
+	}
+	return Operation{Operator: OpCast, Operands: []Operand{{Handle: args.word(0)}}, Type: tfhe.FheUintType(args.byte(1))}, nil
+}
+
+// Decodes the (uint256 value, bytes1 toType) input of trivialEncrypt.
+func trivialEncryptOperation(input []byte) (Operation, error) {
+	args, err := decodeABIArgs(typedOperationArgs, input)
+	if err != nil {
+		return Operation{}, err
+	}
+	return Operation{Operator: OpTrivial, Operands: []Operand{{Scalar: args.word(0).Big()}}, Type: tfhe.FheUintType(args.byte(1))}, nil
+}
+
+func runOperationPrecompile(environment EVMEnvironment, name string, decode operationDecoder, input []byte, runSpan trace.Span) ([]byte, error) {
+	logger := environment.GetLogger()
+	operation, err := decode(input)
+	if err != nil {
+		logger.Error(name+" failed to decode input", "err", err, "input", hex.EncodeToString(input))
+		return nil, err
+	}
+	p, err := parseOperation(environment, operation)
+	if err != nil {
+		logger.Error(name+" inputs are invalid", "err", err, "input", hex.EncodeToString(input))
+		return nil, err
+	}
+	p.describeOperands(environment, runSpan)
+
+	result, err := p.runOperation(environment)
+	if err != nil {
+		logger.Error(name+" failed", "err", err)
+		return nil, err
+	}
+	if environment.IsCommitting() {
+		logger.Info(name+" success", "result", result.Hex())
+	}
+	return result.Bytes(), nil
+}
+
+func operationPrecompileRequiredGas(environment EVMEnvironment, name string, decode operationDecoder, input []byte) uint64 {
+	logger := environment.GetLogger()
+	operation, err := decode(input)
+	if err != nil {
+		logger.Error(name+" RequiredGas() failed to decode input", "err", err, "input", hex.EncodeToString(input))
+		return 0
+	}
+	gas, err := OperationRequiredGas(environment, operation)
+	if err != nil {
+		logger.Error(name+" RequiredGas() inputs are invalid", "err", err, "input", hex.EncodeToString(input))
+		return 0
+	}
+	return gas
+}
+
+// Describe the input registers of a program in a span.
+func (p *program) describeOperands(environment EVMEnvironment, span trace.Span) {
+	operands := make([]operand, 0, len(p.inputs))
+	for i, input := range p.inputs {
+		if p.registers[i].scalar {
+			operands = append(operands, plainOperand(*p.scalar(i)))
+		} else {
+			operands = append(operands, encryptedOperand(*getVerifiedCiphertext(environment, input)))
+		}
+	}
+	otelDescribeOperands(span, operands...)
+}
@@ -0,0 +1,123 @@
+package fhevm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+func TestRunOperation(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	lhs := verifyCiphertextInTestMemory(environment, 7, environment.depth, tfhe.FheUint8).GetHash()
+	rhs := verifyCiphertextInTestMemory(environment, 3, environment.depth, tfhe.FheUint8).GetHash()
+
+	testcases := []struct {
+		name      string
+		operation Operation
+		expected  uint64
+	}{
+		{"add", Operation{Operator: OpAdd, Operands: []Operand{{Handle: lhs}, {Handle: rhs}}}, 10},
+		{"scalar sub", Operation{Operator: OpSub, Operands: []Operand{{Handle: lhs}, {Scalar: big.NewInt(2)}}}, 5},
+		{"scalar div", Operation{Operator: OpDiv, Operands: []Operand{{Handle: lhs}, {Scalar: big.NewInt(2)}}}, 3},
+		{"gt", Operation{Operator: OpGt, Operands: []Operand{{Handle: lhs}, {Handle: rhs}}}, 1},
+		{"neg", Operation{Operator: OpNeg, Operands: []Operand{{Handle: rhs}}}, 253},
+		{"cast", Operation{Operator: OpCast, Operands: []Operand{{Handle: lhs}}, Type: tfhe.FheUint32}, 7},
+		{"trivial", Operation{Operator: OpTrivial, Operands: []Operand{{Scalar: big.NewInt(42)}}, Type: tfhe.FheUint16}, 42},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := OperationRequiredGas(environment, tc.operation); err != nil {
+				t.Fatalf(err.Error())
+			}
+			result, err := RunOperation(environment, tc.operation)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			value, err := DecryptCiphertext(environment, result)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			if value.Uint64() != tc.expected {
+				t.Fatalf("decrypt result not equal to value, result %v != value %v", value, tc.expected)
+			}
+		})
+	}
+}
+
+func TestOperationRequiredGasMatchesPrecompile(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	lhs := verifyCiphertextInTestMemory(environment, 7, environment.depth, tfhe.FheUint16).GetHash()
+	rhs := verifyCiphertextInTestMemory(environment, 3, environment.depth, tfhe.FheUint16).GetHash()
+
+	gas, err := OperationRequiredGas(environment, Operation{Operator: OpMul, Operands: []Operand{{Handle: lhs}, {Scalar: big.NewInt(3)}}})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if expected := FheLibRequiredGas(environment, 0, toLibPrecompileInput("fheMul(uint256,uint256,bytes1)", true, lhs, common.BigToHash(big.NewInt(3)))); gas != expected {
+		t.Fatalf("incorrect gas, expected=%d, got=%d", expected, gas)
+	}
+	gas, err = OperationRequiredGas(environment, Operation{Operator: OpMul, Operands: []Operand{{Handle: lhs}, {Handle: rhs}}})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if expected := FheLibRequiredGas(environment, 0, toLibPrecompileInput("fheMul(uint256,uint256,bytes1)", false, lhs, rhs)); gas != expected {
+		t.Fatalf("incorrect gas, expected=%d, got=%d", expected, gas)
+	}
+}
+
+func TestRunOperationInvalid(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	uint8Ct := verifyCiphertextInTestMemory(environment, 2, environment.depth, tfhe.FheUint8).GetHash()
+	uint16Ct := verifyCiphertextInTestMemory(environment, 2, environment.depth, tfhe.FheUint16).GetHash()
+
+	testcases := []struct {
+		name      string
+		operation Operation
+	}{
+		{"unknown operator", Operation{Operator: 0x7f, Operands: []Operand{{Handle: uint8Ct}}}},
+		{"wrong operand count", Operation{Operator: OpAdd, Operands: []Operand{{Handle: uint8Ct}}}},
+		{"unverified operand", Operation{Operator: OpNeg, Operands: []Operand{{Handle: common.HexToHash("0xabcd")}}}},
+		{"type mismatch", Operation{Operator: OpAdd, Operands: []Operand{{Handle: uint8Ct}, {Handle: uint16Ct}}}},
+		{"negative scalar", Operation{Operator: OpAdd, Operands: []Operand{{Handle: uint8Ct}, {Scalar: big.NewInt(-1)}}}},
+		{"division by zero", Operation{Operator: OpDiv, Operands: []Operand{{Handle: uint8Ct}, {Scalar: big.NewInt(0)}}}},
+		{"select on a non-bool", Operation{Operator: OpSelect, Operands: []Operand{{Handle: uint8Ct}, {Handle: uint8Ct}, {Handle: uint8Ct}}}},
+		{"invalid trivial type", Operation{Operator: OpTrivial, Operands: []Operand{{Scalar: big.NewInt(1)}}, Type: 0xee}},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := OperationRequiredGas(environment, tc.operation); err == nil {
+				t.Fatalf("expected the gas of an invalid operation to fail")
+			}
+			if _, err := RunOperation(environment, tc.operation); err == nil {
+				t.Fatalf("expected an invalid operation to fail")
+			}
+		})
+	}
+}
+
+func TestRunOperationGasEstimation(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	environment.commit = false
+	lhs := verifyCiphertextInTestMemory(environment, 7, environment.depth, tfhe.FheUint8).GetHash()
+	result, err := RunOperation(environment, Operation{Operator: OpEq, Operands: []Operand{{Handle: lhs}, {Scalar: big.NewInt(7)}}})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	ct := getVerifiedCiphertext(environment, result)
+	if ct == nil || ct.fheUintType() != tfhe.FheBool {
+		t.Fatalf("expected a random FheBool result during gas estimation")
+	}
+	value, err := DecryptCiphertext(environment, result)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if value.BitLen() != 256 {
+		t.Fatalf("expected the maximum value during gas estimation, got %v", value)
+	}
+}
@@ -1,111 +1,17 @@
 package fhevm
 
-import "encoding/hex"
-
 func fheAddSubRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	input = input[:minInt(65, len(input))]
-
-	logger := environment.GetLogger()
-	isScalar, err := isScalarOp(input)
-	if err != nil {
-		logger.Error("fheAdd/Sub RequiredGas() can not detect if operator is meant to be scalar", "err", err, "input", hex.EncodeToString(input))
-		return 0
-	}
-	var lhs, rhs *verifiedCiphertext
-	if !isScalar {
-		lhs, rhs, err = get2VerifiedOperands(environment, input)
-		if err != nil {
-			logger.Error("fheAdd/Sub RequiredGas() ciphertext inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return 0
-		}
-		if lhs.fheUintType() != rhs.fheUintType() {
-			logger.Error("fheAdd/Sub RequiredGas() operand type mismatch", "lhs", lhs.fheUintType(), "rhs", rhs.fheUintType())
-			return 0
-		}
-	} else {
-		lhs, _, err = getScalarOperands(environment, input)
-		if err != nil {
-			logger.Error("fheAdd/Sub RequiredGas() scalar inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return 0
-		}
-	}
-
-	return environment.FhevmParams().GasCosts.FheAddSub[lhs.fheUintType()]
+	return operationPrecompileRequiredGas(environment, "fheAdd/Sub", binaryOperation(OpAdd), input)
 }
 
 func fheMulRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	input = input[:minInt(65, len(input))]
-
-	logger := environment.GetLogger()
-	isScalar, err := isScalarOp(input)
-	if err != nil {
-		logger.Error("fheMul RequiredGas() can not detect if operator is meant to be scalar", "err", err, "input", hex.EncodeToString(input))
-		return 0
-	}
-	var lhs, rhs *verifiedCiphertext
-	if !isScalar {
-		lhs, rhs, err = get2VerifiedOperands(environment, input)
-		if err != nil {
-			logger.Error("fheMul RequiredGas() ciphertext inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return 0
-		}
-		if lhs.fheUintType() != rhs.fheUintType() {
-			logger.Error("fheMul RequiredGas() operand type mismatch", "lhs", lhs.fheUintType(), "rhs", rhs.fheUintType())
-			return 0
-		}
-		return environment.FhevmParams().GasCosts.FheMul[lhs.fheUintType()]
-	} else {
-		lhs, _, err = getScalarOperands(environment, input)
-		if err != nil {
-			logger.Error("fheMul RequiredGas() scalar inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return 0
-		}
-		return environment.FhevmParams().GasCosts.FheScalarMul[lhs.fheUintType()]
-	}
+	return operationPrecompileRequiredGas(environment, "fheMul", binaryOperation(OpMul), input)
 }
 
 func fheDivRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	input = input[:minInt(65, len(input))]
-
-	logger := environment.GetLogger()
-	isScalar, err := isScalarOp(input)
-	if err != nil {
-		logger.Error("fheDiv RequiredGas() cannot detect if operator is meant to be scalar", "err", err, "input", hex.EncodeToString(input))
-		return 0
-	}
-	var lhs *verifiedCiphertext
-	if !isScalar {
-		logger.Error("fheDiv RequiredGas() only scalar in division is supported, two ciphertexts received", "input", hex.EncodeToString(input))
-		return 0
-	} else {
-		lhs, _, err = getScalarOperands(environment, input)
-		if err != nil {
-			logger.Error("fheDiv RequiredGas() scalar inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return 0
-		}
-		return environment.FhevmParams().GasCosts.FheScalarDiv[lhs.fheUintType()]
-	}
+	return operationPrecompileRequiredGas(environment, "fheDiv", binaryOperation(OpDiv), input)
 }
 
 func fheRemRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	input = input[:minInt(65, len(input))]
-
-	logger := environment.GetLogger()
-	isScalar, err := isScalarOp(input)
-	if err != nil {
-		logger.Error("fheRem RequiredGas() cannot detect if operator is meant to be scalar", "err", err, "input", hex.EncodeToString(input))
-		return 0
-	}
-	var lhs *verifiedCiphertext
-	if !isScalar {
-		logger.Error("fheRem RequiredGas() only scalar in division is supported, two ciphertexts received", "input", hex.EncodeToString(input))
-		return 0
-	} else {
-		lhs, _, err = getScalarOperands(environment, input)
-		if err != nil {
-			logger.Error("fheRem RequiredGas() scalar inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return 0
-		}
-		return environment.FhevmParams().GasCosts.FheScalarRem[lhs.fheUintType()]
-	}
+	return operationPrecompileRequiredGas(environment, "fheRem", binaryOperation(OpRem), input)
 }
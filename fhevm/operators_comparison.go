@@ -1,572 +1,42 @@
 package fhevm
 
 import (
-	"encoding/hex"
-	"errors"
-
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
 	"go.opentelemetry.io/otel/trace"
 )
 
 func fheLeRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	input = input[:minInt(65, len(input))]
-
-	logger := environment.GetLogger()
-
-	isScalar, err := isScalarOp(input)
-	if err != nil {
-		logger.Error("fheLe can not detect if operator is meant to be scalar", "err", err, "input", hex.EncodeToString(input))
-		return nil, err
-	}
-
-	if !isScalar {
-		lhs, rhs, err := get2VerifiedOperands(environment, input)
-		otelDescribeOperands(runSpan, encryptedOperand(*lhs), encryptedOperand(*rhs))
-		if err != nil {
-			logger.Error("fheLe inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return nil, err
-		}
-		if lhs.fheUintType() != rhs.fheUintType() {
-			msg := "fheLe operand type mismatch"
-			logger.Error(msg, "lhs", lhs.fheUintType(), "rhs", rhs.fheUintType())
-			return nil, errors.New(msg)
-		}
-
-		// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
-		if !environment.IsCommitting() && !environment.IsEthCall() {
-			return importRandomCiphertext(environment, tfhe.FheBool), nil
-		}
-
-		result, err := lhs.ciphertext.Le(rhs.ciphertext)
-		if err != nil {
-			logger.Error("fheLe failed", "err", err)
-			return nil, err
-		}
-		importCiphertext(environment, result)
-
-		resultHash := result.GetHash()
-		logger.Info("fheLe success", "lhs", lhs.hash().Hex(), "rhs", rhs.hash().Hex(), "result", resultHash.Hex())
-		return resultHash[:], nil
-
-	} else {
-		lhs, rhs, err := getScalarOperands(environment, input)
-		otelDescribeOperands(runSpan, encryptedOperand(*lhs), plainOperand(*rhs))
-		if err != nil {
-			logger.Error("fheLe scalar inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return nil, err
-		}
-
-		// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
-		if !environment.IsCommitting() && !environment.IsEthCall() {
-			return importRandomCiphertext(environment, tfhe.FheBool), nil
-		}
-
-		result, err := lhs.ciphertext.ScalarLe(rhs)
-		if err != nil {
-			logger.Error("fheLe failed", "err", err)
-			return nil, err
-		}
-		importCiphertext(environment, result)
-
-		resultHash := result.GetHash()
-		logger.Info("fheLe scalar success", "lhs", lhs.hash().Hex(), "rhs", rhs.Uint64(), "result", resultHash.Hex())
-		return resultHash[:], nil
-	}
+	return runOperationPrecompile(environment, "fheLe", binaryOperation(OpLe), input, runSpan)
 }
 
 func fheLtRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	input = input[:minInt(65, len(input))]
-
-	logger := environment.GetLogger()
-
-	isScalar, err := isScalarOp(input)
-	if err != nil {
-		logger.Error("fheLt can not detect if operator is meant to be scalar", "err", err, "input", hex.EncodeToString(input))
-		return nil, err
-	}
-
-	if !isScalar {
-		lhs, rhs, err := get2VerifiedOperands(environment, input)
-		otelDescribeOperands(runSpan, encryptedOperand(*lhs), encryptedOperand(*rhs))
-		if err != nil {
-			logger.Error("fheLt inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return nil, err
-		}
-		if lhs.fheUintType() != rhs.fheUintType() {
-			msg := "fheLt operand type mismatch"
-			logger.Error(msg, "lhs", lhs.fheUintType(), "rhs", rhs.fheUintType())
-			return nil, errors.New(msg)
-		}
-
-		// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
-		if !environment.IsCommitting() && !environment.IsEthCall() {
-			return importRandomCiphertext(environment, tfhe.FheBool), nil
-		}
-
-		result, err := lhs.ciphertext.Lt(rhs.ciphertext)
-		if err != nil {
-			logger.Error("fheLt failed", "err", err)
-			return nil, err
-		}
-		importCiphertext(environment, result)
-
-		resultHash := result.GetHash()
-		logger.Info("fheLt success", "lhs", lhs.hash().Hex(), "rhs", rhs.hash().Hex(), "result", resultHash.Hex())
-		return resultHash[:], nil
-
-	} else {
-		lhs, rhs, err := getScalarOperands(environment, input)
-		otelDescribeOperands(runSpan, encryptedOperand(*lhs), plainOperand(*rhs))
-		if err != nil {
-			logger.Error("fheLt scalar inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return nil, err
-		}
-
-		// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
-		if !environment.IsCommitting() && !environment.IsEthCall() {
-			return importRandomCiphertext(environment, tfhe.FheBool), nil
-		}
-
-		result, err := lhs.ciphertext.ScalarLt(rhs)
-		if err != nil {
-			logger.Error("fheLt failed", "err", err)
-			return nil, err
-		}
-		importCiphertext(environment, result)
-
-		resultHash := result.GetHash()
-		logger.Info("fheLt scalar success", "lhs", lhs.hash().Hex(), "rhs", rhs.Uint64(), "result", resultHash.Hex())
-		return resultHash[:], nil
-	}
+	return runOperationPrecompile(environment, "fheLt", binaryOperation(OpLt), input, runSpan)
 }
 
 func fheEqRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	input = input[:minInt(65, len(input))]
-
-	logger := environment.GetLogger()
-
-	isScalar, err := isScalarOp(input)
-	if err != nil {
-		logger.Error("fheEq can not detect if operator is meant to be scalar", "err", err, "input", hex.EncodeToString(input))
-		return nil, err
-	}
-
-	if !isScalar {
-		lhs, rhs, err := get2VerifiedOperands(environment, input)
-		otelDescribeOperands(runSpan, encryptedOperand(*lhs), encryptedOperand(*rhs))
-		if err != nil {
-			logger.Error("fheEq inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return nil, err
-		}
-		if lhs.fheUintType() != rhs.fheUintType() {
-			msg := "fheEq operand type mismatch"
-			logger.Error(msg, "lhs", lhs.fheUintType(), "rhs", rhs.fheUintType())
-			return nil, errors.New(msg)
-		}
-
-		// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
-		if !environment.IsCommitting() && !environment.IsEthCall() {
-			return importRandomCiphertext(environment, tfhe.FheBool), nil
-		}
-
-		result, err := lhs.ciphertext.Eq(rhs.ciphertext)
-		if err != nil {
-			logger.Error("fheEq failed", "err", err)
-			return nil, err
-		}
-		importCiphertext(environment, result)
-
-		resultHash := result.GetHash()
-		logger.Info("fheEq success", "lhs", lhs.hash().Hex(), "rhs", rhs.hash().Hex(), "result", resultHash.Hex())
-		return resultHash[:], nil
-
-	} else {
-		lhs, rhs, err := getScalarOperands(environment, input)
-		otelDescribeOperands(runSpan, encryptedOperand(*lhs), plainOperand(*rhs))
-		if err != nil {
-			logger.Error("fheEq scalar inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return nil, err
-		}
-
-		// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
-		if !environment.IsCommitting() && !environment.IsEthCall() {
-			return importRandomCiphertext(environment, tfhe.FheBool), nil
-		}
-
-		result, err := lhs.ciphertext.ScalarEq(rhs)
-		if err != nil {
-			logger.Error("fheEq failed", "err", err)
-			return nil, err
-		}
-		importCiphertext(environment, result)
-
-		resultHash := result.GetHash()
-		logger.Info("fheEq scalar success", "lhs", lhs.hash().Hex(), "rhs", rhs.Uint64(), "result", resultHash.Hex())
-		return resultHash[:], nil
-	}
+	return runOperationPrecompile(environment, "fheEq", binaryOperation(OpEq), input, runSpan)
 }
 
 func fheGeRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	input = input[:minInt(65, len(input))]
-
-	logger := environment.GetLogger()
-
-	isScalar, err := isScalarOp(input)
-	if err != nil {
-		logger.Error("fheGe can not detect if operator is meant to be scalar", "err", err, "input", hex.EncodeToString(input))
-		return nil, err
-	}
-
-	if !isScalar {
-		lhs, rhs, err := get2VerifiedOperands(environment, input)
-		otelDescribeOperands(runSpan, encryptedOperand(*lhs), encryptedOperand(*rhs))
-		if err != nil {
-			logger.Error("fheGe inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return nil, err
-		}
-		if lhs.fheUintType() != rhs.fheUintType() {
-			msg := "fheGe operand type mismatch"
-			logger.Error(msg, "lhs", lhs.fheUintType(), "rhs", rhs.fheUintType())
-			return nil, errors.New(msg)
-		}
-
-		// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
-		if !environment.IsCommitting() && !environment.IsEthCall() {
-			return importRandomCiphertext(environment, tfhe.FheBool), nil
-		}
-
-		result, err := lhs.ciphertext.Ge(rhs.ciphertext)
-		if err != nil {
-			logger.Error("fheGe failed", "err", err)
-			return nil, err
-		}
-		importCiphertext(environment, result)
-
-		resultHash := result.GetHash()
-		logger.Info("fheGe success", "lhs", lhs.hash().Hex(), "rhs", rhs.hash().Hex(), "result", resultHash.Hex())
-		return resultHash[:], nil
-
-	} else {
-		lhs, rhs, err := getScalarOperands(environment, input)
-		otelDescribeOperands(runSpan, encryptedOperand(*lhs), plainOperand(*rhs))
-		if err != nil {
-			logger.Error("fheGe scalar inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return nil, err
-		}
-
-		// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
-		if !environment.IsCommitting() && !environment.IsEthCall() {
-			return importRandomCiphertext(environment, tfhe.FheBool), nil
-		}
-
-		result, err := lhs.ciphertext.ScalarGe(rhs)
-		if err != nil {
-			logger.Error("fheGe failed", "err", err)
-			return nil, err
-		}
-		importCiphertext(environment, result)
-
-		resultHash := result.GetHash()
-		logger.Info("fheGe scalar success", "lhs", lhs.hash().Hex(), "rhs", rhs.Uint64(), "result", resultHash.Hex())
-		return resultHash[:], nil
-	}
+	return runOperationPrecompile(environment, "fheGe", binaryOperation(OpGe), input, runSpan)
 }
 
 func fheGtRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	input = input[:minInt(65, len(input))]
-
-	logger := environment.GetLogger()
-
-	isScalar, err := isScalarOp(input)
-	if err != nil {
-		logger.Error("fheGt can not detect if operator is meant to be scalar", "err", err, "input", hex.EncodeToString(input))
-		return nil, err
-	}
-
-	if !isScalar {
-		lhs, rhs, err := get2VerifiedOperands(environment, input)
-		otelDescribeOperands(runSpan, encryptedOperand(*lhs), encryptedOperand(*rhs))
-		if err != nil {
-			logger.Error("fheGt inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return nil, err
-		}
-		if lhs.fheUintType() != rhs.fheUintType() {
-			msg := "fheGt operand type mismatch"
-			logger.Error(msg, "lhs", lhs.fheUintType(), "rhs", rhs.fheUintType())
-			return nil, errors.New(msg)
-		}
-
-		// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
-		if !environment.IsCommitting() && !environment.IsEthCall() {
-			return importRandomCiphertext(environment, tfhe.FheBool), nil
-		}
-
-		result, err := lhs.ciphertext.Gt(rhs.ciphertext)
-		if err != nil {
-			logger.Error("fheGt failed", "err", err)
-			return nil, err
-		}
-		importCiphertext(environment, result)
-
-		resultHash := result.GetHash()
-		logger.Info("fheGt success", "lhs", lhs.hash().Hex(), "rhs", rhs.hash().Hex(), "result", resultHash.Hex())
-		return resultHash[:], nil
-
-	} else {
-		lhs, rhs, err := getScalarOperands(environment, input)
-		otelDescribeOperands(runSpan, encryptedOperand(*lhs), plainOperand(*rhs))
-		if err != nil {
-			logger.Error("fheGt scalar inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return nil, err
-		}
-
-		// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
-		if !environment.IsCommitting() && !environment.IsEthCall() {
-			return importRandomCiphertext(environment, tfhe.FheBool), nil
-		}
-
-		result, err := lhs.ciphertext.ScalarGt(rhs)
-		if err != nil {
-			logger.Error("fheGt failed", "err", err)
-			return nil, err
-		}
-		importCiphertext(environment, result)
-
-		resultHash := result.GetHash()
-		logger.Info("fheGt scalar success", "lhs", lhs.hash().Hex(), "rhs", rhs.Uint64(), "result", resultHash.Hex())
-		return resultHash[:], nil
-	}
+	return runOperationPrecompile(environment, "fheGt", binaryOperation(OpGt), input, runSpan)
 }
 
 func fheNeRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	input = input[:minInt(65, len(input))]
-
-	logger := environment.GetLogger()
-
-	isScalar, err := isScalarOp(input)
-	if err != nil {
-		logger.Error("fheNe can not detect if operator is meant to be scalar", "err", err, "input", hex.EncodeToString(input))
-		return nil, err
-	}
-
-	if !isScalar {
-		lhs, rhs, err := get2VerifiedOperands(environment, input)
-		otelDescribeOperands(runSpan, encryptedOperand(*lhs), encryptedOperand(*rhs))
-		if err != nil {
-			logger.Error("fheNe inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return nil, err
-		}
-		if lhs.fheUintType() != rhs.fheUintType() {
-			msg := "fheNe operand type mismatch"
-			logger.Error(msg, "lhs", lhs.fheUintType(), "rhs", rhs.fheUintType())
-			return nil, errors.New(msg)
-		}
-
-		// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
-		if !environment.IsCommitting() && !environment.IsEthCall() {
-			return importRandomCiphertext(environment, tfhe.FheBool), nil
-		}
-
-		result, err := lhs.ciphertext.Ne(rhs.ciphertext)
-		if err != nil {
-			logger.Error("fheNe failed", "err", err)
-			return nil, err
-		}
-		importCiphertext(environment, result)
-
-		resultHash := result.GetHash()
-		logger.Info("fheNe success", "lhs", lhs.hash().Hex(), "rhs", rhs.hash().Hex(), "result", resultHash.Hex())
-		return resultHash[:], nil
-
-	} else {
-		lhs, rhs, err := getScalarOperands(environment, input)
-		otelDescribeOperands(runSpan, encryptedOperand(*lhs), plainOperand(*rhs))
-		if err != nil {
-			logger.Error("fheNe scalar inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return nil, err
-		}
-
-		// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
-		if !environment.IsCommitting() && !environment.IsEthCall() {
-			return importRandomCiphertext(environment, tfhe.FheBool), nil
-		}
-
-		result, err := lhs.ciphertext.ScalarNe(rhs)
-		if err != nil {
-			logger.Error("fheNe failed", "err", err)
-			return nil, err
-		}
-		importCiphertext(environment, result)
-
-		resultHash := result.GetHash()
-		logger.Info("fheNe scalar success", "lhs", lhs.hash().Hex(), "rhs", rhs.Uint64(), "result", resultHash.Hex())
-		return resultHash[:], nil
-	}
+	return runOperationPrecompile(environment, "fheNe", binaryOperation(OpNe), input, runSpan)
 }
 
 func fheMinRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	input = input[:minInt(65, len(input))]
-
-	logger := environment.GetLogger()
-
-	isScalar, err := isScalarOp(input)
-	if err != nil {
-		logger.Error("fheMin can not detect if operator is meant to be scalar", "err", err, "input", hex.EncodeToString(input))
-		return nil, err
-	}
-
-	if !isScalar {
-		lhs, rhs, err := get2VerifiedOperands(environment, input)
-		otelDescribeOperands(runSpan, encryptedOperand(*lhs), encryptedOperand(*rhs))
-		if err != nil {
-			logger.Error("fheMin inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return nil, err
-		}
-		if lhs.fheUintType() != rhs.fheUintType() {
-			msg := "fheMin operand type mismatch"
-			logger.Error(msg, "lhs", lhs.fheUintType(), "rhs", rhs.fheUintType())
-			return nil, errors.New(msg)
-		}
-
-		// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
-		if !environment.IsCommitting() && !environment.IsEthCall() {
-			return importRandomCiphertext(environment, lhs.fheUintType()), nil
-		}
-
-		result, err := lhs.ciphertext.Min(rhs.ciphertext)
-		if err != nil {
-			logger.Error("fheMin failed", "err", err)
-			return nil, err
-		}
-		importCiphertext(environment, result)
-
-		resultHash := result.GetHash()
-		logger.Info("fheMin success", "lhs", lhs.hash().Hex(), "rhs", rhs.hash().Hex(), "result", resultHash.Hex())
-		return resultHash[:], nil
-
-	} else {
-		lhs, rhs, err := getScalarOperands(environment, input)
-		otelDescribeOperands(runSpan, encryptedOperand(*lhs), plainOperand(*rhs))
-		if err != nil {
-			logger.Error("fheMin scalar inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return nil, err
-		}
-
-		// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
-		if !environment.IsCommitting() && !environment.IsEthCall() {
-			return importRandomCiphertext(environment, lhs.fheUintType()), nil
-		}
-
-		result, err := lhs.ciphertext.ScalarMin(rhs)
-		if err != nil {
-			logger.Error("fheMin failed", "err", err)
-			return nil, err
-		}
-		importCiphertext(environment, result)
-
-		resultHash := result.GetHash()
-		logger.Info("fheMin scalar success", "lhs", lhs.hash().Hex(), "rhs", rhs.Uint64(), "result", resultHash.Hex())
-		return resultHash[:], nil
-	}
+	return runOperationPrecompile(environment, "fheMin", binaryOperation(OpMin), input, runSpan)
 }
 
 func fheMaxRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	input = input[:minInt(65, len(input))]
-
-	logger := environment.GetLogger()
-
-	isScalar, err := isScalarOp(input)
-	if err != nil {
-		logger.Error("fheMax can not detect if operator is meant to be scalar", "err", err, "input", hex.EncodeToString(input))
-		return nil, err
-	}
-
-	if !isScalar {
-		lhs, rhs, err := get2VerifiedOperands(environment, input)
-		otelDescribeOperands(runSpan, encryptedOperand(*lhs), encryptedOperand(*rhs))
-		if err != nil {
-			logger.Error("fheMax inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return nil, err
-		}
-		if lhs.fheUintType() != rhs.fheUintType() {
-			msg := "fheMax operand type mismatch"
-			logger.Error(msg, "lhs", lhs.fheUintType(), "rhs", rhs.fheUintType())
-			return nil, errors.New(msg)
-		}
-
-		// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
-		if !environment.IsCommitting() && !environment.IsEthCall() {
-			return importRandomCiphertext(environment, lhs.fheUintType()), nil
-		}
-
-		result, err := lhs.ciphertext.Max(rhs.ciphertext)
-		if err != nil {
-			logger.Error("fheMax failed", "err", err)
-			return nil, err
-		}
-		importCiphertext(environment, result)
-
-		resultHash := result.GetHash()
-		logger.Info("fheMax success", "lhs", lhs.hash().Hex(), "rhs", rhs.hash().Hex(), "result", resultHash.Hex())
-		return resultHash[:], nil
-
-	} else {
-		lhs, rhs, err := getScalarOperands(environment, input)
-		otelDescribeOperands(runSpan, encryptedOperand(*lhs), plainOperand(*rhs))
-		if err != nil {
-			logger.Error("fheMax scalar inputs not verified", "err", err, "input", hex.EncodeToString(input))
-			return nil, err
-		}
-
-		// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
-		if !environment.IsCommitting() && !environment.IsEthCall() {
-			return importRandomCiphertext(environment, lhs.fheUintType()), nil
-		}
-
-		result, err := lhs.ciphertext.ScalarMax(rhs)
-		if err != nil {
-			logger.Error("fheMax failed", "err", err)
-			return nil, err
-		}
-		importCiphertext(environment, result)
-
-		resultHash := result.GetHash()
-		logger.Info("fheMax scalar success", "lhs", lhs.hash().Hex(), "rhs", rhs.Uint64(), "result", resultHash.Hex())
-		return resultHash[:], nil
-	}
+	return runOperationPrecompile(environment, "fheMax", binaryOperation(OpMax), input, runSpan)
 }
 
 func fheIfThenElseRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	input = input[:minInt(96, len(input))]
-
-	logger := environment.GetLogger()
-	first, second, third, err := get3VerifiedOperands(environment, input)
-	otelDescribeOperands(runSpan, encryptedOperand(*first), encryptedOperand(*second), encryptedOperand(*third))
-	if err != nil {
-		logger.Error("fheIfThenElse inputs not verified", "err", err, "input", hex.EncodeToString(input))
-		return nil, err
-	}
-
-	if second.fheUintType() != third.fheUintType() {
-		msg := "fheIfThenElse operand type mismatch"
-		logger.Error(msg, "second", second.fheUintType(), "third", third.fheUintType())
-		return nil, errors.New(msg)
-	}
-
-	// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
-	if !environment.IsCommitting() && !environment.IsEthCall() {
-		return importRandomCiphertext(environment, second.fheUintType()), nil
-	}
-
-	result, err := first.ciphertext.IfThenElse(second.ciphertext, third.ciphertext)
-	if err != nil {
-		logger.Error("fheIfThenElse failed", "err", err)
-		return nil, err
-	}
-	importCiphertext(environment, result)
-
-	resultHash := result.GetHash()
-	logger.Info("fheIfThenElse success", "first", first.hash().Hex(), "second", second.hash().Hex(), "third", third.hash().Hex(), "result", resultHash.Hex())
-	return resultHash[:], nil
+	return runOperationPrecompile(environment, "fheIfThenElse", selectOperation, input, runSpan)
 }
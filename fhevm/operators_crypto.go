@@ -1,9 +1,7 @@
 package fhevm
 
 import (
-	"bytes"
 	"crypto/rand"
-	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"math/big"
@@ -14,24 +12,16 @@ import (
 	"golang.org/x/crypto/nacl/box"
 )
 
+var verifyCiphertextArgs = mustParseABITypes("(bytes)")
+
 func verifyCiphertextRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
 	logger := environment.GetLogger()
-	// first 32 bytes of the payload is offset, then 32 bytes are size of byte array
-	if len(input) <= 68 {
-		err := errors.New("verifyCiphertext(bytes) must contain at least 68 bytes for selector, byte offset and size")
-		logger.Error("fheLib precompile error", "err", err, "input", hex.EncodeToString(input))
+	args, err := decodeABIArgs(verifyCiphertextArgs, input)
+	if err != nil {
+		logger.Error("verifyCiphertext failed to decode input", "err", err, "input", hex.EncodeToString(input))
 		return nil, err
 	}
-	bytesPaddingSize := 32
-	bytesSizeSlotSize := 32
-	// read only last 4 bytes of padded number for byte array size
-	sizeStart := bytesPaddingSize + bytesSizeSlotSize - 4
-	sizeEnd := sizeStart + 4
-	bytesSize := binary.BigEndian.Uint32(input[sizeStart:sizeEnd])
-	bytesStart := bytesPaddingSize + bytesSizeSlotSize
-	bytesEnd := bytesStart + int(bytesSize)
-	input = input[bytesStart:minInt(bytesEnd, len(input))]
-
+	input = args.bytes(0)
 	if len(input) <= 1 {
 		msg := "verifyCiphertext Run() input needs to contain a ciphertext and one byte for its type"
 		logger.Error(msg, "len", len(input))
@@ -61,8 +51,7 @@ func verifyCiphertextRun(environment EVMEnvironment, caller common.Address, addr
 	}
 
 	ct := new(tfhe.TfheCiphertext)
-	err := ct.DeserializeCompact(ctBytes, ctType)
-	if err != nil {
+	if err := ct.DeserializeCompact(ctBytes, ctType); err != nil {
 		logger.Error("verifyCiphertext failed to deserialize input ciphertext",
 			"err", err,
 			"len", len(ctBytes),
@@ -72,7 +61,7 @@ func verifyCiphertextRun(environment EVMEnvironment, caller common.Address, addr
 
 	if tomlConfig.Fhevm.MockOpsFlag {
 		logger.Info("[Caution!!] MockOpsFlag is enabled, decrypting ciphertext. Please make sure you're not using it in production.")
-		plaintext, err := decryptValue(environment, ct)
+		plaintext, err := decryptValue(environment, common.Address{}, ct)
 		if err != nil {
 			logger.Error("verifyCiphertext failed to decrypt input ciphertext")
 			return nil, err
@@ -82,8 +71,8 @@ func verifyCiphertextRun(environment EVMEnvironment, caller common.Address, addr
 		ct = ct.TrivialEncrypt(*pt, ctType)
 	}
 
-	ctHash := ct.GetHash()
 	importCiphertext(environment, ct)
+	ctHash := ct.GetHash()
 	if environment.IsCommitting() {
 		logger.Info("verifyCiphertext success",
 			"ctHash", ctHash.Hex(),
@@ -109,182 +98,147 @@ func encryptToUserKey(value *big.Int, pubKey []byte) ([]byte, error) {
 	return ct, nil
 }
 
-func reencryptRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	input = input[:minInt(64, len(input))]
-	// precompileBytes, err := reencryptRun(environment, caller, addr, bwCompatBytes, readOnly)
+// ABI-encodes a re-encrypted value as dynamic bytes.
+func encodeReencryptedValue(reencryptedValue []byte) []byte {
+	reencryptedValue = toEVMBytes(reencryptedValue)
+	// pad according to abi specification, first add offset to the dynamic bytes argument
+	outputBytes := make([]byte, 32, len(reencryptedValue)+32)
+	outputBytes[31] = 0x20
+	outputBytes = append(outputBytes, reencryptedValue...)
+	return padArrayTo32Multiple(outputBytes)
+}
 
+func reencryptRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
 	logger := environment.GetLogger()
 	if !environment.IsEthCall() {
 		msg := "reencrypt only supported on EthCall"
 		logger.Error(msg)
 		return nil, errors.New(msg)
 	}
-	if len(input) != 64 {
-		msg := "reencrypt input len must be 64 bytes"
-		logger.Error(msg, "input", hex.EncodeToString(input), "len", len(input))
-		return nil, errors.New(msg)
+	ct, public, err := getDecryptableCiphertext(environment, args.word(0), false)
+	if err != nil {
+		logger.Error("reencrypt failed to load a publicly decryptable ciphertext", "err", err)
+		return nil, err
 	}
-	ct := getVerifiedCiphertext(environment, common.BytesToHash(input[0:32]))
 	if ct != nil {
-		otelDescribeOperandsFheTypes(runSpan, ct.fheUintType())
-
-		decryptedValue, err := ct.ciphertext.Decrypt()
-		if err != nil {
-			logger.Error("reencrypt decryption failed", "err", err)
+		otelDescribeOperandsFheTypes(runSpan, ct.Type())
+		if err := verifyUnlocked(environment, ct.GetHash()); err != nil {
 			return nil, err
 		}
-		pubKey := input[32:64]
-		reencryptedValue, err := encryptToUserKey(&decryptedValue, pubKey)
+
+		pubKey := args.word(1).Bytes()
+		var signer common.Address
+		if !public {
+			signer, err = verifyAuthorization(environment, caller, addr, ct.GetHash(), args.word(1), args.authorization)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		reencryptedValue, err := getDecryptionBackend(environment).Reencrypt(decryptionContext(environment), environment, ciphertextContract(caller, public), ct, pubKey)
 		if err != nil {
-			logger.Error("reencrypt failed to encrypt to user key", "err", err)
+			logger.Error("reencrypt failed", "err", err)
 			return nil, err
 		}
 
-		// TODO: decide if `res.Signature` should be verified here
-
-		logger.Info("reencrypt success", "input", hex.EncodeToString(input), "callerAddr", caller, "reencryptedValue", reencryptedValue, "len", len(reencryptedValue))
-		reencryptedValue = toEVMBytes(reencryptedValue)
-		// pad according to abi specification, first add offset to the dynamic bytes argument
-		outputBytes := make([]byte, 32, len(reencryptedValue)+32)
-		outputBytes[31] = 0x20
-		outputBytes = append(outputBytes, reencryptedValue...)
-		return padArrayTo32Multiple(outputBytes), nil
+		logger.Info("reencrypt success", "handle", args.word(0).Hex(), "callerAddr", caller, "signer", signer, "reencryptedValue", reencryptedValue, "len", len(reencryptedValue))
+		return encodeReencryptedValue(reencryptedValue), nil
 	}
 	msg := "reencrypt unverified ciphertext handle"
-	logger.Error(msg, "input", hex.EncodeToString(input))
+	logger.Error(msg, "handle", args.word(0).Hex())
 	return nil, errors.New(msg)
 }
 
-func decryptRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	input = input[:minInt(32, len(input))]
-
+func decryptRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
 	logger := environment.GetLogger()
-	// if not gas estimation and not view function fail if decryptions are disabled in transactions
-	if environment.IsCommitting() && !environment.IsEthCall() && environment.FhevmParams().DisableDecryptionsInTransaction {
-		msg := "decryptions during transaction are disabled"
-		logger.Error(msg, "input", hex.EncodeToString(input))
-		return nil, errors.New(msg)
-	}
-	if len(input) != 32 {
-		msg := "decrypt input len must be 32 bytes"
-		logger.Error(msg, "input", hex.EncodeToString(input), "len", len(input))
-		return nil, errors.New(msg)
-	}
-	ct := getVerifiedCiphertext(environment, common.BytesToHash(input))
-	if ct == nil {
-		msg := "decrypt unverified handle"
-		logger.Error(msg, "input", hex.EncodeToString(input))
-		return nil, errors.New(msg)
-	}
-	otelDescribeOperandsFheTypes(runSpan, ct.fheUintType())
-
-	// If we are doing gas estimation, skip decryption and make sure we return the maximum possible value.
-	// We need that, because non-zero bytes cost more than zero bytes in some contexts (e.g. SSTORE or memory operations).
-	if !environment.IsCommitting() && !environment.IsEthCall() {
-		return bytes.Repeat([]byte{0xFF}, 32), nil
+	handle := args.word(0)
+	if ct := getVerifiedCiphertext(environment, handle); ct != nil {
+		otelDescribeOperandsFheTypes(runSpan, ct.fheUintType())
 	}
 
-	plaintext, err := decryptValue(environment, ct.ciphertext)
+	// On eth_call, the plaintext is returned to whoever runs the call, so the user must authorize it,
+	// unless the handle is publicly decryptable.
+	plaintext, err := decryptHandle(environment, caller, handle, func(ct *tfhe.TfheCiphertext) error {
+		_, err := verifyAuthorization(environment, caller, addr, ct.GetHash(), common.Hash{}, args.authorization)
+		return err
+	})
 	if err != nil {
-		logger.Error("decrypt failed", "err", err)
+		logger.Error("decrypt failed", "err", err, "handle", handle.Hex())
 		return nil, err
 	}
-
-	logger.Info("decrypt success", "plaintext", plaintext)
+	if environment.IsCommitting() || environment.IsEthCall() {
+		logger.Info("decrypt success", "plaintext", plaintext)
+	}
 
 	// Always return a 32-byte big-endian integer.
 	ret := make([]byte, 32)
-	bigIntValue := big.NewInt(0)
-	bigIntValue.SetUint64(plaintext)
-	bigIntValue.FillBytes(ret)
+	plaintext.FillBytes(ret)
 	return ret, nil
 }
 
-func getCiphertextRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	input = input[:minInt(64, len(input))]
-
+func getCiphertextRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
 	logger := environment.GetLogger()
 	if !environment.IsEthCall() {
 		msg := "getCiphertext only supported on EthCall"
 		logger.Error(msg)
 		return nil, errors.New(msg)
 	}
-	if len(input) != 64 {
-		msg := "getCiphertext input len must be 64 bytes"
-		logger.Error(msg, "input", hex.EncodeToString(input), "len", len(input))
-		return nil, errors.New(msg)
-	}
 
-	contractAddress := common.BytesToAddress(input[:32])
-	handle := common.BytesToHash(input[32:])
+	contractAddress := args.address(0)
+	handle := args.word(1)
 
 	ciphertext := getCiphertextFromProtectedStoage(environment, contractAddress, handle)
 	if ciphertext == nil {
 		return make([]byte, 0), nil
 	}
+	if !canReadCiphertext(environment, caller, contractAddress, handle) {
+		msg := "getCiphertext caller is not allowed to read the ciphertext"
+		logger.Error(msg, "handle", handle.Hex(), "contract", contractAddress.Hex(), "caller", caller.Hex())
+		return nil, errors.New(msg)
+	}
 	otelDescribeOperandsFheTypes(runSpan, ciphertext.metadata.fheUintType)
+	if ciphertext.isComputedByCoprocessor() {
+		ct, err := awaitCoprocessorCiphertext(environment, handle)()
+		if err != nil {
+			msg := "getCiphertext failed to get the ciphertext from the coprocessor"
+			logger.Error(msg, "handle", handle.Hex(), "err", err)
+			return nil, errors.New(msg)
+		}
+		return ct.Serialize(), nil
+	}
 	return ciphertext.bytes, nil
 }
 
-func decryptValue(environment EVMEnvironment, ct *tfhe.TfheCiphertext) (uint64, error) {
-	v, err := ct.Decrypt()
-	return v.Uint64(), err
+// Returns true if the caller can read the ciphertext of a handle persisted by a contract with getCiphertext. Like
+// SLOAD, a ciphertext persisted in the protected storage of a contract is allowed to it, and the caller must be the
+// contract or be allowed to use the handle too. Ciphertexts of decryption requests, persisted by the decryption oracle for relayers, and publicly
+// decryptable ciphertexts, persisted by the ACL, can be read by anyone.
+func canReadCiphertext(environment EVMEnvironment, caller common.Address, contract common.Address, handle common.Hash) bool {
+	switch contract {
+	case decryptionOracleAddress:
+		return true
+	case aclContractAddress:
+		return isHandlePubliclyDecryptable(environment, handle)
+	}
+	return caller == contract || isHandleAllowed(environment, handle, caller)
 }
 
-func castRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	input = input[:minInt(33, len(input))]
-
-	logger := environment.GetLogger()
-	if len(input) != 33 {
-		msg := "cast Run() input needs to contain a ciphertext and one byte for its type"
-		logger.Error(msg, "len", len(input))
-		return nil, errors.New(msg)
-	}
-
-	ct := getVerifiedCiphertext(environment, common.BytesToHash(input[0:32]))
-	if ct == nil {
-		logger.Error("cast input not verified")
-		return nil, errors.New("unverified ciphertext handle")
-	}
-
-	if !tfhe.IsValidFheType(input[32]) {
-		logger.Error("invalid type to cast to")
-		return nil, errors.New("invalid type provided")
-	}
-	castToType := tfhe.FheUintType(input[32])
-
-	otelDescribeOperandsFheTypes(runSpan, ct.fheUintType(), castToType)
-
-	// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
-	if !environment.IsCommitting() && !environment.IsEthCall() {
-		return importRandomCiphertext(environment, castToType), nil
-	}
-
-	res, err := ct.ciphertext.CastTo(castToType)
+func decryptValue(environment EVMEnvironment, contract common.Address, ct *tfhe.TfheCiphertext) (uint64, error) {
+	v, err := getDecryptionBackend(environment).Decrypt(decryptionContext(environment), environment, contract, ct)
 	if err != nil {
-		msg := "cast Run() error casting ciphertext to"
-		logger.Error(msg, "type", castToType)
-		return nil, errors.New(msg)
-	}
-
-	resHash := res.GetHash()
-
-	importCiphertext(environment, res)
-	if environment.IsCommitting() {
-		logger.Info("cast success",
-			"ctHash", resHash.Hex(),
-		)
+		return 0, err
 	}
+	return v.Uint64(), nil
+}
 
-	return resHash.Bytes(), nil
+func castRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return runOperationPrecompile(environment, "cast", castOperation, input, runSpan)
 }
 
 var fhePubKeyHashPrecompile = common.BytesToAddress([]byte{93})
 var fhePubKeyHashSlot = common.Hash{}
 
-func fhePubKeyRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	input = input[:minInt(1, len(input))]
-
+func fhePubKeyRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
 	existing := environment.GetState(fhePubKeyHashPrecompile, fhePubKeyHashSlot)
 	if existing != tfhe.GetPksHash() {
 		msg := "fhePubKey FHE public key hash doesn't match one stored in state"
@@ -297,7 +251,7 @@ func fhePubKeyRun(environment EVMEnvironment, caller common.Address, addr common
 		return nil, err
 	}
 	// If we have a single byte with the value of 1, make as an EVM array.
-	if len(input) == 1 && input[0] == 1 {
+	if args.byte(0) == 1 {
 		pksBytes = toEVMBytes(pksBytes)
 	}
 	// pad according to abi specification, first add offset to the dynamic bytes argument
@@ -308,27 +262,5 @@ func fhePubKeyRun(environment EVMEnvironment, caller common.Address, addr common
 }
 
 func trivialEncryptRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	input = input[:minInt(33, len(input))]
-
-	logger := environment.GetLogger()
-	if len(input) != 33 {
-		msg := "trivialEncrypt input len must be 33 bytes"
-		logger.Error(msg, "input", hex.EncodeToString(input), "len", len(input))
-		return nil, errors.New(msg)
-	}
-
-	valueToEncrypt := *new(big.Int).SetBytes(input[0:32])
-	encryptToType := tfhe.FheUintType(input[32])
-	otelDescribeOperandsFheTypes(runSpan, encryptToType)
-
-	ct := new(tfhe.TfheCiphertext).TrivialEncrypt(valueToEncrypt, encryptToType)
-
-	ctHash := ct.GetHash()
-	importCiphertext(environment, ct)
-	if environment.IsCommitting() {
-		logger.Info("trivialEncrypt success",
-			"ctHash", ctHash.Hex(),
-			"valueToEncrypt", valueToEncrypt.Uint64())
-	}
-	return ctHash.Bytes(), nil
+	return runOperationPrecompile(environment, "trivialEncrypt", trivialEncryptOperation, input, runSpan)
 }
@@ -3,50 +3,33 @@ package fhevm
 import (
 	"encoding/hex"
 
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
 )
 
 func verifyCiphertextRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	if len(input) <= 1 {
+	args, err := decodeABIArgs(verifyCiphertextArgs, input)
+	if err != nil || len(args.bytes(0)) <= 1 {
 		environment.GetLogger().Error(
 			"verifyCiphertext RequiredGas() input needs to contain a ciphertext and one byte for its type",
-			"len", len(input))
+			"err", err, "input", hex.EncodeToString(input))
 		return 0
 	}
-	ctType := tfhe.FheUintType(input[len(input)-1])
+	ciphertext := args.bytes(0)
+	ctType := tfhe.FheUintType(ciphertext[len(ciphertext)-1])
 	return environment.FhevmParams().GasCosts.FheVerify[ctType]
 }
 
-func reencryptRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	input = input[:minInt(64, len(input))]
-
-	logger := environment.GetLogger()
-	if len(input) != 64 {
-		logger.Error("reencrypt RequiredGas() input len must be 64 bytes", "input", hex.EncodeToString(input), "len", len(input))
-		return 0
-	}
-	ct := getVerifiedCiphertext(environment, common.BytesToHash(input[0:32]))
+func reencryptRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	ct := getVerifiedCiphertext(environment, args.word(0))
 	if ct == nil {
-		logger.Error("reencrypt RequiredGas() input doesn't point to verified ciphertext", "input", hex.EncodeToString(input))
+		environment.GetLogger().Error("reencrypt RequiredGas() input doesn't point to verified ciphertext", "handle", args.word(0).Hex())
 		return 0
 	}
 	return environment.FhevmParams().GasCosts.FheReencrypt[ct.fheUintType()]
 }
 
-func getCiphertextRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	input = input[:minInt(64, len(input))]
-
-	logger := environment.GetLogger()
-	if len(input) != 64 {
-		logger.Error("getCiphertext RequiredGas() input len must be 64 bytes",
-			"input", hex.EncodeToString(input), "len", len(input))
-		return 0
-	}
-
-	contractAddress := common.BytesToAddress(input[:32])
-	handle := common.BytesToHash(input[32:])
-	metadata := getCiphertextMetadataFromProtectedStorage(environment, contractAddress, handle)
+func getCiphertextRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	metadata := getCiphertextMetadataFromProtectedStorage(environment, args.address(0), args.word(1))
 	if metadata == nil {
 		return GetNonExistentCiphertextGas
 	}
@@ -54,31 +37,16 @@ func getCiphertextRequiredGas(environment EVMEnvironment, suppliedGas uint64, in
 }
 
 func castRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	input = input[:minInt(33, len(input))]
-
-	if len(input) != 33 {
-		environment.GetLogger().Error(
-			"cast RequiredGas() input needs to contain a ciphertext and one byte for its type",
-			"len", len(input))
-		return 0
-	}
-	return environment.FhevmParams().GasCosts.FheCast
+	return operationPrecompileRequiredGas(environment, "cast", castOperation, input)
 }
 
-func decryptRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	input = input[:minInt(32, len(input))]
-
-	logger := environment.GetLogger()
-	if len(input) != 32 {
-		logger.Error("decrypt RequiredGas() input len must be 32 bytes", "input", hex.EncodeToString(input), "len", len(input))
+func decryptRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	gas, err := DecryptRequiredGas(environment, args.word(0))
+	if err != nil {
+		environment.GetLogger().Error("decrypt RequiredGas() input doesn't point to verified ciphertext", "err", err, "handle", args.word(0).Hex())
 		return 0
 	}
-	ct := getVerifiedCiphertext(environment, common.BytesToHash(input))
-	if ct == nil {
-		logger.Error("decrypt RequiredGas() input doesn't point to verified ciphertext", "input", hex.EncodeToString(input))
-		return 0
-	}
-	return environment.FhevmParams().GasCosts.FheDecrypt[ct.fheUintType()]
+	return gas
 }
 
 func fhePubKeyRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
@@ -86,13 +54,5 @@ func fhePubKeyRequiredGas(environment EVMEnvironment, suppliedGas uint64, input
 }
 
 func trivialEncryptRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	input = input[:minInt(33, len(input))]
-
-	logger := environment.GetLogger()
-	if len(input) != 33 {
-		logger.Error("trivialEncrypt RequiredGas() input len must be 33 bytes", "input", hex.EncodeToString(input), "len", len(input))
-		return 0
-	}
-	encryptToType := tfhe.FheUintType(input[32])
-	return environment.FhevmParams().GasCosts.FheTrivialEncrypt[encryptToType]
+	return operationPrecompileRequiredGas(environment, "trivialEncrypt", trivialEncryptOperation, input)
 }
@@ -74,6 +74,7 @@ func generateRandom(environment EVMEnvironment, caller common.Address, resultTyp
 	nextRngNonce := uint256.NewInt(0).SetBytes(currentRngNonceBytes)
 	nextRngNonce = nextRngNonce.AddUint64(nextRngNonce, 1)
 	environment.SetState(protectedStorage, rngNonceKey, nextRngNonce.Bytes32())
+	addHandleEntropy(environment, currentRngNonceBytes)
 
 	// Compute the seed and use it to create a new cipher.
 	hasher := crypto.NewKeccakState()
@@ -138,34 +139,36 @@ func generateRandom(environment EVMEnvironment, caller common.Address, resultTyp
 }
 
 func fheRandRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	input = input[:minInt(1, len(input))]
-
 	logger := environment.GetLogger()
 	if environment.IsEthCall() {
 		msg := "fheRand cannot be called via EthCall, because it needs to mutate internal state"
 		logger.Error(msg)
 		return nil, errors.New(msg)
 	}
-	if len(input) != 1 || !tfhe.IsValidFheType(input[0]) {
-		msg := "fheRand input len must be at least 1 byte and be a valid FheUint type"
-		logger.Error(msg, "input", hex.EncodeToString(input), "len", len(input))
+	if readOnly {
+		return nil, ErrWriteProtection
+	}
+	resultType, err := parseRandInput(input)
+	if err != nil {
+		msg := "fheRand input must be a valid FheUint type"
+		logger.Error(msg, "input", hex.EncodeToString(input), "err", err)
 		return nil, errors.New(msg)
 	}
-	resultType := tfhe.FheUintType(input[0])
 	otelDescribeOperandsFheTypes(runSpan, resultType)
 	var noUpperBound *uint64 = nil
 	return generateRandom(environment, caller, resultType, noUpperBound)
 }
 
 func fheRandBoundedRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	input = input[:minInt(33, len(input))]
-
 	logger := environment.GetLogger()
 	if environment.IsEthCall() {
 		msg := "fheRandBoundedRun cannot be called via EthCall, because it needs to mutate internal state"
 		logger.Error(msg)
 		return nil, errors.New(msg)
 	}
+	if readOnly {
+		return nil, ErrWriteProtection
+	}
 	randType, bound, err := parseRandUpperBoundInput(input)
 	otelDescribeOperandsFheTypes(runSpan, randType)
 	if err != nil {
@@ -9,25 +9,44 @@ import (
 	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
 )
 
-func fheRandRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	input = input[:minInt(1, len(input))]
+var (
+	randArgs        = mustParseABITypes("(bytes1)")
+	randBoundedArgs = mustParseABITypes("(uint256,bytes1)")
+)
 
-	logger := environment.GetLogger()
-	if len(input) != 1 || !tfhe.IsValidFheType(input[0]) {
-		logger.Error("fheRand RequiredGas() input len must be at least 1 byte and be a valid FheUint type", "input", hex.EncodeToString(input), "len", len(input))
+func fheRandRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
+	t, err := parseRandInput(input)
+	if err != nil {
+		environment.GetLogger().Error("fheRand RequiredGas() input must be a valid FheUint type", "input", hex.EncodeToString(input), "err", err)
 		return 0
 	}
-	t := tfhe.FheUintType(input[0])
 	return environment.FhevmParams().GasCosts.FheRand[t]
 }
 
+// Decodes the bytes1 randType input of fheRand.
+func parseRandInput(input []byte) (tfhe.FheUintType, error) {
+	args, err := decodeABIArgs(randArgs, input)
+	if err != nil {
+		return tfhe.FheUint8, err
+	}
+	if !tfhe.IsValidFheType(args.byte(0)) {
+		return tfhe.FheUint8, fmt.Errorf("invalid type %d", args.byte(0))
+	}
+	return tfhe.FheUintType(args.byte(0)), nil
+}
+
+// Decodes the (uint256 upperBound, bytes1 randType) input of fheRandBounded.
 func parseRandUpperBoundInput(input []byte) (randType tfhe.FheUintType, upperBound *uint256.Int, err error) {
-	if len(input) != 33 || !tfhe.IsValidFheType(input[32]) {
-		return tfhe.FheUint8, nil, fmt.Errorf("parseRandUpperBoundInput() invalid input len or type")
+	args, err := decodeABIArgs(randBoundedArgs, input)
+	if err != nil {
+		return tfhe.FheUint8, nil, err
+	}
+	if !tfhe.IsValidFheType(args.byte(1)) {
+		return tfhe.FheUint8, nil, fmt.Errorf("parseRandUpperBoundInput() invalid type")
 	}
-	randType = tfhe.FheUintType(input[32])
+	randType = tfhe.FheUintType(args.byte(1))
 	upperBound = uint256.NewInt(0)
-	upperBound.SetBytes32(input)
+	upperBound.SetBytes32(args.word(0).Bytes())
 	// For now, we only support bounds of up to 64 bits.
 	if !upperBound.IsUint64() {
 		return tfhe.FheUint8, nil, fmt.Errorf("parseRandUpperBoundInput() only supports bounds up to 64 bits")
@@ -41,8 +60,6 @@ func parseRandUpperBoundInput(input []byte) (randType tfhe.FheUintType, upperBou
 }
 
 func fheRandBoundedRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	input = input[:minInt(33, len(input))]
-
 	logger := environment.GetLogger()
 	randType, _, err := parseRandUpperBoundInput(input)
 	if err != nil {
@@ -0,0 +1,271 @@
+// Package ops is a typed Go API over the FheLib operations, for Go services that run them without crafting
+// ABI inputs, such as indexers, simulators and test harnesses. It shares the validation, gas and computation
+// of the FheLib precompiles.
+//
+// Operations run in the given environment at its current depth: operands must be verified at that depth and
+// results are imported at it, as with the precompiles. Unlike the precompiles, results aren't allowed to a
+// caller and Decrypt doesn't require an authorization on eth_call.
+//
+// Warning: Decrypt skips the EIP-712 authorization that the decrypt precompile requires on eth_call, so it
+// decrypts any verified handle. It is unsafe to expose to RPC users, e.g. from a custom RPC method: the
+// caller must check that the user may decrypt the handle.
+package ops
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zama-ai/fhevm-go/fhevm"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+// A Handle identifies a verified ciphertext.
+type Handle = common.Hash
+
+func run(env fhevm.EVMEnvironment, operator fhevm.Operator, operands ...fhevm.Operand) (Handle, error) {
+	return fhevm.RunOperation(env, fhevm.Operation{Operator: operator, Operands: operands})
+}
+
+func ct(handle Handle) fhevm.Operand {
+	return fhevm.Operand{Handle: handle}
+}
+
+func scalar(value *big.Int) fhevm.Operand {
+	return fhevm.Operand{Scalar: value}
+}
+
+// Add returns lhs + rhs.
+func Add(env fhevm.EVMEnvironment, lhs, rhs Handle) (Handle, error) {
+	return run(env, fhevm.OpAdd, ct(lhs), ct(rhs))
+}
+
+// ScalarAdd returns lhs + rhs, where rhs is a plaintext.
+func ScalarAdd(env fhevm.EVMEnvironment, lhs Handle, rhs *big.Int) (Handle, error) {
+	return run(env, fhevm.OpAdd, ct(lhs), scalar(rhs))
+}
+
+// Sub returns lhs - rhs.
+func Sub(env fhevm.EVMEnvironment, lhs, rhs Handle) (Handle, error) {
+	return run(env, fhevm.OpSub, ct(lhs), ct(rhs))
+}
+
+// ScalarSub returns lhs - rhs, where rhs is a plaintext.
+func ScalarSub(env fhevm.EVMEnvironment, lhs Handle, rhs *big.Int) (Handle, error) {
+	return run(env, fhevm.OpSub, ct(lhs), scalar(rhs))
+}
+
+// Mul returns lhs * rhs.
+func Mul(env fhevm.EVMEnvironment, lhs, rhs Handle) (Handle, error) {
+	return run(env, fhevm.OpMul, ct(lhs), ct(rhs))
+}
+
+// ScalarMul returns lhs * rhs, where rhs is a plaintext.
+func ScalarMul(env fhevm.EVMEnvironment, lhs Handle, rhs *big.Int) (Handle, error) {
+	return run(env, fhevm.OpMul, ct(lhs), scalar(rhs))
+}
+
+// Min returns the minimum of lhs and rhs.
+func Min(env fhevm.EVMEnvironment, lhs, rhs Handle) (Handle, error) {
+	return run(env, fhevm.OpMin, ct(lhs), ct(rhs))
+}
+
+// ScalarMin returns the minimum of lhs and rhs, where rhs is a plaintext.
+func ScalarMin(env fhevm.EVMEnvironment, lhs Handle, rhs *big.Int) (Handle, error) {
+	return run(env, fhevm.OpMin, ct(lhs), scalar(rhs))
+}
+
+// Max returns the maximum of lhs and rhs.
+func Max(env fhevm.EVMEnvironment, lhs, rhs Handle) (Handle, error) {
+	return run(env, fhevm.OpMax, ct(lhs), ct(rhs))
+}
+
+// ScalarMax returns the maximum of lhs and rhs, where rhs is a plaintext.
+func ScalarMax(env fhevm.EVMEnvironment, lhs Handle, rhs *big.Int) (Handle, error) {
+	return run(env, fhevm.OpMax, ct(lhs), scalar(rhs))
+}
+
+// Shl returns lhs shifted left by rhs.
+func Shl(env fhevm.EVMEnvironment, lhs, rhs Handle) (Handle, error) {
+	return run(env, fhevm.OpShl, ct(lhs), ct(rhs))
+}
+
+// ScalarShl returns lhs shifted left by rhs, where rhs is a plaintext.
+func ScalarShl(env fhevm.EVMEnvironment, lhs Handle, rhs *big.Int) (Handle, error) {
+	return run(env, fhevm.OpShl, ct(lhs), scalar(rhs))
+}
+
+// Shr returns lhs shifted right by rhs.
+func Shr(env fhevm.EVMEnvironment, lhs, rhs Handle) (Handle, error) {
+	return run(env, fhevm.OpShr, ct(lhs), ct(rhs))
+}
+
+// ScalarShr returns lhs shifted right by rhs, where rhs is a plaintext.
+func ScalarShr(env fhevm.EVMEnvironment, lhs Handle, rhs *big.Int) (Handle, error) {
+	return run(env, fhevm.OpShr, ct(lhs), scalar(rhs))
+}
+
+// Rotl returns lhs rotated left by rhs.
+func Rotl(env fhevm.EVMEnvironment, lhs, rhs Handle) (Handle, error) {
+	return run(env, fhevm.OpRotl, ct(lhs), ct(rhs))
+}
+
+// ScalarRotl returns lhs rotated left by rhs, where rhs is a plaintext.
+func ScalarRotl(env fhevm.EVMEnvironment, lhs Handle, rhs *big.Int) (Handle, error) {
+	return run(env, fhevm.OpRotl, ct(lhs), scalar(rhs))
+}
+
+// Rotr returns lhs rotated right by rhs.
+func Rotr(env fhevm.EVMEnvironment, lhs, rhs Handle) (Handle, error) {
+	return run(env, fhevm.OpRotr, ct(lhs), ct(rhs))
+}
+
+// ScalarRotr returns lhs rotated right by rhs, where rhs is a plaintext.
+func ScalarRotr(env fhevm.EVMEnvironment, lhs Handle, rhs *big.Int) (Handle, error) {
+	return run(env, fhevm.OpRotr, ct(lhs), scalar(rhs))
+}
+
+// Eq returns an FheBool of lhs == rhs.
+func Eq(env fhevm.EVMEnvironment, lhs, rhs Handle) (Handle, error) {
+	return run(env, fhevm.OpEq, ct(lhs), ct(rhs))
+}
+
+// ScalarEq returns an FheBool of lhs == rhs, where rhs is a plaintext.
+func ScalarEq(env fhevm.EVMEnvironment, lhs Handle, rhs *big.Int) (Handle, error) {
+	return run(env, fhevm.OpEq, ct(lhs), scalar(rhs))
+}
+
+// Ne returns an FheBool of lhs != rhs.
+func Ne(env fhevm.EVMEnvironment, lhs, rhs Handle) (Handle, error) {
+	return run(env, fhevm.OpNe, ct(lhs), ct(rhs))
+}
+
+// ScalarNe returns an FheBool of lhs != rhs, where rhs is a plaintext.
+func ScalarNe(env fhevm.EVMEnvironment, lhs Handle, rhs *big.Int) (Handle, error) {
+	return run(env, fhevm.OpNe, ct(lhs), scalar(rhs))
+}
+
+// Ge returns an FheBool of lhs >= rhs.
+func Ge(env fhevm.EVMEnvironment, lhs, rhs Handle) (Handle, error) {
+	return run(env, fhevm.OpGe, ct(lhs), ct(rhs))
+}
+
+// ScalarGe returns an FheBool of lhs >= rhs, where rhs is a plaintext.
+func ScalarGe(env fhevm.EVMEnvironment, lhs Handle, rhs *big.Int) (Handle, error) {
+	return run(env, fhevm.OpGe, ct(lhs), scalar(rhs))
+}
+
+// Gt returns an FheBool of lhs > rhs.
+func Gt(env fhevm.EVMEnvironment, lhs, rhs Handle) (Handle, error) {
+	return run(env, fhevm.OpGt, ct(lhs), ct(rhs))
+}
+
+// ScalarGt returns an FheBool of lhs > rhs, where rhs is a plaintext.
+func ScalarGt(env fhevm.EVMEnvironment, lhs Handle, rhs *big.Int) (Handle, error) {
+	return run(env, fhevm.OpGt, ct(lhs), scalar(rhs))
+}
+
+// Le returns an FheBool of lhs <= rhs.
+func Le(env fhevm.EVMEnvironment, lhs, rhs Handle) (Handle, error) {
+	return run(env, fhevm.OpLe, ct(lhs), ct(rhs))
+}
+
+// ScalarLe returns an FheBool of lhs <= rhs, where rhs is a plaintext.
+func ScalarLe(env fhevm.EVMEnvironment, lhs Handle, rhs *big.Int) (Handle, error) {
+	return run(env, fhevm.OpLe, ct(lhs), scalar(rhs))
+}
+
+// Lt returns an FheBool of lhs < rhs.
+func Lt(env fhevm.EVMEnvironment, lhs, rhs Handle) (Handle, error) {
+	return run(env, fhevm.OpLt, ct(lhs), ct(rhs))
+}
+
+// ScalarLt returns an FheBool of lhs < rhs, where rhs is a plaintext.
+func ScalarLt(env fhevm.EVMEnvironment, lhs Handle, rhs *big.Int) (Handle, error) {
+	return run(env, fhevm.OpLt, ct(lhs), scalar(rhs))
+}
+
+// BitTest returns an FheBool of the bit of lhs at index rhs.
+func BitTest(env fhevm.EVMEnvironment, lhs, rhs Handle) (Handle, error) {
+	return run(env, fhevm.OpBitTest, ct(lhs), ct(rhs))
+}
+
+// ScalarBitTest returns an FheBool of the bit of lhs at index rhs, where rhs is a plaintext.
+func ScalarBitTest(env fhevm.EVMEnvironment, lhs Handle, rhs *big.Int) (Handle, error) {
+	return run(env, fhevm.OpBitTest, ct(lhs), scalar(rhs))
+}
+
+// BitAnd returns lhs & rhs.
+func BitAnd(env fhevm.EVMEnvironment, lhs, rhs Handle) (Handle, error) {
+	return run(env, fhevm.OpBitAnd, ct(lhs), ct(rhs))
+}
+
+// BitOr returns lhs | rhs.
+func BitOr(env fhevm.EVMEnvironment, lhs, rhs Handle) (Handle, error) {
+	return run(env, fhevm.OpBitOr, ct(lhs), ct(rhs))
+}
+
+// BitXor returns lhs ^ rhs.
+func BitXor(env fhevm.EVMEnvironment, lhs, rhs Handle) (Handle, error) {
+	return run(env, fhevm.OpBitXor, ct(lhs), ct(rhs))
+}
+
+// Div returns lhs / rhs, where rhs is a non-zero plaintext.
+func Div(env fhevm.EVMEnvironment, lhs Handle, rhs *big.Int) (Handle, error) {
+	return run(env, fhevm.OpDiv, ct(lhs), scalar(rhs))
+}
+
+// Rem returns lhs % rhs, where rhs is a non-zero plaintext.
+func Rem(env fhevm.EVMEnvironment, lhs Handle, rhs *big.Int) (Handle, error) {
+	return run(env, fhevm.OpRem, ct(lhs), scalar(rhs))
+}
+
+// Neg returns -handle.
+func Neg(env fhevm.EVMEnvironment, handle Handle) (Handle, error) {
+	return run(env, fhevm.OpNeg, ct(handle))
+}
+
+// Not returns ^handle.
+func Not(env fhevm.EVMEnvironment, handle Handle) (Handle, error) {
+	return run(env, fhevm.OpNot, ct(handle))
+}
+
+// Popcount returns the number of set bits of handle.
+func Popcount(env fhevm.EVMEnvironment, handle Handle) (Handle, error) {
+	return run(env, fhevm.OpPopcount, ct(handle))
+}
+
+// Clz returns the number of leading zeros of handle.
+func Clz(env fhevm.EVMEnvironment, handle Handle) (Handle, error) {
+	return run(env, fhevm.OpClz, ct(handle))
+}
+
+// Ctz returns the number of trailing zeros of handle.
+func Ctz(env fhevm.EVMEnvironment, handle Handle) (Handle, error) {
+	return run(env, fhevm.OpCtz, ct(handle))
+}
+
+// Select returns ifTrue if the FheBool condition is true, and ifFalse otherwise.
+func Select(env fhevm.EVMEnvironment, condition, ifTrue, ifFalse Handle) (Handle, error) {
+	return run(env, fhevm.OpSelect, ct(condition), ct(ifTrue), ct(ifFalse))
+}
+
+// Cast converts a ciphertext to the given type.
+func Cast(env fhevm.EVMEnvironment, handle Handle, toType tfhe.FheUintType) (Handle, error) {
+	return fhevm.RunOperation(env, fhevm.Operation{Operator: fhevm.OpCast, Operands: []fhevm.Operand{ct(handle)}, Type: toType})
+}
+
+// TrivialEncrypt encrypts a plaintext to the given type, without hiding it.
+func TrivialEncrypt(env fhevm.EVMEnvironment, value *big.Int, toType tfhe.FheUintType) (Handle, error) {
+	return fhevm.RunOperation(env, fhevm.Operation{Operator: fhevm.OpTrivial, Operands: []fhevm.Operand{scalar(value)}, Type: toType})
+}
+
+// Decrypt returns the plaintext of a verified or publicly decryptable ciphertext. It doesn't check any
+// authorization, see the package documentation.
+func Decrypt(env fhevm.EVMEnvironment, handle Handle) (*big.Int, error) {
+	return fhevm.DecryptCiphertext(env, handle)
+}
+
+// RequiredGas returns the gas the FheLib precompile charges for an operation.
+func RequiredGas(env fhevm.EVMEnvironment, operation fhevm.Operation) (uint64, error) {
+	return fhevm.OperationRequiredGas(env, operation)
+}
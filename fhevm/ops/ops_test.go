@@ -0,0 +1,208 @@
+package ops
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/zama-ai/fhevm-go/fhevm"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+func TestMain(m *testing.M) {
+	if !tfhe.AllGlobalKeysPresent() {
+		tfhe.InitGlobalKeysWithNewKeys()
+	}
+	os.Exit(m.Run())
+}
+
+// An EVM environment with in-memory state, committing and at depth 1.
+type testEnvironment struct {
+	state     map[common.Address]map[common.Hash]common.Hash
+	fhevmData fhevm.FhevmData
+	params    fhevm.FhevmParams
+	ethCall   bool
+}
+
+func newTestEnvironment() *testEnvironment {
+	return &testEnvironment{
+		state:     make(map[common.Address]map[common.Hash]common.Hash),
+		fhevmData: fhevm.NewFhevmData(),
+		params:    fhevm.DefaultFhevmParams(),
+	}
+}
+
+func (e *testEnvironment) GetState(addr common.Address, key common.Hash) common.Hash {
+	return e.state[addr][key]
+}
+
+func (e *testEnvironment) SetState(addr common.Address, key common.Hash, value common.Hash) {
+	if e.state[addr] == nil {
+		e.state[addr] = make(map[common.Hash]common.Hash)
+	}
+	e.state[addr][key] = value
+}
+
+func (e *testEnvironment) GetNonce(common.Address) uint64      { return 0 }
+func (e *testEnvironment) AddBalance(common.Address, *big.Int) {}
+func (e *testEnvironment) GetBalance(common.Address) *big.Int  { return big.NewInt(0) }
+func (e *testEnvironment) Suicide(common.Address) bool         { return false }
+func (e *testEnvironment) GetDepth() int                       { return 1 }
+func (e *testEnvironment) GetLogger() fhevm.Logger             { return fhevm.NewDefaultLogger() }
+func (e *testEnvironment) IsCommitting() bool                  { return true }
+func (e *testEnvironment) IsEthCall() bool                     { return e.ethCall }
+func (e *testEnvironment) IsReadOnly() bool                    { return false }
+func (e *testEnvironment) FhevmData() *fhevm.FhevmData         { return &e.fhevmData }
+func (e *testEnvironment) FhevmParams() *fhevm.FhevmParams     { return &e.params }
+func (e *testEnvironment) GasLimit() uint64                    { return 0 }
+func (e *testEnvironment) BlockNumber() *big.Int               { return big.NewInt(0) }
+func (e *testEnvironment) ChainID() *big.Int                   { return big.NewInt(9000) }
+func (e *testEnvironment) BlockTimestamp() uint64              { return 0 }
+func (e *testEnvironment) OtelContext() context.Context        { return nil }
+func (e *testEnvironment) CreateContract(common.Address, []byte, uint64, *big.Int, common.Address) ([]byte, common.Address, uint64, error) {
+	return nil, common.Address{}, 0, nil
+}
+func (e *testEnvironment) CreateContract2(common.Address, []byte, common.Hash, uint64, *big.Int, common.Address) ([]byte, common.Address, uint64, error) {
+	return nil, common.Address{}, 0, nil
+}
+func (e *testEnvironment) Call(common.Address, common.Address, []byte, uint64, *big.Int) ([]byte, uint64, error) {
+	return nil, 0, nil
+}
+
+// Returns the ABI input of a FheLib method whose arguments are all 32-byte words.
+func precompileInput(signature string, words ...[]byte) []byte {
+	input := crypto.Keccak256([]byte(signature))[0:4]
+	for _, word := range words {
+		input = append(input, common.LeftPadBytes(word, 32)...)
+	}
+	return input
+}
+
+func typeWord(b byte) []byte {
+	return common.RightPadBytes([]byte{b}, 32)
+}
+
+func encrypt(t *testing.T, env fhevm.EVMEnvironment, value uint64, typ tfhe.FheUintType) Handle {
+	handle, err := TrivialEncrypt(env, new(big.Int).SetUint64(value), typ)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return handle
+}
+
+func decrypt(t *testing.T, env fhevm.EVMEnvironment, handle Handle) uint64 {
+	plaintext, err := Decrypt(env, handle)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return plaintext.Uint64()
+}
+
+func TestAdd(t *testing.T) {
+	env := newTestEnvironment()
+	lhs := encrypt(t, env, 200, tfhe.FheUint8)
+	rhs := encrypt(t, env, 100, tfhe.FheUint8)
+
+	sum, err := Add(env, lhs, rhs)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if result := decrypt(t, env, sum); result != 44 {
+		t.Fatalf("expected 200 + 100 to wrap to 44, got %d", result)
+	}
+	sum, err = ScalarAdd(env, lhs, big.NewInt(5))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if result := decrypt(t, env, sum); result != 205 {
+		t.Fatalf("expected 200 + 5 = 205, got %d", result)
+	}
+
+	if _, err := Add(env, lhs, common.HexToHash("0x1234")); err == nil {
+		t.Fatalf("expected an unverified operand to be rejected")
+	}
+	if _, err := Add(env, lhs, encrypt(t, env, 1, tfhe.FheUint16)); err == nil {
+		t.Fatalf("expected operands of different types to be rejected")
+	}
+}
+
+func TestCast(t *testing.T) {
+	env := newTestEnvironment()
+	handle := encrypt(t, env, 300, tfhe.FheUint16)
+
+	narrow, err := Cast(env, handle, tfhe.FheUint8)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if result := decrypt(t, env, narrow); result != 44 {
+		t.Fatalf("expected 300 to be truncated to 44, got %d", result)
+	}
+	wide, err := Cast(env, narrow, tfhe.FheUint32)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if result := decrypt(t, env, wide); result != 44 {
+		t.Fatalf("expected 44 to be kept, got %d", result)
+	}
+	if _, err := Cast(env, handle, tfhe.FheUintType(99)); err == nil {
+		t.Fatalf("expected an invalid type to be rejected")
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	env := newTestEnvironment()
+	env.ethCall = true
+	handle := encrypt(t, env, 42, tfhe.FheUint32)
+
+	// Unlike the decrypt precompile, Decrypt doesn't require an authorization on eth_call.
+	if result := decrypt(t, env, handle); result != 42 {
+		t.Fatalf("decrypt result not equal to value, result %v != value %v", result, 42)
+	}
+	caller := common.HexToAddress("0x1000")
+	if _, err := fhevm.FheLibRun(env, caller, caller, precompileInput("decrypt(uint256)", handle.Bytes()), true); err == nil {
+		t.Fatalf("expected the decrypt precompile to require an authorization")
+	}
+
+	if _, err := Decrypt(env, common.HexToHash("0x1234")); err == nil {
+		t.Fatalf("expected an unverified handle to be rejected")
+	}
+}
+
+func TestRequiredGas(t *testing.T) {
+	env := newTestEnvironment()
+	// Casts are free by default. Price them so that a zero gas means a failure.
+	env.params.GasCosts.FheCast = 1000
+	lhs := encrypt(t, env, 1, tfhe.FheUint32)
+	rhs := encrypt(t, env, 2, tfhe.FheUint32)
+
+	testcases := []struct {
+		description string
+		operation   fhevm.Operation
+		input       []byte
+	}{
+		{"add", fhevm.Operation{Operator: fhevm.OpAdd, Operands: []fhevm.Operand{{Handle: lhs}, {Handle: rhs}}},
+			precompileInput("fheAdd(uint256,uint256,bytes1)", lhs.Bytes(), rhs.Bytes(), typeWord(0))},
+		{"scalar add", fhevm.Operation{Operator: fhevm.OpAdd, Operands: []fhevm.Operand{{Handle: lhs}, {Scalar: big.NewInt(2)}}},
+			precompileInput("fheAdd(uint256,uint256,bytes1)", lhs.Bytes(), []byte{2}, typeWord(1))},
+		{"cast", fhevm.Operation{Operator: fhevm.OpCast, Operands: []fhevm.Operand{{Handle: lhs}}, Type: tfhe.FheUint8},
+			precompileInput("cast(uint256,bytes1)", lhs.Bytes(), typeWord(byte(tfhe.FheUint8)))},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			gas, err := RequiredGas(env, tc.operation)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			if expected := fhevm.FheLibRequiredGas(env, 0, tc.input); gas == 0 || gas != expected {
+				t.Fatalf("expected the gas of the precompile, %d, got %d", expected, gas)
+			}
+		})
+	}
+
+	if _, err := RequiredGas(env, fhevm.Operation{Operator: fhevm.OpAdd, Operands: []fhevm.Operand{{Handle: lhs}, {Handle: common.HexToHash("0x1234")}}}); err == nil {
+		t.Fatalf("expected an unverified operand to be rejected")
+	}
+}
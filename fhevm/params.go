@@ -1,6 +1,9 @@
 package fhevm
 
-import "github.com/zama-ai/fhevm-go/fhevm/tfhe"
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
 
 // This file contains default gas costs of fhEVM-related operations.
 // Users can change the values based on specific requirements in their blockchain.
@@ -10,6 +13,7 @@ import "github.com/zama-ai/fhevm-go/fhevm/tfhe"
 const EvmNetSstoreInitGas uint64 = 20000
 const AdjustFHEGas uint64 = 10000
 const ColdSloadCostEIP2929 uint64 = 2100
+const EvmTstoreGas uint64 = 100
 
 const GetNonExistentCiphertextGas uint64 = 1000
 
@@ -17,9 +21,12 @@ var (
 	// TODO: The values here are chosen somewhat arbitrarily (at least the 8 bit ones). Also, we don't
 	// take into account whether a ciphertext existed (either "current" or "original") for the given handle.
 	// Finally, costs are likely to change in the future.
-	FheUint8ProtectedStorageSstoreGas  uint64 = EvmNetSstoreInitGas + 2000
-	FheUint16ProtectedStorageSstoreGas uint64 = FheUint8ProtectedStorageSstoreGas * 2
-	FheUint32ProtectedStorageSstoreGas uint64 = FheUint16ProtectedStorageSstoreGas * 2
+	FheUint8ProtectedStorageSstoreGas   uint64 = EvmNetSstoreInitGas + 2000
+	FheUint16ProtectedStorageSstoreGas  uint64 = FheUint8ProtectedStorageSstoreGas * 2
+	FheUint32ProtectedStorageSstoreGas  uint64 = FheUint16ProtectedStorageSstoreGas * 2
+	FheUint64ProtectedStorageSstoreGas  uint64 = FheUint32ProtectedStorageSstoreGas * 2
+	FheUint128ProtectedStorageSstoreGas uint64 = FheUint64ProtectedStorageSstoreGas * 2
+	FheUint160ProtectedStorageSstoreGas uint64 = FheUint128ProtectedStorageSstoreGas + FheUint32ProtectedStorageSstoreGas
 
 	// TODO: We don't take whether the slot is cold or warm into consideration.
 	FheUint8ProtectedStorageSloadGas  uint64 = ColdSloadCostEIP2929 + 200
@@ -37,6 +44,16 @@ func DefaultFhevmParams() FhevmParams {
 type FhevmParams struct {
 	GasCosts                        GasCosts
 	DisableDecryptionsInTransaction bool
+	// Addresses of the KMS keys trusted to sign the results of asynchronous decryption requests.
+	DecryptionOracleSigners []common.Address
+	// Backend of decrypt and reencrypt. The local client key is used if nil.
+	DecryptionBackend DecryptionBackend
+	// Derive the handles of results from the operation and its inputs, instead of hashing the result ciphertexts.
+	SymbolicHandles bool
+	// Record FHE operations in transactions and compute them asynchronously. Implies symbolic handles.
+	Coprocessor *Coprocessor
+	// Compute the FHE operations of a transaction in parallel, as futures. Requires SymbolicHandles.
+	Scheduler *OperationScheduler
 }
 
 type GasCosts struct {
@@ -58,16 +75,25 @@ type GasCosts struct {
 	FheScalarMinMax   map[tfhe.FheUintType]uint64
 	FheNot            map[tfhe.FheUintType]uint64
 	FheNeg            map[tfhe.FheUintType]uint64
+	FheBitCount       map[tfhe.FheUintType]uint64
+	FheBitTest        map[tfhe.FheUintType]uint64
+	FheScalarBitTest  map[tfhe.FheUintType]uint64
 	FheReencrypt      map[tfhe.FheUintType]uint64
 	FheTrivialEncrypt map[tfhe.FheUintType]uint64
 	FheRand           map[tfhe.FheUintType]uint64
 	FheIfThenElse     map[tfhe.FheUintType]uint64
 	FheVerify         map[tfhe.FheUintType]uint64
 	FheGetCiphertext  map[tfhe.FheUintType]uint64
+	// Dispatch overhead included in the cost of each operation, charged once per batch.
+	FheBatchDispatch uint64
 
 	// TEE Operations
 	TeeAddSub     map[tfhe.FheUintType]uint64
 	TeeMul        map[tfhe.FheUintType]uint64
+	TeeMulHi      map[tfhe.FheUintType]uint64
+	TeeMulDiv     map[tfhe.FheUintType]uint64
+	TeePow        map[tfhe.FheUintType]uint64
+	TeeSqrt       map[tfhe.FheUintType]uint64
 	TeeDiv        map[tfhe.FheUintType]uint64
 	TeeRem        map[tfhe.FheUintType]uint64
 	TeeEncrypt    map[tfhe.FheUintType]uint64
@@ -77,7 +103,36 @@ type GasCosts struct {
 	TeeNot        map[tfhe.FheUintType]uint64
 	TeeNeg        map[tfhe.FheUintType]uint64
 	TeeBitwiseOp  map[tfhe.FheUintType]uint64
+	TeeBitCount   map[tfhe.FheUintType]uint64
+	TeeBitTest    map[tfhe.FheUintType]uint64
 	TeeCast       uint64
+	// Dispatch overhead included in the cost of each operation, charged once per batch.
+	TeeBatchDispatch uint64
+
+	// ACL
+	AclAllow          uint64
+	AclAllowTransient uint64
+	AclIsAllowed      uint64
+
+	// Public decryption
+	MakePubliclyDecryptable map[tfhe.FheUintType]uint64
+	IsPubliclyDecryptable   uint64
+
+	// Time locks
+	SetUnlockHeight uint64
+	GetUnlockHeight uint64
+
+	// Viewing keys
+	RegisterViewingKey uint64
+	RevokeViewingKey   uint64
+	GetViewingKey      uint64
+
+	// Decryption oracle
+	DecryptionRequest          uint64
+	DecryptionRequestPerHandle map[tfhe.FheUintType]uint64
+	DecryptionFulfill          uint64
+	// Gas forwarded to the callback of the requesting contract. The callback doesn't refund what it leaves.
+	DecryptionCallback uint64
 }
 
 func DefaultGasCosts() GasCosts {
@@ -189,6 +244,28 @@ func DefaultGasCosts() GasCosts {
 			tfhe.FheUint32: 150000 + AdjustFHEGas,
 			tfhe.FheUint64: 189000 + AdjustFHEGas,
 		},
+		FheBitCount: map[tfhe.FheUintType]uint64{
+			tfhe.FheUint4:  95000 + AdjustFHEGas,
+			tfhe.FheUint8:  130000 + AdjustFHEGas,
+			tfhe.FheUint16: 170000 + AdjustFHEGas,
+			tfhe.FheUint32: 215000 + AdjustFHEGas,
+			tfhe.FheUint64: 270000 + AdjustFHEGas,
+		},
+		// Bit test is a shift, a scalar bitand and a scalar comparison.
+		FheBitTest: map[tfhe.FheUintType]uint64{
+			tfhe.FheUint4:  150000 + AdjustFHEGas,
+			tfhe.FheUint8:  170000 + AdjustFHEGas,
+			tfhe.FheUint16: 190000 + AdjustFHEGas,
+			tfhe.FheUint32: 248000 + AdjustFHEGas,
+			tfhe.FheUint64: 296000 + AdjustFHEGas,
+		},
+		FheScalarBitTest: map[tfhe.FheUintType]uint64{
+			tfhe.FheUint4:  69000 + AdjustFHEGas,
+			tfhe.FheUint8:  72000 + AdjustFHEGas,
+			tfhe.FheUint16: 73000 + AdjustFHEGas,
+			tfhe.FheUint32: 100000 + AdjustFHEGas,
+			tfhe.FheUint64: 107000 + AdjustFHEGas,
+		},
 		// TODO: Costs will depend on the complexity of doing reencryption/decryption by the oracle.
 		FheReencrypt: map[tfhe.FheUintType]uint64{
 			tfhe.FheBool:   1000,
@@ -235,21 +312,60 @@ func DefaultGasCosts() GasCosts {
 			tfhe.FheUint32: 18000,
 			tfhe.FheUint64: 28000,
 		},
+		FheBatchDispatch: AdjustFHEGas,
 
 		// TEE
 		TeeAddSub: map[tfhe.FheUintType]uint64{
-			tfhe.FheUint4:  55,
-			tfhe.FheUint8:  84,
-			tfhe.FheUint16: 123,
-			tfhe.FheUint32: 152,
-			tfhe.FheUint64: 178,
+			tfhe.FheUint4:   55,
+			tfhe.FheUint8:   84,
+			tfhe.FheUint16:  123,
+			tfhe.FheUint32:  152,
+			tfhe.FheUint64:  178,
+			tfhe.FheDecimal: 250,
 		},
 		TeeMul: map[tfhe.FheUintType]uint64{
-			tfhe.FheUint4:  140,
-			tfhe.FheUint8:  187,
-			tfhe.FheUint16: 252,
-			tfhe.FheUint32: 349,
-			tfhe.FheUint64: 631,
+			tfhe.FheUint4:   140,
+			tfhe.FheUint8:   187,
+			tfhe.FheUint16:  252,
+			tfhe.FheUint32:  349,
+			tfhe.FheUint64:  631,
+			tfhe.FheDecimal: 1400,
+		},
+		TeeMulHi: map[tfhe.FheUintType]uint64{
+			tfhe.FheUint4:   150,
+			tfhe.FheUint8:   200,
+			tfhe.FheUint16:  270,
+			tfhe.FheUint32:  375,
+			tfhe.FheUint64:  680,
+			tfhe.FheUint128: 1250,
+		},
+		// MulDiv is a double width multiplication followed by a division.
+		TeeMulDiv: map[tfhe.FheUintType]uint64{
+			tfhe.FheUint4:   420,
+			tfhe.FheUint8:   650,
+			tfhe.FheUint16:  880,
+			tfhe.FheUint32:  1170,
+			tfhe.FheUint64:  1780,
+			tfhe.FheUint128: 3300,
+		},
+		// Pow is priced as one multiplication per bit of the exponent.
+		TeePow: map[tfhe.FheUintType]uint64{
+			tfhe.FheUint4:   560,
+			tfhe.FheUint8:   1496,
+			tfhe.FheUint16:  4032,
+			tfhe.FheUint32:  11168,
+			tfhe.FheUint64:  40384,
+			tfhe.FheUint128: 160000,
+		},
+		TeeSqrt: map[tfhe.FheUintType]uint64{
+			tfhe.FheBool:    20,
+			tfhe.FheUint4:   60,
+			tfhe.FheUint8:   100,
+			tfhe.FheUint16:  150,
+			tfhe.FheUint32:  250,
+			tfhe.FheUint64:  450,
+			tfhe.FheUint128: 850,
+			tfhe.FheUint160: 1050,
 		},
 		TeeEncrypt: map[tfhe.FheUintType]uint64{
 			tfhe.FheBool:   10,
@@ -260,18 +376,20 @@ func DefaultGasCosts() GasCosts {
 			tfhe.FheUint64: 60,
 		},
 		TeeDecrypt: map[tfhe.FheUintType]uint64{
-			tfhe.FheUint4:  50,
-			tfhe.FheUint8:  50,
-			tfhe.FheUint16: 50,
-			tfhe.FheUint32: 50,
-			tfhe.FheUint64: 50,
+			tfhe.FheUint4:   50,
+			tfhe.FheUint8:   50,
+			tfhe.FheUint16:  50,
+			tfhe.FheUint32:  50,
+			tfhe.FheUint64:  50,
+			tfhe.FheDecimal: 50,
 		},
 		TeeDiv: map[tfhe.FheUintType]uint64{
-			tfhe.FheUint4:  276,
-			tfhe.FheUint8:  450,
-			tfhe.FheUint16: 612,
-			tfhe.FheUint32: 795,
-			tfhe.FheUint64: 1095,
+			tfhe.FheUint4:   276,
+			tfhe.FheUint8:   450,
+			tfhe.FheUint16:  612,
+			tfhe.FheUint32:  795,
+			tfhe.FheUint64:  1095,
+			tfhe.FheDecimal: 2300,
 		},
 		TeeRem: map[tfhe.FheUintType]uint64{
 			tfhe.FheUint4:  129,
@@ -281,11 +399,12 @@ func DefaultGasCosts() GasCosts {
 			tfhe.FheUint64: 574,
 		},
 		TeeComparison: map[tfhe.FheUintType]uint64{
-			tfhe.FheUint4:  60,
-			tfhe.FheUint8:  72,
-			tfhe.FheUint16: 95,
-			tfhe.FheUint32: 118,
-			tfhe.FheUint64: 146,
+			tfhe.FheUint4:   60,
+			tfhe.FheUint8:   72,
+			tfhe.FheUint16:  95,
+			tfhe.FheUint32:  118,
+			tfhe.FheUint64:  146,
+			tfhe.FheDecimal: 200,
 		},
 		TeeShift: map[tfhe.FheUintType]uint64{
 			tfhe.FheUint4:  106,
@@ -308,6 +427,68 @@ func DefaultGasCosts() GasCosts {
 			tfhe.FheUint32: 150,
 			tfhe.FheUint64: 189,
 		},
+		TeeBitCount: map[tfhe.FheUintType]uint64{
+			tfhe.FheBool:    20,
+			tfhe.FheUint4:   20,
+			tfhe.FheUint8:   24,
+			tfhe.FheUint16:  25,
+			tfhe.FheUint32:  26,
+			tfhe.FheUint64:  27,
+			tfhe.FheUint128: 30,
+			tfhe.FheUint160: 32,
+		},
+		TeeBatchDispatch: 10,
+		TeeBitTest: map[tfhe.FheUintType]uint64{
+			tfhe.FheBool:    20,
+			tfhe.FheUint4:   20,
+			tfhe.FheUint8:   24,
+			tfhe.FheUint16:  25,
+			tfhe.FheUint32:  26,
+			tfhe.FheUint64:  27,
+			tfhe.FheUint128: 30,
+			tfhe.FheUint160: 32,
+		},
+
+		// ACL
+		AclAllow:          EvmNetSstoreInitGas,
+		AclAllowTransient: EvmTstoreGas,
+		AclIsAllowed:      ColdSloadCostEIP2929,
+
+		// Public decryption
+		// The ciphertext is persisted in the ACL's protected storage.
+		MakePubliclyDecryptable: protectedStorageSstoreGas(EvmNetSstoreInitGas),
+		IsPubliclyDecryptable:   ColdSloadCostEIP2929,
+
+		// Time locks
+		SetUnlockHeight: EvmNetSstoreInitGas,
+		GetUnlockHeight: ColdSloadCostEIP2929,
+
+		// Viewing keys
+		RegisterViewingKey: EvmNetSstoreInitGas,
+		RevokeViewingKey:   EvmNetSstoreInitGas,
+		GetViewingKey:      ColdSloadCostEIP2929,
+
+		// Decryption oracle
+		DecryptionRequest: EvmNetSstoreInitGas * 4,
+		// The handle and its type are recorded, and its ciphertext is persisted in the oracle's protected storage.
+		DecryptionRequestPerHandle: protectedStorageSstoreGas(2 * EvmNetSstoreInitGas),
+		DecryptionFulfill:          EvmNetSstoreInitGas,
+		DecryptionCallback:         500000,
+	}
+}
+
+// Returns the gas of persisting a ciphertext of each type to protected storage, plus a fixed overhead.
+func protectedStorageSstoreGas(overhead uint64) map[tfhe.FheUintType]uint64 {
+	return map[tfhe.FheUintType]uint64{
+		tfhe.FheBool:    overhead + FheUint8ProtectedStorageSstoreGas,
+		tfhe.FheUint4:   overhead + FheUint8ProtectedStorageSstoreGas,
+		tfhe.FheUint8:   overhead + FheUint8ProtectedStorageSstoreGas,
+		tfhe.FheUint16:  overhead + FheUint16ProtectedStorageSstoreGas,
+		tfhe.FheUint32:  overhead + FheUint32ProtectedStorageSstoreGas,
+		tfhe.FheUint64:  overhead + FheUint64ProtectedStorageSstoreGas,
+		tfhe.FheUint128: overhead + FheUint128ProtectedStorageSstoreGas,
+		tfhe.FheUint160: overhead + FheUint160ProtectedStorageSstoreGas,
+		tfhe.FheDecimal: overhead + FheUint128ProtectedStorageSstoreGas,
 	}
 }
 
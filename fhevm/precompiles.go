@@ -10,6 +10,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/naoina/toml"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type tomlConfigOptions struct {
@@ -43,7 +44,8 @@ func init() {
 	}
 }
 
-func FheLibRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
+func FheLibRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) (gas uint64) {
+	defer recoverGasPanic(environment, fheLibRegistry, input, suppliedGas, &gas)
 	logger := environment.GetLogger()
 	if len(input) < 4 {
 		err := errors.New("input must contain at least 4 bytes for method signature")
@@ -61,46 +63,85 @@ func FheLibRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []b
 	}
 	// we remove function signature
 	input = input[4:]
-	return fheLibMethod.RequiredGas(environment, suppliedGas, input)
+	args, err := fheLibMethod.decodeInput(input)
+	if err != nil {
+		logger.Error("fheLib precompile input is malformed", "method", fheLibMethod.name, "err", err, "input", hex.EncodeToString(input))
+		return 0
+	}
+	// operands granted with allowTransient are priced like the ones verified at the current depth
+	defer importTransientlyAllowedHandlesForGas(environment, input)()
+	// gas only depends on the types of the operands, don't compute pending ones
+	return fheLibMethod.requiredGas(&symbolicEnvironment{environment}, suppliedGas, input, args)
 }
 
 func FheLibRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool) (ret []byte, err error) {
+	// a bug in a method must not crash the node
+	defer recoverRunPanic(environment, fheLibRegistry, input, &ret, &err)
 	logger := environment.GetLogger()
 	if len(input) < 4 {
-		err := errors.New("input must contain at least 4 bytes for method signature")
-		logger.Error("fheLib precompile error", "err", err, "input", hex.EncodeToString(input))
-		return nil, err
+		msg := "input must contain at least 4 bytes for method signature"
+		logger.Error("fheLib precompile error", "err", msg, "input", hex.EncodeToString(input))
+		return revertWithReason(msg)
 	}
 	// first 4 bytes are for the function signature
 	signature := binary.BigEndian.Uint32(input[0:4])
 
 	fheLibMethod, found := GetFheLibMethod(signature)
 	if !found {
-		err := errors.New("precompile method not found")
-		logger.Error("fheLib precompile error", "err", err, "input", hex.EncodeToString(input))
-		return nil, err
+		msg := "precompile method not found"
+		logger.Error("fheLib precompile error", "err", msg, "input", hex.EncodeToString(input))
+		return revertWithReason(msg)
 	}
 	// remove function signature
 	input = input[4:]
+	args, err := fheLibMethod.decodeInput(input)
+	if err != nil {
+		logger.Error("fheLib precompile input is malformed", "method", fheLibMethod.name, "err", err, "input", hex.EncodeToString(input))
+		return revertWithReason(fheLibMethod.name + ": " + err.Error())
+	}
+	// methods that write state fail in static calls, even if they don't check it themselves
+	if readOnly && fheLibMethod.writesState {
+		logger.Error("fheLib precompile method writes state in a static call", "method", fheLibMethod.name)
+		return nil, ErrWriteProtection
+	}
+	// make handles granted with allowTransient available to the caller
+	importTransientlyAllowedHandles(environment, caller, input)
+	// results get symbolic handles derived from this call, if they are enabled
+	defer setHandleContext(environment, signature, caller, input)()
+	// in coprocessor mode, operations are recorded and their results are computed asynchronously
+	// with a scheduler, operations return futures and are computed in parallel
+	run := func(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
+		return fheLibMethod.run(environment, caller, addr, input, args, readOnly, runSpan)
+	}
+	if shouldRecordComputation(environment, fheLibMethod) {
+		run = environment.FhevmParams().Coprocessor.recorder(fheLibMethod, signature)
+	} else if shouldScheduleOperation(environment, fheLibMethod) {
+		run = environment.FhevmParams().Scheduler.recorder(fheLibMethod, signature)
+	}
 	// trace function execution
 
 	if ctx := environment.OtelContext(); ctx != nil {
 		_, span := otel.Tracer("fhevm").Start(ctx, fheLibMethod.name)
-		ret, err = fheLibMethod.Run(environment, caller, addr, input, readOnly, span)
+		ret, err = run(environment, caller, addr, input, readOnly, span)
 		if err != nil {
 			span.RecordError(err)
 		}
 		span.End()
 	} else {
-		ret, err = fheLibMethod.Run(environment, caller, addr, input, readOnly, nil)
+		ret, err = run(environment, caller, addr, input, readOnly, nil)
 	}
 
+	// Results of operations are automatically allowed to their caller.
+	if err == nil {
+		allowResultToCaller(environment, caller, input, ret)
+	}
 	return
 }
 
 // TeeLibRequiredGas is the entry point for go-ethereum's
 // PrecompiledContract#RequiredGas method for the Tee precompiles.
-func TeeLibRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
+func TeeLibRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) (gas uint64) {
+	defer recoverGasPanic(environment, teeLibRegistry, input, suppliedGas, &gas)
 	logger := environment.GetLogger()
 	if len(input) < 4 {
 		err := errors.New("input must contain at least 4 bytes for method signature")
@@ -118,41 +159,69 @@ func TeeLibRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []b
 	}
 	// we remove function signature
 	input = input[4:]
-	return teeLibMethod.RequiredGas(environment, suppliedGas, input)
+	args, err := teeLibMethod.decodeInput(input)
+	if err != nil {
+		logger.Error("teeLib precompile input is malformed", "method", teeLibMethod.name, "err", err, "input", hex.EncodeToString(input))
+		return 0
+	}
+	// operands granted with allowTransient are priced like the ones verified at the current depth
+	defer importTransientlyAllowedHandlesForGas(environment, input)()
+	// gas only depends on the types of the operands, don't compute pending ones
+	return teeLibMethod.requiredGas(&symbolicEnvironment{environment}, suppliedGas, input, args)
 }
 
 // TeeLibRun is the entry point for go-ethereum's PrecompiledContract#Run
 // method for the Tee precompiles.
 func TeeLibRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool) (ret []byte, err error) {
+	// a bug in a method must not crash the node
+	defer recoverRunPanic(environment, teeLibRegistry, input, &ret, &err)
 	logger := environment.GetLogger()
 	if len(input) < 4 {
-		err := errors.New("input must contain at least 4 bytes for method signature")
-		logger.Error("teeLib precompile error", "err", err, "input", hex.EncodeToString(input))
-		return nil, err
+		msg := "input must contain at least 4 bytes for method signature"
+		logger.Error("teeLib precompile error", "err", msg, "input", hex.EncodeToString(input))
+		return revertWithReason(msg)
 	}
 	// first 4 bytes are for the function signature
 	signature := binary.BigEndian.Uint32(input[0:4])
 
 	teeLibMethod, found := GetTeeLibMethod(signature)
 	if !found {
-		err := errors.New("precompile method not found")
-		logger.Error("teeLib precompile error", "err", err, "input", hex.EncodeToString(input))
-		return nil, err
+		msg := "precompile method not found"
+		logger.Error("teeLib precompile error", "err", msg, "input", hex.EncodeToString(input))
+		return revertWithReason(msg)
 	}
 	// remove function signature
 	input = input[4:]
+	args, err := teeLibMethod.decodeInput(input)
+	if err != nil {
+		logger.Error("teeLib precompile input is malformed", "method", teeLibMethod.name, "err", err, "input", hex.EncodeToString(input))
+		return revertWithReason(teeLibMethod.name + ": " + err.Error())
+	}
+	// methods that write state fail in static calls, even if they don't check it themselves
+	if readOnly && teeLibMethod.writesState {
+		logger.Error("teeLib precompile method writes state in a static call", "method", teeLibMethod.name)
+		return nil, ErrWriteProtection
+	}
+	// make handles granted with allowTransient available to the caller
+	importTransientlyAllowedHandles(environment, caller, input)
+	// results get symbolic handles derived from this call, if they are enabled
+	defer setHandleContext(environment, signature, caller, input)()
 	// trace function execution
 
 	if ctx := environment.OtelContext(); ctx != nil {
 		_, span := otel.Tracer("fhevm").Start(ctx, teeLibMethod.name)
-		ret, err = teeLibMethod.Run(environment, caller, addr, input, readOnly, span)
+		ret, err = teeLibMethod.run(environment, caller, addr, input, args, readOnly, span)
 		if err != nil {
 			span.RecordError(err)
 		}
 		span.End()
 	} else {
-		ret, err = teeLibMethod.Run(environment, caller, addr, input, readOnly, nil)
+		ret, err = teeLibMethod.run(environment, caller, addr, input, args, readOnly, nil)
 	}
 
+	// Results of operations are automatically allowed to their caller.
+	if err == nil {
+		allowResultToCaller(environment, caller, input, ret)
+	}
 	return
 }
@@ -0,0 +1,636 @@
+package fhevm
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+	"github.com/zama-ai/fhevm-go/tee"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Programs run several operations in one precompile call.
+//
+// fheProgram and teeProgram take (bytes program, uint256[] inputs) and return the uint256[] handles of the
+// outputs, which are allowed to the caller. Intermediate results are never imported.
+//
+// A program starts with the number of inputs, followed by one kind byte per input: 0 for a ciphertext handle
+// and 1 for a plaintext scalar. Inputs are the first registers of the program. Each instruction is an opcode
+// followed by one byte per operand, and writes its result to the next register:
+//
+//	add, sub, mul, min, max, shl, shr, rotl, rotr  lhs rhs        rhs is a ciphertext or a scalar
+//	eq, ne, ge, gt, le, lt, bittest                lhs rhs        rhs is a ciphertext or a scalar
+//	div, rem                                       lhs rhs        rhs is a non-zero scalar
+//	and, or, xor                                   lhs rhs        rhs is a ciphertext
+//	neg, not, popcount, clz, ctz                   operand
+//	select                                         cond lhs rhs   cond is an FheBool
+//	cast                                           operand type   type is an immediate byte
+//	trivial                                        scalar type    encrypts a scalar to the type
+//	output                                         register       doesn't write a register
+//
+// Operands are register indexes, except the type of cast and trivial. Ciphertext operands of an instruction
+// have the same type. Comparisons and bittest return an FheBool. Every program has at least one output. For
+// example, with the inputs a, b, c and the scalar 0, select(a > b, a - b, 0) + c is:
+//
+//	04 00 00 00 01   gt 0 1 (r4), sub 0 1 (r5), trivial 3 type (r6), select 4 5 6 (r7), add 7 2 (r8), output 8
+const (
+	programAdd byte = iota + 1
+	programSub
+	programMul
+	programDiv
+	programRem
+	programMin
+	programMax
+	programAnd
+	programOr
+	programXor
+	programShl
+	programShr
+	programEq
+	programNe
+	programGe
+	programGt
+	programLe
+	programLt
+	programNeg
+	programNot
+	programSelect
+	programCast
+	programTrivial
+	programRotl
+	programRotr
+	programBitTest
+	programPopcount
+	programClz
+	programCtz
+	programOutput byte = 0xff
+)
+
+const (
+	programCiphertextInput byte = 0
+	programScalarInput     byte = 1
+)
+
+const maxProgramInstructions = 128
+const maxProgramOutputs = 16
+
+type programInstruction struct {
+	opcode   byte
+	operands []int
+	// The immediate type of cast and trivial.
+	castType tfhe.FheUintType
+}
+
+// The type of a register: a ciphertext type, or a scalar.
+type programRegister struct {
+	scalar      bool
+	fheUintType tfhe.FheUintType
+}
+
+type program struct {
+	instructions []programInstruction
+	inputs       []common.Hash
+	registers    []programRegister
+	outputs      []int
+}
+
+// Returns the handles of the inputs that aren't scalars.
+func (p *program) ciphertextInputs() []common.Hash {
+	handles := make([]common.Hash, 0, len(p.inputs))
+	for i, input := range p.inputs {
+		if !p.registers[i].scalar {
+			handles = append(handles, input)
+		}
+	}
+	return handles
+}
+
+func isProgramBinaryOp(opcode byte) bool {
+	return (opcode >= programAdd && opcode <= programLt) || (opcode >= programRotl && opcode <= programBitTest)
+}
+
+func isProgramUnaryOp(opcode byte) bool {
+	return opcode == programNeg || opcode == programNot || (opcode >= programPopcount && opcode <= programCtz)
+}
+
+// Returns true if the result of an opcode is an FheBool.
+func isProgramComparison(opcode byte) bool {
+	return (opcode >= programEq && opcode <= programLt) || opcode == programBitTest
+}
+
+// Returns the number of operands of an opcode, immediate included, or -1 if the opcode is unknown.
+func programOperandCount(opcode byte) int {
+	switch {
+	case isProgramBinaryOp(opcode):
+		return 2
+	case isProgramUnaryOp(opcode), opcode == programOutput:
+		return 1
+	case opcode == programSelect:
+		return 3
+	case opcode == programCast, opcode == programTrivial:
+		return 2
+	default:
+		return -1
+	}
+}
+
+// Decode a program and check that it is well-typed, given the verified inputs.
+func parseProgram(environment EVMEnvironment, code []byte, inputs []common.Hash, isTee bool) (*program, error) {
+	if len(code) == 0 {
+		return nil, errors.New("program is empty")
+	}
+	inputCount := int(code[0])
+	if len(code) < 1+inputCount {
+		return nil, errors.New("program is too short for its input kinds")
+	}
+	if inputCount != len(inputs) {
+		return nil, fmt.Errorf("program takes %d inputs, got %d", inputCount, len(inputs))
+	}
+	p := &program{inputs: inputs}
+	for i, kind := range code[1 : 1+inputCount] {
+		switch kind {
+		case programCiphertextInput:
+			ct := getVerifiedCiphertext(environment, inputs[i])
+			if ct == nil {
+				return nil, fmt.Errorf("program input %d is not a verified ciphertext handle", i)
+			}
+			p.registers = append(p.registers, programRegister{fheUintType: ct.fheUintType()})
+		case programScalarInput:
+			p.registers = append(p.registers, programRegister{scalar: true})
+		default:
+			return nil, fmt.Errorf("program input %d has an invalid kind %d", i, kind)
+		}
+	}
+
+	for pc := 1 + inputCount; pc < len(code); {
+		opcode := code[pc]
+		count := programOperandCount(opcode)
+		if count < 0 {
+			return nil, fmt.Errorf("invalid opcode %#02x at %d", opcode, pc)
+		}
+		if pc+1+count > len(code) {
+			return nil, fmt.Errorf("truncated instruction at %d", pc)
+		}
+		if len(p.instructions) == maxProgramInstructions {
+			return nil, fmt.Errorf("program has more than %d instructions", maxProgramInstructions)
+		}
+		instruction := programInstruction{opcode: opcode}
+		for _, operand := range code[pc+1 : pc+1+count] {
+			instruction.operands = append(instruction.operands, int(operand))
+		}
+		if opcode == programCast || opcode == programTrivial {
+			instruction.castType = tfhe.FheUintType(instruction.operands[1])
+			instruction.operands = instruction.operands[:1]
+		}
+		if err := p.check(instruction, isTee); err != nil {
+			return nil, fmt.Errorf("instruction at %d: %w", pc, err)
+		}
+		p.instructions = append(p.instructions, instruction)
+		pc += 1 + count
+	}
+	if len(p.outputs) == 0 {
+		return nil, errors.New("program has no output")
+	}
+	return p, nil
+}
+
+// Type-check an instruction and record the type of its result.
+func (p *program) check(instruction programInstruction, isTee bool) error {
+	operands := make([]programRegister, 0, len(instruction.operands))
+	for _, operand := range instruction.operands {
+		if operand >= len(p.registers) {
+			return fmt.Errorf("register %d isn't written yet", operand)
+		}
+		operands = append(operands, p.registers[operand])
+	}
+	// Only the scalar of trivial and the rhs of binary operations can be scalars.
+	for i, operand := range operands {
+		if !operand.scalar {
+			continue
+		}
+		if !(instruction.opcode == programTrivial || (isProgramBinaryOp(instruction.opcode) && i == 1)) {
+			return fmt.Errorf("operand %d must be a ciphertext", i)
+		}
+	}
+
+	result := programRegister{}
+	switch opcode := instruction.opcode; {
+	case isProgramBinaryOp(opcode):
+		lhs, rhs := operands[0], operands[1]
+		switch {
+		case rhs.scalar && (opcode == programAnd || opcode == programOr || opcode == programXor):
+			return errors.New("bitwise operations take two ciphertexts")
+		case !rhs.scalar && (opcode == programDiv || opcode == programRem):
+			return errors.New("division takes a scalar divisor")
+		case rhs.scalar && (opcode == programDiv || opcode == programRem) && p.scalar(instruction.operands[1]).Sign() == 0:
+			return errors.New("division by zero")
+		case !rhs.scalar && lhs.fheUintType != rhs.fheUintType:
+			return errors.New("operand type mismatch")
+		}
+		result.fheUintType = lhs.fheUintType
+		if isProgramComparison(opcode) {
+			result.fheUintType = tfhe.FheBool
+		}
+	case isProgramUnaryOp(opcode):
+		result.fheUintType = operands[0].fheUintType
+	case opcode == programSelect:
+		if operands[0].fheUintType != tfhe.FheBool {
+			return errors.New("select condition must be an FheBool")
+		}
+		if operands[1].fheUintType != operands[2].fheUintType {
+			return errors.New("operand type mismatch")
+		}
+		result.fheUintType = operands[1].fheUintType
+	case opcode == programCast, opcode == programTrivial:
+		if opcode == programTrivial && !operands[0].scalar {
+			return errors.New("trivial encryption takes a scalar")
+		}
+		if !isValidProgramType(byte(instruction.castType), isTee) {
+			return fmt.Errorf("invalid type %d", instruction.castType)
+		}
+		result.fheUintType = instruction.castType
+	case opcode == programOutput:
+		if len(p.outputs) == maxProgramOutputs {
+			return fmt.Errorf("program has more than %d outputs", maxProgramOutputs)
+		}
+		if instruction.operands[0] < len(p.inputs) {
+			return errors.New("inputs can't be outputs")
+		}
+		p.outputs = append(p.outputs, instruction.operands[0])
+		return nil
+	}
+	if !isValidProgramType(byte(result.fheUintType), isTee) {
+		return fmt.Errorf("unsupported type %d", result.fheUintType)
+	}
+	p.registers = append(p.registers, result)
+	return nil
+}
+
+func isValidProgramType(t byte, isTee bool) bool {
+	if isTee {
+		_, err := teeTypeBits(tfhe.FheUintType(t))
+		return err == nil
+	}
+	return tfhe.IsValidFheType(t)
+}
+
+// Returns the value of a scalar input register.
+func (p *program) scalar(register int) *big.Int {
+	return p.inputs[register].Big()
+}
+
+// A register value: a ciphertext with the FHE backend, a plaintext with the TEE backend, or a scalar input.
+type programValue struct {
+	ciphertext *tfhe.TfheCiphertext
+	plaintext  *big.Int
+}
+
+// A programBackend computes the instructions of a program. Inputs are loaded and outputs are stored all at once.
+type programBackend interface {
+	load(cts []*tfhe.TfheCiphertext) ([]programValue, error)
+	apply(instruction programInstruction, operands []programValue, registers []programRegister) (programValue, error)
+	store(values []programValue, types []tfhe.FheUintType) ([]*tfhe.TfheCiphertext, error)
+}
+
+// Run a program and return the ciphertexts of its outputs.
+func (p *program) run(environment EVMEnvironment, backend programBackend) ([]*tfhe.TfheCiphertext, error) {
+	cts := make([]*tfhe.TfheCiphertext, 0, len(p.inputs))
+	for i, input := range p.inputs {
+		if !p.registers[i].scalar {
+			cts = append(cts, getVerifiedCiphertext(environment, input).ciphertext)
+		}
+	}
+	loaded, err := backend.load(cts)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]programValue, 0, len(p.registers))
+	for i := range p.inputs {
+		if p.registers[i].scalar {
+			values = append(values, programValue{plaintext: p.scalar(i)})
+			continue
+		}
+		values = append(values, loaded[0])
+		loaded = loaded[1:]
+	}
+	for _, instruction := range p.instructions {
+		if instruction.opcode == programOutput {
+			continue
+		}
+		operands := make([]programValue, 0, len(instruction.operands))
+		registers := make([]programRegister, 0, len(instruction.operands))
+		for _, operand := range instruction.operands {
+			operands = append(operands, values[operand])
+			registers = append(registers, p.registers[operand])
+		}
+		value, err := backend.apply(instruction, operands, registers)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	outputs := make([]programValue, 0, len(p.outputs))
+	types := make([]tfhe.FheUintType, 0, len(p.outputs))
+	for _, output := range p.outputs {
+		outputs = append(outputs, values[output])
+		types = append(types, p.registers[output].fheUintType)
+	}
+	return backend.store(outputs, types)
+}
+
+type fheProgramBackend struct{}
+
+type fheProgramBinaryOp struct {
+	encrypted func(lhs *tfhe.TfheCiphertext, rhs *tfhe.TfheCiphertext) (*tfhe.TfheCiphertext, error)
+	scalar    func(lhs *tfhe.TfheCiphertext, rhs *big.Int) (*tfhe.TfheCiphertext, error)
+}
+
+var fheProgramBinaryOps = map[byte]fheProgramBinaryOp{
+	programAdd: {(*tfhe.TfheCiphertext).Add, (*tfhe.TfheCiphertext).ScalarAdd},
+	programSub: {(*tfhe.TfheCiphertext).Sub, (*tfhe.TfheCiphertext).ScalarSub},
+	programMul: {(*tfhe.TfheCiphertext).Mul, (*tfhe.TfheCiphertext).ScalarMul},
+	programDiv: {nil, (*tfhe.TfheCiphertext).ScalarDiv},
+	programRem: {nil, (*tfhe.TfheCiphertext).ScalarRem},
+	programMin: {(*tfhe.TfheCiphertext).Min, (*tfhe.TfheCiphertext).ScalarMin},
+	programMax: {(*tfhe.TfheCiphertext).Max, (*tfhe.TfheCiphertext).ScalarMax},
+	programAnd: {(*tfhe.TfheCiphertext).Bitand, nil},
+	programOr:  {(*tfhe.TfheCiphertext).Bitor, nil},
+	programXor: {(*tfhe.TfheCiphertext).Bitxor, nil},
+	programShl: {(*tfhe.TfheCiphertext).Shl, (*tfhe.TfheCiphertext).ScalarShl},
+	programShr: {(*tfhe.TfheCiphertext).Shr, (*tfhe.TfheCiphertext).ScalarShr},
+	programEq:  {(*tfhe.TfheCiphertext).Eq, (*tfhe.TfheCiphertext).ScalarEq},
+	programNe:  {(*tfhe.TfheCiphertext).Ne, (*tfhe.TfheCiphertext).ScalarNe},
+	programGe:  {(*tfhe.TfheCiphertext).Ge, (*tfhe.TfheCiphertext).ScalarGe},
+	programGt:  {(*tfhe.TfheCiphertext).Gt, (*tfhe.TfheCiphertext).ScalarGt},
+	programLe:  {(*tfhe.TfheCiphertext).Le, (*tfhe.TfheCiphertext).ScalarLe},
+	programLt:  {(*tfhe.TfheCiphertext).Lt, (*tfhe.TfheCiphertext).ScalarLt},
+
+	programRotl:    {(*tfhe.TfheCiphertext).Rotl, (*tfhe.TfheCiphertext).ScalarRotl},
+	programRotr:    {(*tfhe.TfheCiphertext).Rotr, (*tfhe.TfheCiphertext).ScalarRotr},
+	programBitTest: {(*tfhe.TfheCiphertext).BitTest, (*tfhe.TfheCiphertext).ScalarBitTest},
+}
+
+var fheProgramUnaryOps = map[byte]func(*tfhe.TfheCiphertext) (*tfhe.TfheCiphertext, error){
+	programNeg:      (*tfhe.TfheCiphertext).Neg,
+	programNot:      (*tfhe.TfheCiphertext).Not,
+	programPopcount: (*tfhe.TfheCiphertext).CountOnes,
+	programClz:      (*tfhe.TfheCiphertext).LeadingZeros,
+	programCtz:      (*tfhe.TfheCiphertext).TrailingZeros,
+}
+
+func (fheProgramBackend) load(cts []*tfhe.TfheCiphertext) ([]programValue, error) {
+	values := make([]programValue, 0, len(cts))
+	for _, ct := range cts {
+		values = append(values, programValue{ciphertext: ct})
+	}
+	return values, nil
+}
+
+func (fheProgramBackend) apply(instruction programInstruction, operands []programValue, registers []programRegister) (programValue, error) {
+	var result *tfhe.TfheCiphertext
+	var err error
+	switch opcode := instruction.opcode; {
+	case isProgramBinaryOp(opcode):
+		op := fheProgramBinaryOps[opcode]
+		if registers[1].scalar {
+			result, err = op.scalar(operands[0].ciphertext, operands[1].plaintext)
+		} else {
+			result, err = op.encrypted(operands[0].ciphertext, operands[1].ciphertext)
+		}
+	case isProgramUnaryOp(opcode):
+		result, err = fheProgramUnaryOps[opcode](operands[0].ciphertext)
+	case opcode == programSelect:
+		result, err = operands[0].ciphertext.IfThenElse(operands[1].ciphertext, operands[2].ciphertext)
+	case opcode == programCast:
+		result, err = operands[0].ciphertext.CastTo(instruction.castType)
+	case opcode == programTrivial:
+		result = new(tfhe.TfheCiphertext).TrivialEncrypt(*operands[0].plaintext, instruction.castType)
+	}
+	return programValue{ciphertext: result}, err
+}
+
+func (fheProgramBackend) store(values []programValue, types []tfhe.FheUintType) ([]*tfhe.TfheCiphertext, error) {
+	cts := make([]*tfhe.TfheCiphertext, 0, len(values))
+	for _, value := range values {
+		cts = append(cts, value.ciphertext)
+	}
+	return cts, nil
+}
+
+// The TEE backend decrypts the inputs, computes on plaintexts wrapped at the width of their type and only
+// encrypts the outputs. Inputs and outputs each take a single round trip to the TEE.
+type teeProgramBackend struct {
+	caller common.Address
+}
+
+func (teeProgramBackend) load(cts []*tfhe.TfheCiphertext) ([]programValue, error) {
+	plaintexts, err := tee.DecryptBatch(cts)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]programValue, 0, len(plaintexts))
+	for _, plaintext := range plaintexts {
+		values = append(values, programValue{plaintext: new(big.Int).SetBytes(plaintext.Value)})
+	}
+	return values, nil
+}
+
+func (teeProgramBackend) apply(instruction programInstruction, operands []programValue, registers []programRegister) (programValue, error) {
+	t := registers[0].fheUintType
+	if instruction.opcode == programSelect {
+		t = registers[1].fheUintType
+	}
+	bits, err := teeTypeBits(t)
+	if err != nil {
+		return programValue{}, err
+	}
+	values := make([]*big.Int, 0, len(operands))
+	for _, operand := range operands {
+		values = append(values, operand.plaintext)
+	}
+	boolValue := func(b bool) *big.Int {
+		return new(big.Int).SetUint64(boolToUint64(b))
+	}
+
+	var result *big.Int
+	switch instruction.opcode {
+	case programAdd:
+		result = new(big.Int).Add(values[0], values[1])
+	case programSub:
+		result = new(big.Int).Sub(values[0], values[1])
+	case programMul:
+		result = new(big.Int).Mul(values[0], values[1])
+	case programDiv:
+		result = new(big.Int).Quo(values[0], values[1])
+	case programRem:
+		result = new(big.Int).Rem(values[0], values[1])
+	case programMin, programMax:
+		result = values[0]
+		if (values[1].Cmp(values[0]) < 0) == (instruction.opcode == programMin) {
+			result = values[1]
+		}
+	case programAnd:
+		result = new(big.Int).And(values[0], values[1])
+	case programOr:
+		result = new(big.Int).Or(values[0], values[1])
+	case programXor:
+		result = new(big.Int).Xor(values[0], values[1])
+	case programShl, programShr, programRotl, programRotr, programBitTest:
+		// Shift amounts and bit indexes are taken modulo the width of the type.
+		shift := uint(new(big.Int).Mod(values[1], big.NewInt(int64(bits))).Uint64())
+		switch instruction.opcode {
+		case programShl:
+			result = new(big.Int).Lsh(values[0], shift)
+		case programShr:
+			result = new(big.Int).Rsh(values[0], shift)
+		case programRotl:
+			result = new(big.Int).Or(new(big.Int).Lsh(values[0], shift), new(big.Int).Rsh(values[0], bits-shift))
+		case programRotr:
+			result = new(big.Int).Or(new(big.Int).Rsh(values[0], shift), new(big.Int).Lsh(values[0], bits-shift))
+		case programBitTest:
+			return programValue{plaintext: big.NewInt(int64(values[0].Bit(int(shift))))}, nil
+		}
+	case programEq:
+		return programValue{plaintext: boolValue(values[0].Cmp(values[1]) == 0)}, nil
+	case programNe:
+		return programValue{plaintext: boolValue(values[0].Cmp(values[1]) != 0)}, nil
+	case programGe:
+		return programValue{plaintext: boolValue(values[0].Cmp(values[1]) >= 0)}, nil
+	case programGt:
+		return programValue{plaintext: boolValue(values[0].Cmp(values[1]) > 0)}, nil
+	case programLe:
+		return programValue{plaintext: boolValue(values[0].Cmp(values[1]) <= 0)}, nil
+	case programLt:
+		return programValue{plaintext: boolValue(values[0].Cmp(values[1]) < 0)}, nil
+	case programNeg:
+		result = new(big.Int).Neg(values[0])
+	case programNot:
+		result = new(big.Int).Not(values[0])
+	case programPopcount:
+		count := int64(0)
+		for i := 0; i < values[0].BitLen(); i++ {
+			count += int64(values[0].Bit(i))
+		}
+		result = big.NewInt(count)
+	case programClz:
+		result = big.NewInt(int64(bits) - int64(values[0].BitLen()))
+	case programCtz:
+		// The trailing zeros of zero are the full width of the type, like teeCtz.
+		result = big.NewInt(int64(bits))
+		if values[0].Sign() != 0 {
+			result = big.NewInt(int64(values[0].TrailingZeroBits()))
+		}
+	case programSelect:
+		result = values[2]
+		if values[0].Sign() != 0 {
+			result = values[1]
+		}
+	case programCast, programTrivial:
+		// Casts to FheBool treat any non-zero value as true, like teeCast.
+		if instruction.castType == tfhe.FheBool {
+			return programValue{plaintext: boolValue(values[0].Sign() != 0)}, nil
+		}
+		if bits, err = teeTypeBits(instruction.castType); err != nil {
+			return programValue{}, err
+		}
+		result = values[0]
+	}
+	// Wrap the result at the width of its type, as an overflowing unsigned integer would.
+	modulus := new(big.Int).Lsh(big.NewInt(1), bits)
+	return programValue{plaintext: result.Mod(result, modulus)}, nil
+}
+
+func (b teeProgramBackend) store(values []programValue, types []tfhe.FheUintType) ([]*tfhe.TfheCiphertext, error) {
+	plaintexts := make([]tee.TeePlaintext, 0, len(values))
+	for i, value := range values {
+		bz, err := marshalTfheType(value.plaintext, types[i])
+		if err != nil {
+			return nil, err
+		}
+		plaintexts = append(plaintexts, tee.NewTeePlaintext(bz, types[i], b.caller))
+	}
+	encrypted, err := tee.EncryptBatch(plaintexts)
+	if err != nil {
+		return nil, err
+	}
+	cts := make([]*tfhe.TfheCiphertext, 0, len(encrypted))
+	for i := range encrypted {
+		cts = append(cts, &encrypted[i])
+	}
+	return cts, nil
+}
+
+var programArgs = mustParseABITypes("(bytes,uint256[])")
+
+// Decodes the (bytes program, uint256[] inputs) input of fheProgram and teeProgram.
+func decodeProgramInput(input []byte) ([]byte, []common.Hash, error) {
+	args, err := decodeABIArgs(programArgs, input)
+	if err != nil {
+		return nil, nil, err
+	}
+	return args.bytes(0), args.words(1), nil
+}
+
+func fheProgramRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return runProgram(environment, caller, input, readOnly, runSpan, false)
+}
+
+func teeProgramRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return runProgram(environment, caller, input, readOnly, runSpan, true)
+}
+
+func runProgram(environment EVMEnvironment, caller common.Address, input []byte, readOnly bool, runSpan trace.Span, isTee bool) ([]byte, error) {
+	logger := environment.GetLogger()
+	code, inputs, err := decodeProgramInput(input)
+	if err != nil {
+		logger.Error("program failed to decode input", "err", err, "input", hex.EncodeToString(input))
+		return nil, err
+	}
+	p, err := parseProgram(environment, code, inputs, isTee)
+	if err != nil {
+		logger.Error("program is invalid", "err", err, "program", hex.EncodeToString(code))
+		return nil, err
+	}
+	types := make([]tfhe.FheUintType, 0, len(p.registers))
+	for _, register := range p.registers[:len(inputs)] {
+		if !register.scalar {
+			types = append(types, register.fheUintType)
+		}
+	}
+	otelDescribeOperandsFheTypes(runSpan, types...)
+
+	handles := make([]common.Hash, 0, len(p.outputs))
+	// If we are doing gas estimation, skip execution and insert random ciphertexts as outputs.
+	if !environment.IsCommitting() && !environment.IsEthCall() {
+		for _, output := range p.outputs {
+			handles = append(handles, common.BytesToHash(importRandomCiphertext(environment, p.registers[output].fheUintType)))
+		}
+	} else {
+		var backend programBackend = fheProgramBackend{}
+		if isTee {
+			backend = teeProgramBackend{caller: caller}
+		}
+		outputs, err := p.run(environment, backend)
+		if err != nil {
+			logger.Error("program failed", "err", err)
+			return nil, err
+		}
+		for _, ct := range outputs {
+			importCiphertext(environment, ct)
+			handles = append(handles, ct.GetHash())
+		}
+	}
+
+	// Outputs are allowed to the caller, like the result of a single operation. They inherit the time lock of
+	// every input, whether they depend on it or not.
+	for _, handle := range handles {
+		allowResult(environment, caller, handle, p.ciphertextInputs())
+	}
+	if environment.IsCommitting() {
+		logger.Info("program success", "instructions", len(p.instructions), "outputs", len(handles))
+	}
+	return encodeWordArray(handles), nil
+}
@@ -0,0 +1,133 @@
+package fhevm
+
+import (
+	"encoding/hex"
+
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+func fheProgramRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
+	return programRequiredGas(environment, input, false)
+}
+
+func teeProgramRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
+	return programRequiredGas(environment, input, true)
+}
+
+func programRequiredGas(environment EVMEnvironment, input []byte, isTee bool) uint64 {
+	logger := environment.GetLogger()
+	code, inputs, err := decodeProgramInput(input)
+	if err != nil {
+		logger.Error("program RequiredGas() failed to decode input", "err", err, "input", hex.EncodeToString(input))
+		return 0
+	}
+	p, err := parseProgram(environment, code, inputs, isTee)
+	if err != nil {
+		logger.Error("program RequiredGas() program is invalid", "err", err, "program", hex.EncodeToString(code))
+		return 0
+	}
+	return p.requiredGas(&environment.FhevmParams().GasCosts, isTee)
+}
+
+// Returns the gas of a program, the sum of the gas of its instructions.
+func (p *program) requiredGas(costs *GasCosts, isTee bool) uint64 {
+	gas := uint64(0)
+	for _, instruction := range p.instructions {
+		if instruction.opcode == programOutput {
+			continue
+		}
+		t := p.registers[instruction.operands[0]].fheUintType
+		scalar := len(instruction.operands) == 2 && p.registers[instruction.operands[1]].scalar
+		if instruction.opcode == programSelect {
+			t = p.registers[instruction.operands[1]].fheUintType
+		}
+		if isTee {
+			gas += teeProgramInstructionGas(costs, instruction, t)
+		} else {
+			gas += fheProgramInstructionGas(costs, instruction, t, scalar)
+		}
+	}
+	return gas
+}
+
+func fheProgramInstructionGas(costs *GasCosts, instruction programInstruction, t tfhe.FheUintType, scalar bool) uint64 {
+	switch instruction.opcode {
+	case programAdd, programSub:
+		return costs.FheAddSub[t]
+	case programMul:
+		if scalar {
+			return costs.FheScalarMul[t]
+		}
+		return costs.FheMul[t]
+	case programDiv:
+		return costs.FheScalarDiv[t]
+	case programRem:
+		return costs.FheScalarRem[t]
+	case programMin, programMax:
+		if scalar {
+			return costs.FheScalarMinMax[t]
+		}
+		return costs.FheMinMax[t]
+	case programAnd, programOr, programXor:
+		return costs.FheBitwiseOp[t]
+	case programShl, programShr, programRotl, programRotr:
+		if scalar {
+			return costs.FheScalarShift[t]
+		}
+		return costs.FheShift[t]
+	case programBitTest:
+		if scalar {
+			return costs.FheScalarBitTest[t]
+		}
+		return costs.FheBitTest[t]
+	case programPopcount, programClz, programCtz:
+		return costs.FheBitCount[t]
+	case programEq, programNe:
+		return costs.FheEq[t]
+	case programGe, programGt, programLe, programLt:
+		return costs.FheLe[t]
+	case programNeg:
+		return costs.FheNeg[t]
+	case programNot:
+		return costs.FheNot[t]
+	case programSelect:
+		return costs.FheIfThenElse[t]
+	case programCast:
+		return costs.FheCast
+	case programTrivial:
+		return costs.FheTrivialEncrypt[instruction.castType]
+	}
+	return 0
+}
+
+func teeProgramInstructionGas(costs *GasCosts, instruction programInstruction, t tfhe.FheUintType) uint64 {
+	switch instruction.opcode {
+	case programAdd, programSub:
+		return costs.TeeAddSub[t]
+	case programMul:
+		return costs.TeeMul[t]
+	case programDiv:
+		return costs.TeeDiv[t]
+	case programRem:
+		return costs.TeeRem[t]
+	case programMin, programMax, programEq, programNe, programGe, programGt, programLe, programLt, programSelect:
+		return costs.TeeComparison[t]
+	case programAnd, programOr, programXor:
+		return costs.TeeBitwiseOp[t]
+	case programShl, programShr, programRotl, programRotr:
+		return costs.TeeShift[t]
+	case programBitTest:
+		return costs.TeeBitTest[t]
+	case programPopcount, programClz, programCtz:
+		return costs.TeeBitCount[t]
+	case programNeg:
+		return costs.TeeNeg[t]
+	case programNot:
+		return costs.TeeNot[t]
+	case programCast:
+		return costs.TeeCast
+	case programTrivial:
+		return costs.TeeEncrypt[instruction.castType]
+	}
+	return 0
+}
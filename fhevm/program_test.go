@@ -0,0 +1,258 @@
+package fhevm
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+	"github.com/zama-ai/fhevm-go/tee"
+)
+
+func toProgramInput(method string, code []byte, inputs ...common.Hash) []byte {
+	input := crypto.Keccak256([]byte(method))[0:4]
+	input = append(input, uint256.NewInt(64).PaddedBytes(32)...)
+	input = append(input, uint256.NewInt(uint64(96+32*((len(code)+31)/32))).PaddedBytes(32)...)
+	input = append(input, uint256.NewInt(uint64(len(code))).PaddedBytes(32)...)
+	input = append(input, common.RightPadBytes(code, 32*((len(code)+31)/32))...)
+	input = append(input, uint256.NewInt(uint64(len(inputs))).PaddedBytes(32)...)
+	for _, handle := range inputs {
+		input = append(input, handle.Bytes()...)
+	}
+	return input
+}
+
+func decodeHandlesInTest(t *testing.T, out []byte) []common.Hash {
+	args, err := decodeABIArgs(mustParseABITypes("(uint256[])"), out)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return args.words(0)
+}
+
+// select(a > b, a - b, 0) + c over the inputs a, b, c and the scalar 0.
+func selectSubAddProgram(t tfhe.FheUintType) []byte {
+	return []byte{
+		4, programCiphertextInput, programCiphertextInput, programCiphertextInput, programScalarInput,
+		programGt, 0, 1,
+		programSub, 0, 1,
+		programTrivial, 3, byte(t),
+		programSelect, 4, 5, 6,
+		programAdd, 7, 2,
+		programOutput, 8,
+	}
+}
+
+func TestFheProgramRun(t *testing.T) {
+	testcases := []struct {
+		a, b, c  uint64
+		expected uint64
+	}{
+		{7, 3, 2, 6},
+		{3, 7, 2, 2},
+		{250, 10, 20, 4},
+	}
+	for _, tc := range testcases {
+		t.Run(fmt.Sprintf("select(%d > %d, %d - %d, 0) + %d", tc.a, tc.b, tc.a, tc.b, tc.c), func(t *testing.T) {
+			environment := newTestEVMEnvironment()
+			environment.depth = 1
+			contract := common.HexToAddress("0x1000")
+			var inputs []common.Hash
+			for _, value := range []uint64{tc.a, tc.b, tc.c} {
+				ct := new(tfhe.TfheCiphertext).TrivialEncrypt(*new(big.Int).SetUint64(value), tfhe.FheUint8)
+				inputs = append(inputs, verifyTfheCiphertextInTestMemory(environment, ct, environment.depth).GetHash())
+			}
+			inputs = append(inputs, common.Hash{})
+			imported := len(environment.FhevmData().verifiedCiphertexts)
+
+			input := toProgramInput("fheProgram(bytes,uint256[])", selectSubAddProgram(tfhe.FheUint8), inputs...)
+			costs := environment.FhevmParams().GasCosts
+			expectedGas := costs.FheLe[tfhe.FheUint8] + costs.FheAddSub[tfhe.FheUint8] + costs.FheTrivialEncrypt[tfhe.FheUint8] +
+				costs.FheIfThenElse[tfhe.FheUint8] + costs.FheAddSub[tfhe.FheUint8]
+			if gas := FheLibRequiredGas(environment, 0, input); gas != expectedGas {
+				t.Fatalf("incorrect gas, expected=%d, got=%d", expectedGas, gas)
+			}
+			out, err := FheLibRun(environment, contract, contract, input, false)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			outputs := decodeHandlesInTest(t, out)
+			if len(outputs) != 1 {
+				t.Fatalf("expected one output, got %d", len(outputs))
+			}
+			// The output may be the ciphertext of c.
+			if len(environment.FhevmData().verifiedCiphertexts) > imported+1 {
+				t.Fatalf("expected only the output to be imported")
+			}
+			if value := decryptInTest(t, environment, contract, outputs[0]); value != tc.expected {
+				t.Fatalf("decrypt result not equal to value, result %v != value %v", value, tc.expected)
+			}
+		})
+	}
+}
+
+func TestTeeProgramRun(t *testing.T) {
+	testcases := []struct {
+		typ      tfhe.FheUintType
+		a, b, c  uint64
+		expected uint64
+	}{
+		{tfhe.FheUint8, 7, 3, 2, 6},
+		{tfhe.FheUint8, 3, 7, 2, 2},
+		{tfhe.FheUint8, 250, 10, 20, 4},
+		{tfhe.FheUint32, 1333337, 4283, 1, 1329055},
+		{tfhe.FheUint64, 13333377777777777, 1, 1, 13333377777777777},
+	}
+	for _, tc := range testcases {
+		t.Run(fmt.Sprintf("teeProgram with %s", tc.typ), func(t *testing.T) {
+			environment := newTestEVMEnvironment()
+			environment.depth = 1
+			contract := common.HexToAddress("0x1000")
+			var inputs []common.Hash
+			for _, value := range []uint64{tc.a, tc.b, tc.c} {
+				ct, err := importTeePlaintextToEVM(environment, environment.depth, value, tc.typ)
+				if err != nil {
+					t.Fatalf(err.Error())
+				}
+				inputs = append(inputs, ct.GetHash())
+			}
+			inputs = append(inputs, common.Hash{})
+
+			input := toProgramInput("teeProgram(bytes,uint256[])", selectSubAddProgram(tc.typ), inputs...)
+			costs := environment.FhevmParams().GasCosts
+			expectedGas := 2*costs.TeeComparison[tc.typ] + 2*costs.TeeAddSub[tc.typ] + costs.TeeEncrypt[tc.typ]
+			if gas := TeeLibRequiredGas(environment, 0, input); gas != expectedGas {
+				t.Fatalf("incorrect gas, expected=%d, got=%d", expectedGas, gas)
+			}
+			out, err := TeeLibRun(environment, contract, contract, input, false)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			outputs := decodeHandlesInTest(t, out)
+			if len(outputs) != 1 {
+				t.Fatalf("expected one output, got %d", len(outputs))
+			}
+			res := getVerifiedCiphertextFromEVM(environment, outputs[0])
+			if res == nil {
+				t.Fatalf("output ciphertext is not found in verifiedCiphertexts")
+			}
+			plaintext, err := tee.Decrypt(res.ciphertext)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			if plaintext.FheUintType != tc.typ {
+				t.Fatalf("incorrect fheUintType, expected=%s, got=%s", tc.typ, plaintext.FheUintType)
+			}
+			if result := new(big.Int).SetBytes(plaintext.Value); result.Uint64() != tc.expected {
+				t.Fatalf("incorrect result, expected=%d, got=%d", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestTeeProgramWraps(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	contract := common.HexToAddress("0x1000")
+	lhs, err := importTeePlaintextToEVM(environment, environment.depth, uint64(3), tfhe.FheUint8)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	rhs, err := importTeePlaintextToEVM(environment, environment.depth, uint64(5), tfhe.FheUint8)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	// 3 - 5, -3 and not 3 on 8 bits.
+	code := []byte{2, programCiphertextInput, programCiphertextInput,
+		programSub, 0, 1, programNeg, 0, programNot, 0,
+		programOutput, 2, programOutput, 3, programOutput, 4}
+	out, err := TeeLibRun(environment, contract, contract, toProgramInput("teeProgram(bytes,uint256[])", code, lhs.GetHash(), rhs.GetHash()), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for i, expected := range []uint64{254, 253, 252} {
+		plaintext, err := tee.Decrypt(getVerifiedCiphertextFromEVM(environment, decodeHandlesInTest(t, out)[i]).ciphertext)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if result := new(big.Int).SetBytes(plaintext.Value); result.Uint64() != expected {
+			t.Fatalf("incorrect result %d, expected=%d, got=%d", i, expected, result)
+		}
+	}
+}
+
+func TestTeeProgramBitOperations(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	contract := common.HexToAddress("0x1000")
+	ct, err := importTeePlaintextToEVM(environment, environment.depth, uint64(0x81), tfhe.FheUint8)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	// rotl 0x81 by 1, rotr 0x81 by 15 and bit 15 of 0x81, taken modulo 8, then popcount, clz and ctz of 0x81.
+	code := []byte{3, programCiphertextInput, programScalarInput, programScalarInput,
+		programRotl, 0, 1, programRotr, 0, 2, programBitTest, 0, 2, programPopcount, 0, programClz, 0, programCtz, 0,
+		programOutput, 3, programOutput, 4, programOutput, 5, programOutput, 6, programOutput, 7, programOutput, 8}
+	input := toProgramInput("teeProgram(bytes,uint256[])", code, ct.GetHash(), common.BigToHash(big.NewInt(1)), common.BigToHash(big.NewInt(15)))
+	out, err := TeeLibRun(environment, contract, contract, input, false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for i, expected := range []uint64{0x03, 0x03, 1, 2, 0, 0} {
+		plaintext, err := tee.Decrypt(getVerifiedCiphertextFromEVM(environment, decodeHandlesInTest(t, out)[i]).ciphertext)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if result := new(big.Int).SetBytes(plaintext.Value); result.Uint64() != expected {
+			t.Fatalf("incorrect result %d, expected=%d, got=%d", i, expected, result)
+		}
+	}
+}
+
+func TestProgramInvalid(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	contract := common.HexToAddress("0x1000")
+	uint8Ct := verifyCiphertextInTestMemory(environment, 2, environment.depth, tfhe.FheUint8).GetHash()
+	uint16Ct := verifyCiphertextInTestMemory(environment, 2, environment.depth, tfhe.FheUint16).GetHash()
+	inputs := []common.Hash{uint8Ct, uint16Ct, common.Hash{}}
+	kinds := []byte{3, programCiphertextInput, programCiphertextInput, programScalarInput}
+
+	testcases := []struct {
+		name         string
+		instructions []byte
+	}{
+		{"no output", []byte{programAdd, 0, 0}},
+		{"unknown opcode", []byte{0x7f, 0, programOutput, 3}},
+		{"truncated instruction", []byte{programAdd, 0}},
+		{"type mismatch", []byte{programAdd, 0, 1, programOutput, 3}},
+		{"register not written", []byte{programAdd, 0, 4, programOutput, 3}},
+		{"division by zero", []byte{programDiv, 0, 2, programOutput, 3}},
+		{"encrypted divisor", []byte{programDiv, 0, 0, programOutput, 3}},
+		{"scalar bitwise operand", []byte{programAnd, 0, 2, programOutput, 3}},
+		{"scalar lhs", []byte{programAdd, 2, 0, programOutput, 3}},
+		{"select on a non-bool", []byte{programSelect, 0, 0, 0, programOutput, 3}},
+		{"invalid cast type", []byte{programCast, 0, 0xee, programOutput, 3}},
+		{"input output", []byte{programOutput, 0}},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			input := toProgramInput("fheProgram(bytes,uint256[])", append(append([]byte{}, kinds...), tc.instructions...), inputs...)
+			if gas := FheLibRequiredGas(environment, 0, input); gas != 0 {
+				t.Fatalf("expected no gas for an invalid program, got %d", gas)
+			}
+			if _, err := FheLibRun(environment, contract, contract, input, false); err == nil {
+				t.Fatalf("expected an invalid program to fail")
+			}
+		})
+	}
+
+	// The number of inputs must match the program.
+	input := toProgramInput("fheProgram(bytes,uint256[])", []byte{1, programCiphertextInput, programNeg, 0, programOutput, 1}, uint8Ct, uint8Ct)
+	if _, err := FheLibRun(environment, contract, contract, input, false); err == nil {
+		t.Fatalf("expected a program with the wrong number of inputs to fail")
+	}
+}
@@ -21,12 +21,19 @@ func minUint64(a, b uint64) uint64 {
 	return b
 }
 
+// Flags of the ciphertext metadata.
+const (
+	// The ciphertext is computed by a coprocessor and persisted without its bytes, see coprocessor.go.
+	ciphertextFlagComputedByCoprocessor uint64 = 1 << iota
+)
+
 // Ciphertext metadata is stored in protected storage, in a 32-byte slot.
-// Currently, we only utilize 17 bytes from the slot.
+// Each field takes a 64-bit word of the slot.
 type ciphertextMetadata struct {
 	refCount    uint64
 	length      uint64
 	fheUintType tfhe.FheUintType
+	flags       uint64
 }
 
 func (m ciphertextMetadata) serialize() [32]byte {
@@ -34,6 +41,7 @@ func (m ciphertextMetadata) serialize() [32]byte {
 	u[0] = m.refCount
 	u[1] = m.length
 	u[2] = uint64(m.fheUintType)
+	u[3] = m.flags
 	return u.Bytes32()
 }
 
@@ -43,6 +51,7 @@ func (m *ciphertextMetadata) deserialize(buf [32]byte) *ciphertextMetadata {
 	m.refCount = u[0]
 	m.length = u[1]
 	m.fheUintType = tfhe.FheUintType(u[2])
+	m.flags = u[3]
 	return m
 }
 
@@ -56,6 +65,11 @@ type ciphertextData struct {
 	bytes    []byte
 }
 
+// Ciphertexts computed by a coprocessor are persisted without their bytes, see coprocessor.go.
+func (d *ciphertextData) isComputedByCoprocessor() bool {
+	return d.metadata.flags&ciphertextFlagComputedByCoprocessor != 0
+}
+
 func getCiphertextMetadataKey(handle common.Hash) common.Hash {
 	return crypto.Keccak256Hash(handle.Bytes())
 }
@@ -72,7 +86,7 @@ func getCiphertextMetadataFromProtectedStorage(env EVMEnvironment, contractAddre
 }
 
 // Returns the ciphertext data for the given handle or nil if it doesn't point to a ciphertext.
-func getCiphertextFromProtectedStoage(env EVMEnvironment, contractAddress common.Address, handle common.Hash) *ciphertextData {
+func getCiphertextFromProtectedStoage(env StateReader, contractAddress common.Address, handle common.Hash) *ciphertextData {
 	metadataKey := getCiphertextMetadataKey(handle)
 	protectedStorage := fhevm_crypto.CreateProtectedStorageContractAddress(contractAddress)
 	metadataInt := newInt(env.GetState(protectedStorage, metadataKey).Bytes())
@@ -107,6 +121,15 @@ func persistIfVerifiedCiphertext(flagHandleLocation common.Hash, handle common.H
 	}
 	logger := env.GetLogger()
 
+	// Ciphertexts that a coprocessor is computing are stored by it: only persist their metadata, flagged as such.
+	computedByCoprocessor := verifiedCiphertext.isPending() && env.FhevmParams().Coprocessor != nil
+	if !computedByCoprocessor {
+		if err := verifiedCiphertext.resolve(); err != nil {
+			logger.Error("opSstore failed to compute a pending ciphertext", "handle", handle.Hex(), "err", err)
+			return
+		}
+	}
+
 	// Try to read ciphertext metadata from protected storage.
 	metadataKey := crypto.Keccak256Hash(handle.Bytes())
 	metadataInt := newInt(env.GetState(protectedStorage, metadataKey).Bytes())
@@ -118,7 +141,14 @@ func persistIfVerifiedCiphertext(flagHandleLocation common.Hash, handle common.H
 	if metadataInt.IsZero() {
 		// If no metadata, it means this ciphertext itself hasn't been persisted to protected storage yet. We do that as part of SSTORE.
 		metadata.refCount = 1
-		metadata.length = uint64(tfhe.ExpandedFheCiphertextSize[verifiedCiphertext.ciphertext.FheUintType])
+		ctBytes := make([]byte, 0)
+		if computedByCoprocessor {
+			metadata.flags |= ciphertextFlagComputedByCoprocessor
+		} else {
+			ctBytes = verifiedCiphertext.ciphertext.Serialize()
+		}
+		// Use the actual serialization length, as TEE ciphertexts are not of the expanded FHE ciphertext size.
+		metadata.length = uint64(len(ctBytes))
 		metadata.fheUintType = verifiedCiphertext.ciphertext.FheUintType
 		ciphertextSlot := newInt(metadataKey.Bytes())
 		ciphertextSlot.AddUint64(ciphertextSlot, 1)
@@ -132,7 +162,6 @@ func persistIfVerifiedCiphertext(flagHandleLocation common.Hash, handle common.H
 		}
 		ctPart32 := make([]byte, 32)
 		partIdx := 0
-		ctBytes := verifiedCiphertext.ciphertext.Serialize()
 		for i, b := range ctBytes {
 			if i%32 == 0 && i != 0 {
 				env.SetState(protectedStorage, ciphertextSlot.Bytes32(), common.BytesToHash(ctPart32))
@@ -0,0 +1,127 @@
+package fhevm
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Publicly decryptable handles.
+//
+// A contract allowed to use a handle can make it publicly decryptable, e.g. once an auction ends. The flag
+// is stored in the ACL's protected storage at keccak256("publicDecryption" || handle) and is never cleared.
+// The ciphertext is also persisted there with its own reference, so it stays available after the contracts
+// that stored it garbage collect their copies. It can be read with getCiphertext at the ACL address, and
+// decrypt, teeDecrypt and reencrypt serve it on eth_call without an authorization.
+func getPublicDecryptionKey(handle common.Hash) common.Hash {
+	return crypto.Keccak256Hash([]byte("publicDecryption"), handle.Bytes())
+}
+
+// Returns true if the ciphertext handle has been made publicly decryptable.
+func isHandlePubliclyDecryptable(environment EVMEnvironment, handle common.Hash) bool {
+	return environment.GetState(getAclProtectedStorage(), getPublicDecryptionKey(handle)) == flag
+}
+
+// Returns the publicly decryptable ciphertext of a handle from the ACL's protected storage, or nil if the
+// handle isn't publicly decryptable.
+func getPubliclyDecryptableCiphertext(environment EVMEnvironment, handle common.Hash, isTee bool) (*tfhe.TfheCiphertext, error) {
+	if !isHandlePubliclyDecryptable(environment, handle) {
+		return nil, nil
+	}
+	return loadCiphertextFromProtectedStorage(environment, aclContractAddress, handle, isTee)
+}
+
+// Returns the ciphertext of a handle persisted in the protected storage of a contract, or nil if there is none.
+func loadCiphertextFromProtectedStorage(environment EVMEnvironment, contract common.Address, handle common.Hash, isTee bool) (*tfhe.TfheCiphertext, error) {
+	ciphertext := getCiphertextFromProtectedStoage(environment, contract, handle)
+	if ciphertext == nil {
+		return nil, nil
+	}
+	if ciphertext.isComputedByCoprocessor() {
+		return awaitCoprocessorCiphertext(environment, handle)()
+	}
+	if isTee {
+		return &tfhe.TfheCiphertext{FheUintType: ciphertext.metadata.fheUintType, Serialization: ciphertext.bytes, Hash: &handle}, nil
+	}
+	ct := new(tfhe.TfheCiphertext)
+	if err := ct.Deserialize(ciphertext.bytes, ciphertext.metadata.fheUintType); err != nil {
+		return nil, err
+	}
+	// Symbolic handles aren't the hash of the ciphertext.
+	ct.Hash = &handle
+	return ct, nil
+}
+
+// Returns the ciphertext to decrypt: the one verified at the current depth or, on eth_call, a publicly
+// decryptable one. The boolean is true if no authorization is needed.
+func getDecryptableCiphertext(environment EVMEnvironment, handle common.Hash, isTee bool) (*tfhe.TfheCiphertext, bool, error) {
+	public := environment.IsEthCall() && isHandlePubliclyDecryptable(environment, handle)
+	if ct := getVerifiedCiphertext(environment, handle); ct != nil {
+		return ct.ciphertext, public, nil
+	}
+	if !public {
+		return nil, false, nil
+	}
+	ct, err := getPubliclyDecryptableCiphertext(environment, handle, isTee)
+	return ct, ct != nil, err
+}
+
+// Returns the contract whose protected storage holds a ciphertext used by contract: the ACL for publicly
+// decryptable ones, which it persists.
+func ciphertextContract(contract common.Address, public bool) common.Address {
+	if public {
+		return aclContractAddress
+	}
+	return contract
+}
+
+func makePubliclyDecryptableRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	logger := environment.GetLogger()
+	if readOnly {
+		return nil, ErrWriteProtection
+	}
+
+	handle := args.word(0)
+	ct := getVerifiedCiphertextFromEVM(environment, handle)
+	if ct == nil {
+		msg := "makePubliclyDecryptable unverified ciphertext handle"
+		logger.Error(msg, "handle", handle.Hex())
+		return nil, errors.New(msg)
+	}
+	if !isHandleUsable(environment, handle, caller) {
+		msg := "makePubliclyDecryptable caller is not allowed to use the ciphertext handle"
+		logger.Error(msg, "handle", handle.Hex(), "caller", caller.Hex())
+		return nil, errors.New(msg)
+	}
+	otelDescribeOperandsFheTypes(runSpan, ct.fheUintType())
+
+	if isHandlePubliclyDecryptable(environment, handle) {
+		return nil, nil
+	}
+	publicKey := getPublicDecryptionKey(handle)
+	environment.SetState(getAclProtectedStorage(), publicKey, flag)
+	// Flag a location of our own, so that no contract's garbage collection ever releases this reference.
+	flagHandleLocation := crypto.Keccak256Hash(publicKey.Bytes())
+	persistIfVerifiedCiphertext(flagHandleLocation, handle, getAclProtectedStorage(), environment)
+	persistUnlockHeight(environment, handle)
+
+	if environment.IsCommitting() {
+		logger.Info("makePubliclyDecryptable success",
+			"handle", handle.Hex(),
+			"caller", caller.Hex(),
+		)
+	}
+	return nil, nil
+}
+
+func isPubliclyDecryptableRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	// Always return a 32-byte big-endian boolean.
+	ret := make([]byte, 32)
+	if isHandlePubliclyDecryptable(environment, args.word(0)) {
+		ret[31] = 1
+	}
+	return ret, nil
+}
@@ -0,0 +1,14 @@
+package fhevm
+
+func makePubliclyDecryptableRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	ct := getVerifiedCiphertext(environment, args.word(0))
+	if ct == nil {
+		environment.GetLogger().Error("makePubliclyDecryptable RequiredGas() input doesn't point to verified ciphertext", "handle", args.word(0).Hex())
+		return 0
+	}
+	return environment.FhevmParams().GasCosts.MakePubliclyDecryptable[ct.fheUintType()]
+}
+
+func isPubliclyDecryptableRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
+	return environment.FhevmParams().GasCosts.IsPubliclyDecryptable
+}
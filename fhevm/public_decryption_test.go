@@ -0,0 +1,117 @@
+package fhevm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	fhevm_crypto "github.com/zama-ai/fhevm-go/fhevm/crypto"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+func toPublicDecryptionInput(method string, handle common.Hash) []byte {
+	input := crypto.Keccak256([]byte(method))[0:4]
+	return append(input, handle.Bytes()...)
+}
+
+// Start an eth_call on the same state, with nothing verified in memory.
+func newEthCallOnSameState(environment *MockEVMEnvironment) *MockEVMEnvironment {
+	fhevmData := NewFhevmData()
+	return &MockEVMEnvironment{fhevmData: &fhevmData, stateDb: environment.stateDb, depth: 1, commit: false, ethCall: true, fhevmParams: environment.fhevmParams, chainID: environment.chainID, blockTimestamp: environment.blockTimestamp}
+}
+
+func TestMakePubliclyDecryptable(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	contract := common.HexToAddress("0x1000")
+	ct := verifyCiphertextInTestMemory(environment, 42, environment.depth, tfhe.FheUint8)
+	handle := ct.GetHash()
+	allowHandle(environment, handle, contract)
+
+	// The contract stores the handle, then garbage collects it.
+	protectedStorage := fhevm_crypto.CreateProtectedStorageContractAddress(contract)
+	flagHandleLocation := crypto.Keccak256Hash([]byte{1})
+	persistIfVerifiedCiphertext(flagHandleLocation, handle, protectedStorage, environment)
+
+	gas := FheLibRequiredGas(environment, 0, toPublicDecryptionInput("makePubliclyDecryptable(uint256)", handle))
+	if gas != environment.FhevmParams().GasCosts.MakePubliclyDecryptable[tfhe.FheUint8] {
+		t.Fatalf("expected makePubliclyDecryptable to be priced for the type of the handle, got %d", gas)
+	}
+	if _, err := FheLibRun(environment, contract, contract, toPublicDecryptionInput("makePubliclyDecryptable(uint256)", handle), false); err != nil {
+		t.Fatalf(err.Error())
+	}
+	garbageCollectProtectedStorage(flagHandleLocation, handle, protectedStorage, environment)
+	if getCiphertextFromProtectedStoage(environment, contract, handle) != nil {
+		t.Fatalf("expected the contract's copy to be garbage collected")
+	}
+
+	ethCall := newEthCallOnSameState(environment)
+	user := common.HexToAddress("0x2000")
+	out, err := FheLibRun(ethCall, user, user, toPublicDecryptionInput("isPubliclyDecryptable(uint256)", handle), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if out[31] != 1 {
+		t.Fatalf("expected the handle to be publicly decryptable")
+	}
+	out, err = FheLibRun(ethCall, user, user, toDecryptInput("decrypt(uint256)", handle, nil), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if new(big.Int).SetBytes(out).Uint64() != 42 {
+		t.Fatalf("decrypt result not equal to value, result %v != value %v", new(big.Int).SetBytes(out).Uint64(), 42)
+	}
+	input := append(crypto.Keccak256([]byte("getCiphertext(address,uint256)"))[0:4], common.BytesToHash(aclContractAddress.Bytes()).Bytes()...)
+	out, err = FheLibRun(ethCall, user, user, append(input, handle.Bytes()...), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if string(out) != string(ct.Serialize()) {
+		t.Fatalf("expected getCiphertext to serve the publicly decryptable ciphertext")
+	}
+}
+
+func TestMakePubliclyDecryptableNotAllowed(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	contract := common.HexToAddress("0x1000")
+	handle := verifyCiphertextInTestMemory(environment, 42, environment.depth, tfhe.FheUint8).GetHash()
+
+	if _, err := FheLibRun(environment, contract, contract, toPublicDecryptionInput("makePubliclyDecryptable(uint256)", handle), false); err == nil {
+		t.Fatalf("expected makePubliclyDecryptable to fail for a caller that is not allowed to use the handle")
+	}
+	if isHandlePubliclyDecryptable(environment, handle) {
+		t.Fatalf("expected the handle not to be publicly decryptable")
+	}
+	// Without the flag, an eth_call can't decrypt the handle.
+	ethCall := newEthCallOnSameState(environment)
+	if _, err := FheLibRun(ethCall, contract, contract, toDecryptInput("decrypt(uint256)", handle, nil), false); err == nil {
+		t.Fatalf("expected decrypt to fail")
+	}
+}
+
+func TestTeeMakePubliclyDecryptable(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	contract := common.HexToAddress("0x1000")
+	ct, err := importTeePlaintextToEVM(environment, environment.depth, uint64(7), tfhe.FheUint32)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	handle := ct.GetHash()
+	allowHandle(environment, handle, contract)
+	if _, err := TeeLibRun(environment, contract, contract, toPublicDecryptionInput("makePubliclyDecryptable(uint256)", handle), false); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	ethCall := newEthCallOnSameState(environment)
+	user := common.HexToAddress("0x2000")
+	out, err := TeeLibRun(ethCall, user, user, toDecryptInput("teeDecrypt(uint256)", handle, nil), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if new(big.Int).SetBytes(out).Uint64() != 7 {
+		t.Fatalf("teeDecrypt result not equal to value, result %v != value %v", new(big.Int).SetBytes(out).Uint64(), 7)
+	}
+}
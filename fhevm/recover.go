@@ -0,0 +1,73 @@
+package fhevm
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// ErrPrecompilePanic is wrapped by the errors of precompile calls that panicked.
+var ErrPrecompilePanic = errors.New("precompile panicked")
+
+// PanicError is returned by FheLibRun and TeeLibRun when a method panics, instead of crashing the node. Panics are
+// bugs, so the call fails without revert data and consumes its gas.
+type PanicError struct {
+	// "fheLib" or "teeLib"
+	Precompile string
+	// The name of the method, empty if the panic happened before it was found.
+	Method string
+	// The value passed to panic.
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	if e.Method == "" {
+		return fmt.Sprintf("%s precompile panicked: %v", e.Precompile, e.Value)
+	}
+	return fmt.Sprintf("%s method %s panicked: %v", e.Precompile, e.Method, e.Value)
+}
+
+func (e *PanicError) Unwrap() error {
+	return ErrPrecompilePanic
+}
+
+// Returns the name of the method called by a precompile input, if it is registered.
+func (r *methodRegistry) methodName(input []byte) string {
+	if len(input) < 4 {
+		return ""
+	}
+	if method, found := r.get(binary.BigEndian.Uint32(input[0:4])); found {
+		return method.name
+	}
+	return ""
+}
+
+func newPanicError(environment EVMEnvironment, registry *methodRegistry, input []byte, value any) *PanicError {
+	err := &PanicError{
+		Precompile: registry.precompile,
+		Method:     registry.methodName(input),
+		Value:      value,
+		Stack:      debug.Stack(),
+	}
+	environment.GetLogger().Error(registry.precompile+" precompile panicked", "method", err.Method, "panic", fmt.Sprint(value), "input", hex.EncodeToString(input), "stack", string(err.Stack))
+	return err
+}
+
+// Must be deferred by the run entry points, with the whole input, to turn a panic into a PanicError.
+func recoverRunPanic(environment EVMEnvironment, registry *methodRegistry, input []byte, ret *[]byte, err *error) {
+	if value := recover(); value != nil {
+		*ret, *err = nil, newPanicError(environment, registry, input, value)
+	}
+}
+
+// Must be deferred by the gas entry points, with the whole input and the supplied gas. A call whose gas function
+// panicked is charged all of its supplied gas, so that a panic can't be used to run the precompile for free.
+func recoverGasPanic(environment EVMEnvironment, registry *methodRegistry, input []byte, suppliedGas uint64, gas *uint64) {
+	if value := recover(); value != nil {
+		newPanicError(environment, registry, input, value)
+		*gas = suppliedGas
+	}
+}
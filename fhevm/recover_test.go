@@ -0,0 +1,58 @@
+package fhevm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestLibRunRecoversPanics(t *testing.T) {
+	method := testLibMethod("pluginPanic")
+	method.RequiredGas = func(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
+		panic("gas bug")
+	}
+	method.Run = func(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
+		panic("run bug")
+	}
+	if err := RegisterTeeLibMethod(method); err != nil {
+		t.Fatalf(err.Error())
+	}
+	environment := newTestEVMEnvironment()
+	contract := common.HexToAddress("0x1000")
+	input := toLibPrecompileInputNoScalar("pluginPanic(uint256)", common.Hash{})
+	if gas := TeeLibRequiredGas(environment, 1000000, input); gas != 1000000 {
+		t.Fatalf("expected all the supplied gas to be charged when the gas function panics, got %d", gas)
+	}
+	out, err := TeeLibRun(environment, contract, contract, input, false)
+	if out != nil || !errors.Is(err, ErrPrecompilePanic) {
+		t.Fatalf("expected ErrPrecompilePanic, got %x, %v", out, err)
+	}
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("expected a PanicError, got %T", err)
+	}
+	if panicErr.Precompile != "teeLib" || panicErr.Method != "pluginPanic" || panicErr.Value != "run bug" || len(panicErr.Stack) == 0 {
+		t.Fatalf("unexpected panic error %+v", panicErr)
+	}
+}
+
+func TestRunComputationRecoversPanics(t *testing.T) {
+	method := testLibMethod("pluginDeferrablePanic")
+	method.Backend = DeferrableFheBackend
+	method.Run = func(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
+		panic("run bug")
+	}
+	if err := RegisterFheLibMethod(method); err != nil {
+		t.Fatalf(err.Error())
+	}
+	computation := &Computation{
+		Selector: makeKeccakSignature("pluginDeferrablePanic(uint256)"),
+		Input:    common.Hash{}.Bytes(),
+	}
+	ct, err := runComputation(newTestEVMEnvironment().GetLogger(), computation, nil)
+	if ct != nil || !errors.Is(err, ErrPrecompilePanic) {
+		t.Fatalf("expected ErrPrecompilePanic, got %v", err)
+	}
+}
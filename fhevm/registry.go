@@ -0,0 +1,213 @@
+package fhevm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrLibMethodsFrozen is returned when a method is registered after FreezeLibMethods.
+var ErrLibMethodsFrozen = errors.New("precompile methods are frozen")
+
+// MethodBackend tells how a precompile method computes its result.
+type MethodBackend int
+
+const (
+	// The method runs in the EVM when it is called.
+	EVMBackend MethodBackend = iota
+	// The method computes an FHE operation whose result only depends on its input ciphertexts. With a coprocessor
+	// or a scheduler, it is computed asynchronously, so it must be registered on every node that computes it.
+	DeferrableFheBackend
+)
+
+// LibMethod describes a method added to the FheLib or TeeLib precompile by RegisterFheLibMethod or
+// RegisterTeeLibMethod. Its selector is the first 4 bytes of the Keccak hash of Name followed by ArgTypes,
+// e.g. "fheAdd(uint256,uint256,bytes1)". Run and RequiredGas get the input without the selector, once it is
+// checked to be the canonical ABI encoding of ArgTypes. A 32-byte result of Run is allowed to the caller, like
+// the result of the built-in operations.
+type LibMethod struct {
+	Name string
+	// format is "(type1,type2...)" (e.g "(uint256,bytes1)")
+	ArgTypes string
+	// Types of the ABI encoded result of Run, in the same format. Empty means "(uint256)", a handle.
+	ReturnTypes string
+	RequiredGas func(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64
+	// The GasCosts fields that price the method, comma-separated, for tooling.
+	GasCategory string
+	Run         func(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error)
+	Backend     MethodBackend
+	// True if Run writes state. The precompile then fails with ErrWriteProtection in static calls, before Run.
+	WritesState bool
+	// True if Run has no side effects. Other methods are declared nonpayable in the generated ABI.
+	View bool
+}
+
+// The methods of a precompile, by selector.
+type methodRegistry struct {
+	mu         sync.RWMutex
+	precompile string
+	methods    map[uint32]*FheLibMethod
+	frozen     bool
+}
+
+var fheLibRegistry = &methodRegistry{precompile: "fheLib", methods: map[uint32]*FheLibMethod{}}
+var teeLibRegistry = &methodRegistry{precompile: "teeLib", methods: map[uint32]*FheLibMethod{}}
+
+func (r *methodRegistry) register(method *FheLibMethod) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.frozen {
+		return ErrLibMethodsFrozen
+	}
+	signature := method.name + method.argTypes
+	if (method.requiredGasFunction == nil) == (method.requiredGasArgsFunction == nil) || (method.runFunction == nil) == (method.runArgsFunction == nil) {
+		return fmt.Errorf("%s method %s needs one gas function and one run function", r.precompile, signature)
+	}
+	args, err := parseABITypes(method.argTypes)
+	if err != nil {
+		return fmt.Errorf("%s method %s: %w", r.precompile, signature, err)
+	}
+	if existing, found := r.methods[method.Signature()]; found {
+		if existing.name+existing.argTypes == signature {
+			return fmt.Errorf("%s method %s is already registered", r.precompile, signature)
+		}
+		return fmt.Errorf("%s method %s has the same selector %08x as %s", r.precompile, signature, method.Signature(), existing.name+existing.argTypes)
+	}
+	method.args = args
+	r.methods[method.Signature()] = method
+	return nil
+}
+
+func (r *methodRegistry) get(selector uint32) (*FheLibMethod, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	method, found := r.methods[selector]
+	return method, found
+}
+
+// Returns the methods sorted by signature.
+func (r *methodRegistry) list() []*FheLibMethod {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	methods := make([]*FheLibMethod, 0, len(r.methods))
+	for _, method := range r.methods {
+		methods = append(methods, method)
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].name+methods[i].argTypes < methods[j].name+methods[j].argTypes
+	})
+	return methods
+}
+
+// Returns the overloads of a method, sorted by signature.
+func (r *methodRegistry) find(name string) []*FheLibMethod {
+	var methods []*FheLibMethod
+	for _, method := range r.list() {
+		if method.name == name {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+func (r *methodRegistry) freeze() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frozen = true
+}
+
+// Register built-in methods, which must not collide.
+func (r *methodRegistry) mustRegister(methods []*FheLibMethod) {
+	for _, method := range methods {
+		if err := r.register(method); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func newLibMethod(method LibMethod) (*FheLibMethod, error) {
+	switch {
+	case method.Name == "":
+		return nil, errors.New("method has no name")
+	case !isTypeList(method.ArgTypes):
+		return nil, fmt.Errorf("method %s has invalid argument types %q", method.Name, method.ArgTypes)
+	case method.ReturnTypes != "" && !isTypeList(method.ReturnTypes):
+		return nil, fmt.Errorf("method %s has invalid return types %q", method.Name, method.ReturnTypes)
+	case method.RequiredGas == nil || method.Run == nil:
+		return nil, fmt.Errorf("method %s needs a gas function and a run function", method.Name)
+	case method.Backend != EVMBackend && method.Backend != DeferrableFheBackend:
+		return nil, fmt.Errorf("method %s has an unknown backend %d", method.Name, method.Backend)
+	}
+	return &FheLibMethod{
+		name:                method.Name,
+		argTypes:            method.ArgTypes,
+		returnTypes:         method.ReturnTypes,
+		requiredGasFunction: method.RequiredGas,
+		gasCategory:         method.GasCategory,
+		runFunction:         method.Run,
+		deferrable:          method.Backend == DeferrableFheBackend,
+		writesState:         method.WritesState,
+		view:                method.View,
+		plugin:              true,
+	}, nil
+}
+
+func isTypeList(types string) bool {
+	return len(types) >= 2 && types[0] == '(' && types[len(types)-1] == ')'
+}
+
+// RegisterFheLibMethod adds a method to the FheLib precompile. It fails if the method has the same selector as
+// a registered one, or if the methods are frozen. Methods must be registered on every node, before it processes
+// blocks.
+func RegisterFheLibMethod(method LibMethod) error {
+	m, err := newLibMethod(method)
+	if err != nil {
+		return err
+	}
+	return fheLibRegistry.register(m)
+}
+
+// RegisterTeeLibMethod adds a method to the TeeLib precompile, like RegisterFheLibMethod. TEE methods run in
+// the EVM, so their backend must be EVMBackend.
+func RegisterTeeLibMethod(method LibMethod) error {
+	if method.Backend != EVMBackend {
+		return fmt.Errorf("teeLib method %s must use the EVM backend", method.Name)
+	}
+	m, err := newLibMethod(method)
+	if err != nil {
+		return err
+	}
+	return teeLibRegistry.register(m)
+}
+
+// FheLibMethods returns the methods of the FheLib precompile, sorted by signature.
+func FheLibMethods() []*FheLibMethod {
+	return fheLibRegistry.list()
+}
+
+// TeeLibMethods returns the methods of the TeeLib precompile, sorted by signature.
+func TeeLibMethods() []*FheLibMethod {
+	return teeLibRegistry.list()
+}
+
+// FindFheLibMethods returns the FheLib methods with the given name, one per overload. GetFheLibMethod looks a
+// method up by selector.
+func FindFheLibMethods(name string) []*FheLibMethod {
+	return fheLibRegistry.find(name)
+}
+
+// FindTeeLibMethods returns the TeeLib methods with the given name, one per overload.
+func FindTeeLibMethods(name string) []*FheLibMethod {
+	return teeLibRegistry.find(name)
+}
+
+// FreezeLibMethods prevents further registrations in the FheLib and TeeLib precompiles. Hosts should call it
+// once plugins are registered, before processing blocks.
+func FreezeLibMethods() {
+	fheLibRegistry.freeze()
+	teeLibRegistry.freeze()
+}
@@ -0,0 +1,140 @@
+package fhevm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func testLibMethod(name string) LibMethod {
+	return LibMethod{
+		Name:     name,
+		ArgTypes: "(uint256)",
+		RequiredGas: func(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
+			return 42
+		},
+		Run: func(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
+			return []byte{1}, nil
+		},
+	}
+}
+
+func TestRegisterFheLibMethod(t *testing.T) {
+	if err := RegisterFheLibMethod(testLibMethod("pluginIdentity")); err != nil {
+		t.Fatalf(err.Error())
+	}
+	environment := newTestEVMEnvironment()
+	contract := common.HexToAddress("0x1000")
+	input := toLibPrecompileInputNoScalar("pluginIdentity(uint256)", common.Hash{})
+	if gas := FheLibRequiredGas(environment, 0, input); gas != 42 {
+		t.Fatalf("incorrect gas, expected=42, got=%d", gas)
+	}
+	out, err := FheLibRun(environment, contract, contract, input, false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(out) != 1 || out[0] != 1 {
+		t.Fatalf("unexpected result %x", out)
+	}
+	if _, found := GetTeeLibMethod(makeKeccakSignature("pluginIdentity(uint256)")); found {
+		t.Fatalf("expected the method to be registered in FheLib only")
+	}
+}
+
+func TestRegisteredMethodWritesState(t *testing.T) {
+	slot := common.HexToHash("0x01")
+	writer := testLibMethod("pluginWriter")
+	writer.WritesState = true
+	// The method doesn't check readOnly itself.
+	writer.Run = func(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
+		environment.SetState(caller, slot, common.BytesToHash([]byte{1}))
+		return []byte{1}, nil
+	}
+	if err := RegisterFheLibMethod(writer); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := RegisterTeeLibMethod(writer); err != nil {
+		t.Fatalf(err.Error())
+	}
+	input := toLibPrecompileInputNoScalar("pluginWriter(uint256)", common.Hash{})
+	for _, run := range []func(EVMEnvironment, common.Address, common.Address, []byte, bool) ([]byte, error){FheLibRun, TeeLibRun} {
+		environment := newTestEVMEnvironment()
+		contract := common.HexToAddress("0x1000")
+		if _, err := run(environment, contract, contract, input, true); !errors.Is(err, ErrWriteProtection) {
+			t.Fatalf("expected ErrWriteProtection in a static call, got %v", err)
+		}
+		if environment.GetState(contract, slot) != (common.Hash{}) {
+			t.Fatalf("expected the state not to be written in a static call")
+		}
+		if _, err := run(environment, contract, contract, input, false); err != nil {
+			t.Fatalf(err.Error())
+		}
+		if environment.GetState(contract, slot) == (common.Hash{}) {
+			t.Fatalf("expected the state to be written")
+		}
+	}
+}
+
+func TestRegisterLibMethodCollisions(t *testing.T) {
+	if err := RegisterFheLibMethod(testLibMethod("fheAdd")); err != nil {
+		t.Fatalf("expected a new overload of a built-in method to be registered: %s", err.Error())
+	}
+	duplicate := testLibMethod("fheAdd")
+	duplicate.ArgTypes = "(uint256,uint256,bytes1)"
+	if err := RegisterFheLibMethod(duplicate); err == nil {
+		t.Fatalf("expected a built-in method to be registered only once")
+	}
+	// pluginMethod25113(uint256) and pluginMethod157854(uint256) have the same selector 6d2aa769.
+	if err := RegisterTeeLibMethod(testLibMethod("pluginMethod25113")); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := RegisterTeeLibMethod(testLibMethod("pluginMethod157854")); err == nil {
+		t.Fatalf("expected a selector collision to fail")
+	}
+	if err := RegisterTeeLibMethod(testLibMethod("pluginMethod25113")); err == nil {
+		t.Fatalf("expected a method to be registered only once")
+	}
+}
+
+func TestRegisterLibMethodInvalid(t *testing.T) {
+	deferrable := testLibMethod("pluginDeferrable")
+	deferrable.Backend = DeferrableFheBackend
+	if err := RegisterTeeLibMethod(deferrable); err == nil {
+		t.Fatalf("expected a deferrable TEE method to fail")
+	}
+	noRun := testLibMethod("pluginNoRun")
+	noRun.Run = nil
+	if err := RegisterFheLibMethod(noRun); err == nil {
+		t.Fatalf("expected a method without a run function to fail")
+	}
+	badArgs := testLibMethod("pluginBadArgs")
+	badArgs.ArgTypes = "uint256"
+	if err := RegisterFheLibMethod(badArgs); err == nil {
+		t.Fatalf("expected a method with invalid argument types to fail")
+	}
+}
+
+func TestMethodRegistryFreeze(t *testing.T) {
+	// Use a separate registry, so that other tests can still register methods.
+	registry := &methodRegistry{precompile: "fheLib", methods: map[uint32]*FheLibMethod{}}
+	first, err := newLibMethod(testLibMethod("pluginFirst"))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := registry.register(first); err != nil {
+		t.Fatalf(err.Error())
+	}
+	registry.freeze()
+	second, err := newLibMethod(testLibMethod("pluginSecond"))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := registry.register(second); !errors.Is(err, ErrLibMethodsFrozen) {
+		t.Fatalf("expected ErrLibMethodsFrozen, got %v", err)
+	}
+	if _, found := registry.get(first.Signature()); !found {
+		t.Fatalf("expected methods registered before freezing to be found")
+	}
+}
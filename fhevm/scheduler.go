@@ -0,0 +1,172 @@
+package fhevm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Parallel execution of the FHE operations of a transaction.
+//
+// With FhevmParams.Scheduler, FHE operations return the symbolic handle of their result before it is computed.
+// The result is a future, computed on the scheduler's pool of workers as soon as its inputs are, so operations
+// that don't depend on each other run in parallel. Results are materialized, waiting for their computation,
+// when they are stored with SSTORE, decrypted or re-encrypted, and at the end of the transaction.
+//
+// Operations are checked when they are called, so invalid inputs, e.g. an unverified handle or a zero divisor, fail
+// the call like without a scheduler. A computation that fails afterwards fails the first call that materializes its
+// result or the result of a dependent operation, and MaterializeCiphertexts at the end of the transaction. Its error
+// names the method and the result of the failed operation.
+//
+// The scheduler requires FhevmParams.SymbolicHandles, as results get their handles before they are computed.
+// Coprocessor mode implies them.
+
+// ErrSchedulerWithoutSymbolicHandles is returned by FHE operations when FhevmParams.Scheduler is set without
+// FhevmParams.SymbolicHandles.
+var ErrSchedulerWithoutSymbolicHandles = errors.New("FhevmParams.Scheduler requires FhevmParams.SymbolicHandles")
+
+// OperationScheduler computes the FHE operations of transactions on a bounded pool of workers.
+type OperationScheduler struct {
+	ctx   context.Context
+	tasks chan func()
+}
+
+// NewOperationScheduler starts the given number of workers, or one per CPU if it is zero. They stop when the
+// context is done.
+func NewOperationScheduler(ctx context.Context, workers int) *OperationScheduler {
+	if workers == 0 {
+		workers = runtime.NumCPU()
+	}
+	s := &OperationScheduler{ctx: ctx, tasks: make(chan func())}
+	for i := 0; i < workers; i++ {
+		go s.work()
+	}
+	return s
+}
+
+func (s *OperationScheduler) work() {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case task := <-s.tasks:
+			task()
+		}
+	}
+}
+
+// The result of a scheduled operation.
+type operationFuture struct {
+	done       chan struct{}
+	ciphertext *tfhe.TfheCiphertext
+	err        error
+}
+
+// Blocks until the operation is computed.
+func (f *operationFuture) wait() (*tfhe.TfheCiphertext, error) {
+	<-f.done
+	if f.err != nil {
+		return nil, f.err
+	}
+	// Callers may set the handle of their copy.
+	ct := *f.ciphertext
+	return &ct, nil
+}
+
+// Compute an operation once its dependencies are computed. Waiting doesn't take a worker.
+func (s *OperationScheduler) schedule(logger Logger, name string, computation *Computation, dependencies []func() (*tfhe.TfheCiphertext, error)) *operationFuture {
+	future := &operationFuture{done: make(chan struct{})}
+	go func() {
+		defer close(future.done)
+		defer func() {
+			if future.err != nil {
+				future.err = fmt.Errorf("%s with result %s: %w", name, computation.Handle.Hex(), future.err)
+			}
+		}()
+		inputs := append([]*tfhe.TfheCiphertext{}, computation.Operands...)
+		for i, dependency := range dependencies {
+			ct, err := dependency()
+			if err != nil {
+				future.err = fmt.Errorf("input ciphertext %s: %w", computation.Dependencies[i].Hex(), err)
+				return
+			}
+			// Import the input under the handle it is pending under.
+			input := *ct
+			input.Hash = &computation.Dependencies[i]
+			inputs = append(inputs, &input)
+		}
+		computed := make(chan struct{})
+		task := func() {
+			defer close(computed)
+			future.ciphertext, future.err = runComputation(logger, computation, inputs)
+		}
+		select {
+		case s.tasks <- task:
+			<-computed
+		case <-s.ctx.Done():
+			future.err = s.ctx.Err()
+		}
+	}()
+	return future
+}
+
+// Returns a run function that schedules the operation of a deferrable method and returns the handle of its
+// result, which is pending until the operation is computed.
+func (s *OperationScheduler) recorder(method *FheLibMethod, selector uint32) func(EVMEnvironment, common.Address, common.Address, []byte, bool, trace.Span) ([]byte, error) {
+	return func(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
+		if params := environment.FhevmParams(); !params.SymbolicHandles && params.Coprocessor == nil {
+			environment.GetLogger().Error("fheLib scheduler error", "method", method.name, "err", ErrSchedulerWithoutSymbolicHandles)
+			return nil, ErrSchedulerWithoutSymbolicHandles
+		}
+		computation, result, err := recordComputation(environment, method, selector, caller, addr, input, readOnly, runSpan)
+		if err != nil {
+			return nil, err
+		}
+		// Take the futures of pending inputs now, as they are cleared when the inputs are materialized.
+		dependencies := make([]func() (*tfhe.TfheCiphertext, error), 0, len(computation.Dependencies))
+		for _, dependency := range computation.Dependencies {
+			dependencies = append(dependencies, environment.FhevmData().verifiedCiphertexts[dependency].compute)
+		}
+		future := s.schedule(environment.GetLogger(), method.name, computation, dependencies)
+		result.compute = future.wait
+		environment.FhevmData().scheduledOperations = append(environment.FhevmData().scheduledOperations, computation.Handle)
+		return computation.Handle.Bytes(), nil
+	}
+}
+
+// Returns true if a deferrable method must be scheduled instead of being computed right away.
+func shouldScheduleOperation(environment EVMEnvironment, method *FheLibMethod) bool {
+	return environment.FhevmParams().Scheduler != nil && method.deferrable && (environment.IsCommitting() || environment.IsEthCall())
+}
+
+// Wait for the pending result of an operation, unless a coprocessor computes it.
+func materializeCiphertext(environment EVMEnvironment, handle common.Hash) error {
+	ct := getVerifiedCiphertextFromEVM(environment, handle)
+	if ct == nil || !ct.isPending() || environment.FhevmParams().Coprocessor != nil {
+		return nil
+	}
+	return ct.resolve()
+}
+
+// Wait for all operations scheduled in the transaction and return the first that failed.
+// Must be called at the end of each transaction when FhevmParams.Scheduler is set. The transaction must fail if it
+// returns an error, as the results of failed operations can't be used.
+func MaterializeCiphertexts(environment EVMEnvironment) error {
+	var firstErr error
+	for _, handle := range environment.FhevmData().scheduledOperations {
+		ct, ok := environment.FhevmData().verifiedCiphertexts[handle]
+		if !ok {
+			continue
+		}
+		if err := ct.resolve(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	environment.FhevmData().scheduledOperations = nil
+	return firstErr
+}
@@ -0,0 +1,154 @@
+package fhevm
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+func newSchedulerTestEnvironment(t *testing.T, workers int) *MockEVMEnvironment {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	environment.chainID = 9000
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	environment.fhevmParams.Scheduler = NewOperationScheduler(ctx, workers)
+	environment.fhevmParams.SymbolicHandles = true
+	return environment
+}
+
+func TestSchedulerRequiresSymbolicHandles(t *testing.T) {
+	environment := newSchedulerTestEnvironment(t, 1)
+	environment.fhevmParams.SymbolicHandles = false
+	contract := common.HexToAddress("0x1000")
+	lhs := verifyTfheCiphertextInTestMemory(environment, new(tfhe.TfheCiphertext).TrivialEncrypt(*big.NewInt(2), tfhe.FheUint8), environment.depth).GetHash()
+	allowHandle(environment, lhs, contract)
+	_, err := FheLibRun(environment, contract, contract, toBinaryOpInput("fheAdd(uint256,uint256,bytes1)", lhs, lhs), false)
+	if !errors.Is(err, ErrSchedulerWithoutSymbolicHandles) {
+		t.Fatalf("expected ErrSchedulerWithoutSymbolicHandles, got %v", err)
+	}
+}
+
+func TestSchedulerOperationErrors(t *testing.T) {
+	environment := newSchedulerTestEnvironment(t, 1)
+	contract := common.HexToAddress("0x1000")
+	failing := common.HexToHash("0xabcd")
+	importPendingCiphertext(environment, failing, tfhe.FheUint8, func() (*tfhe.TfheCiphertext, error) {
+		return nil, errors.New("computation failed")
+	})
+	allowHandle(environment, failing, contract)
+	lhs := verifyTfheCiphertextInTestMemory(environment, new(tfhe.TfheCiphertext).TrivialEncrypt(*big.NewInt(2), tfhe.FheUint8), environment.depth).GetHash()
+	allowHandle(environment, lhs, contract)
+
+	// Invalid inputs fail the call itself.
+	if _, err := FheLibRun(environment, contract, contract, toLibPrecompileInput("fheDiv(uint256,uint256,bytes1)", true, lhs, common.BigToHash(big.NewInt(0))), false); err == nil {
+		t.Fatalf("expected a division by zero to fail when it is called")
+	}
+
+	// A failed computation fails the calls that materialize its result, and names the operation.
+	out, err := FheLibRun(environment, contract, contract, toBinaryOpInput("fheAdd(uint256,uint256,bytes1)", failing, lhs), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	result := common.BytesToHash(out)
+	_, err = FheLibRun(environment, contract, contract, toDecryptInput("decrypt(uint256)", result, nil), false)
+	if err == nil {
+		t.Fatalf("expected decrypt of a failed operation to fail")
+	}
+	err = MaterializeCiphertexts(environment)
+	if err == nil || !strings.Contains(err.Error(), "fheAdd with result "+result.Hex()) {
+		t.Fatalf("expected MaterializeCiphertexts to name the failed operation, got %v", err)
+	}
+}
+
+func TestSchedulerComputesFutures(t *testing.T) {
+	environment := newSchedulerTestEnvironment(t, 2)
+	contract := common.HexToAddress("0x1000")
+	first, second := runChainedAdds(t, environment, contract)
+
+	expectedFirst, expectedSecond := runChainedAdds(t, newSymbolicHandlesTestEnvironment(), contract)
+	if first != expectedFirst || second != expectedSecond {
+		t.Fatalf("expected the handles of scheduled operations to be the symbolic handles")
+	}
+	if value := decryptInTest(t, environment, contract, second); value != 8 {
+		t.Fatalf("decrypt result not equal to value, result %v != value %v", value, 8)
+	}
+
+	if err := MaterializeCiphertexts(environment); err != nil {
+		t.Fatalf(err.Error())
+	}
+	for _, handle := range []common.Hash{first, second} {
+		if environment.FhevmData().verifiedCiphertexts[handle].isPending() {
+			t.Fatalf("expected the result %s to be materialized at the end of the transaction", handle.Hex())
+		}
+	}
+	if value := decryptInTest(t, environment, contract, first); value != 5 {
+		t.Fatalf("decrypt result not equal to value, result %v != value %v", value, 5)
+	}
+}
+
+func TestSchedulerRunsIndependentOperations(t *testing.T) {
+	environment := newSchedulerTestEnvironment(t, 1)
+	contract := common.HexToAddress("0x1000")
+	blocked := common.HexToHash("0xabcd")
+	unblock := make(chan struct{})
+	importPendingCiphertext(environment, blocked, tfhe.FheUint8, func() (*tfhe.TfheCiphertext, error) {
+		<-unblock
+		return new(tfhe.TfheCiphertext).TrivialEncrypt(*big.NewInt(7), tfhe.FheUint8), nil
+	})
+	allowHandle(environment, blocked, contract)
+	lhs := verifyTfheCiphertextInTestMemory(environment, new(tfhe.TfheCiphertext).TrivialEncrypt(*big.NewInt(2), tfhe.FheUint8), environment.depth).GetHash()
+	rhs := verifyTfheCiphertextInTestMemory(environment, new(tfhe.TfheCiphertext).TrivialEncrypt(*big.NewInt(3), tfhe.FheUint8), environment.depth).GetHash()
+	allowHandle(environment, lhs, contract)
+	allowHandle(environment, rhs, contract)
+
+	out, err := FheLibRun(environment, contract, contract, toBinaryOpInput("fheAdd(uint256,uint256,bytes1)", blocked, lhs), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	dependent := common.BytesToHash(out)
+	out, err = FheLibRun(environment, contract, contract, toBinaryOpInput("fheMul(uint256,uint256,bytes1)", lhs, rhs), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	independent := common.BytesToHash(out)
+
+	// The independent operation doesn't wait for the blocked one, even with a single worker.
+	if value := decryptInTest(t, environment, contract, independent); value != 6 {
+		t.Fatalf("decrypt result not equal to value, result %v != value %v", value, 6)
+	}
+	if !environment.FhevmData().verifiedCiphertexts[dependent].isPending() {
+		t.Fatalf("expected the dependent operation to be pending")
+	}
+	close(unblock)
+	if value := decryptInTest(t, environment, contract, dependent); value != 9 {
+		t.Fatalf("decrypt result not equal to value, result %v != value %v", value, 9)
+	}
+}
+
+func TestSchedulerMaterializesOnSstore(t *testing.T) {
+	environment := newSchedulerTestEnvironment(t, 2)
+	contract := common.HexToAddress("0x1000")
+	_, handle := runChainedAdds(t, environment, contract)
+
+	pc := uint64(0)
+	scope := newTestScopeConext()
+	scope.pushToStack(uint256FromBig(handle.Big()))
+	scope.pushToStack(uint256.NewInt(10))
+	if _, err := OpSstore(&pc, environment, scope); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if environment.FhevmData().verifiedCiphertexts[handle].isPending() {
+		t.Fatalf("expected the stored result to be materialized")
+	}
+	ciphertext := getCiphertextFromProtectedStoage(environment, scope.GetContract().Address(), handle)
+	if ciphertext == nil || ciphertext.isComputedByCoprocessor() {
+		t.Fatalf("expected the stored result to be persisted with its bytes")
+	}
+}
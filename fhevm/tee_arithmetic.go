@@ -1,36 +1,85 @@
 package fhevm
 
 import (
+	"math"
+	"math/big"
+
 	"github.com/ethereum/go-ethereum/common"
 	"go.opentelemetry.io/otel/trace"
 )
 
-func teeAddRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doOp(environment, caller, input, runSpan, func(a, b uint64) uint64 {
+func teeAddRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	if isTeeDecimalOp(environment, args) {
+		return doDecimalOp(environment, caller, args, runSpan, teeDecimalAdd, "teeAddRun")
+	}
+	return doOp(environment, caller, args, runSpan, func(a, b uint64) uint64 {
 		return a + b
 	}, "teeAddRun")
 }
 
-func teeSubRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doOp(environment, caller, input, runSpan, func(a, b uint64) uint64 {
+func teeSubRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	if isTeeDecimalOp(environment, args) {
+		return doDecimalOp(environment, caller, args, runSpan, teeDecimalSub, "teeSubRun")
+	}
+	return doOp(environment, caller, args, runSpan, func(a, b uint64) uint64 {
 		return a - b
 	}, "teeSubRun")
 }
 
-func teeMulRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doOp(environment, caller, input, runSpan, func(a, b uint64) uint64 {
+func teeMulRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	if isTeeDecimalOp(environment, args) {
+		return doDecimalOp(environment, caller, args, runSpan, teeDecimalMul, "teeMulRun")
+	}
+	return doOp(environment, caller, args, runSpan, func(a, b uint64) uint64 {
 		return a * b
 	}, "teeMulRun")
 }
 
-func teeDivRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doOp(environment, caller, input, runSpan, func(a, b uint64) uint64 {
+func teeDivRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	if isTeeDecimalOp(environment, args) {
+		return doDecimalOp(environment, caller, args, runSpan, teeDecimalDiv, "teeDivRun")
+	}
+	return doOp(environment, caller, args, runSpan, func(a, b uint64) uint64 {
+		// Like FHE division, division by zero returns the maximum value of the type.
+		if b == 0 {
+			return math.MaxUint64
+		}
 		return a / b
 	}, "teeDivRun")
 }
 
-func teeRemRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doOp(environment, caller, input, runSpan, func(a, b uint64) uint64 {
+func teeRemRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return doOp(environment, caller, args, runSpan, func(a, b uint64) uint64 {
+		// Like FHE remainder, the remainder of a division by zero is the dividend.
+		if b == 0 {
+			return a
+		}
 		return a % b
 	}, "teeRemRun")
 }
+
+func teeMulHiRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return doWideOp(environment, caller, args, runSpan, func(a, b *big.Int, bits uint) *big.Int {
+		// The product of two bits-wide values fits in 2*bits, return its upper half.
+		product := big.NewInt(0).Mul(a, b)
+		return product.Rsh(product, bits)
+	}, "teeMulHiRun")
+}
+
+func teeMulDivRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return doMulDivOp(environment, caller, args, runSpan, "teeMulDivRun")
+}
+
+func teePowRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return doWideOp(environment, caller, args, runSpan, func(a, b *big.Int, bits uint) *big.Int {
+		// Wrap at the type width, by exponentiating modulo 2^bits.
+		modulus := big.NewInt(0).Lsh(big.NewInt(1), bits)
+		return big.NewInt(0).Exp(a, b, modulus)
+	}, "teePowRun")
+}
+
+func teeSqrtRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return doUnaryBigOp(environment, caller, args, runSpan, func(a *big.Int, bits uint) *big.Int {
+		return big.NewInt(0).Sqrt(a)
+	}, "teeSqrtRun")
+}
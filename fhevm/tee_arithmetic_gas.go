@@ -1,17 +1,38 @@
 package fhevm
 
-func teeAddSubRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	return teeOperationGas("teeAddSub", environment, input, environment.FhevmParams().GasCosts.TeeAddSub)
+func teeAddSubRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	return teeOperationGas("teeAddSub", environment, args, environment.FhevmParams().GasCosts.TeeAddSub)
 }
 
-func teeMulRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	return teeOperationGas("teeMul", environment, input, environment.FhevmParams().GasCosts.TeeMul)
+func teeMulRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	return teeOperationGas("teeMul", environment, args, environment.FhevmParams().GasCosts.TeeMul)
 }
 
-func teeDivRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	return teeOperationGas("teeDiv", environment, input, environment.FhevmParams().GasCosts.TeeDiv)
+func teeDivRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	return teeOperationGas("teeDiv", environment, args, environment.FhevmParams().GasCosts.TeeDiv)
 }
 
-func teeRemRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	return teeOperationGas("teeRem", environment, input, environment.FhevmParams().GasCosts.TeeRem)
+func teeRemRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	return teeOperationGas("teeRem", environment, args, environment.FhevmParams().GasCosts.TeeRem)
+}
+
+func teeMulHiRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	return teeOperationGas("teeMulHi", environment, args, environment.FhevmParams().GasCosts.TeeMulHi)
+}
+
+func teeMulDivRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	a := getVerifiedCiphertext(environment, args.word(0))
+	if a == nil {
+		environment.GetLogger().Error("teeMulDiv input not verified", "a", args.word(0).Hex())
+		return 0
+	}
+	return environment.FhevmParams().GasCosts.TeeMulDiv[a.fheUintType()]
+}
+
+func teePowRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	return teeOperationGas("teePow", environment, args, environment.FhevmParams().GasCosts.TeePow)
+}
+
+func teeSqrtRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	return teeUnaryOperationGas("teeSqrt", environment, args, environment.FhevmParams().GasCosts.TeeSqrt)
 }
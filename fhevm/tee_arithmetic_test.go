@@ -2,9 +2,12 @@ package fhevm
 
 import (
 	"fmt"
+	"math/big"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+	"pgregory.net/rapid"
 )
 
 func TestTeeAddRun(t *testing.T) {
@@ -126,3 +129,175 @@ func TestTeeRemRun(t *testing.T) {
 		})
 	}
 }
+
+func TestTeeMulHiRun(t *testing.T) {
+	signature := "teeMulHi(uint256,uint256,bytes1)"
+
+	testcases := []struct {
+		typ      tfhe.FheUintType
+		lhs      *big.Int
+		rhs      *big.Int
+		expected *big.Int
+	}{
+		{tfhe.FheUint4, big.NewInt(13), big.NewInt(11), big.NewInt(8)},
+		{tfhe.FheUint8, big.NewInt(200), big.NewInt(200), big.NewInt(156)},
+		{tfhe.FheUint16, big.NewInt(4283), big.NewInt(60000), big.NewInt(3921)},
+		{tfhe.FheUint32, big.NewInt(1333337), big.NewInt(4000000000), big.NewInt(1241766)},
+		{tfhe.FheUint64, mustBigInt("13333377777777777"), mustBigInt("18000000000000000000"), mustBigInt("13010469437912951")},
+		{tfhe.FheUint128, mustBigInt("170141183460469231731687303715884118073"), mustBigInt("1267650600228229401496703205383"), mustBigInt("633825300114114700748351602691")},
+	}
+	for _, tc := range testcases {
+		t.Run(fmt.Sprintf("teeMulHi with %s", tc.typ), func(t *testing.T) {
+			teeOperationHelper(t, tc.typ, tc.lhs, tc.rhs, tc.expected, signature, false)
+			// scalar operations
+			teeOperationHelper(t, tc.typ, tc.lhs, tc.rhs, tc.expected, signature, true)
+		})
+	}
+}
+
+func TestTeeMulDivRun(t *testing.T) {
+	testcases := []struct {
+		typ      tfhe.FheUintType
+		a        *big.Int
+		b        *big.Int
+		c        *big.Int
+		expected *big.Int
+	}{
+		{tfhe.FheUint4, big.NewInt(13), big.NewInt(11), big.NewInt(7), big.NewInt(4)},
+		{tfhe.FheUint8, big.NewInt(200), big.NewInt(200), big.NewInt(250), big.NewInt(160)},
+		// The quotient is truncated to the operand width.
+		{tfhe.FheUint8, big.NewInt(200), big.NewInt(200), big.NewInt(1), big.NewInt(64)},
+		// Division by zero returns the maximum value of the type.
+		{tfhe.FheUint8, big.NewInt(200), big.NewInt(200), big.NewInt(0), big.NewInt(255)},
+		{tfhe.FheUint16, big.NewInt(4283), big.NewInt(60000), big.NewInt(65000), big.NewInt(3953)},
+		{tfhe.FheUint32, big.NewInt(1333337), big.NewInt(4000000000), big.NewInt(4294967295), big.NewInt(1241766)},
+		{tfhe.FheUint64, mustBigInt("13333377777777777"), mustBigInt("18000000000000000000"), mustBigInt("18446744073709551615"), mustBigInt("13010469437912951")},
+		{tfhe.FheUint128, mustBigInt("170141183460469231731687303715884118073"), mustBigInt("1267650600228229401496703205383"), mustBigInt("2535301200456458802993406410752"), mustBigInt("85070591730234615865843651858411821084")},
+	}
+	for _, tc := range testcases {
+		t.Run(fmt.Sprintf("teeMulDiv with %s", tc.typ), func(t *testing.T) {
+			teeMulDivHelper(t, tc.typ, tc.a, tc.b, tc.c, tc.expected, 0)
+			// scalar operations
+			teeMulDivHelper(t, tc.typ, tc.a, tc.b, tc.c, tc.expected, 1)
+			teeMulDivHelper(t, tc.typ, tc.a, tc.b, tc.c, tc.expected, 2)
+			teeMulDivHelper(t, tc.typ, tc.a, tc.b, tc.c, tc.expected, 3)
+		})
+	}
+}
+
+func TestTeeMulDivUnsupportedType(t *testing.T) {
+	depth := 1
+	environment := newTestEVMEnvironment()
+	environment.depth = depth
+	addr := common.Address{}
+	aCt, err := importTeePlaintextToEVM(environment, depth, uint64(1), tfhe.FheUint160)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	input := toLibPrecompileInput("teeMulDiv(uint256,uint256,uint256,bytes1)", false, aCt.GetHash(), aCt.GetHash(), aCt.GetHash())
+	_, err = TeeLibRun(environment, addr, addr, input, false)
+	if err == nil {
+		t.Fatalf("expected teeMulDiv to fail on FheUint160")
+	}
+}
+
+func mustBigInt(s string) *big.Int {
+	value, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		panic(fmt.Sprintf("invalid big integer: %s", s))
+	}
+	return value
+}
+
+func TestTeePowRun(t *testing.T) {
+	signature := "teePow(uint256,uint256,bytes1)"
+
+	testcases := []struct {
+		typ      tfhe.FheUintType
+		lhs      *big.Int
+		rhs      *big.Int
+		expected *big.Int
+	}{
+		{tfhe.FheUint4, big.NewInt(3), big.NewInt(3), big.NewInt(11)},
+		{tfhe.FheUint8, big.NewInt(3), big.NewInt(5), big.NewInt(243)},
+		// The result wraps at the type width.
+		{tfhe.FheUint8, big.NewInt(7), big.NewInt(3), big.NewInt(87)},
+		{tfhe.FheUint8, big.NewInt(7), big.NewInt(0), big.NewInt(1)},
+		{tfhe.FheUint16, big.NewInt(3), big.NewInt(11), big.NewInt(46075)},
+		{tfhe.FheUint32, big.NewInt(1337), big.NewInt(3), big.NewInt(2389979753)},
+		{tfhe.FheUint64, big.NewInt(13), big.NewInt(17), mustBigInt("8650415919381337933")},
+		{tfhe.FheUint128, big.NewInt(1333337), big.NewInt(6), mustBigInt("5618748401186065437151596493642285009")},
+	}
+	for _, tc := range testcases {
+		t.Run(fmt.Sprintf("teePow with %s", tc.typ), func(t *testing.T) {
+			teeOperationHelper(t, tc.typ, tc.lhs, tc.rhs, tc.expected, signature, false)
+			// scalar operations
+			teeOperationHelper(t, tc.typ, tc.lhs, tc.rhs, tc.expected, signature, true)
+		})
+	}
+}
+
+func TestTeePowProperties(t *testing.T) {
+	signature := "teePow(uint256,uint256,bytes1)"
+	rapid.Check(t, func(t *rapid.T) {
+		typ := rapid.SampledFrom([]tfhe.FheUintType{tfhe.FheUint4, tfhe.FheUint8, tfhe.FheUint16, tfhe.FheUint32, tfhe.FheUint64, tfhe.FheUint128}).Draw(t, "typ")
+		bits, _ := teeTypeBits(typ)
+		x := drawTeeValue(t, typ, "x")
+		n := drawTeeValue(t, typ, "n")
+		isScalar := rapid.Bool().Draw(t, "isScalar")
+
+		expected := new(big.Int).Exp(x, n, new(big.Int).Lsh(big.NewInt(1), bits))
+		teeOperationHelper(t, typ, x, n, expected, signature, isScalar)
+	})
+}
+
+func TestTeeSqrtRun(t *testing.T) {
+	signature := "teeSqrt(uint256)"
+
+	testcases := []struct {
+		typ      tfhe.FheUintType
+		chs      *big.Int
+		expected *big.Int
+	}{
+		{tfhe.FheBool, big.NewInt(1), big.NewInt(1)},
+		{tfhe.FheUint4, big.NewInt(15), big.NewInt(3)},
+		{tfhe.FheUint8, big.NewInt(200), big.NewInt(14)},
+		{tfhe.FheUint16, big.NewInt(4283), big.NewInt(65)},
+		{tfhe.FheUint32, big.NewInt(1333337), big.NewInt(1154)},
+		{tfhe.FheUint64, big.NewInt(13333377777777777), big.NewInt(115470246)},
+		{tfhe.FheUint128, mustBigInt("170141183460469231731687303715884118073"), mustBigInt("13043817825332782212")},
+		{tfhe.FheUint160, mustBigInt("730750818665451459101842416358141509827966271489"), mustBigInt("854839645001009215068541")},
+	}
+	for _, tc := range testcases {
+		t.Run(fmt.Sprintf("teeSqrt with %s", tc.typ), func(t *testing.T) {
+			teeNegNotOperationHelper(t, tc.typ, tc.chs, tc.expected, signature)
+		})
+	}
+}
+
+func TestTeeSqrtProperties(t *testing.T) {
+	signature := "teeSqrt(uint256)"
+	rapid.Check(t, func(t *rapid.T) {
+		typ := rapid.SampledFrom([]tfhe.FheUintType{tfhe.FheBool, tfhe.FheUint4, tfhe.FheUint8, tfhe.FheUint16, tfhe.FheUint32, tfhe.FheUint64, tfhe.FheUint128, tfhe.FheUint160}).Draw(t, "typ")
+		x := drawTeeValue(t, typ, "x")
+
+		// The floor square root r is the only value with r^2 <= x < (r+1)^2.
+		expected := new(big.Int).Sqrt(x)
+		next := new(big.Int).Add(expected, big.NewInt(1))
+		if new(big.Int).Mul(expected, expected).Cmp(x) > 0 || new(big.Int).Mul(next, next).Cmp(x) <= 0 {
+			t.Fatalf("math/big returned %d as the square root of %d", expected, x)
+		}
+		teeNegNotOperationHelper(t, typ, x, expected, signature)
+	})
+}
+
+// drawTeeValue draws a value that fits in the given type.
+func drawTeeValue(t *rapid.T, typ tfhe.FheUintType, label string) *big.Int {
+	bits, err := teeTypeBits(typ)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	size := int(bits+7) / 8
+	bz := rapid.SliceOfN(rapid.Byte(), size, size).Draw(t, label)
+	return teeTruncate(new(big.Int).SetBytes(bz), bits)
+}
@@ -2,14 +2,15 @@ package fhevm
 
 import (
 	"fmt"
+	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
 	"go.opentelemetry.io/otel/trace"
 )
 
-func teeShlRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doShiftOp(environment, caller, input, runSpan, func(a, b uint64, typ tfhe.FheUintType) (uint64, error) {
+func teeShlRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return doShiftOp(environment, caller, args, runSpan, func(a, b uint64, typ tfhe.FheUintType) (uint64, error) {
 		switch typ {
 		// There isn't bitwise shift operation between ebool. So it doesn't include case 0.
 		case tfhe.FheUint4:
@@ -32,8 +33,8 @@ func teeShlRun(environment EVMEnvironment, caller common.Address, addr common.Ad
 	}, "teeShlRun")
 }
 
-func teeShrRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doShiftOp(environment, caller, input, runSpan, func(a, b uint64, typ tfhe.FheUintType) (uint64, error) {
+func teeShrRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return doShiftOp(environment, caller, args, runSpan, func(a, b uint64, typ tfhe.FheUintType) (uint64, error) {
 		switch typ {
 		// There isn't bitwise shift operation between ebool. So it doesn't include case 0.
 		case tfhe.FheUint4:
@@ -56,8 +57,8 @@ func teeShrRun(environment EVMEnvironment, caller common.Address, addr common.Ad
 	}, "teeShrRun")
 }
 
-func teeRotlRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doShiftOp(environment, caller, input, runSpan, func(a, b uint64, typ tfhe.FheUintType) (uint64, error) {
+func teeRotlRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return doShiftOp(environment, caller, args, runSpan, func(a, b uint64, typ tfhe.FheUintType) (uint64, error) {
 		// Rotate the bits of 'a' to the right by 'b' positions.
 		// '(a >> b)' shifts bits to the right, discarding bits shifted out.
 		// '(a << (typ - b))' shifts bits to the left by 'typ - b' positions, effectively moving the discarded bits from the right shift to the left end.
@@ -84,8 +85,8 @@ func teeRotlRun(environment EVMEnvironment, caller common.Address, addr common.A
 	}, "teeRotl")
 }
 
-func teeRotrRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doShiftOp(environment, caller, input, runSpan, func(a, b uint64, typ tfhe.FheUintType) (uint64, error) {
+func teeRotrRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return doShiftOp(environment, caller, args, runSpan, func(a, b uint64, typ tfhe.FheUintType) (uint64, error) {
 		// Rotate the bits of 'a' to the left by 'b' positions.
 		// '(a << b)' shifts bits to the left, moving bits towards the most significant bit (left end) and discarding bits that fall off the left end.
 		// '(a >> (typ - b))' shifts bits to the right by 'typ - b' positions, effectively moving the discarded bits from the left shift to the right end.
@@ -112,32 +113,62 @@ func teeRotrRun(environment EVMEnvironment, caller common.Address, addr common.A
 	}, "teeRotr")
 }
 
-func teeBitAndRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doOp(environment, caller, input, runSpan, func(a, b uint64) uint64 {
+func teeBitAndRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return doOp(environment, caller, args, runSpan, func(a, b uint64) uint64 {
 		return a & b
 	}, "teeBitAnd")
 }
 
-func teeBitOrRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doOp(environment, caller, input, runSpan, func(a, b uint64) uint64 {
+func teeBitOrRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return doOp(environment, caller, args, runSpan, func(a, b uint64) uint64 {
 		return a | b
 	}, "teeBitOr")
 }
 
-func teeBitXorRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doOp(environment, caller, input, runSpan, func(a, b uint64) uint64 {
+func teeBitXorRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return doOp(environment, caller, args, runSpan, func(a, b uint64) uint64 {
 		return a ^ b
 	}, "teeBitXor")
 }
 
-func teeNegRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doNegNotOp(environment, caller, input, runSpan, func(a uint64) uint64 {
+func teeNegRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return doNegNotOp(environment, caller, args, runSpan, func(a uint64) uint64 {
 		return ^a + 1
 	}, "teeNeg")
 }
 
-func teeNotRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doNegNotOp(environment, caller, input, runSpan, func(a uint64) uint64 {
+func teeNotRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return doNegNotOp(environment, caller, args, runSpan, func(a uint64) uint64 {
 		return ^a
 	}, "teeNot")
 }
+
+func teePopcountRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return doUnaryBigOp(environment, caller, args, runSpan, func(a *big.Int, bits uint) *big.Int {
+		count := int64(0)
+		for i := 0; i < a.BitLen(); i++ {
+			count += int64(a.Bit(i))
+		}
+		return big.NewInt(count)
+	}, "teePopcount")
+}
+
+func teeClzRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return doUnaryBigOp(environment, caller, args, runSpan, func(a *big.Int, bits uint) *big.Int {
+		return big.NewInt(int64(bits) - int64(a.BitLen()))
+	}, "teeClz")
+}
+
+func teeCtzRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return doUnaryBigOp(environment, caller, args, runSpan, func(a *big.Int, bits uint) *big.Int {
+		// The trailing zeros of zero are the full bit width of the type.
+		if a.Sign() == 0 {
+			return big.NewInt(int64(bits))
+		}
+		return big.NewInt(int64(a.TrailingZeroBits()))
+	}, "teeCtz")
+}
+
+func teeBitTestRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return doBitTestOp(environment, caller, args, runSpan, "teeBitTest")
+}
@@ -1,17 +1,25 @@
 package fhevm
 
-func teeShiftRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	return teeOperationGas("teeShift", environment, input, environment.FhevmParams().GasCosts.TeeShift)
+func teeShiftRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	return teeOperationGas("teeShift", environment, args, environment.FhevmParams().GasCosts.TeeShift)
 }
 
-func teeBitwiseOpRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	return teeOperationGas("teeBitwiseOp", environment, input, environment.FhevmParams().GasCosts.TeeBitwiseOp)
+func teeBitwiseOpRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	return teeOperationGas("teeBitwiseOp", environment, args, environment.FhevmParams().GasCosts.TeeBitwiseOp)
 }
 
-func teeNotRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	return teeOperationGas("teeNot", environment, input, environment.FhevmParams().GasCosts.TeeNot)
+func teeNotRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	return teeUnaryOperationGas("teeNot", environment, args, environment.FhevmParams().GasCosts.TeeNot)
 }
 
-func teeNegRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	return teeOperationGas("teeNeg", environment, input, environment.FhevmParams().GasCosts.TeeNeg)
+func teeNegRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	return teeUnaryOperationGas("teeNeg", environment, args, environment.FhevmParams().GasCosts.TeeNeg)
+}
+
+func teeBitCountRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	return teeUnaryOperationGas("teeBitCount", environment, args, environment.FhevmParams().GasCosts.TeeBitCount)
+}
+
+func teeBitTestRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	return teeOperationGas("teeBitTest", environment, args, environment.FhevmParams().GasCosts.TeeBitTest)
 }
@@ -199,6 +199,25 @@ func TestTeeNegRun(t *testing.T) {
 	}
 }
 
+func TestTeeNegNotRequiredGas(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	ct, err := importTeePlaintextToEVM(environment, environment.depth, uint64(2), tfhe.FheUint8)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	costs := environment.FhevmParams().GasCosts
+	for signature, expected := range map[string]uint64{
+		"teeNeg(uint256)": costs.TeeNeg[tfhe.FheUint8],
+		"teeNot(uint256)": costs.TeeNot[tfhe.FheUint8],
+	} {
+		input := toLibPrecompileInputNoScalar(signature, ct.GetHash())
+		if gas := TeeLibRequiredGas(environment, 0, input); gas != expected {
+			t.Fatalf("%s: incorrect gas, expected=%d, got=%d", signature, expected, gas)
+		}
+	}
+}
+
 func TestTeeNotRun(t *testing.T) {
 	signature := "teeNot(uint256)"
 
@@ -219,3 +238,105 @@ func TestTeeNotRun(t *testing.T) {
 		})
 	}
 }
+
+func TestTeePopcountRun(t *testing.T) {
+	signature := "teePopcount(uint256)"
+
+	testcases := []struct {
+		typ      tfhe.FheUintType
+		chs      uint64
+		expected uint64
+	}{
+		{tfhe.FheBool, 1, 1},
+		{tfhe.FheUint4, 11, 3},
+		{tfhe.FheUint8, 173, 5},
+		{tfhe.FheUint16, 4283, 7},
+		{tfhe.FheUint32, 1333337, 9},
+		{tfhe.FheUint64, 13333377777777777, 31},
+		{tfhe.FheUint128, 13333377777777777, 31},
+		{tfhe.FheUint160, 13333377777777777, 31},
+	}
+	for _, tc := range testcases {
+		t.Run(fmt.Sprintf("teePopcount with %s", tc.typ), func(t *testing.T) {
+			teeNegNotOperationHelper(t, tc.typ, tc.chs, tc.expected, signature)
+		})
+	}
+}
+
+func TestTeeClzRun(t *testing.T) {
+	signature := "teeClz(uint256)"
+
+	testcases := []struct {
+		typ      tfhe.FheUintType
+		chs      uint64
+		expected uint64
+	}{
+		{tfhe.FheBool, 0, 1},
+		{tfhe.FheUint4, 3, 2},
+		{tfhe.FheUint8, 17, 3},
+		{tfhe.FheUint16, 4283, 3},
+		{tfhe.FheUint32, 1333337, 11},
+		{tfhe.FheUint64, 13333377777777777, 10},
+		{tfhe.FheUint128, 13333377777777777, 74},
+		{tfhe.FheUint160, 13333377777777777, 106},
+	}
+	for _, tc := range testcases {
+		t.Run(fmt.Sprintf("teeClz with %s", tc.typ), func(t *testing.T) {
+			teeNegNotOperationHelper(t, tc.typ, tc.chs, tc.expected, signature)
+		})
+	}
+}
+
+func TestTeeCtzRun(t *testing.T) {
+	signature := "teeCtz(uint256)"
+
+	testcases := []struct {
+		typ      tfhe.FheUintType
+		chs      uint64
+		expected uint64
+	}{
+		{tfhe.FheBool, 0, 1},
+		{tfhe.FheUint4, 4, 2},
+		{tfhe.FheUint8, 48, 4},
+		{tfhe.FheUint16, 4288, 6},
+		{tfhe.FheUint32, 1333376, 7},
+		{tfhe.FheUint64, 13333377777778176, 9},
+		{tfhe.FheUint128, 0, 128},
+		{tfhe.FheUint160, 1099511627776, 40},
+	}
+	for _, tc := range testcases {
+		t.Run(fmt.Sprintf("teeCtz with %s", tc.typ), func(t *testing.T) {
+			teeNegNotOperationHelper(t, tc.typ, tc.chs, tc.expected, signature)
+		})
+	}
+}
+
+func TestTeeBitTestRun(t *testing.T) {
+	signature := "teeBitTest(uint256,uint256,bytes1)"
+
+	testcases := []struct {
+		typ      tfhe.FheUintType
+		lhs      uint64
+		rhs      uint64
+		expected bool
+	}{
+		{tfhe.FheBool, 1, 0, true},
+		{tfhe.FheUint4, 10, 1, true},
+		// The bit index wraps around the bit width of the type.
+		{tfhe.FheUint4, 10, 6, false},
+		{tfhe.FheUint8, 173, 2, true},
+		{tfhe.FheUint8, 173, 9, false},
+		{tfhe.FheUint16, 4283, 12, true},
+		{tfhe.FheUint32, 1333337, 17, false},
+		{tfhe.FheUint64, 13333377777777777, 42, true},
+		{tfhe.FheUint128, 13333377777777777, 53, true},
+		{tfhe.FheUint160, 13333377777777777, 200, false},
+	}
+	for _, tc := range testcases {
+		t.Run(fmt.Sprintf("teeBitTest with %s", tc.typ), func(t *testing.T) {
+			teeOperationWithResultTypeHelper(t, tc.typ, tfhe.FheBool, tc.lhs, tc.rhs, tc.expected, signature, false)
+			// scalar operations
+			teeOperationWithResultTypeHelper(t, tc.typ, tfhe.FheBool, tc.lhs, tc.rhs, tc.expected, signature, true)
+		})
+	}
+}
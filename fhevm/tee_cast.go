@@ -10,24 +10,62 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
+// teeCastTo casts a TEE ciphertext to another type.
+//
+// All types are unsigned, so there is no sign extension:
+//   - the input value is first reduced to the width of its own type;
+//   - casting between integer types keeps the value modulo 2^bits of the
+//     target type, i.e. widening zero-extends and narrowing keeps the low bits;
+//   - casting to FheBool is normalized: any non-zero value is true, not only
+//     values whose lowest bit is set;
+//   - casting from FheBool yields 0 or 1.
+//
+// Casting from FheDecimal rounds down to an integer before the rules above,
+// except for FheBool where any non-zero decimal is true. Casting to FheDecimal
+// scales the integer value, keeping it modulo 2^128 once scaled.
+//
+// The result keeps the address of the input plaintext.
 func teeCastTo(ciphertext *tfhe.TfheCiphertext, castToType tfhe.FheUintType) (*tfhe.TfheCiphertext, error) {
 	if ciphertext.FheUintType == castToType {
 		return nil, errors.New("casting to same type is not supported")
 	}
 
+	fromBits, err := teeCastTypeBits(ciphertext.FheUintType)
+	if err != nil {
+		return nil, err
+	}
+	toBits, err := teeCastTypeBits(castToType)
+	if err != nil {
+		return nil, err
+	}
+
 	result, err := tee.Decrypt(ciphertext)
 	if err != nil {
 		return nil, errors.New("decryption failed")
 	}
 
-	value := big.NewInt(0).SetBytes(result.Value).Uint64()
+	value := big.NewInt(0).SetBytes(result.Value)
+	if ciphertext.FheUintType != tfhe.FheBool {
+		value = teeTruncate(value, fromBits)
+	}
+	if ciphertext.FheUintType == tfhe.FheDecimal && castToType != tfhe.FheBool {
+		value.Quo(value, teeDecimalScale)
+	}
+	if ciphertext.FheUintType == tfhe.FheBool || castToType == tfhe.FheBool {
+		value.SetUint64(boolToUint64(value.Sign() != 0))
+	}
+	if castToType == tfhe.FheDecimal {
+		value.Mul(value, teeDecimalScale)
+	}
+	if castToType != tfhe.FheBool {
+		value = teeTruncate(value, toBits)
+	}
 
 	resultBz, err := marshalTfheType(value, castToType)
-
 	if err != nil {
 		return nil, errors.New("marshalling failed")
 	}
-	teePlaintext := tee.NewTeePlaintext(resultBz, castToType, common.Address{})
+	teePlaintext := tee.NewTeePlaintext(resultBz, castToType, result.Address)
 
 	resultCt, err := tee.Encrypt(teePlaintext)
 	if err != nil {
@@ -37,27 +75,20 @@ func teeCastTo(ciphertext *tfhe.TfheCiphertext, castToType tfhe.FheUintType) (*t
 	return &resultCt, nil
 }
 
-func teeCastRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	input = input[:minInt(33, len(input))]
-
+func teeCastRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
 	logger := environment.GetLogger()
-	if len(input) != 33 {
-		msg := "cast Run() input needs to contain a ciphertext and one byte for its type"
-		logger.Error(msg, "len", len(input))
-		return nil, errors.New(msg)
-	}
 
-	ct := getVerifiedCiphertext(environment, common.BytesToHash(input[0:32]))
+	ct := getVerifiedCiphertext(environment, args.word(0))
 	if ct == nil {
 		logger.Error("cast input not verified")
 		return nil, errors.New("unverified ciphertext handle")
 	}
 
-	if !tfhe.IsValidFheType(input[32]) {
+	if !isValidTeeType(args.byte(1)) {
 		logger.Error("invalid type to cast to")
 		return nil, errors.New("invalid type provided")
 	}
-	castToType := tfhe.FheUintType(input[32])
+	castToType := tfhe.FheUintType(args.byte(1))
 
 	otelDescribeOperandsFheTypes(runSpan, ct.fheUintType(), castToType)
 
@@ -74,9 +105,9 @@ func teeCastRun(environment EVMEnvironment, caller common.Address, addr common.A
 		return nil, errors.New(msg)
 	}
 
+	importCiphertext(environment, res)
 	resHash := res.GetHash()
 
-	importCiphertext(environment, res)
 	if environment.IsCommitting() {
 		logger.Info("cast success",
 			"ctHash", resHash.Hex(),
@@ -85,3 +116,12 @@ func teeCastRun(environment EVMEnvironment, caller common.Address, addr common.A
 
 	return resHash.Bytes(), nil
 }
+
+// teeCastTypeBits returns the width of the integer backing a type that
+// teeCast supports
+func teeCastTypeBits(typ tfhe.FheUintType) (uint, error) {
+	if typ == tfhe.FheDecimal {
+		return teeDecimalBits, nil
+	}
+	return teeTypeBits(typ)
+}
@@ -2,9 +2,12 @@ package fhevm
 
 import (
 	"fmt"
+	"math/big"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+	"github.com/zama-ai/fhevm-go/tee"
 )
 
 func TestTeeCast(t *testing.T) {
@@ -44,3 +47,141 @@ func TestTeeCast(t *testing.T) {
 		})
 	}
 }
+
+func TestTeeCastMatrix(t *testing.T) {
+	provenance := common.HexToAddress("0x5b38da6a701c568545dcfcb03fcb875f56beddc4")
+
+	testcases := []struct {
+		from      tfhe.FheUintType
+		to        tfhe.FheUintType
+		input     string
+		expected  string
+		expectErr bool
+	}{
+		{tfhe.FheBool, tfhe.FheBool, "0x1", "", true},
+		{tfhe.FheBool, tfhe.FheUint4, "0x1", "0x1", false},
+		{tfhe.FheBool, tfhe.FheUint8, "0x1", "0x1", false},
+		{tfhe.FheBool, tfhe.FheUint16, "0x1", "0x1", false},
+		{tfhe.FheBool, tfhe.FheUint32, "0x1", "0x1", false},
+		{tfhe.FheBool, tfhe.FheUint64, "0x1", "0x1", false},
+		{tfhe.FheBool, tfhe.FheUint128, "0x1", "0x1", false},
+		{tfhe.FheBool, tfhe.FheUint160, "0x1", "0x1", false},
+		{tfhe.FheUint4, tfhe.FheBool, "0xd", "0x1", false},
+		{tfhe.FheUint4, tfhe.FheUint4, "0xd", "", true},
+		{tfhe.FheUint4, tfhe.FheUint8, "0xd", "0xd", false},
+		{tfhe.FheUint4, tfhe.FheUint16, "0xd", "0xd", false},
+		{tfhe.FheUint4, tfhe.FheUint32, "0xd", "0xd", false},
+		{tfhe.FheUint4, tfhe.FheUint64, "0xd", "0xd", false},
+		{tfhe.FheUint4, tfhe.FheUint128, "0xd", "0xd", false},
+		{tfhe.FheUint4, tfhe.FheUint160, "0xd", "0xd", false},
+		{tfhe.FheUint8, tfhe.FheBool, "0xa5", "0x1", false},
+		{tfhe.FheUint8, tfhe.FheUint4, "0xa5", "0x5", false},
+		{tfhe.FheUint8, tfhe.FheUint8, "0xa5", "", true},
+		{tfhe.FheUint8, tfhe.FheUint16, "0xa5", "0xa5", false},
+		{tfhe.FheUint8, tfhe.FheUint32, "0xa5", "0xa5", false},
+		{tfhe.FheUint8, tfhe.FheUint64, "0xa5", "0xa5", false},
+		{tfhe.FheUint8, tfhe.FheUint128, "0xa5", "0xa5", false},
+		{tfhe.FheUint8, tfhe.FheUint160, "0xa5", "0xa5", false},
+		{tfhe.FheUint16, tfhe.FheBool, "0x1200", "0x1", false},
+		{tfhe.FheUint16, tfhe.FheUint4, "0x1200", "0x0", false},
+		{tfhe.FheUint16, tfhe.FheUint8, "0x1200", "0x0", false},
+		{tfhe.FheUint16, tfhe.FheUint16, "0x1200", "", true},
+		{tfhe.FheUint16, tfhe.FheUint32, "0x1200", "0x1200", false},
+		{tfhe.FheUint16, tfhe.FheUint64, "0x1200", "0x1200", false},
+		{tfhe.FheUint16, tfhe.FheUint128, "0x1200", "0x1200", false},
+		{tfhe.FheUint16, tfhe.FheUint160, "0x1200", "0x1200", false},
+		{tfhe.FheUint32, tfhe.FheBool, "0xdeadbeef", "0x1", false},
+		{tfhe.FheUint32, tfhe.FheUint4, "0xdeadbeef", "0xf", false},
+		{tfhe.FheUint32, tfhe.FheUint8, "0xdeadbeef", "0xef", false},
+		{tfhe.FheUint32, tfhe.FheUint16, "0xdeadbeef", "0xbeef", false},
+		{tfhe.FheUint32, tfhe.FheUint32, "0xdeadbeef", "", true},
+		{tfhe.FheUint32, tfhe.FheUint64, "0xdeadbeef", "0xdeadbeef", false},
+		{tfhe.FheUint32, tfhe.FheUint128, "0xdeadbeef", "0xdeadbeef", false},
+		{tfhe.FheUint32, tfhe.FheUint160, "0xdeadbeef", "0xdeadbeef", false},
+		{tfhe.FheUint64, tfhe.FheBool, "0x123456789abcdef", "0x1", false},
+		{tfhe.FheUint64, tfhe.FheUint4, "0x123456789abcdef", "0xf", false},
+		{tfhe.FheUint64, tfhe.FheUint8, "0x123456789abcdef", "0xef", false},
+		{tfhe.FheUint64, tfhe.FheUint16, "0x123456789abcdef", "0xcdef", false},
+		{tfhe.FheUint64, tfhe.FheUint32, "0x123456789abcdef", "0x89abcdef", false},
+		{tfhe.FheUint64, tfhe.FheUint64, "0x123456789abcdef", "", true},
+		{tfhe.FheUint64, tfhe.FheUint128, "0x123456789abcdef", "0x123456789abcdef", false},
+		{tfhe.FheUint64, tfhe.FheUint160, "0x123456789abcdef", "0x123456789abcdef", false},
+		{tfhe.FheUint128, tfhe.FheBool, "0xfedcba98765432100000000000000000", "0x1", false},
+		{tfhe.FheUint128, tfhe.FheUint4, "0xfedcba98765432100000000000000000", "0x0", false},
+		{tfhe.FheUint128, tfhe.FheUint8, "0xfedcba98765432100000000000000000", "0x0", false},
+		{tfhe.FheUint128, tfhe.FheUint16, "0xfedcba98765432100000000000000000", "0x0", false},
+		{tfhe.FheUint128, tfhe.FheUint32, "0xfedcba98765432100000000000000000", "0x0", false},
+		{tfhe.FheUint128, tfhe.FheUint64, "0xfedcba98765432100000000000000000", "0x0", false},
+		{tfhe.FheUint128, tfhe.FheUint128, "0xfedcba98765432100000000000000000", "", true},
+		{tfhe.FheUint128, tfhe.FheUint160, "0xfedcba98765432100000000000000000", "0xfedcba98765432100000000000000000", false},
+		{tfhe.FheUint160, tfhe.FheBool, "0xffffffff00000000000000000000000000000007", "0x1", false},
+		{tfhe.FheUint160, tfhe.FheUint4, "0xffffffff00000000000000000000000000000007", "0x7", false},
+		{tfhe.FheUint160, tfhe.FheUint8, "0xffffffff00000000000000000000000000000007", "0x7", false},
+		{tfhe.FheUint160, tfhe.FheUint16, "0xffffffff00000000000000000000000000000007", "0x7", false},
+		{tfhe.FheUint160, tfhe.FheUint32, "0xffffffff00000000000000000000000000000007", "0x7", false},
+		{tfhe.FheUint160, tfhe.FheUint64, "0xffffffff00000000000000000000000000000007", "0x7", false},
+		{tfhe.FheUint160, tfhe.FheUint128, "0xffffffff00000000000000000000000000000007", "0x7", false},
+		{tfhe.FheUint160, tfhe.FheUint160, "0xffffffff00000000000000000000000000000007", "", true},
+		{tfhe.FheBool, tfhe.FheDecimal, "0x1", "0xde0b6b3a7640000", false},
+		{tfhe.FheUint4, tfhe.FheDecimal, "0xd", "0xb469471f80140000", false},
+		{tfhe.FheUint8, tfhe.FheDecimal, "0xa5", "0x8f1d5c1cae3740000", false},
+		{tfhe.FheUint16, tfhe.FheDecimal, "0x1200", "0xf9ccd8a1c508000000", false},
+		{tfhe.FheUint32, tfhe.FheDecimal, "0xdeadbeef", "0xc1249a4fef4be897e5c0000", false},
+		{tfhe.FheUint64, tfhe.FheDecimal, "0x123456789abcdef", "0xfca32dc55c71c62e9c2905a5c0000", false},
+		{tfhe.FheUint128, tfhe.FheDecimal, "0xfedcba98765432100000000000000000", "0x8f3586bbfe4000000000000000000000", false},
+		{tfhe.FheUint160, tfhe.FheDecimal, "0xffffffff00000000000000000000000000000007", "0x6124fee993bc0000", false},
+		{tfhe.FheDecimal, tfhe.FheDecimal, "0x42ec210956b3ba0000", "", true},
+		{tfhe.FheDecimal, tfhe.FheBool, "0x42ec210956b3ba0000", "0x1", false},
+		{tfhe.FheDecimal, tfhe.FheUint4, "0x42ec210956b3ba0000", "0x2", false},
+		{tfhe.FheDecimal, tfhe.FheUint8, "0x42ec210956b3ba0000", "0xd2", false},
+		{tfhe.FheDecimal, tfhe.FheUint16, "0x42ec210956b3ba0000", "0x4d2", false},
+		{tfhe.FheDecimal, tfhe.FheUint32, "0x42ec210956b3ba0000", "0x4d2", false},
+		{tfhe.FheDecimal, tfhe.FheUint64, "0x42ec210956b3ba0000", "0x4d2", false},
+		{tfhe.FheDecimal, tfhe.FheUint128, "0x42ec210956b3ba0000", "0x4d2", false},
+		{tfhe.FheDecimal, tfhe.FheUint160, "0x42ec210956b3ba0000", "0x4d2", false},
+		// Bool conversion is normalized, zero is the only false value.
+		{tfhe.FheDecimal, tfhe.FheBool, "500000000000000000", "0x1", false},
+		{tfhe.FheDecimal, tfhe.FheBool, "0x0", "0x0", false},
+		{tfhe.FheUint16, tfhe.FheBool, "0x0", "0x0", false},
+		{tfhe.FheUint128, tfhe.FheBool, "0x0", "0x0", false},
+		// Decimals are rounded down when cast to an integer.
+		{tfhe.FheDecimal, tfhe.FheUint8, "500000000000000000", "0x0", false},
+		// Bits above the width of the source type are ignored.
+		{tfhe.FheUint8, tfhe.FheUint16, "0x1a5", "0xa5", false},
+		{tfhe.FheUint8, tfhe.FheBool, "0x100", "0x0", false},
+	}
+	for _, tc := range testcases {
+		t.Run(fmt.Sprintf("teeCast %s to %s", tc.from, tc.to), func(t *testing.T) {
+			ct, err := tee.Encrypt(tee.NewTeePlaintext(mustBigInt(tc.input).Bytes(), tc.from, provenance))
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+
+			res, err := teeCastTo(&ct, tc.to)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected casting %s to %s to fail", tc.from, tc.to)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+
+			teePlaintext, err := tee.Decrypt(res)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			if res.FheUintType != tc.to || teePlaintext.FheUintType != tc.to {
+				t.Fatalf("incorrect fheUintType, expected=%s, got=%s", tc.to, teePlaintext.FheUintType)
+			}
+			if teePlaintext.Address != provenance {
+				t.Fatalf("incorrect address, expected=%s, got=%s", provenance, teePlaintext.Address)
+			}
+			result := new(big.Int).SetBytes(teePlaintext.Value)
+			if result.Cmp(mustBigInt(tc.expected)) != 0 {
+				t.Fatalf("incorrect result, expected=%s, got=%#x", tc.expected, result)
+			}
+		})
+	}
+}
@@ -10,20 +10,29 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
-func teeLeRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doOp(environment, caller, input, runSpan, func(a, b uint64) uint64 {
+func teeLeRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	if isTeeDecimalOp(environment, args) {
+		return doDecimalComparisonOp(environment, caller, args, runSpan, func(cmp int) bool { return cmp <= 0 }, "teeLeRun")
+	}
+	return doOp(environment, caller, args, runSpan, func(a, b uint64) uint64 {
 		return boolToUint64(a <= b)
 	}, "teeLeRun")
 }
 
-func teeLtRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doOp(environment, caller, input, runSpan, func(a, b uint64) uint64 {
+func teeLtRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	if isTeeDecimalOp(environment, args) {
+		return doDecimalComparisonOp(environment, caller, args, runSpan, func(cmp int) bool { return cmp < 0 }, "teeLtRun")
+	}
+	return doOp(environment, caller, args, runSpan, func(a, b uint64) uint64 {
 		return boolToUint64(a < b)
 	}, "teeLtRun")
 }
 
-func teeEqRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doEqNeOp(environment, caller, input, runSpan, func(a, b *big.Int) bool {
+func teeEqRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	if isTeeDecimalOp(environment, args) {
+		return doDecimalComparisonOp(environment, caller, args, runSpan, func(cmp int) bool { return cmp == 0 }, "teeEqRun")
+	}
+	return doEqNeOp(environment, caller, args, runSpan, func(a, b *big.Int) bool {
 		if a.Cmp(b) == 0 {
 			return true
 		} else {
@@ -32,20 +41,29 @@ func teeEqRun(environment EVMEnvironment, caller common.Address, addr common.Add
 	}, "teeEqRun")
 }
 
-func teeGeRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doOp(environment, caller, input, runSpan, func(a, b uint64) uint64 {
+func teeGeRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	if isTeeDecimalOp(environment, args) {
+		return doDecimalComparisonOp(environment, caller, args, runSpan, func(cmp int) bool { return cmp >= 0 }, "teeGeRun")
+	}
+	return doOp(environment, caller, args, runSpan, func(a, b uint64) uint64 {
 		return boolToUint64(a >= b)
 	}, "teeGeRun")
 }
 
-func teeGtRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doOp(environment, caller, input, runSpan, func(a, b uint64) uint64 {
+func teeGtRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	if isTeeDecimalOp(environment, args) {
+		return doDecimalComparisonOp(environment, caller, args, runSpan, func(cmp int) bool { return cmp > 0 }, "teeGtRun")
+	}
+	return doOp(environment, caller, args, runSpan, func(a, b uint64) uint64 {
 		return boolToUint64(a > b)
 	}, "teeGtRun")
 }
 
-func teeNeRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doEqNeOp(environment, caller, input, runSpan, func(a, b *big.Int) bool {
+func teeNeRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	if isTeeDecimalOp(environment, args) {
+		return doDecimalComparisonOp(environment, caller, args, runSpan, func(cmp int) bool { return cmp != 0 }, "teeNeRun")
+	}
+	return doEqNeOp(environment, caller, args, runSpan, func(a, b *big.Int) bool {
 		if a.Cmp(b) != 0 {
 			return true
 		} else {
@@ -54,8 +72,11 @@ func teeNeRun(environment EVMEnvironment, caller common.Address, addr common.Add
 	}, "teeNeRun")
 }
 
-func teeMinRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doOp(environment, caller, input, runSpan, func(a, b uint64) uint64 {
+func teeMinRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	if isTeeDecimalOp(environment, args) {
+		return doDecimalOp(environment, caller, args, runSpan, teeDecimalMin, "teeMinRun")
+	}
+	return doOp(environment, caller, args, runSpan, func(a, b uint64) uint64 {
 		if a >= b {
 			return b
 		} else {
@@ -64,8 +85,11 @@ func teeMinRun(environment EVMEnvironment, caller common.Address, addr common.Ad
 	}, "teeMinRun")
 }
 
-func teeMaxRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	return doOp(environment, caller, input, runSpan, func(a, b uint64) uint64 {
+func teeMaxRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	if isTeeDecimalOp(environment, args) {
+		return doDecimalOp(environment, caller, args, runSpan, teeDecimalMax, "teeMaxRun")
+	}
+	return doOp(environment, caller, args, runSpan, func(a, b uint64) uint64 {
 		if a >= b {
 			return a
 		} else {
@@ -74,10 +98,10 @@ func teeMaxRun(environment EVMEnvironment, caller common.Address, addr common.Ad
 	}, "teeMaxRun")
 }
 
-func teeSelectRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
+func teeSelectRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
 	logger := environment.GetLogger()
 
-	p1, p2, _, h1, h2, h3, err := extract3Operands("teeSelect", environment, input, runSpan)
+	p1, p2, _, h1, h2, h3, err := extract3Operands("teeSelect", environment, args, runSpan)
 	if err != nil {
 		logger.Error("teeSelect", "failed", "err", err)
 		return nil, err
@@ -1,5 +1,19 @@
 package fhevm
 
-func teeComparisonRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	return teeOperationGas("teeComparison", environment, input, environment.FhevmParams().GasCosts.TeeComparison)
+func teeComparisonRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	return teeOperationGas("teeComparison", environment, args, environment.FhevmParams().GasCosts.TeeComparison)
+}
+
+// teeSelect has no scalar byte, its gas depends on the type of the selected operands
+func teeSelectRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	_, second, third, err := get3VerifiedOperands(environment, args)
+	if err != nil {
+		environment.GetLogger().Error("teeSelect RequiredGas() inputs not verified", "err", err)
+		return 0
+	}
+	if second.fheUintType() != third.fheUintType() {
+		environment.GetLogger().Error("teeSelect RequiredGas() operand type mismatch", "second", second.fheUintType(), "third", third.fheUintType())
+		return 0
+	}
+	return environment.FhevmParams().GasCosts.TeeComparison[second.fheUintType()]
 }
@@ -2,9 +2,7 @@ package fhevm
 
 import (
 	"bytes"
-	"encoding/hex"
 	"errors"
-	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
@@ -12,21 +10,14 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
-func teeEncryptRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	input = input[:minInt(33, len(input))]
-
+func teeEncryptRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
 	logger := environment.GetLogger()
-	if len(input) != 33 {
-		msg := "teeEncrypt input len must be 33 bytes"
-		logger.Error(msg, "input", hex.EncodeToString(input), "len", len(input))
-		return nil, errors.New(msg)
-	}
 
-	valueToEncrypt := *new(big.Int).SetBytes(input[0:32])
-	encryptToType := tfhe.FheUintType(input[32])
+	valueToEncrypt := args.word(0).Big()
+	encryptToType := tfhe.FheUintType(args.byte(1))
 	otelDescribeOperandsFheTypes(runSpan, encryptToType)
 
-	teePlaintext := tee.NewTeePlaintext(input[0:32], encryptToType, caller)
+	teePlaintext := tee.NewTeePlaintext(args.word(0).Bytes(), encryptToType, caller)
 
 	ct, err := tee.Encrypt(teePlaintext)
 
@@ -35,8 +26,8 @@ func teeEncryptRun(environment EVMEnvironment, caller common.Address, addr commo
 		return nil, err
 	}
 
-	ctHash := ct.GetHash()
 	importCiphertext(environment, &ct)
+	ctHash := ct.GetHash()
 	if environment.IsCommitting() {
 		logger.Info("teeEncrypt success",
 			"ctHash", ctHash.Hex(),
@@ -45,28 +36,29 @@ func teeEncryptRun(environment EVMEnvironment, caller common.Address, addr commo
 	return ctHash.Bytes(), nil
 }
 
-func teeDecryptRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
-	input = input[:minInt(32, len(input))]
-
+func teeDecryptRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
 	logger := environment.GetLogger()
+	handle := args.word(0)
 	// if not gas estimation and not view function fail if decryptions are disabled in transactions
 	if environment.IsCommitting() && !environment.IsEthCall() && environment.FhevmParams().DisableDecryptionsInTransaction {
 		msg := "decryptions during transaction are disabled"
-		logger.Error(msg, "input", hex.EncodeToString(input))
+		logger.Error(msg, "handle", handle.Hex())
 		return nil, errors.New(msg)
 	}
-	if len(input) != 32 {
-		msg := "decrypt input len must be 32 bytes"
-		logger.Error(msg, "input", hex.EncodeToString(input), "len", len(input))
-		return nil, errors.New(msg)
+	ct, public, err := getDecryptableCiphertext(environment, handle, true)
+	if err != nil {
+		logger.Error("teeDecrypt failed to load a publicly decryptable ciphertext", "err", err)
+		return nil, err
 	}
-	ct := getVerifiedCiphertext(environment, common.BytesToHash(input))
 	if ct == nil {
 		msg := "decrypt unverified handle"
-		logger.Error(msg, "input", hex.EncodeToString(input))
+		logger.Error(msg, "handle", handle.Hex())
 		return nil, errors.New(msg)
 	}
-	otelDescribeOperandsFheTypes(runSpan, ct.fheUintType())
+	otelDescribeOperandsFheTypes(runSpan, ct.Type())
+	if err := verifyUnlocked(environment, ct.GetHash()); err != nil {
+		return nil, err
+	}
 
 	// If we are doing gas estimation, skip decryption and make sure we return the maximum possible value.
 	// We need that, because non-zero bytes cost more than zero bytes in some contexts (e.g. SSTORE or memory operations).
@@ -74,7 +66,15 @@ func teeDecryptRun(environment EVMEnvironment, caller common.Address, addr commo
 		return bytes.Repeat([]byte{0xFF}, 32), nil
 	}
 
-	result, err := tee.Decrypt(ct.ciphertext)
+	// On eth_call, the plaintext is returned to whoever runs the call, so the user must authorize it,
+	// unless the handle is publicly decryptable.
+	if environment.IsEthCall() && !public {
+		if _, err := verifyAuthorization(environment, caller, addr, ct.GetHash(), common.Hash{}, args.authorization); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := tee.Decrypt(ct)
 	if err != nil {
 		logger.Error("teeDecrypt failed", "err", err)
 		return nil, err
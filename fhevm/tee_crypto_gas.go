@@ -1,35 +1,18 @@
 package fhevm
 
 import (
-	"encoding/hex"
-
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
 )
 
-func teeEncryptRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	input = input[:minInt(33, len(input))]
-
-	logger := environment.GetLogger()
-	if len(input) != 33 {
-		logger.Error("teeEncrypt RequiredGas() input len must be 33 bytes", "input", hex.EncodeToString(input), "len", len(input))
-		return 0
-	}
-	encryptToType := tfhe.FheUintType(input[32])
+func teeEncryptRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	encryptToType := tfhe.FheUintType(args.byte(1))
 	return environment.FhevmParams().GasCosts.FheTrivialEncrypt[encryptToType]
 }
 
-func teeDecryptRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-	input = input[:minInt(32, len(input))]
-
-	logger := environment.GetLogger()
-	if len(input) != 32 {
-		logger.Error("teeDecrypt RequiredGas() input len must be 32 bytes", "input", hex.EncodeToString(input), "len", len(input))
-		return 0
-	}
-	ct := getVerifiedCiphertext(environment, common.BytesToHash(input))
+func teeDecryptRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	ct := getVerifiedCiphertext(environment, args.word(0))
 	if ct == nil {
-		logger.Error("teeDecrypt RequiredGas() input doesn't point to verified ciphertext", "input", hex.EncodeToString(input))
+		environment.GetLogger().Error("teeDecrypt RequiredGas() input doesn't point to verified ciphertext", "handle", args.word(0).Hex())
 		return 0
 	}
 	return environment.FhevmParams().GasCosts.TeeDecrypt[ct.fheUintType()]
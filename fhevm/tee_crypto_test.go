@@ -33,7 +33,7 @@ func TestTeeDecryptRun(t *testing.T) {
 				t.Fatalf(err.Error())
 			}
 
-			input := toLibPrecompileInput(signature, false, ct.GetHash())
+			input := toLibPrecompileInputNoScalar(signature, ct.GetHash())
 			out, err := TeeLibRun(environment, addr, addr, input, readOnly)
 			if err != nil {
 				t.Fatalf(err.Error())
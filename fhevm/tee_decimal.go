@@ -0,0 +1,165 @@
+package fhevm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+	"github.com/zama-ai/fhevm-go/tee"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// teeDecimalBits is the width of the integer backing a FheDecimal.
+const teeDecimalBits = 128
+
+// teeDecimalScale is the integer representation of 1 as a FheDecimal.
+var teeDecimalScale = new(big.Int).Exp(big.NewInt(10), big.NewInt(tfhe.FheDecimalPlaces), nil)
+
+// ErrTeeDecimalUnsupported is returned by the TEE operations that are not
+// defined on FheDecimal operands, e.g. teeRem and the bitwise operations.
+var ErrTeeDecimalUnsupported = errors.New("operation not supported on FheDecimal")
+
+// isValidTeeType reports whether t is a type supported by TEE operations,
+// i.e. every FHE type and FheDecimal.
+func isValidTeeType(t byte) bool {
+	return tfhe.IsValidFheType(t) || tfhe.FheUintType(t) == tfhe.FheDecimal
+}
+
+// isTeeDecimalOp reports whether the first operand of a TEE operation is a FheDecimal.
+func isTeeDecimalOp(environment EVMEnvironment, args abiArgs) bool {
+	ct := getVerifiedCiphertext(environment, args.word(0))
+	return ct != nil && ct.fheUintType() == tfhe.FheDecimal
+}
+
+// doDecimalOp is a function to do TEE arithmetic on FheDecimal operands
+// Operands and result are the raw fixed-point integers, i.e. the decimal
+// value times 10^FheDecimalPlaces, and the operator does the rescaling.
+// A scalar operand is a raw fixed-point integer too. Like the integer
+// types, the result wraps at the width of the type.
+func doDecimalOp(
+	environment EVMEnvironment,
+	caller common.Address,
+	args abiArgs,
+	runSpan trace.Span,
+	operator func(a, b *big.Int) *big.Int,
+	op string,
+) ([]byte, error) {
+	return doDecimalOpWithResultType(environment, caller, args, runSpan, operator, tfhe.FheDecimal, op)
+}
+
+// doDecimalComparisonOp compares FheDecimal operands and returns a FheBool.
+func doDecimalComparisonOp(
+	environment EVMEnvironment,
+	caller common.Address,
+	args abiArgs,
+	runSpan trace.Span,
+	predicate func(cmp int) bool,
+	op string,
+) ([]byte, error) {
+	return doDecimalOpWithResultType(environment, caller, args, runSpan, teeDecimalCompare(predicate), tfhe.FheBool, op)
+}
+
+func doDecimalOpWithResultType(
+	environment EVMEnvironment,
+	caller common.Address,
+	args abiArgs,
+	runSpan trace.Span,
+	operator func(a, b *big.Int) *big.Int,
+	resultType tfhe.FheUintType,
+	op string,
+) ([]byte, error) {
+	logger := environment.GetLogger()
+
+	lp, rp, lhs, rhs, isScalar, err := extract2Operands(op, environment, args, runSpan)
+	if err != nil {
+		logger.Error(op, "failed", "err", err)
+		return nil, err
+	}
+
+	// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
+	if !environment.IsCommitting() && !environment.IsEthCall() {
+		return importRandomCiphertext(environment, resultType), nil
+	}
+
+	l := teeTruncate(big.NewInt(0).SetBytes(lp.Value), teeDecimalBits)
+	r := big.NewInt(0).SetBytes(rp.Value)
+	if !isScalar {
+		r = teeTruncate(r, teeDecimalBits)
+	}
+
+	result := teeTruncate(operator(l, r), teeDecimalBits)
+
+	resultBz, err := marshalTfheType(result, resultType)
+	if err != nil {
+		logger.Error(op, "failed", "err", err)
+		return nil, err
+	}
+
+	teePlaintext := tee.NewTeePlaintext(resultBz, resultType, caller)
+
+	resultCt, err := tee.Encrypt(teePlaintext)
+	if err != nil {
+		logger.Error(op, "failed", "err", err)
+		return nil, err
+	}
+	importCiphertext(environment, &resultCt)
+
+	resultHash := resultCt.GetHash()
+	if !isScalar {
+		logger.Info(fmt.Sprintf("%s success", op), "lhs", lhs.hash().Hex(), "rhs", rhs.hash().Hex(), "result", resultHash.Hex())
+	} else {
+		logger.Info(fmt.Sprintf("%s success", op), "lhs", lhs.hash().Hex(), "rhs", r, "result", resultHash.Hex())
+	}
+	return resultHash[:], nil
+}
+
+func teeDecimalAdd(a, b *big.Int) *big.Int {
+	return new(big.Int).Add(a, b)
+}
+
+func teeDecimalSub(a, b *big.Int) *big.Int {
+	return new(big.Int).Sub(a, b)
+}
+
+// teeDecimalCompare returns 1 if the predicate holds for the comparison of
+// its operands, and 0 otherwise.
+func teeDecimalCompare(predicate func(cmp int) bool) func(a, b *big.Int) *big.Int {
+	return func(a, b *big.Int) *big.Int {
+		if predicate(a.Cmp(b)) {
+			return big.NewInt(1)
+		}
+		return big.NewInt(0)
+	}
+}
+
+func teeDecimalMin(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return b
+	}
+	return a
+}
+
+func teeDecimalMax(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
+
+// teeDecimalMul rounds the product down to the closest FheDecimal.
+func teeDecimalMul(a, b *big.Int) *big.Int {
+	product := new(big.Int).Mul(a, b)
+	return product.Quo(product, teeDecimalScale)
+}
+
+// teeDecimalDiv rounds the quotient down to the closest FheDecimal.
+// Division by zero returns the maximum value, as for the integer types.
+func teeDecimalDiv(a, b *big.Int) *big.Int {
+	if b.Sign() == 0 {
+		return big.NewInt(-1)
+	}
+	scaled := new(big.Int).Mul(a, teeDecimalScale)
+	return scaled.Quo(scaled, b)
+}
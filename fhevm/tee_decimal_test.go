@@ -0,0 +1,125 @@
+package fhevm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+func TestTeeDecimalArithmetic(t *testing.T) {
+	maxDecimal := teeTruncate(big.NewInt(-1), teeDecimalBits)
+
+	testcases := []struct {
+		description string
+		signature   string
+		lhs         *big.Int
+		rhs         *big.Int
+		expected    *big.Int
+	}{
+		{"1.5 + 2.25", "teeAdd(uint256,uint256,bytes1)", mustBigInt("1500000000000000000"), mustBigInt("2250000000000000000"), mustBigInt("3750000000000000000")},
+		{"2.25 - 1.5", "teeSub(uint256,uint256,bytes1)", mustBigInt("2250000000000000000"), mustBigInt("1500000000000000000"), mustBigInt("750000000000000000")},
+		{"1.5 - 2.25 wraps", "teeSub(uint256,uint256,bytes1)", mustBigInt("1500000000000000000"), mustBigInt("2250000000000000000"), new(big.Int).Sub(maxDecimal, mustBigInt("749999999999999999"))},
+		{"1.5 * 2.25", "teeMul(uint256,uint256,bytes1)", mustBigInt("1500000000000000000"), mustBigInt("2250000000000000000"), mustBigInt("3375000000000000000")},
+		{"1e9 * 1e9", "teeMul(uint256,uint256,bytes1)", mustBigInt("1000000000000000000000000000"), mustBigInt("1000000000000000000000000000"), mustBigInt("1000000000000000000000000000000000000")},
+		{"1e-18 * 0.5 rounds down", "teeMul(uint256,uint256,bytes1)", big.NewInt(1), mustBigInt("500000000000000000"), big.NewInt(0)},
+		{"3.375 / 1.5", "teeDiv(uint256,uint256,bytes1)", mustBigInt("3375000000000000000"), mustBigInt("1500000000000000000"), mustBigInt("2250000000000000000")},
+		{"1 / 3 rounds down", "teeDiv(uint256,uint256,bytes1)", mustBigInt("1000000000000000000"), mustBigInt("3000000000000000000"), mustBigInt("333333333333333333")},
+		{"1 / 0", "teeDiv(uint256,uint256,bytes1)", mustBigInt("1000000000000000000"), big.NewInt(0), maxDecimal},
+	}
+	for _, tc := range testcases {
+		t.Run(fmt.Sprintf("teeDecimal %s", tc.description), func(t *testing.T) {
+			teeOperationHelper(t, tfhe.FheDecimal, tc.lhs, tc.rhs, tc.expected, tc.signature, false)
+			// scalar operations
+			teeOperationHelper(t, tfhe.FheDecimal, tc.lhs, tc.rhs, tc.expected, tc.signature, true)
+		})
+	}
+}
+
+func TestTeeDecimalComparison(t *testing.T) {
+	// Both values are above 2^64 and equal modulo 2^64.
+	small := mustBigInt("20000000000000000000")
+	large := new(big.Int).Add(small, new(big.Int).Lsh(big.NewInt(1), 64))
+
+	// Comparisons return a FheBool, teeMin and teeMax a FheDecimal.
+	testcases := []struct {
+		description string
+		signature   string
+		lhs         *big.Int
+		rhs         *big.Int
+		expected    *big.Int
+		resultType  tfhe.FheUintType
+	}{
+		{"small < large", "teeLt(uint256,uint256,bytes1)", small, large, big.NewInt(1), tfhe.FheBool},
+		{"large < small", "teeLt(uint256,uint256,bytes1)", large, small, big.NewInt(0), tfhe.FheBool},
+		{"small <= large", "teeLe(uint256,uint256,bytes1)", small, large, big.NewInt(1), tfhe.FheBool},
+		{"large > small", "teeGt(uint256,uint256,bytes1)", large, small, big.NewInt(1), tfhe.FheBool},
+		{"small >= large", "teeGe(uint256,uint256,bytes1)", small, large, big.NewInt(0), tfhe.FheBool},
+		{"small == large", "teeEq(uint256,uint256,bytes1)", small, large, big.NewInt(0), tfhe.FheBool},
+		{"small != large", "teeNe(uint256,uint256,bytes1)", small, large, big.NewInt(1), tfhe.FheBool},
+		{"min(small, large)", "teeMin(uint256,uint256,bytes1)", small, large, small, tfhe.FheDecimal},
+		{"max(small, large)", "teeMax(uint256,uint256,bytes1)", small, large, large, tfhe.FheDecimal},
+	}
+	for _, tc := range testcases {
+		t.Run(fmt.Sprintf("teeDecimal %s", tc.description), func(t *testing.T) {
+			teeOperationWithResultTypeHelper(t, tfhe.FheDecimal, tc.resultType, tc.lhs, tc.rhs, tc.expected, tc.signature, false)
+			// scalar operations
+			teeOperationWithResultTypeHelper(t, tfhe.FheDecimal, tc.resultType, tc.lhs, tc.rhs, tc.expected, tc.signature, true)
+		})
+	}
+	if gas := DefaultGasCosts().TeeComparison[tfhe.FheDecimal]; gas == 0 {
+		t.Fatalf("expected a gas cost for FheDecimal comparisons")
+	}
+}
+
+func TestTeeDecimalUnsupportedOperations(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	environment.commit = true
+	addr := common.Address{}
+	value := mustBigInt("20000000000000000000")
+	lhs, err := importTeePlaintextToEVM(environment, environment.depth, value, tfhe.FheDecimal)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	rhs, err := importTeePlaintextToEVM(environment, environment.depth, value, tfhe.FheDecimal)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for _, signature := range []string{"teeRem(uint256,uint256,bytes1)", "teeBitAnd(uint256,uint256,bytes1)", "teeBitOr(uint256,uint256,bytes1)", "teeBitXor(uint256,uint256,bytes1)", "teeShl(uint256,uint256,bytes1)"} {
+		input := toLibPrecompileInput(signature, false, lhs.GetHash(), rhs.GetHash())
+		if _, err := TeeLibRun(environment, addr, addr, input, false); !errors.Is(err, ErrTeeDecimalUnsupported) {
+			t.Fatalf("expected %s to reject FheDecimal operands, got %v", signature, err)
+		}
+	}
+	for _, signature := range []string{"teeNeg(uint256)", "teeNot(uint256)"} {
+		input := toLibPrecompileInputNoScalar(signature, lhs.GetHash())
+		if _, err := TeeLibRun(environment, addr, addr, input, false); !errors.Is(err, ErrTeeDecimalUnsupported) {
+			t.Fatalf("expected %s to reject a FheDecimal operand, got %v", signature, err)
+		}
+	}
+}
+
+func TestTeeDecimalCast(t *testing.T) {
+	signature := "teeCast(uint256,bytes1)"
+
+	testcases := []struct {
+		description string
+		intyp       tfhe.FheUintType
+		outtyp      tfhe.FheUintType
+		input       uint64
+		expected    uint64
+	}{
+		{"TeeUint8CastDecimal", tfhe.FheUint8, tfhe.FheDecimal, 3, 3000000000000000000},
+		{"TeeDecimalCastUint8", tfhe.FheDecimal, tfhe.FheUint8, 3500000000000000000, 3},
+		{"TeeDecimalCastBool", tfhe.FheDecimal, tfhe.FheBool, 1, 1},
+	}
+	for _, tc := range testcases {
+		t.Run(fmt.Sprintf("teeCast with %s", tc.description), func(t *testing.T) {
+			teeCastHelper(t, tc.intyp, tc.outtyp, tc.input, tc.expected, signature)
+		})
+	}
+}
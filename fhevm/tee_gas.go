@@ -1,29 +1,21 @@
 package fhevm
 
 import (
-	"encoding/hex"
 	"fmt"
 
 	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
 )
 
-func teeOperationGas(op string, environment EVMEnvironment, input []byte, gasCosts map[tfhe.FheUintType]uint64) uint64 {
-	input = input[:minInt(65, len(input))]
-
+func teeOperationGas(op string, environment EVMEnvironment, args abiArgs, gasCosts map[tfhe.FheUintType]uint64) uint64 {
 	logger := environment.GetLogger()
 
-	isScalar, err := isScalarOp(input)
-	if err != nil {
-		logger.Error(fmt.Sprintf("%s can not detect if operator was meant to be scalar", op), "err", err, "input", hex.EncodeToString(input))
-		return 0
-	}
-
 	var lhs, rhs *verifiedCiphertext
+	var err error
 
-	if !isScalar {
-		lhs, rhs, err = get2VerifiedOperands(environment, input)
+	if !isScalarOp(args) {
+		lhs, rhs, err = get2VerifiedOperands(environment, args)
 		if err != nil {
-			logger.Error(op, "RequiredGas() ciphertext inputs not verified", "err", err, "input", hex.EncodeToString(input))
+			logger.Error(op, "RequiredGas() ciphertext inputs not verified", "err", err, "lhs", args.word(0).Hex(), "rhs", args.word(1).Hex())
 			return 0
 		}
 		if lhs.fheUintType() != rhs.fheUintType() {
@@ -31,12 +23,21 @@ func teeOperationGas(op string, environment EVMEnvironment, input []byte, gasCos
 			return 0
 		}
 	} else {
-		lhs, _, err = getScalarOperands(environment, input)
+		lhs, _, err = getScalarOperands(environment, args)
 		if err != nil {
-			logger.Error("scalar inputs not verified", "err", err, "input", hex.EncodeToString(input))
+			logger.Error("scalar inputs not verified", "err", err, "lhs", args.word(0).Hex())
 			return 0
 		}
 	}
 
 	return gasCosts[lhs.fheUintType()]
 }
+
+func teeUnaryOperationGas(op string, environment EVMEnvironment, args abiArgs, gasCosts map[tfhe.FheUintType]uint64) uint64 {
+	ct := getVerifiedCiphertext(environment, args.word(0))
+	if ct == nil {
+		environment.GetLogger().Error(fmt.Sprintf("%s input not verified", op), "handle", args.word(0).Hex())
+		return 0
+	}
+	return gasCosts[ct.fheUintType()]
+}
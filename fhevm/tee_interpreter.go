@@ -2,7 +2,6 @@ package fhevm
 
 import (
 	"encoding/binary"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/big"
@@ -15,21 +14,27 @@ import (
 
 // doOp is a function to do TEE operations
 // We use uint64 because we need to use only types smaller than uint64
+// FheDecimal operands are 128-bit wide, so they are rejected: operations
+// defined on decimals use doDecimalOp instead.
 func doOp(
 	environment EVMEnvironment,
 	caller common.Address,
-	input []byte,
+	args abiArgs,
 	runSpan trace.Span,
 	operator func(a, b uint64) uint64,
 	op string,
 ) ([]byte, error) {
 	logger := environment.GetLogger()
 
-	lp, rp, lhs, rhs, isScalar, err := extract2Operands(op, environment, input, runSpan)
+	lp, rp, lhs, rhs, isScalar, err := extract2Operands(op, environment, args, runSpan)
 	if err != nil {
 		logger.Error(op, "failed", "err", err)
 		return nil, err
 	}
+	if lhs.fheUintType() == tfhe.FheDecimal {
+		logger.Error(op, "failed", "err", ErrTeeDecimalUnsupported)
+		return nil, ErrTeeDecimalUnsupported
+	}
 
 	// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
 	if !environment.IsCommitting() && !environment.IsEthCall() {
@@ -84,14 +89,14 @@ func doOp(
 func doEqNeOp(
 	environment EVMEnvironment,
 	caller common.Address,
-	input []byte,
+	args abiArgs,
 	runSpan trace.Span,
 	operator func(a, b *big.Int) bool,
 	op string,
 ) ([]byte, error) {
 	logger := environment.GetLogger()
 
-	lp, rp, lhs, rhs, isScalar, err := extract2Operands(op, environment, input, runSpan)
+	lp, rp, lhs, rhs, isScalar, err := extract2Operands(op, environment, args, runSpan)
 	if err != nil {
 		logger.Error(op, "failed", "err", err)
 		return nil, err
@@ -144,21 +149,26 @@ func doEqNeOp(
 
 // doShiftOp is a function to do TEE bit shift operations
 // We use uint64 because we need to use only types smaller than uint64
+// FheDecimal operands are rejected, as by doOp.
 func doShiftOp(
 	environment EVMEnvironment,
 	caller common.Address,
-	input []byte,
+	args abiArgs,
 	runSpan trace.Span,
 	operator func(a, b uint64, typ tfhe.FheUintType) (uint64, error),
 	op string,
 ) ([]byte, error) {
 	logger := environment.GetLogger()
 
-	lp, rp, lhs, rhs, isScalar, err := extract2Operands(op, environment, input, runSpan)
+	lp, rp, lhs, rhs, isScalar, err := extract2Operands(op, environment, args, runSpan)
 	if err != nil {
 		logger.Error(op, "failed", "err", err)
 		return nil, err
 	}
+	if lhs.fheUintType() == tfhe.FheDecimal {
+		logger.Error(op, "failed", "err", ErrTeeDecimalUnsupported)
+		return nil, ErrTeeDecimalUnsupported
+	}
 
 	// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
 	if !environment.IsCommitting() && !environment.IsEthCall() {
@@ -213,21 +223,26 @@ func doShiftOp(
 
 // doNegNotOp is a generic function to do TEE bit inverse operations
 // We use uint64 because we need to use only types smaller than uint64
+// FheDecimal operands are rejected, as by doOp.
 func doNegNotOp(
 	environment EVMEnvironment,
 	caller common.Address,
-	input []byte,
+	args abiArgs,
 	runSpan trace.Span,
 	operator func(a uint64) uint64,
 	op string,
 ) ([]byte, error) {
 	logger := environment.GetLogger()
 
-	cp, ct, err := extract1Operands(op, environment, input, runSpan)
+	cp, ct, err := extract1Operands(op, environment, args, runSpan)
 	if err != nil {
 		logger.Error(op, "failed", "err", err)
 		return nil, err
 	}
+	if cp.FheUintType == tfhe.FheDecimal {
+		logger.Error(op, "failed", "err", ErrTeeDecimalUnsupported)
+		return nil, ErrTeeDecimalUnsupported
+	}
 
 	// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
 	if !environment.IsCommitting() && !environment.IsEthCall() {
@@ -272,15 +287,245 @@ func doNegNotOp(
 	return resultHash[:], nil
 }
 
-func extract1Operands(op string, environment EVMEnvironment, input []byte, runSpan trace.Span) (*tee.TeePlaintext, *verifiedCiphertext, error) {
-	input = input[:minInt(32, len(input))]
+// doUnaryBigOp is a generic function to do TEE unary operations on every width
+// We use big.Int because it must work for FheUint128 and FheUint160 too
+func doUnaryBigOp(
+	environment EVMEnvironment,
+	caller common.Address,
+	args abiArgs,
+	runSpan trace.Span,
+	operator func(a *big.Int, bits uint) *big.Int,
+	op string,
+) ([]byte, error) {
+	logger := environment.GetLogger()
+
+	cp, ct, err := extract1Operands(op, environment, args, runSpan)
+	if err != nil {
+		logger.Error(op, "failed", "err", err)
+		return nil, err
+	}
+
+	// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
+	if !environment.IsCommitting() && !environment.IsEthCall() {
+		return importRandomCiphertext(environment, cp.FheUintType), nil
+	}
+
+	bits, err := teeTypeBits(cp.FheUintType)
+	if err != nil {
+		logger.Error(op, "failed", "err", err)
+		return nil, err
+	}
+
+	c := big.NewInt(0).SetBytes(cp.Value)
+
+	result := teeTruncate(operator(c, bits), bits)
+
+	resultBz, err := marshalTfheType(result, cp.FheUintType)
+	if err != nil {
+		logger.Error(op, "failed", "err", err)
+		return nil, err
+	}
+
+	teePlaintext := tee.NewTeePlaintext(resultBz, cp.FheUintType, caller)
+
+	resultCt, err := tee.Encrypt(teePlaintext)
+	if err != nil {
+		logger.Error(op, "failed", "err", err)
+		return nil, err
+	}
+	importCiphertext(environment, &resultCt)
+
+	resultHash := resultCt.GetHash()
+	logger.Info(fmt.Sprintf("%s success", op), "ct", ct.hash().Hex(), "result", resultHash.Hex())
+	return resultHash[:], nil
+}
+
+// doBitTestOp is a function to do the TEE bit test operation
+// The bit index is taken modulo the bit width of the operand, as for shifts.
+func doBitTestOp(
+	environment EVMEnvironment,
+	caller common.Address,
+	args abiArgs,
+	runSpan trace.Span,
+	op string,
+) ([]byte, error) {
+	logger := environment.GetLogger()
+
+	lp, rp, lhs, rhs, isScalar, err := extract2Operands(op, environment, args, runSpan)
+	if err != nil {
+		logger.Error(op, "failed", "err", err)
+		return nil, err
+	}
+
+	// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
+	if !environment.IsCommitting() && !environment.IsEthCall() {
+		return importRandomCiphertext(environment, tfhe.FheBool), nil
+	}
+
+	bits, err := teeTypeBits(lp.FheUintType)
+	if err != nil {
+		logger.Error(op, "failed", "err", err)
+		return nil, err
+	}
+
+	l := big.NewInt(0).SetBytes(lp.Value)
+	r := big.NewInt(0).SetBytes(rp.Value)
+	index := big.NewInt(0).Mod(r, big.NewInt(int64(bits))).Uint64()
+
+	result := uint64(l.Bit(int(index)))
+
+	resultBz, err := marshalTfheType(result, tfhe.FheBool)
+	if err != nil {
+		logger.Error(op, "failed", "err", err)
+		return nil, err
+	}
+
+	teePlaintext := tee.NewTeePlaintext(resultBz, tfhe.FheBool, caller)
+
+	resultCt, err := tee.Encrypt(teePlaintext)
+	if err != nil {
+		logger.Error(op, "failed", "err", err)
+		return nil, err
+	}
+	importCiphertext(environment, &resultCt)
+
+	resultHash := resultCt.GetHash()
+	if !isScalar {
+		logger.Info(fmt.Sprintf("%s success", op), "lhs", lhs.hash().Hex(), "rhs", rhs.hash().Hex(), "result", resultHash.Hex())
+	} else {
+		logger.Info(fmt.Sprintf("%s success", op), "lhs", lhs.hash().Hex(), "rhs", r, "result", resultHash.Hex())
+	}
+	return resultHash[:], nil
+}
+
+// doWideOp is a function to do TEE operations whose intermediate result
+// doesn't fit in the operand width, such as the high half of a product.
+// We use big.Int because the intermediate result can be up to 256 bits wide.
+func doWideOp(
+	environment EVMEnvironment,
+	caller common.Address,
+	args abiArgs,
+	runSpan trace.Span,
+	operator func(a, b *big.Int, bits uint) *big.Int,
+	op string,
+) ([]byte, error) {
+	logger := environment.GetLogger()
+
+	lp, rp, lhs, rhs, isScalar, err := extract2Operands(op, environment, args, runSpan)
+	if err != nil {
+		logger.Error(op, "failed", "err", err)
+		return nil, err
+	}
+
+	bits, err := teeWideTypeBits(lp.FheUintType)
+	if err != nil {
+		logger.Error(op, "failed", "err", err)
+		return nil, err
+	}
+
+	// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
+	if !environment.IsCommitting() && !environment.IsEthCall() {
+		return importRandomCiphertext(environment, lhs.fheUintType()), nil
+	}
+
+	l := big.NewInt(0).SetBytes(lp.Value)
+	r := big.NewInt(0).SetBytes(rp.Value)
+
+	result := teeTruncate(operator(l, r, bits), bits)
+
+	resultBz, err := marshalTfheType(result, lp.FheUintType)
+	if err != nil {
+		logger.Error(op, "failed", "err", err)
+		return nil, err
+	}
+
+	teePlaintext := tee.NewTeePlaintext(resultBz, lp.FheUintType, caller)
+
+	resultCt, err := tee.Encrypt(teePlaintext)
+	if err != nil {
+		logger.Error(op, "failed", "err", err)
+		return nil, err
+	}
+	importCiphertext(environment, &resultCt)
+
+	resultHash := resultCt.GetHash()
+	if !isScalar {
+		logger.Info(fmt.Sprintf("%s success", op), "lhs", lhs.hash().Hex(), "rhs", rhs.hash().Hex(), "result", resultHash.Hex())
+	} else {
+		logger.Info(fmt.Sprintf("%s success", op), "lhs", lhs.hash().Hex(), "rhs", r, "result", resultHash.Hex())
+	}
+	return resultHash[:], nil
+}
+
+// doMulDivOp is a function to do the TEE a * b / c operation
+// The product is computed at full precision, so it never overflows before the division.
+// The quotient is then truncated to the type of a. Division by zero returns the
+// maximum value of the type, as tfhe-rs does, so that it doesn't leak c through a revert.
+func doMulDivOp(
+	environment EVMEnvironment,
+	caller common.Address,
+	args abiArgs,
+	runSpan trace.Span,
+	op string,
+) ([]byte, error) {
+	logger := environment.GetLogger()
+
+	ap, bp, cp, a, err := extractMulDivOperands(op, environment, args, runSpan)
+	if err != nil {
+		logger.Error(op, "failed", "err", err)
+		return nil, err
+	}
+
+	bits, err := teeWideTypeBits(ap.FheUintType)
+	if err != nil {
+		logger.Error(op, "failed", "err", err)
+		return nil, err
+	}
+
+	// If we are doing gas estimation, skip execution and insert a random ciphertext as a result.
+	if !environment.IsCommitting() && !environment.IsEthCall() {
+		return importRandomCiphertext(environment, a.fheUintType()), nil
+	}
+
+	x := big.NewInt(0).SetBytes(ap.Value)
+	y := big.NewInt(0).SetBytes(bp.Value)
+	z := big.NewInt(0).SetBytes(cp.Value)
+
+	var result *big.Int
+	if z.Sign() == 0 {
+		result = teeTruncate(big.NewInt(-1), bits)
+	} else {
+		product := big.NewInt(0).Mul(x, y)
+		result = teeTruncate(product.Quo(product, z), bits)
+	}
+
+	resultBz, err := marshalTfheType(result, ap.FheUintType)
+	if err != nil {
+		logger.Error(op, "failed", "err", err)
+		return nil, err
+	}
+
+	teePlaintext := tee.NewTeePlaintext(resultBz, ap.FheUintType, caller)
+
+	resultCt, err := tee.Encrypt(teePlaintext)
+	if err != nil {
+		logger.Error(op, "failed", "err", err)
+		return nil, err
+	}
+	importCiphertext(environment, &resultCt)
+
+	resultHash := resultCt.GetHash()
+	logger.Info(fmt.Sprintf("%s success", op), "a", a.hash().Hex(), "result", resultHash.Hex())
+	return resultHash[:], nil
+}
 
+func extract1Operands(op string, environment EVMEnvironment, args abiArgs, runSpan trace.Span) (*tee.TeePlaintext, *verifiedCiphertext, error) {
 	logger := environment.GetLogger()
 
-	ct := getVerifiedCiphertext(environment, common.BytesToHash(input[0:32]))
+	ct := getVerifiedCiphertext(environment, args.word(0))
 	if ct == nil {
-		msg := "fheNeg input not verified"
-		logger.Error(msg, msg, "input", hex.EncodeToString(input))
+		msg := fmt.Sprintf("%s input not verified", op)
+		logger.Error(msg, "handle", args.word(0).Hex())
 		return nil, nil, errors.New(msg)
 	}
 	otelDescribeOperandsFheTypes(runSpan, ct.fheUintType())
@@ -294,24 +539,17 @@ func extract1Operands(op string, environment EVMEnvironment, input []byte, runSp
 	return &cp, ct, nil
 }
 
-func extract2Operands(op string, environment EVMEnvironment, input []byte, runSpan trace.Span) (*tee.TeePlaintext, *tee.TeePlaintext, *verifiedCiphertext, *verifiedCiphertext, bool, error) {
-	input = input[:minInt(65, len(input))]
-
+func extract2Operands(op string, environment EVMEnvironment, args abiArgs, runSpan trace.Span) (*tee.TeePlaintext, *tee.TeePlaintext, *verifiedCiphertext, *verifiedCiphertext, bool, error) {
 	logger := environment.GetLogger()
 
-	isScalar, err := isScalarOp(input)
-	if err != nil {
-		logger.Error(fmt.Sprintf("%s can not detect if operator is meant to be scalar", op), "err", err, "input", hex.EncodeToString(input))
-		return nil, nil, nil, nil, false, err
-	}
-
+	isScalar := isScalarOp(args)
 	if !isScalar {
-		lhs, rhs, err := get2VerifiedOperands(environment, input)
-		otelDescribeOperands(runSpan, encryptedOperand(*lhs), encryptedOperand(*rhs))
+		lhs, rhs, err := get2VerifiedOperands(environment, args)
 		if err != nil {
-			logger.Error(fmt.Sprintf("%s inputs not verified", op), "err", err, "input", hex.EncodeToString(input))
+			logger.Error(fmt.Sprintf("%s inputs not verified", op), "err", err, "lhs", args.word(0).Hex(), "rhs", args.word(1).Hex())
 			return nil, nil, nil, nil, isScalar, err
 		}
+		otelDescribeOperands(runSpan, encryptedOperand(*lhs), encryptedOperand(*rhs))
 		if lhs.fheUintType() != rhs.fheUintType() {
 			logger.Error(fmt.Sprintf("%s operand type mismatch", op), "lhs", lhs.fheUintType(), "rhs", rhs.fheUintType())
 			return nil, nil, lhs, rhs, isScalar, errors.New("operand type mismatch")
@@ -331,12 +569,12 @@ func extract2Operands(op string, environment EVMEnvironment, input []byte, runSp
 
 		return &lp, &rp, lhs, rhs, isScalar, nil
 	} else {
-		lhs, rhs, err := getScalarOperands(environment, input)
-		otelDescribeOperands(runSpan, encryptedOperand(*lhs), plainOperand(*rhs))
+		lhs, rhs, err := getScalarOperands(environment, args)
 		if err != nil {
-			logger.Error(fmt.Sprintf("%s inputs not verified", op), "err", err, "input", hex.EncodeToString(input))
+			logger.Error(fmt.Sprintf("%s inputs not verified", op), "err", err, "lhs", args.word(0).Hex())
 			return nil, nil, nil, nil, isScalar, err
 		}
+		otelDescribeOperands(runSpan, encryptedOperand(*lhs), plainOperand(*rhs))
 
 		rp := tee.NewTeePlaintext(rhs.Bytes(), tfhe.FheUint128, common.Address{})
 
@@ -351,17 +589,15 @@ func extract2Operands(op string, environment EVMEnvironment, input []byte, runSp
 
 }
 
-func extract3Operands(op string, environment EVMEnvironment, input []byte, runSpan trace.Span) (*tee.TeePlaintext, *tee.TeePlaintext, *tee.TeePlaintext, *verifiedCiphertext, *verifiedCiphertext, *verifiedCiphertext, error) {
-	input = input[:minInt(96, len(input))]
-
+func extract3Operands(op string, environment EVMEnvironment, args abiArgs, runSpan trace.Span) (*tee.TeePlaintext, *tee.TeePlaintext, *tee.TeePlaintext, *verifiedCiphertext, *verifiedCiphertext, *verifiedCiphertext, error) {
 	logger := environment.GetLogger()
 
-	fhs, shs, ths, err := get3VerifiedOperands(environment, input)
-	otelDescribeOperands(runSpan, encryptedOperand(*fhs), encryptedOperand(*shs), encryptedOperand(*ths))
+	fhs, shs, ths, err := get3VerifiedOperands(environment, args)
 	if err != nil {
-		logger.Error(fmt.Sprintf("%s inputs not verified", op), "err", err, "input", hex.EncodeToString(input))
+		logger.Error(fmt.Sprintf("%s inputs not verified", op), "err", err)
 		return nil, nil, nil, nil, nil, nil, err
 	}
+	otelDescribeOperands(runSpan, encryptedOperand(*fhs), encryptedOperand(*shs), encryptedOperand(*ths))
 	if shs.fheUintType() != ths.fheUintType() {
 		logger.Error(fmt.Sprintf("%s operand type mismatch", op), "shs", shs.fheUintType(), "ths", ths.fheUintType())
 		return nil, nil, nil, nil, nil, nil, errors.New("operand type mismatch")
@@ -388,6 +624,62 @@ func extract3Operands(op string, environment EVMEnvironment, input []byte, runSp
 	return &fp, &sp, &tp, fhs, shs, ths, nil
 }
 
+// extractMulDivOperands decrypts the operands of a * b / c.
+// The arguments are three 256-bit values followed by a byte of flags: bit 0 set
+// means b is a plaintext scalar and bit 1 set means c is a plaintext scalar.
+// Scalars are used at full precision. Encrypted b and c must have the type of a.
+func extractMulDivOperands(op string, environment EVMEnvironment, args abiArgs, runSpan trace.Span) (*tee.TeePlaintext, *tee.TeePlaintext, *tee.TeePlaintext, *verifiedCiphertext, error) {
+	logger := environment.GetLogger()
+
+	isScalarB := args.byte(3)&1 != 0
+	isScalarC := args.byte(3)&2 != 0
+
+	a := getVerifiedCiphertext(environment, args.word(0))
+	if a == nil {
+		logger.Error(fmt.Sprintf("%s inputs not verified", op), "a", args.word(0).Hex())
+		return nil, nil, nil, nil, errors.New("unverified ciphertext handle")
+	}
+
+	operands := []operand{encryptedOperand(*a)}
+	plaintexts := make([]*tee.TeePlaintext, 0, 3)
+	ap, err := tee.Decrypt(a.ciphertext)
+	if err != nil {
+		logger.Error(fmt.Sprintf("%s failed", op), "err", err)
+		return nil, nil, nil, a, err
+	}
+	plaintexts = append(plaintexts, &ap)
+
+	for i, isScalar := range []bool{isScalarB, isScalarC} {
+		word := args.word(i + 1)
+		if isScalar {
+			value := word.Big()
+			operands = append(operands, plainOperand(*value))
+			pt := tee.NewTeePlaintext(value.Bytes(), tfhe.FheUint128, common.Address{})
+			plaintexts = append(plaintexts, &pt)
+			continue
+		}
+		ct := getVerifiedCiphertext(environment, word)
+		if ct == nil {
+			logger.Error(fmt.Sprintf("%s inputs not verified", op), "operand", word.Hex())
+			return nil, nil, nil, a, errors.New("unverified ciphertext handle")
+		}
+		if ct.fheUintType() != a.fheUintType() {
+			logger.Error(fmt.Sprintf("%s operand type mismatch", op), "a", a.fheUintType(), "operand", ct.fheUintType())
+			return nil, nil, nil, a, errors.New("operand type mismatch")
+		}
+		operands = append(operands, encryptedOperand(*ct))
+		pt, err := tee.Decrypt(ct.ciphertext)
+		if err != nil {
+			logger.Error(fmt.Sprintf("%s failed", op), "err", err)
+			return nil, nil, nil, a, err
+		}
+		plaintexts = append(plaintexts, &pt)
+	}
+	otelDescribeOperands(runSpan, operands...)
+
+	return plaintexts[0], plaintexts[1], plaintexts[2], a, nil
+}
+
 // marshalTfheType converts a any to a byte slice
 func marshalTfheType(value any, typ tfhe.FheUintType) ([]byte, error) {
 	switch value := any(value).(type) {
@@ -415,6 +707,10 @@ func marshalTfheType(value any, typ tfhe.FheUintType) ([]byte, error) {
 			resultBz := make([]byte, 8)
 			binary.BigEndian.PutUint64(resultBz, value)
 			return resultBz, nil
+		case tfhe.FheUint128, tfhe.FheDecimal:
+			resultBz := make([]byte, 16)
+			binary.BigEndian.PutUint64(resultBz[8:], value)
+			return resultBz, nil
 		case tfhe.FheUint160:
 			resultBz := make([]byte, 8)
 			binary.BigEndian.PutUint64(resultBz, value)
@@ -446,3 +742,43 @@ func boolToUint64(b bool) uint64 {
 	}
 	return 0 // false converts to 0
 }
+
+// teeTypeBits returns the bit width of a FheUintType
+func teeTypeBits(typ tfhe.FheUintType) (uint, error) {
+	switch typ {
+	case tfhe.FheBool:
+		return 1, nil
+	case tfhe.FheUint4:
+		return 4, nil
+	case tfhe.FheUint8:
+		return 8, nil
+	case tfhe.FheUint16:
+		return 16, nil
+	case tfhe.FheUint32:
+		return 32, nil
+	case tfhe.FheUint64:
+		return 64, nil
+	case tfhe.FheUint128:
+		return 128, nil
+	case tfhe.FheUint160:
+		return 160, nil
+	default:
+		return 0, fmt.Errorf("unsupported FheUintType: %s", typ)
+	}
+}
+
+// teeWideTypeBits returns the bit width of a FheUintType that supports wide
+// arithmetic, i.e. every integer type up to FheUint128
+func teeWideTypeBits(typ tfhe.FheUintType) (uint, error) {
+	if typ == tfhe.FheBool || typ == tfhe.FheUint160 {
+		return 0, fmt.Errorf("unsupported FheUintType: %s", typ)
+	}
+	return teeTypeBits(typ)
+}
+
+// teeTruncate keeps the low bits of value, the way an overflowing operation
+// on a bits-wide unsigned integer would
+func teeTruncate(value *big.Int, bits uint) *big.Int {
+	mask := big.NewInt(0).Sub(big.NewInt(0).Lsh(big.NewInt(1), bits), big.NewInt(1))
+	return mask.And(mask, value)
+}
@@ -1,20 +1,16 @@
 package fhevm
 
 import (
-	"math/big"
-
 	"github.com/ethereum/go-ethereum/common"
 	"go.opentelemetry.io/otel/trace"
 )
 
-func teePadGasRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
-
-	input = input[:minInt(32, len(input))]
+func teePadGasRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
 
 	gasLimit := environment.GasLimit()
 	consumedGas := gasLimit - suppliedGas
 
-	padTo := new(big.Int).SetBytes(input)
+	padTo := args.word(0).Big()
 
 	if consumedGas > padTo.Uint64() {
 		return 0
@@ -24,6 +20,6 @@ func teePadGasRequiredGas(environment EVMEnvironment, suppliedGas uint64, input
 
 }
 
-func teePadGasRun(environment EVMEnvironment, caller common.Address, addr common.Address, input []byte, readOnly bool, runSpan trace.Span) ([]byte, error) {
+func teePadGasRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
 	return nil, nil
 }
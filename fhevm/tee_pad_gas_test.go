@@ -24,7 +24,7 @@ func TestTeePadRequiredGas(t *testing.T) {
 			environment.gasLimit = 100
 
 			padToBytes := tc.padTo.Bytes()
-			result := teePadGasRequiredGas(environment, tc.suppliedGas, padToBytes)
+			result := teePadGasRequiredGas(environment, tc.suppliedGas, abiArgs{values: [][]byte{padToBytes}})
 			if result != tc.expected {
 				t.Fatalf("incorrect result, expected=%d, got=%d", tc.expected, result)
 			}
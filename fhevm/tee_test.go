@@ -10,9 +10,21 @@ import (
 	"github.com/zama-ai/fhevm-go/tee"
 )
 
+// teeTestingT is the subset of testing.T used by the helpers below, so that
+// they can also be called from rapid property tests.
+type teeTestingT interface {
+	Fatalf(format string, args ...any)
+}
+
 // teeOperationHelper is a helper function to test TEE operations,
 // which are passed into the last argument as a function.
-func teeOperationHelper(t *testing.T, fheUintType tfhe.FheUintType, lhs, rhs, expected any, signature string, isScalar bool) {
+func teeOperationHelper(t teeTestingT, fheUintType tfhe.FheUintType, lhs, rhs, expected any, signature string, isScalar bool) {
+	teeOperationWithResultTypeHelper(t, fheUintType, fheUintType, lhs, rhs, expected, signature, isScalar)
+}
+
+// teeOperationWithResultTypeHelper is like teeOperationHelper, for TEE
+// operations whose result type differs from the operand type.
+func teeOperationWithResultTypeHelper(t teeTestingT, fheUintType tfhe.FheUintType, resultType tfhe.FheUintType, lhs, rhs, expected any, signature string, isScalar bool) {
 	depth := 1
 	environment := newTestEVMEnvironment()
 	environment.depth = depth
@@ -51,22 +63,74 @@ func teeOperationHelper(t *testing.T, fheUintType tfhe.FheUintType, lhs, rhs, ex
 		t.Fatalf(err.Error())
 	}
 
-	if teePlaintext.FheUintType != fheUintType {
-		t.Fatalf("incorrect fheUintType, expected=%s, got=%s", fheUintType, teePlaintext.FheUintType)
+	if teePlaintext.FheUintType != resultType {
+		t.Fatalf("incorrect fheUintType, expected=%s, got=%s", resultType, teePlaintext.FheUintType)
 	}
 
-	result := new(big.Int).SetBytes(teePlaintext.Value).Uint64()
+	result := new(big.Int).SetBytes(teePlaintext.Value)
 
-	var expect uint64
+	expect := new(big.Int)
 	switch expected := expected.(type) {
 	case bool:
-		expect = boolToUint64(expected)
+		expect.SetUint64(boolToUint64(expected))
 	case uint64:
-		expect = expected
-	default:
-		expect = 0
+		expect.SetUint64(expected)
+	case *big.Int:
+		expect.Set(expected)
 	}
-	if result != expect {
+	if result.Cmp(expect) != 0 {
+		t.Fatalf("incorrect result, expected=%d, got=%d", expect, result)
+	}
+}
+
+// teeMulDivHelper is a helper function to test the teeMulDiv operation.
+// Bit 0 of flags makes b a plaintext scalar, bit 1 makes c a plaintext scalar.
+func teeMulDivHelper(t *testing.T, fheUintType tfhe.FheUintType, a, b, c, expected *big.Int, flags byte) {
+	depth := 1
+	environment := newTestEVMEnvironment()
+	environment.depth = depth
+	addr := common.Address{}
+	readOnly := false
+	aCt, err := importTeePlaintextToEVM(environment, depth, a, fheUintType)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	hashes := []common.Hash{aCt.GetHash()}
+	for i, value := range []*big.Int{b, c} {
+		if flags&(1<<i) != 0 {
+			hashes = append(hashes, common.BigToHash(value))
+			continue
+		}
+		ct, err := importTeePlaintextToEVM(environment, depth, value, fheUintType)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		hashes = append(hashes, ct.GetHash())
+	}
+
+	input := toLibPrecompileInputNoScalar("teeMulDiv(uint256,uint256,uint256,bytes1)", hashes...)
+	input = append(input, flags)
+	input = append(input, scalarBytePadding...)
+	out, err := TeeLibRun(environment, addr, addr, input, readOnly)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	res := getVerifiedCiphertextFromEVM(environment, common.BytesToHash(out))
+	if res == nil {
+		t.Fatalf("output ciphertext is not found in verifiedCiphertexts")
+	}
+	teePlaintext, err := tee.Decrypt(res.ciphertext)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if teePlaintext.FheUintType != fheUintType {
+		t.Fatalf("incorrect fheUintType, expected=%s, got=%s", fheUintType, teePlaintext.FheUintType)
+	}
+
+	result := new(big.Int).SetBytes(teePlaintext.Value)
+	if result.Cmp(expected) != 0 {
 		t.Fatalf("incorrect result, expected=%d, got=%d", expected, result)
 	}
 }
@@ -92,7 +156,10 @@ func teeSelectOperationHelper(t *testing.T, fheUintType tfhe.FheUintType, fhs bo
 		t.Fatalf(err.Error())
 	}
 
-	input := toLibPrecompileInput(signature, false, fhsCt.GetHash(), shsCt.GetHash(), thsCt.GetHash())
+	input := toLibPrecompileInputNoScalar(signature, fhsCt.GetHash(), shsCt.GetHash(), thsCt.GetHash())
+	if gas := TeeLibRequiredGas(environment, 0, input); gas != environment.FhevmParams().GasCosts.TeeComparison[fheUintType] {
+		t.Fatalf("incorrect gas, expected=%d, got=%d", environment.FhevmParams().GasCosts.TeeComparison[fheUintType], gas)
+	}
 	out, err := TeeLibRun(environment, addr, addr, input, readOnly)
 	if err != nil {
 		t.Fatalf(err.Error())
@@ -115,7 +182,7 @@ func teeSelectOperationHelper(t *testing.T, fheUintType tfhe.FheUintType, fhs bo
 
 // teeNotNegHelper is a helper function to test TEE operations,
 // which are passed into the last argument as a function.
-func teeNegNotOperationHelper(t *testing.T, fheUintType tfhe.FheUintType, chs, expected uint64, signature string) {
+func teeNegNotOperationHelper(t teeTestingT, fheUintType tfhe.FheUintType, chs, expected any, signature string) {
 	depth := 1
 	environment := newTestEVMEnvironment()
 	environment.depth = depth
@@ -126,7 +193,7 @@ func teeNegNotOperationHelper(t *testing.T, fheUintType tfhe.FheUintType, chs, e
 		t.Fatalf(err.Error())
 	}
 
-	input := toLibPrecompileInput(signature, false, chsCt.GetHash())
+	input := toLibPrecompileInputNoScalar(signature, chsCt.GetHash())
 	out, err := TeeLibRun(environment, addr, addr, input, readOnly)
 	if err != nil {
 		t.Fatalf(err.Error())
@@ -144,9 +211,17 @@ func teeNegNotOperationHelper(t *testing.T, fheUintType tfhe.FheUintType, chs, e
 		t.Fatalf("incorrect fheUintType, expected=%s, got=%s", fheUintType, teePlaintext.FheUintType)
 	}
 
-	result := new(big.Int).SetBytes(teePlaintext.Value).Uint64()
-	if result != expected {
-		t.Fatalf("incorrect result, expected=%d, got=%d", expected, result)
+	result := new(big.Int).SetBytes(teePlaintext.Value)
+
+	expect := new(big.Int)
+	switch expected := expected.(type) {
+	case uint64:
+		expect.SetUint64(expected)
+	case *big.Int:
+		expect.Set(expected)
+	}
+	if result.Cmp(expect) != 0 {
+		t.Fatalf("incorrect result, expected=%d, got=%d", expect, result)
 	}
 }
 
@@ -184,7 +259,7 @@ func teeCastHelper(t *testing.T, inputType tfhe.FheUintType, outputType tfhe.Fhe
 	input := make([]byte, 0)
 	input = append(input, signatureBytes...)
 	input = append(input, inCt.GetHash().Bytes()...)
-	input = append(input, byte(outputType))
+	input = append(input, common.RightPadBytes([]byte{byte(outputType)}, 32)...)
 	out, err := TeeLibRun(environment, addr, addr, input, readOnly)
 	if err != nil {
 		t.Fatalf(err.Error())
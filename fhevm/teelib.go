@@ -1,178 +1,343 @@
 package fhevm
 
-// Mapping between function signatures and the functions to call
-var signatureToTeeLibMethod = map[uint32]*FheLibMethod{}
-
 func GetTeeLibMethod(signature uint32) (teeLibMethod *FheLibMethod, found bool) {
-	teeLibMethod, found = signatureToTeeLibMethod[signature]
-	return
+	return teeLibRegistry.get(signature)
 }
 
 // All methods available in the teelib precompile
 var teelibMethods = []*FheLibMethod{
 	// TEE operations
 	{
-		name:                "teeEncrypt",
-		argTypes:            "(uint256,bytes1)",
-		requiredGasFunction: teeEncryptRequiredGas,
-		runFunction:         teeEncryptRun,
+		name:                    "teeEncrypt",
+		argTypes:                "(uint256,bytes1)",
+		requiredGasArgsFunction: teeEncryptRequiredGas,
+		gasCategory:             "FheTrivialEncrypt",
+		runArgsFunction:         teeEncryptRun,
 	},
 	{
-		name:                "teeDecrypt",
-		argTypes:            "(uint256)",
-		requiredGasFunction: teeDecryptRequiredGas,
-		runFunction:         teeDecryptRun,
+		name:                    "teeDecrypt",
+		view:                    true,
+		argTypes:                "(uint256)",
+		requiredGasArgsFunction: teeDecryptRequiredGas,
+		gasCategory:             "TeeDecrypt",
+		runArgsFunction:         teeDecryptRun,
+		authorized:              true,
+	},
+	{
+		name:                    "teeAdd",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeAddSubRequiredGas,
+		gasCategory:             "TeeAddSub",
+		runArgsFunction:         teeAddRun,
+	},
+	{
+		name:                    "teeSub",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeAddSubRequiredGas,
+		gasCategory:             "TeeAddSub",
+		runArgsFunction:         teeSubRun,
+	},
+	{
+		name:                    "teeMul",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeMulRequiredGas,
+		gasCategory:             "TeeMul",
+		runArgsFunction:         teeMulRun,
+	},
+	{
+		name:                    "teeMulHi",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeMulHiRequiredGas,
+		gasCategory:             "TeeMulHi",
+		runArgsFunction:         teeMulHiRun,
+	},
+	{
+		name:                    "teeMulDiv",
+		argTypes:                "(uint256,uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeMulDivRequiredGas,
+		gasCategory:             "TeeMulDiv",
+		runArgsFunction:         teeMulDivRun,
+	},
+	{
+		name:                    "teePow",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teePowRequiredGas,
+		gasCategory:             "TeePow",
+		runArgsFunction:         teePowRun,
+	},
+	{
+		name:                    "teeSqrt",
+		argTypes:                "(uint256)",
+		requiredGasArgsFunction: teeSqrtRequiredGas,
+		gasCategory:             "TeeSqrt",
+		runArgsFunction:         teeSqrtRun,
+	},
+	{
+		name:                    "teeDiv",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeDivRequiredGas,
+		gasCategory:             "TeeDiv",
+		runArgsFunction:         teeDivRun,
+	},
+	{
+		name:                    "teeRem",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeRemRequiredGas,
+		gasCategory:             "TeeRem",
+		runArgsFunction:         teeRemRun,
 	},
 	{
-		name:                "teeAdd",
-		argTypes:            "(uint256,uint256,bytes1)",
-		requiredGasFunction: teeAddSubRequiredGas,
-		runFunction:         teeAddRun,
+		name:                    "teeLe",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeComparisonRequiredGas,
+		gasCategory:             "TeeComparison",
+		runArgsFunction:         teeLeRun,
 	},
 	{
-		name:                "teeSub",
-		argTypes:            "(uint256,uint256,bytes1)",
-		requiredGasFunction: teeAddSubRequiredGas,
-		runFunction:         teeSubRun,
+		name:                    "teeLt",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeComparisonRequiredGas,
+		gasCategory:             "TeeComparison",
+		runArgsFunction:         teeLtRun,
 	},
 	{
-		name:                "teeMul",
-		argTypes:            "(uint256,uint256,bytes1)",
-		requiredGasFunction: teeMulRequiredGas,
-		runFunction:         teeMulRun,
+		name:                    "teeEq",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeComparisonRequiredGas,
+		gasCategory:             "TeeComparison",
+		runArgsFunction:         teeEqRun,
 	},
 	{
-		name:                "teeDiv",
-		argTypes:            "(uint256,uint256,bytes1)",
-		requiredGasFunction: teeDivRequiredGas,
-		runFunction:         teeDivRun,
+		name:                    "teeGe",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeComparisonRequiredGas,
+		gasCategory:             "TeeComparison",
+		runArgsFunction:         teeGeRun,
 	},
 	{
-		name:                "teeRem",
-		argTypes:            "(uint256,uint256,bytes1)",
-		requiredGasFunction: teeRemRequiredGas,
-		runFunction:         teeRemRun,
+		name:                    "teeGt",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeComparisonRequiredGas,
+		gasCategory:             "TeeComparison",
+		runArgsFunction:         teeGtRun,
 	},
 	{
-		name:                "teeLe",
-		argTypes:            "(uint256,uint256,bytes1)",
-		requiredGasFunction: teeComparisonRequiredGas,
-		runFunction:         teeLeRun,
+		name:                    "teeNe",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeComparisonRequiredGas,
+		gasCategory:             "TeeComparison",
+		runArgsFunction:         teeNeRun,
 	},
 	{
-		name:                "teeLt",
-		argTypes:            "(uint256,uint256,bytes1)",
-		requiredGasFunction: teeComparisonRequiredGas,
-		runFunction:         teeLtRun,
+		name:                    "teeMin",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeComparisonRequiredGas,
+		gasCategory:             "TeeComparison",
+		runArgsFunction:         teeMinRun,
 	},
 	{
-		name:                "teeEq",
-		argTypes:            "(uint256,uint256,bytes1)",
-		requiredGasFunction: teeComparisonRequiredGas,
-		runFunction:         teeEqRun,
+		name:                    "teeMax",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeComparisonRequiredGas,
+		gasCategory:             "TeeComparison",
+		runArgsFunction:         teeMaxRun,
 	},
 	{
-		name:                "teeGe",
-		argTypes:            "(uint256,uint256,bytes1)",
-		requiredGasFunction: teeComparisonRequiredGas,
-		runFunction:         teeGeRun,
+		name:                    "teeSelect",
+		argTypes:                "(uint256,uint256,uint256)",
+		requiredGasArgsFunction: teeSelectRequiredGas,
+		gasCategory:             "TeeComparison",
+		runArgsFunction:         teeSelectRun,
 	},
 	{
-		name:                "teeGt",
-		argTypes:            "(uint256,uint256,bytes1)",
-		requiredGasFunction: teeComparisonRequiredGas,
-		runFunction:         teeGtRun,
+		name:                    "teeShl",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeShiftRequiredGas,
+		gasCategory:             "TeeShift",
+		runArgsFunction:         teeShlRun,
 	},
 	{
-		name:                "teeNe",
-		argTypes:            "(uint256,uint256,bytes1)",
-		requiredGasFunction: teeComparisonRequiredGas,
-		runFunction:         teeNeRun,
+		name:                    "teeShr",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeShiftRequiredGas,
+		gasCategory:             "TeeShift",
+		runArgsFunction:         teeShrRun,
 	},
 	{
-		name:                "teeMin",
-		argTypes:            "(uint256,uint256,bytes1)",
-		requiredGasFunction: teeComparisonRequiredGas,
-		runFunction:         teeMinRun,
+		name:                    "teeRotl",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeShiftRequiredGas,
+		gasCategory:             "TeeShift",
+		runArgsFunction:         teeRotlRun,
 	},
 	{
-		name:                "teeMax",
-		argTypes:            "(uint256,uint256,bytes1)",
-		requiredGasFunction: teeComparisonRequiredGas,
-		runFunction:         teeMaxRun,
+		name:                    "teeRotr",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeShiftRequiredGas,
+		gasCategory:             "TeeShift",
+		runArgsFunction:         teeRotrRun,
 	},
 	{
-		name:                "teeSelect",
-		argTypes:            "(uint256,uint256,uint256)",
-		requiredGasFunction: teeComparisonRequiredGas,
-		runFunction:         teeSelectRun,
+		name:                    "teeBitAnd",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeBitwiseOpRequiredGas,
+		gasCategory:             "TeeBitwiseOp",
+		runArgsFunction:         teeBitAndRun,
 	},
 	{
-		name:                "teeShl",
-		argTypes:            "(uint256,uint256,bytes1)",
-		requiredGasFunction: teeShiftRequiredGas,
-		runFunction:         teeShlRun,
+		name:                    "teeBitOr",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeBitwiseOpRequiredGas,
+		gasCategory:             "TeeBitwiseOp",
+		runArgsFunction:         teeBitOrRun,
 	},
 	{
-		name:                "teeShr",
-		argTypes:            "(uint256,uint256,bytes1)",
-		requiredGasFunction: teeShiftRequiredGas,
-		runFunction:         teeShrRun,
+		name:                    "teeBitXor",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeBitwiseOpRequiredGas,
+		gasCategory:             "TeeBitwiseOp",
+		runArgsFunction:         teeBitXorRun,
 	},
 	{
-		name:                "teeRotl",
-		argTypes:            "(uint256,uint256,bytes1)",
-		requiredGasFunction: teeShiftRequiredGas,
-		runFunction:         teeRotlRun,
+		name:                    "teeNeg",
+		argTypes:                "(uint256)",
+		requiredGasArgsFunction: teeNegRequiredGas,
+		gasCategory:             "TeeNeg",
+		runArgsFunction:         teeNegRun,
 	},
 	{
-		name:                "teeRotr",
-		argTypes:            "(uint256,uint256,bytes1)",
-		requiredGasFunction: teeShiftRequiredGas,
-		runFunction:         teeRotrRun,
+		name:                    "teeNot",
+		argTypes:                "(uint256)",
+		requiredGasArgsFunction: teeNotRequiredGas,
+		gasCategory:             "TeeNot",
+		runArgsFunction:         teeNotRun,
 	},
 	{
-		name:                "teeBitAnd",
-		argTypes:            "(uint256,uint256,bytes1)",
-		requiredGasFunction: teeBitwiseOpRequiredGas,
-		runFunction:         teeBitAndRun,
+		name:                    "teePopcount",
+		argTypes:                "(uint256)",
+		requiredGasArgsFunction: teeBitCountRequiredGas,
+		gasCategory:             "TeeBitCount",
+		runArgsFunction:         teePopcountRun,
 	},
 	{
-		name:                "teeBitOr",
-		argTypes:            "(uint256,uint256,bytes1)",
-		requiredGasFunction: teeBitwiseOpRequiredGas,
-		runFunction:         teeBitOrRun,
+		name:                    "teeClz",
+		argTypes:                "(uint256)",
+		requiredGasArgsFunction: teeBitCountRequiredGas,
+		gasCategory:             "TeeBitCount",
+		runArgsFunction:         teeClzRun,
 	},
 	{
-		name:                "teeBitXor",
-		argTypes:            "(uint256,uint256,bytes1)",
-		requiredGasFunction: teeBitwiseOpRequiredGas,
-		runFunction:         teeBitXorRun,
+		name:                    "teeCtz",
+		argTypes:                "(uint256)",
+		requiredGasArgsFunction: teeBitCountRequiredGas,
+		gasCategory:             "TeeBitCount",
+		runArgsFunction:         teeCtzRun,
 	},
 	{
-		name:                "teeNeg",
+		name:                    "teeBitTest",
+		argTypes:                "(uint256,uint256,bytes1)",
+		requiredGasArgsFunction: teeBitTestRequiredGas,
+		gasCategory:             "TeeBitTest",
+		runArgsFunction:         teeBitTestRun,
+	},
+	{
+		name:                    "teeCast",
+		argTypes:                "(uint256,bytes1)",
+		requiredGasArgsFunction: teeCastRequiredGas,
+		gasCategory:             "TeeCast",
+		runArgsFunction:         teeCastRun,
+	},
+	{
+		name:                "teeProgram",
+		argTypes:            "(bytes,uint256[])",
+		returnTypes:         "(uint256[])",
+		requiredGasFunction: teeProgramRequiredGas,
+		gasCategory:         "instructions",
+		runFunction:         teeProgramRun,
+	},
+	{
+		name:                "allow",
+		argTypes:            "(uint256,address)",
+		returnTypes:         "()",
+		writesState:         true,
+		requiredGasFunction: aclAllowRequiredGas,
+		gasCategory:         "AclAllow",
+		runArgsFunction:     aclAllowRun,
+	},
+	{
+		name:                "allowTransient",
+		writesState:         true,
+		argTypes:            "(uint256,address)",
+		returnTypes:         "()",
+		requiredGasFunction: aclAllowTransientRequiredGas,
+		gasCategory:         "AclAllowTransient",
+		runArgsFunction:     aclAllowTransientRun,
+	},
+	{
+		name:                "isAllowed",
+		view:                true,
+		argTypes:            "(uint256,address)",
+		returnTypes:         "(bool)",
+		requiredGasFunction: aclIsAllowedRequiredGas,
+		gasCategory:         "AclIsAllowed",
+		runArgsFunction:     aclIsAllowedRun,
+	},
+	{
+		name:                    "makePubliclyDecryptable",
+		argTypes:                "(uint256)",
+		returnTypes:             "()",
+		writesState:             true,
+		requiredGasArgsFunction: makePubliclyDecryptableRequiredGas,
+		gasCategory:             "MakePubliclyDecryptable",
+		runArgsFunction:         makePubliclyDecryptableRun,
+	},
+	{
+		name:                "isPubliclyDecryptable",
+		view:                true,
 		argTypes:            "(uint256)",
-		requiredGasFunction: teeNegRequiredGas,
-		runFunction:         teeNegRun,
+		returnTypes:         "(bool)",
+		requiredGasFunction: isPubliclyDecryptableRequiredGas,
+		gasCategory:         "IsPubliclyDecryptable",
+		runArgsFunction:     isPubliclyDecryptableRun,
 	},
 	{
-		name:                "teeNot",
+		name:                "setUnlockHeight",
+		argTypes:            "(uint256,uint256)",
+		returnTypes:         "()",
+		writesState:         true,
+		requiredGasFunction: setUnlockHeightRequiredGas,
+		gasCategory:         "SetUnlockHeight",
+		runArgsFunction:     setUnlockHeightRun,
+	},
+	{
+		name:                "getUnlockHeight",
+		view:                true,
 		argTypes:            "(uint256)",
-		requiredGasFunction: teeNotRequiredGas,
-		runFunction:         teeNotRun,
+		requiredGasFunction: getUnlockHeightRequiredGas,
+		gasCategory:         "GetUnlockHeight",
+		runArgsFunction:     getUnlockHeightRun,
+	},
+	{
+		name:                "teeRequestDecryption",
+		argTypes:            "(bytes4,uint256[])",
+		writesState:         true,
+		requiredGasFunction: requestDecryptionRequiredGas,
+		gasCategory:         "DecryptionRequest,DecryptionRequestPerHandle",
+		runFunction:         teeRequestDecryptionRun,
 	},
 	{
-		name:                "teeCast",
-		argTypes:            "(uint256,bytes1)",
-		requiredGasFunction: teeCastRequiredGas,
-		runFunction:         teeCastRun,
+		name:                "fulfillDecryption",
+		argTypes:            "(uint256,uint256[],bytes)",
+		returnTypes:         "()",
+		writesState:         true,
+		requiredGasFunction: fulfillDecryptionRequiredGas,
+		gasCategory:         "DecryptionFulfill",
+		runFunction:         fulfillDecryptionRun,
 	},
 }
 
 func init() {
 	// create the mapping for every available fhelib method
-	for _, method := range teelibMethods {
-		signatureToTeeLibMethod[method.Signature()] = method
-	}
-
+	teelibMethods = append(teelibMethods, batchMethods("tee", true)...)
+	teeLibRegistry.mustRegister(teelibMethods)
 }
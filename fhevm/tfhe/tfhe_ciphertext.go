@@ -25,8 +25,14 @@ const (
 	FheUint64  FheUintType = 5
 	FheUint128 FheUintType = 6
 	FheUint160 FheUintType = 7
+	// FheDecimal is an unsigned fixed-point number with FheDecimalPlaces
+	// decimals, stored on 128 bits. Only TEE operations support it.
+	FheDecimal FheUintType = 8
 )
 
+// The number of decimals of a FheDecimal.
+const FheDecimalPlaces = 18
+
 func (t FheUintType) String() string {
 	switch t {
 	case FheBool:
@@ -45,11 +51,15 @@ func (t FheUintType) String() string {
 		return "fheUint128"
 	case FheUint160:
 		return "fheUint160"
+	case FheDecimal:
+		return "fheDecimal"
 	default:
 		return "unknownFheUintType"
 	}
 }
 
+// IsValidFheType reports whether t is a type supported by FHE operations.
+// FheDecimal is not, as it is only supported by TEE operations.
 func IsValidFheType(t byte) bool {
 	if uint8(t) < uint8(FheBool) || uint8(t) > uint8(FheUint160) {
 		return false
@@ -472,7 +482,7 @@ func (ct *TfheCiphertext) executeUnaryCiphertextOperation(rhs *TfheCiphertext,
 		if ct_ptr == nil {
 			return nil, errors.New("32 bit unary op deserialization failed")
 		}
-		res_ptr, err := op16(ct_ptr)
+		res_ptr, err := op32(ct_ptr)
 		if err != nil {
 			return nil, err
 		}
@@ -1997,6 +2007,112 @@ func (lhs *TfheCiphertext) Not() (*TfheCiphertext, error) {
 		})
 }
 
+// Returns the number of bits set to 1, as a ciphertext of the same type.
+func (lhs *TfheCiphertext) CountOnes() (*TfheCiphertext, error) {
+	return lhs.executeUnaryCiphertextOperation(lhs,
+		boolUnaryNotSupportedOp,
+		func(lhs unsafe.Pointer) (unsafe.Pointer, error) {
+			return C.count_ones_fhe_uint4(lhs, sks), nil
+		},
+		func(lhs unsafe.Pointer) (unsafe.Pointer, error) {
+			return C.count_ones_fhe_uint8(lhs, sks), nil
+		},
+		func(lhs unsafe.Pointer) (unsafe.Pointer, error) {
+			return C.count_ones_fhe_uint16(lhs, sks), nil
+		},
+		func(lhs unsafe.Pointer) (unsafe.Pointer, error) {
+			return C.count_ones_fhe_uint32(lhs, sks), nil
+		},
+		func(lhs unsafe.Pointer) (unsafe.Pointer, error) {
+			return C.count_ones_fhe_uint64(lhs, sks), nil
+		})
+}
+
+// Returns the number of leading zero bits, as a ciphertext of the same type.
+func (lhs *TfheCiphertext) LeadingZeros() (*TfheCiphertext, error) {
+	return lhs.executeUnaryCiphertextOperation(lhs,
+		boolUnaryNotSupportedOp,
+		func(lhs unsafe.Pointer) (unsafe.Pointer, error) {
+			return C.leading_zeros_fhe_uint4(lhs, sks), nil
+		},
+		func(lhs unsafe.Pointer) (unsafe.Pointer, error) {
+			return C.leading_zeros_fhe_uint8(lhs, sks), nil
+		},
+		func(lhs unsafe.Pointer) (unsafe.Pointer, error) {
+			return C.leading_zeros_fhe_uint16(lhs, sks), nil
+		},
+		func(lhs unsafe.Pointer) (unsafe.Pointer, error) {
+			return C.leading_zeros_fhe_uint32(lhs, sks), nil
+		},
+		func(lhs unsafe.Pointer) (unsafe.Pointer, error) {
+			return C.leading_zeros_fhe_uint64(lhs, sks), nil
+		})
+}
+
+// Returns the number of trailing zero bits, as a ciphertext of the same type.
+func (lhs *TfheCiphertext) TrailingZeros() (*TfheCiphertext, error) {
+	return lhs.executeUnaryCiphertextOperation(lhs,
+		boolUnaryNotSupportedOp,
+		func(lhs unsafe.Pointer) (unsafe.Pointer, error) {
+			return C.trailing_zeros_fhe_uint4(lhs, sks), nil
+		},
+		func(lhs unsafe.Pointer) (unsafe.Pointer, error) {
+			return C.trailing_zeros_fhe_uint8(lhs, sks), nil
+		},
+		func(lhs unsafe.Pointer) (unsafe.Pointer, error) {
+			return C.trailing_zeros_fhe_uint16(lhs, sks), nil
+		},
+		func(lhs unsafe.Pointer) (unsafe.Pointer, error) {
+			return C.trailing_zeros_fhe_uint32(lhs, sks), nil
+		},
+		func(lhs unsafe.Pointer) (unsafe.Pointer, error) {
+			return C.trailing_zeros_fhe_uint64(lhs, sks), nil
+		})
+}
+
+// Returns an FheBool that is true if the bit at position rhs (modulo the bit width) is set.
+func (lhs *TfheCiphertext) BitTest(rhs *TfheCiphertext) (*TfheCiphertext, error) {
+	return lhs.executeBinaryCiphertextOperation(rhs,
+		boolBinaryNotSupportedOp,
+		func(lhs unsafe.Pointer, rhs unsafe.Pointer) (unsafe.Pointer, error) {
+			return C.bit_test_fhe_uint4(lhs, rhs, sks), nil
+		},
+		func(lhs unsafe.Pointer, rhs unsafe.Pointer) (unsafe.Pointer, error) {
+			return C.bit_test_fhe_uint8(lhs, rhs, sks), nil
+		},
+		func(lhs unsafe.Pointer, rhs unsafe.Pointer) (unsafe.Pointer, error) {
+			return C.bit_test_fhe_uint16(lhs, rhs, sks), nil
+		},
+		func(lhs unsafe.Pointer, rhs unsafe.Pointer) (unsafe.Pointer, error) {
+			return C.bit_test_fhe_uint32(lhs, rhs, sks), nil
+		},
+		func(lhs unsafe.Pointer, rhs unsafe.Pointer) (unsafe.Pointer, error) {
+			return C.bit_test_fhe_uint64(lhs, rhs, sks), nil
+		},
+		fheUint160BinaryNotSupportedOp, true)
+}
+
+func (lhs *TfheCiphertext) ScalarBitTest(rhs *big.Int) (*TfheCiphertext, error) {
+	return lhs.executeBinaryScalarOperation(rhs,
+		boolBinaryScalarNotSupportedOp,
+		func(lhs unsafe.Pointer, rhs C.uint8_t) (unsafe.Pointer, error) {
+			return C.scalar_bit_test_fhe_uint4(lhs, rhs, sks), nil
+		},
+		func(lhs unsafe.Pointer, rhs C.uint8_t) (unsafe.Pointer, error) {
+			return C.scalar_bit_test_fhe_uint8(lhs, rhs, sks), nil
+		},
+		func(lhs unsafe.Pointer, rhs C.uint16_t) (unsafe.Pointer, error) {
+			return C.scalar_bit_test_fhe_uint16(lhs, rhs, sks), nil
+		},
+		func(lhs unsafe.Pointer, rhs C.uint32_t) (unsafe.Pointer, error) {
+			return C.scalar_bit_test_fhe_uint32(lhs, rhs, sks), nil
+		},
+		func(lhs unsafe.Pointer, rhs C.uint64_t) (unsafe.Pointer, error) {
+			return C.scalar_bit_test_fhe_uint64(lhs, rhs, sks), nil
+		},
+		fheUint160BinaryScalarNotSupportedOp, true)
+}
+
 func (condition *TfheCiphertext) IfThenElse(lhs *TfheCiphertext, rhs *TfheCiphertext) (*TfheCiphertext, error) {
 	return condition.executeTernaryCiphertextOperation(lhs, rhs,
 		func(condition unsafe.Pointer, lhs unsafe.Pointer, rhs unsafe.Pointer) unsafe.Pointer {
@@ -1476,6 +1476,162 @@ func TfheNot(t *testing.T, fheUintType FheUintType) {
 	}
 }
 
+func TfheCountOnes(t *testing.T, fheUintType FheUintType) {
+	var a big.Int
+	var expected uint64
+	switch fheUintType {
+	case FheUint4:
+		a.SetUint64(11)
+		expected = uint64(bits.OnesCount8(uint8(a.Uint64())))
+	case FheUint8:
+		a.SetUint64(173)
+		expected = uint64(bits.OnesCount8(uint8(a.Uint64())))
+	case FheUint16:
+		a.SetUint64(4283)
+		expected = uint64(bits.OnesCount16(uint16(a.Uint64())))
+	case FheUint32:
+		a.SetUint64(1333337)
+		expected = uint64(bits.OnesCount32(uint32(a.Uint64())))
+	case FheUint64:
+		a.SetUint64(13333377777777777)
+		expected = uint64(bits.OnesCount64(a.Uint64()))
+	}
+	ctA := new(TfheCiphertext)
+	ctA.Encrypt(a, fheUintType)
+	ctRes, _ := ctA.CountOnes()
+	if ctRes.Type() != fheUintType {
+		t.Fatalf("result type %d != %d", ctRes.Type(), fheUintType)
+	}
+	res, err := ctRes.Decrypt()
+	if err != nil || res.Uint64() != expected {
+		t.Fatalf("%d != %d", res.Uint64(), expected)
+	}
+}
+
+func TfheLeadingZeros(t *testing.T, fheUintType FheUintType) {
+	var a big.Int
+	var expected uint64
+	switch fheUintType {
+	case FheUint4:
+		a.SetUint64(3)
+		expected = uint64(bits.LeadingZeros8(uint8(a.Uint64())) - 4)
+	case FheUint8:
+		a.SetUint64(17)
+		expected = uint64(bits.LeadingZeros8(uint8(a.Uint64())))
+	case FheUint16:
+		a.SetUint64(283)
+		expected = uint64(bits.LeadingZeros16(uint16(a.Uint64())))
+	case FheUint32:
+		a.SetUint64(1333337)
+		expected = uint64(bits.LeadingZeros32(uint32(a.Uint64())))
+	case FheUint64:
+		a.SetUint64(13333377777777)
+		expected = uint64(bits.LeadingZeros64(a.Uint64()))
+	}
+	ctA := new(TfheCiphertext)
+	ctA.Encrypt(a, fheUintType)
+	ctRes, _ := ctA.LeadingZeros()
+	res, err := ctRes.Decrypt()
+	if err != nil || res.Uint64() != expected {
+		t.Fatalf("%d != %d", res.Uint64(), expected)
+	}
+}
+
+func TfheTrailingZeros(t *testing.T, fheUintType FheUintType) {
+	var a big.Int
+	var expected uint64
+	switch fheUintType {
+	case FheUint4:
+		a.SetUint64(4)
+		expected = uint64(bits.TrailingZeros8(uint8(a.Uint64())))
+	case FheUint8:
+		a.SetUint64(48)
+		expected = uint64(bits.TrailingZeros8(uint8(a.Uint64())))
+	case FheUint16:
+		a.SetUint64(4288)
+		expected = uint64(bits.TrailingZeros16(uint16(a.Uint64())))
+	case FheUint32:
+		a.SetUint64(1333376)
+		expected = uint64(bits.TrailingZeros32(uint32(a.Uint64())))
+	case FheUint64:
+		a.SetUint64(13333377777778176)
+		expected = uint64(bits.TrailingZeros64(a.Uint64()))
+	}
+	ctA := new(TfheCiphertext)
+	ctA.Encrypt(a, fheUintType)
+	ctRes, _ := ctA.TrailingZeros()
+	res, err := ctRes.Decrypt()
+	if err != nil || res.Uint64() != expected {
+		t.Fatalf("%d != %d", res.Uint64(), expected)
+	}
+}
+
+func TfheBitTest(t *testing.T, fheUintType FheUintType) {
+	var a, b big.Int
+	switch fheUintType {
+	case FheUint4:
+		a.SetUint64(10)
+		b.SetUint64(1)
+	case FheUint8:
+		a.SetUint64(173)
+		b.SetUint64(4)
+	case FheUint16:
+		a.SetUint64(4283)
+		b.SetUint64(9)
+	case FheUint32:
+		a.SetUint64(1333337)
+		b.SetUint64(17)
+	case FheUint64:
+		a.SetUint64(13333377777777777)
+		b.SetUint64(42)
+	}
+	expected := uint64(a.Bit(int(b.Uint64())))
+	ctA := new(TfheCiphertext)
+	ctA.Encrypt(a, fheUintType)
+	ctB := new(TfheCiphertext)
+	ctB.Encrypt(b, fheUintType)
+	ctRes, _ := ctA.BitTest(ctB)
+	if ctRes.Type() != FheBool {
+		t.Fatalf("result type %d != %d", ctRes.Type(), FheBool)
+	}
+	res, err := ctRes.Decrypt()
+	if err != nil || res.Uint64() != expected {
+		t.Fatalf("%d != %d", res.Uint64(), expected)
+	}
+}
+
+func TfheScalarBitTest(t *testing.T, fheUintType FheUintType) {
+	var a, b big.Int
+	switch fheUintType {
+	case FheUint4:
+		a.SetUint64(10)
+		b.SetUint64(2)
+	case FheUint8:
+		a.SetUint64(173)
+		b.SetUint64(5)
+	case FheUint16:
+		a.SetUint64(4283)
+		b.SetUint64(10)
+	case FheUint32:
+		a.SetUint64(1333337)
+		b.SetUint64(17)
+	case FheUint64:
+		a.SetUint64(13333377777777777)
+		b.SetUint64(42)
+	}
+	expected := uint64(a.Bit(int(b.Uint64())))
+	ctA := new(TfheCiphertext)
+	ctA.Encrypt(a, fheUintType)
+	ctRes, _ := ctA.ScalarBitTest(&b)
+	if ctRes.Type() != FheBool {
+		t.Fatalf("result type %d != %d", ctRes.Type(), FheBool)
+	}
+	res, err := ctRes.Decrypt()
+	if err != nil || res.Uint64() != expected {
+		t.Fatalf("%d != %d", res.Uint64(), expected)
+	}
+}
+
 func TfheIfThenElse(t *testing.T, fheUintType FheUintType) {
 	var condition, condition2, a, b big.Int
 	condition.SetUint64(1)
@@ -2522,6 +2678,106 @@ func TestTfheNot64(t *testing.T) {
 	TfheNot(t, FheUint64)
 }
 
+func TestTfheCountOnes4(t *testing.T) {
+	TfheCountOnes(t, FheUint4)
+}
+
+func TestTfheCountOnes8(t *testing.T) {
+	TfheCountOnes(t, FheUint8)
+}
+
+func TestTfheCountOnes16(t *testing.T) {
+	TfheCountOnes(t, FheUint16)
+}
+
+func TestTfheCountOnes32(t *testing.T) {
+	TfheCountOnes(t, FheUint32)
+}
+
+func TestTfheCountOnes64(t *testing.T) {
+	TfheCountOnes(t, FheUint64)
+}
+
+func TestTfheLeadingZeros4(t *testing.T) {
+	TfheLeadingZeros(t, FheUint4)
+}
+
+func TestTfheLeadingZeros8(t *testing.T) {
+	TfheLeadingZeros(t, FheUint8)
+}
+
+func TestTfheLeadingZeros16(t *testing.T) {
+	TfheLeadingZeros(t, FheUint16)
+}
+
+func TestTfheLeadingZeros32(t *testing.T) {
+	TfheLeadingZeros(t, FheUint32)
+}
+
+func TestTfheLeadingZeros64(t *testing.T) {
+	TfheLeadingZeros(t, FheUint64)
+}
+
+func TestTfheTrailingZeros4(t *testing.T) {
+	TfheTrailingZeros(t, FheUint4)
+}
+
+func TestTfheTrailingZeros8(t *testing.T) {
+	TfheTrailingZeros(t, FheUint8)
+}
+
+func TestTfheTrailingZeros16(t *testing.T) {
+	TfheTrailingZeros(t, FheUint16)
+}
+
+func TestTfheTrailingZeros32(t *testing.T) {
+	TfheTrailingZeros(t, FheUint32)
+}
+
+func TestTfheTrailingZeros64(t *testing.T) {
+	TfheTrailingZeros(t, FheUint64)
+}
+
+func TestTfheBitTest4(t *testing.T) {
+	TfheBitTest(t, FheUint4)
+}
+
+func TestTfheBitTest8(t *testing.T) {
+	TfheBitTest(t, FheUint8)
+}
+
+func TestTfheBitTest16(t *testing.T) {
+	TfheBitTest(t, FheUint16)
+}
+
+func TestTfheBitTest32(t *testing.T) {
+	TfheBitTest(t, FheUint32)
+}
+
+func TestTfheBitTest64(t *testing.T) {
+	TfheBitTest(t, FheUint64)
+}
+
+func TestTfheScalarBitTest4(t *testing.T) {
+	TfheScalarBitTest(t, FheUint4)
+}
+
+func TestTfheScalarBitTest8(t *testing.T) {
+	TfheScalarBitTest(t, FheUint8)
+}
+
+func TestTfheScalarBitTest16(t *testing.T) {
+	TfheScalarBitTest(t, FheUint16)
+}
+
+func TestTfheScalarBitTest32(t *testing.T) {
+	TfheScalarBitTest(t, FheUint32)
+}
+
+func TestTfheScalarBitTest64(t *testing.T) {
+	TfheScalarBitTest(t, FheUint64)
+}
+
 func TestTfheIfThenElse4(t *testing.T) {
 	TfheIfThenElse(t, FheUint4)
 }
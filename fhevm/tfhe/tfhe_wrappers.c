@@ -2538,6 +2538,394 @@ void* not_fhe_uint64(void* ct, void* sks) {
 	return result;
 }
 
+void* count_ones_fhe_uint4(void* ct, void* sks) {
+	FheUint32* count = NULL;
+	FheUint4* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint4_count_ones(ct, &count);
+	if(r != 0) return NULL;
+	r = fhe_uint32_cast_into_fhe_uint4(count, &result);
+	fhe_uint32_destroy(count);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* count_ones_fhe_uint8(void* ct, void* sks) {
+	FheUint32* count = NULL;
+	FheUint8* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint8_count_ones(ct, &count);
+	if(r != 0) return NULL;
+	r = fhe_uint32_cast_into_fhe_uint8(count, &result);
+	fhe_uint32_destroy(count);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* count_ones_fhe_uint16(void* ct, void* sks) {
+	FheUint32* count = NULL;
+	FheUint16* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint16_count_ones(ct, &count);
+	if(r != 0) return NULL;
+	r = fhe_uint32_cast_into_fhe_uint16(count, &result);
+	fhe_uint32_destroy(count);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* count_ones_fhe_uint32(void* ct, void* sks) {
+	FheUint32* result = NULL;
+
+	checked_set_server_key(sks);
+
+	const int r = fhe_uint32_count_ones(ct, &result);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* count_ones_fhe_uint64(void* ct, void* sks) {
+	FheUint32* count = NULL;
+	FheUint64* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint64_count_ones(ct, &count);
+	if(r != 0) return NULL;
+	r = fhe_uint32_cast_into_fhe_uint64(count, &result);
+	fhe_uint32_destroy(count);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* leading_zeros_fhe_uint4(void* ct, void* sks) {
+	FheUint32* count = NULL;
+	FheUint4* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint4_leading_zeros(ct, &count);
+	if(r != 0) return NULL;
+	r = fhe_uint32_cast_into_fhe_uint4(count, &result);
+	fhe_uint32_destroy(count);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* leading_zeros_fhe_uint8(void* ct, void* sks) {
+	FheUint32* count = NULL;
+	FheUint8* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint8_leading_zeros(ct, &count);
+	if(r != 0) return NULL;
+	r = fhe_uint32_cast_into_fhe_uint8(count, &result);
+	fhe_uint32_destroy(count);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* leading_zeros_fhe_uint16(void* ct, void* sks) {
+	FheUint32* count = NULL;
+	FheUint16* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint16_leading_zeros(ct, &count);
+	if(r != 0) return NULL;
+	r = fhe_uint32_cast_into_fhe_uint16(count, &result);
+	fhe_uint32_destroy(count);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* leading_zeros_fhe_uint32(void* ct, void* sks) {
+	FheUint32* result = NULL;
+
+	checked_set_server_key(sks);
+
+	const int r = fhe_uint32_leading_zeros(ct, &result);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* leading_zeros_fhe_uint64(void* ct, void* sks) {
+	FheUint32* count = NULL;
+	FheUint64* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint64_leading_zeros(ct, &count);
+	if(r != 0) return NULL;
+	r = fhe_uint32_cast_into_fhe_uint64(count, &result);
+	fhe_uint32_destroy(count);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* trailing_zeros_fhe_uint4(void* ct, void* sks) {
+	FheUint32* count = NULL;
+	FheUint4* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint4_trailing_zeros(ct, &count);
+	if(r != 0) return NULL;
+	r = fhe_uint32_cast_into_fhe_uint4(count, &result);
+	fhe_uint32_destroy(count);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* trailing_zeros_fhe_uint8(void* ct, void* sks) {
+	FheUint32* count = NULL;
+	FheUint8* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint8_trailing_zeros(ct, &count);
+	if(r != 0) return NULL;
+	r = fhe_uint32_cast_into_fhe_uint8(count, &result);
+	fhe_uint32_destroy(count);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* trailing_zeros_fhe_uint16(void* ct, void* sks) {
+	FheUint32* count = NULL;
+	FheUint16* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint16_trailing_zeros(ct, &count);
+	if(r != 0) return NULL;
+	r = fhe_uint32_cast_into_fhe_uint16(count, &result);
+	fhe_uint32_destroy(count);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* trailing_zeros_fhe_uint32(void* ct, void* sks) {
+	FheUint32* result = NULL;
+
+	checked_set_server_key(sks);
+
+	const int r = fhe_uint32_trailing_zeros(ct, &result);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* trailing_zeros_fhe_uint64(void* ct, void* sks) {
+	FheUint32* count = NULL;
+	FheUint64* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint64_trailing_zeros(ct, &count);
+	if(r != 0) return NULL;
+	r = fhe_uint32_cast_into_fhe_uint64(count, &result);
+	fhe_uint32_destroy(count);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* bit_test_fhe_uint4(void* ct, void* index, void* sks)
+{
+	FheUint4* shifted = NULL;
+	FheUint4* bit = NULL;
+	FheBool* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint4_shr(ct, index, &shifted);
+	if(r != 0) return NULL;
+	r = fhe_uint4_scalar_bitand(shifted, 1, &bit);
+	fhe_uint4_destroy(shifted);
+	if(r != 0) return NULL;
+	r = fhe_uint4_scalar_ne(bit, 0, &result);
+	fhe_uint4_destroy(bit);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* bit_test_fhe_uint8(void* ct, void* index, void* sks)
+{
+	FheUint8* shifted = NULL;
+	FheUint8* bit = NULL;
+	FheBool* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint8_shr(ct, index, &shifted);
+	if(r != 0) return NULL;
+	r = fhe_uint8_scalar_bitand(shifted, 1, &bit);
+	fhe_uint8_destroy(shifted);
+	if(r != 0) return NULL;
+	r = fhe_uint8_scalar_ne(bit, 0, &result);
+	fhe_uint8_destroy(bit);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* bit_test_fhe_uint16(void* ct, void* index, void* sks)
+{
+	FheUint16* shifted = NULL;
+	FheUint16* bit = NULL;
+	FheBool* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint16_shr(ct, index, &shifted);
+	if(r != 0) return NULL;
+	r = fhe_uint16_scalar_bitand(shifted, 1, &bit);
+	fhe_uint16_destroy(shifted);
+	if(r != 0) return NULL;
+	r = fhe_uint16_scalar_ne(bit, 0, &result);
+	fhe_uint16_destroy(bit);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* bit_test_fhe_uint32(void* ct, void* index, void* sks)
+{
+	FheUint32* shifted = NULL;
+	FheUint32* bit = NULL;
+	FheBool* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint32_shr(ct, index, &shifted);
+	if(r != 0) return NULL;
+	r = fhe_uint32_scalar_bitand(shifted, 1, &bit);
+	fhe_uint32_destroy(shifted);
+	if(r != 0) return NULL;
+	r = fhe_uint32_scalar_ne(bit, 0, &result);
+	fhe_uint32_destroy(bit);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* bit_test_fhe_uint64(void* ct, void* index, void* sks)
+{
+	FheUint64* shifted = NULL;
+	FheUint64* bit = NULL;
+	FheBool* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint64_shr(ct, index, &shifted);
+	if(r != 0) return NULL;
+	r = fhe_uint64_scalar_bitand(shifted, 1, &bit);
+	fhe_uint64_destroy(shifted);
+	if(r != 0) return NULL;
+	r = fhe_uint64_scalar_ne(bit, 0, &result);
+	fhe_uint64_destroy(bit);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* scalar_bit_test_fhe_uint4(void* ct, uint8_t index, void* sks)
+{
+	FheUint4* shifted = NULL;
+	FheUint4* bit = NULL;
+	FheBool* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint4_scalar_shr(ct, index, &shifted);
+	if(r != 0) return NULL;
+	r = fhe_uint4_scalar_bitand(shifted, 1, &bit);
+	fhe_uint4_destroy(shifted);
+	if(r != 0) return NULL;
+	r = fhe_uint4_scalar_ne(bit, 0, &result);
+	fhe_uint4_destroy(bit);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* scalar_bit_test_fhe_uint8(void* ct, uint8_t index, void* sks)
+{
+	FheUint8* shifted = NULL;
+	FheUint8* bit = NULL;
+	FheBool* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint8_scalar_shr(ct, index, &shifted);
+	if(r != 0) return NULL;
+	r = fhe_uint8_scalar_bitand(shifted, 1, &bit);
+	fhe_uint8_destroy(shifted);
+	if(r != 0) return NULL;
+	r = fhe_uint8_scalar_ne(bit, 0, &result);
+	fhe_uint8_destroy(bit);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* scalar_bit_test_fhe_uint16(void* ct, uint16_t index, void* sks)
+{
+	FheUint16* shifted = NULL;
+	FheUint16* bit = NULL;
+	FheBool* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint16_scalar_shr(ct, index, &shifted);
+	if(r != 0) return NULL;
+	r = fhe_uint16_scalar_bitand(shifted, 1, &bit);
+	fhe_uint16_destroy(shifted);
+	if(r != 0) return NULL;
+	r = fhe_uint16_scalar_ne(bit, 0, &result);
+	fhe_uint16_destroy(bit);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* scalar_bit_test_fhe_uint32(void* ct, uint32_t index, void* sks)
+{
+	FheUint32* shifted = NULL;
+	FheUint32* bit = NULL;
+	FheBool* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint32_scalar_shr(ct, index, &shifted);
+	if(r != 0) return NULL;
+	r = fhe_uint32_scalar_bitand(shifted, 1, &bit);
+	fhe_uint32_destroy(shifted);
+	if(r != 0) return NULL;
+	r = fhe_uint32_scalar_ne(bit, 0, &result);
+	fhe_uint32_destroy(bit);
+	if(r != 0) return NULL;
+	return result;
+}
+
+void* scalar_bit_test_fhe_uint64(void* ct, uint64_t index, void* sks)
+{
+	FheUint64* shifted = NULL;
+	FheUint64* bit = NULL;
+	FheBool* result = NULL;
+
+	checked_set_server_key(sks);
+
+	int r = fhe_uint64_scalar_shr(ct, index, &shifted);
+	if(r != 0) return NULL;
+	r = fhe_uint64_scalar_bitand(shifted, 1, &bit);
+	fhe_uint64_destroy(shifted);
+	if(r != 0) return NULL;
+	r = fhe_uint64_scalar_ne(bit, 0, &result);
+	fhe_uint64_destroy(bit);
+	if(r != 0) return NULL;
+	return result;
+}
+
 void* if_then_else_fhe_uint4(void* condition, void* ct1, void* ct2, void* sks)
 {
 	FheUint4* result = NULL;
@@ -461,6 +461,56 @@ void* not_fhe_uint32(void* ct, void* sks);
 
 void* not_fhe_uint64(void* ct, void* sks);
 
+void* count_ones_fhe_uint4(void* ct, void* sks);
+
+void* count_ones_fhe_uint8(void* ct, void* sks);
+
+void* count_ones_fhe_uint16(void* ct, void* sks);
+
+void* count_ones_fhe_uint32(void* ct, void* sks);
+
+void* count_ones_fhe_uint64(void* ct, void* sks);
+
+void* leading_zeros_fhe_uint4(void* ct, void* sks);
+
+void* leading_zeros_fhe_uint8(void* ct, void* sks);
+
+void* leading_zeros_fhe_uint16(void* ct, void* sks);
+
+void* leading_zeros_fhe_uint32(void* ct, void* sks);
+
+void* leading_zeros_fhe_uint64(void* ct, void* sks);
+
+void* trailing_zeros_fhe_uint4(void* ct, void* sks);
+
+void* trailing_zeros_fhe_uint8(void* ct, void* sks);
+
+void* trailing_zeros_fhe_uint16(void* ct, void* sks);
+
+void* trailing_zeros_fhe_uint32(void* ct, void* sks);
+
+void* trailing_zeros_fhe_uint64(void* ct, void* sks);
+
+void* bit_test_fhe_uint4(void* ct, void* index, void* sks);
+
+void* bit_test_fhe_uint8(void* ct, void* index, void* sks);
+
+void* bit_test_fhe_uint16(void* ct, void* index, void* sks);
+
+void* bit_test_fhe_uint32(void* ct, void* index, void* sks);
+
+void* bit_test_fhe_uint64(void* ct, void* index, void* sks);
+
+void* scalar_bit_test_fhe_uint4(void* ct, uint8_t index, void* sks);
+
+void* scalar_bit_test_fhe_uint8(void* ct, uint8_t index, void* sks);
+
+void* scalar_bit_test_fhe_uint16(void* ct, uint16_t index, void* sks);
+
+void* scalar_bit_test_fhe_uint32(void* ct, uint32_t index, void* sks);
+
+void* scalar_bit_test_fhe_uint64(void* ct, uint64_t index, void* sks);
+
 void* if_then_else_fhe_uint4(void* condition, void* ct1, void* ct2, void* sks);
 
 void* if_then_else_fhe_uint8(void* condition, void* ct1, void* ct2, void* sks);
@@ -0,0 +1,137 @@
+package fhevm
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Time-locked handles.
+//
+// A contract allowed to use a handle can set the block height from which it can be decrypted, e.g. the
+// reveal block of a sealed-bid auction. The height is stored in the ACL's protected storage at
+// keccak256("unlockHeight" || handle). It can only be raised, so that no one, not even the contract that set
+// it, can unlock the handle early. Until then, decrypt, teeDecrypt, reencrypt and requestDecryption fail,
+// including for publicly decryptable handles. The results of operations inherit the highest unlock height of
+// their operands, so that computing on a locked handle, e.g. adding zero to it, doesn't unlock it early.
+func getUnlockHeightKey(handle common.Hash) common.Hash {
+	return crypto.Keccak256Hash([]byte("unlockHeight"), handle.Bytes())
+}
+
+// Returns the block height from which the handle can be decrypted, zero if it isn't time-locked.
+func getHandleUnlockHeight(environment EVMEnvironment, handle common.Hash) *big.Int {
+	height := environment.GetState(getAclProtectedStorage(), getUnlockHeightKey(handle)).Big()
+	if transient, ok := environment.FhevmData().transientUnlockHeights[handle]; ok && transient.Cmp(height) > 0 {
+		return transient
+	}
+	return height
+}
+
+// Returns the highest unlock height of the handles, zero if none is time-locked.
+func maxUnlockHeight(environment EVMEnvironment, handles []common.Hash) *big.Int {
+	height := new(big.Int)
+	for _, handle := range handles {
+		if h := getHandleUnlockHeight(environment, handle); h.Cmp(height) > 0 {
+			height = h
+		}
+	}
+	return height
+}
+
+// Time-lock the result of an operation until the highest unlock height of its operands. Like setUnlockHeight,
+// it never lowers the height of the result. The height is kept in memory until the end of the transaction, and
+// persisted by persistUnlockHeight if the result outlives it.
+func inheritUnlockHeight(environment EVMEnvironment, result common.Hash, operands []common.Hash) {
+	height := maxUnlockHeight(environment, operands)
+	if height.Sign() == 0 || height.Cmp(getHandleUnlockHeight(environment, result)) <= 0 {
+		return
+	}
+	environment.FhevmData().transientUnlockHeights[result] = height
+}
+
+// Persist the in-memory time lock of a handle, if it is higher than the persisted one. Called when the handle is
+// stored or allowed, so that it stays locked in later transactions.
+func persistUnlockHeight(environment EVMEnvironment, handle common.Hash) {
+	height, ok := environment.FhevmData().transientUnlockHeights[handle]
+	if !ok {
+		return
+	}
+	key := getUnlockHeightKey(handle)
+	if height.Cmp(environment.GetState(getAclProtectedStorage(), key).Big()) > 0 {
+		environment.SetState(getAclProtectedStorage(), key, common.BigToHash(height))
+	}
+}
+
+// Returns the handles verified at the current depth among the 32-byte words of a precompile input, which
+// are the operands of the call.
+func inputHandles(environment EVMEnvironment, input []byte) []common.Hash {
+	var handles []common.Hash
+	for i := 0; i+32 <= len(input); i += 32 {
+		handle := common.BytesToHash(input[i : i+32])
+		if getVerifiedCiphertextFromEVM(environment, handle) != nil {
+			handles = append(handles, handle)
+		}
+	}
+	return handles
+}
+
+// Returns an error if the handle can't be decrypted at the current block.
+func verifyUnlocked(environment EVMEnvironment, handle common.Hash) error {
+	unlockHeight := getHandleUnlockHeight(environment, handle)
+	if unlockHeight.Sign() == 0 {
+		return nil
+	}
+	blockNumber := environment.BlockNumber()
+	if blockNumber.Cmp(unlockHeight) < 0 {
+		msg := "ciphertext handle is time-locked"
+		environment.GetLogger().Error(msg, "handle", handle.Hex(), "unlockHeight", unlockHeight, "blockNumber", blockNumber)
+		return errors.New(msg)
+	}
+	return nil
+}
+
+func setUnlockHeightRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	logger := environment.GetLogger()
+	if readOnly {
+		return nil, ErrWriteProtection
+	}
+
+	handle := args.word(0)
+	unlockHeight := args.word(1)
+	ct := getVerifiedCiphertextFromEVM(environment, handle)
+	if ct == nil {
+		msg := "setUnlockHeight unverified ciphertext handle"
+		logger.Error(msg, "handle", handle.Hex())
+		return nil, errors.New(msg)
+	}
+	if !isHandleUsable(environment, handle, caller) {
+		msg := "setUnlockHeight caller is not allowed to use the ciphertext handle"
+		logger.Error(msg, "handle", handle.Hex(), "caller", caller.Hex())
+		return nil, errors.New(msg)
+	}
+	otelDescribeOperandsFheTypes(runSpan, ct.fheUintType())
+
+	current := getHandleUnlockHeight(environment, handle)
+	if unlockHeight.Big().Cmp(current) < 0 {
+		msg := "setUnlockHeight can't lower the unlock height"
+		logger.Error(msg, "handle", handle.Hex(), "unlockHeight", current, "requested", unlockHeight.Big())
+		return nil, errors.New(msg)
+	}
+	environment.SetState(getAclProtectedStorage(), getUnlockHeightKey(handle), unlockHeight)
+
+	if environment.IsCommitting() {
+		logger.Info("setUnlockHeight success",
+			"handle", handle.Hex(),
+			"unlockHeight", unlockHeight.Big(),
+			"caller", caller.Hex(),
+		)
+	}
+	return nil, nil
+}
+
+func getUnlockHeightRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return common.BigToHash(getHandleUnlockHeight(environment, args.word(0))).Bytes(), nil
+}
@@ -0,0 +1,9 @@
+package fhevm
+
+func setUnlockHeightRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
+	return environment.FhevmParams().GasCosts.SetUnlockHeight
+}
+
+func getUnlockHeightRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
+	return environment.FhevmParams().GasCosts.GetUnlockHeight
+}
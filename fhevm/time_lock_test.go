@@ -0,0 +1,148 @@
+package fhevm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+)
+
+func toSetUnlockHeightInput(handle common.Hash, unlockHeight uint64) []byte {
+	input := crypto.Keccak256([]byte("setUnlockHeight(uint256,uint256)"))[0:4]
+	input = append(input, handle.Bytes()...)
+	return append(input, common.BigToHash(new(big.Int).SetUint64(unlockHeight)).Bytes()...)
+}
+
+func TestSetUnlockHeight(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	environment.blockNumber = 50
+	contract := common.HexToAddress("0x1000")
+	handle := verifyCiphertextInTestMemory(environment, 42, environment.depth, tfhe.FheUint8).GetHash()
+	allowHandle(environment, handle, contract)
+
+	if _, err := FheLibRun(environment, contract, contract, toSetUnlockHeightInput(handle, 100), false); err != nil {
+		t.Fatalf(err.Error())
+	}
+	out, err := FheLibRun(environment, contract, contract, toPublicDecryptionInput("getUnlockHeight(uint256)", handle), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if new(big.Int).SetBytes(out).Uint64() != 100 {
+		t.Fatalf("expected unlock height 100, got %v", new(big.Int).SetBytes(out).Uint64())
+	}
+	if _, err := FheLibRun(environment, contract, contract, toSetUnlockHeightInput(handle, 99), false); err == nil {
+		t.Fatalf("expected setUnlockHeight to fail to lower the unlock height")
+	}
+
+	if _, err := FheLibRun(environment, contract, contract, toDecryptInput("decrypt(uint256)", handle, nil), false); err == nil {
+		t.Fatalf("expected decrypt to fail before the unlock height")
+	}
+	pubKey := make([]byte, 32)
+	if _, err := FheLibRun(environment, contract, contract, toDecryptInput("reencrypt(uint256,uint256)", handle, pubKey), false); err == nil {
+		t.Fatalf("expected reencrypt to fail before the unlock height")
+	}
+	if _, err := FheLibRun(environment, contract, contract, toRequestDecryptionInput("requestDecryption(bytes4,uint256[])", handle), false); err == nil {
+		t.Fatalf("expected requestDecryption to fail before the unlock height")
+	}
+
+	environment.blockNumber = 100
+	out, err = FheLibRun(environment, contract, contract, toDecryptInput("decrypt(uint256)", handle, nil), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if new(big.Int).SetBytes(out).Uint64() != 42 {
+		t.Fatalf("decrypt result not equal to value, result %v != value %v", new(big.Int).SetBytes(out).Uint64(), 42)
+	}
+}
+
+func TestSetUnlockHeightNotAllowed(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	contract := common.HexToAddress("0x1000")
+	handle := verifyCiphertextInTestMemory(environment, 42, environment.depth, tfhe.FheUint8).GetHash()
+
+	if _, err := FheLibRun(environment, contract, contract, toSetUnlockHeightInput(handle, 100), false); err == nil {
+		t.Fatalf("expected setUnlockHeight to fail for a caller that is not allowed to use the handle")
+	}
+	if getHandleUnlockHeight(environment, handle).Sign() != 0 {
+		t.Fatalf("expected the handle not to be time-locked")
+	}
+}
+
+func TestTimeLockedPubliclyDecryptableHandle(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	contract := common.HexToAddress("0x1000")
+	ct, err := importTeePlaintextToEVM(environment, environment.depth, uint64(7), tfhe.FheUint32)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	handle := ct.GetHash()
+	allowHandle(environment, handle, contract)
+	if _, err := TeeLibRun(environment, contract, contract, toSetUnlockHeightInput(handle, 100), false); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if _, err := TeeLibRun(environment, contract, contract, toPublicDecryptionInput("makePubliclyDecryptable(uint256)", handle), false); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	ethCall := newEthCallOnSameState(environment)
+	user := common.HexToAddress("0x2000")
+	ethCall.blockNumber = 99
+	if _, err := TeeLibRun(ethCall, user, user, toDecryptInput("teeDecrypt(uint256)", handle, nil), false); err == nil {
+		t.Fatalf("expected teeDecrypt to fail before the unlock height")
+	}
+	ethCall.blockNumber = 100
+	out, err := TeeLibRun(ethCall, user, user, toDecryptInput("teeDecrypt(uint256)", handle, nil), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if new(big.Int).SetBytes(out).Uint64() != 7 {
+		t.Fatalf("teeDecrypt result not equal to value, result %v != value %v", new(big.Int).SetBytes(out).Uint64(), 7)
+	}
+}
+
+func TestTimeLockPropagatesToResults(t *testing.T) {
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	environment.blockNumber = 50
+	contract := common.HexToAddress("0x1000")
+	locked := verifyCiphertextInTestMemory(environment, 42, environment.depth, tfhe.FheUint8).GetHash()
+	other := verifyCiphertextInTestMemory(environment, 0xff, environment.depth, tfhe.FheUint8).GetHash()
+	allowHandle(environment, locked, contract)
+	allowHandle(environment, other, contract)
+	if _, err := FheLibRun(environment, contract, contract, toSetUnlockHeightInput(locked, 100), false); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	inputs := map[string][]byte{
+		"fheAdd(locked, 0)":         toLibPrecompileInput("fheAdd(uint256,uint256,bytes1)", true, locked, common.Hash{}),
+		"fheBitAnd(locked, other)":  toLibPrecompileInput("fheBitAnd(uint256,uint256,bytes1)", false, locked, other),
+		"fheAddBatch([locked], 0)":  toBatchInput("fheAddBatch(uint256[],uint256[],bytes1)", []common.Hash{locked}, []common.Hash{{}}, true),
+		"fheProgram(add(locked,0))": toProgramInput("fheProgram(bytes,uint256[])", []byte{2, programCiphertextInput, programScalarInput, programAdd, 0, 1, programOutput, 2}, locked, common.Hash{}),
+	}
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			out, err := FheLibRun(environment, contract, contract, input, false)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			result := common.BytesToHash(out[len(out)-32:])
+			if result == locked {
+				t.Fatalf("expected a new handle")
+			}
+			environment.blockNumber = 99
+			if _, err := FheLibRun(environment, contract, contract, toDecryptInput("decrypt(uint256)", result, nil), false); err == nil {
+				t.Fatalf("expected decrypt of the result to fail before the unlock height of its operand")
+			}
+			environment.blockNumber = 100
+			if _, err := FheLibRun(environment, contract, contract, toDecryptInput("decrypt(uint256)", result, nil), false); err != nil {
+				t.Fatalf(err.Error())
+			}
+			environment.blockNumber = 50
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package fhevm
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	fhevm_crypto "github.com/zama-ai/fhevm-go/fhevm/crypto"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Viewing keys.
+//
+// A contract can register auditors, e.g. from a function restricted to its owner. Each auditor has a
+// 32-byte public key, stored in the contract's protected storage at keccak256("viewingKey" || auditor).
+// On eth_call, a registered auditor can then re-encrypt to that key any handle the contract is allowed to use
+// or has persisted, with auditorReencrypt. Auditors can only read: they are not added to the ACL.
+// Every registration, revocation and access is logged, denied accesses included, as an audit trail.
+func getViewingKeyKey(auditor common.Address) common.Hash {
+	return crypto.Keccak256Hash([]byte("viewingKey"), auditor.Bytes())
+}
+
+// Returns the public key registered for an auditor by a contract, zero if there is none.
+func getViewingKey(environment EVMEnvironment, contract common.Address, auditor common.Address) common.Hash {
+	return environment.GetState(fhevm_crypto.CreateProtectedStorageContractAddress(contract), getViewingKeyKey(auditor))
+}
+
+// Returns the ciphertext an auditor of contract can read: the one verified at the current depth if the contract
+// is allowed to use it, or the one persisted in the contract's protected storage.
+func getAuditableCiphertext(environment EVMEnvironment, contract common.Address, handle common.Hash) (*tfhe.TfheCiphertext, error) {
+	if ct := getVerifiedCiphertext(environment, handle); ct != nil && isHandleUsable(environment, handle, contract) {
+		return ct.ciphertext, nil
+	}
+	return loadCiphertextFromProtectedStorage(environment, contract, handle, false)
+}
+
+func registerViewingKeyRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	logger := environment.GetLogger()
+	if readOnly {
+		return nil, ErrWriteProtection
+	}
+
+	auditor := args.address(0)
+	publicKey := args.word(1)
+	if publicKey == (common.Hash{}) {
+		msg := "registerViewingKey public key must not be zero"
+		logger.Error(msg, "contract", caller.Hex(), "auditor", auditor.Hex())
+		return nil, errors.New(msg)
+	}
+	environment.SetState(fhevm_crypto.CreateProtectedStorageContractAddress(caller), getViewingKeyKey(auditor), publicKey)
+
+	if environment.IsCommitting() {
+		logger.Info("viewing key registered",
+			"contract", caller.Hex(),
+			"auditor", auditor.Hex(),
+			"publicKey", publicKey.Hex(),
+		)
+	}
+	return nil, nil
+}
+
+func revokeViewingKeyRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	logger := environment.GetLogger()
+	if readOnly {
+		return nil, ErrWriteProtection
+	}
+
+	auditor := args.address(0)
+	environment.SetState(fhevm_crypto.CreateProtectedStorageContractAddress(caller), getViewingKeyKey(auditor), common.Hash{})
+
+	if environment.IsCommitting() {
+		logger.Info("viewing key revoked",
+			"contract", caller.Hex(),
+			"auditor", auditor.Hex(),
+		)
+	}
+	return nil, nil
+}
+
+func getViewingKeyRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	return getViewingKey(environment, args.address(0), args.address(1)).Bytes(), nil
+}
+
+func auditorReencryptRun(environment EVMEnvironment, caller common.Address, addr common.Address, args abiArgs, readOnly bool, runSpan trace.Span) ([]byte, error) {
+	logger := environment.GetLogger()
+	if !environment.IsEthCall() {
+		msg := "auditorReencrypt only supported on EthCall"
+		logger.Error(msg)
+		return nil, errors.New(msg)
+	}
+	handle := args.word(0)
+	contract := args.address(1)
+	publicKey := args.word(2)
+
+	auditor, err := recoverAuthorizationSigner(environment, addr, handle, contract, publicKey, args.authorization)
+	if err != nil {
+		return nil, err
+	}
+	denied := func(msg string) ([]byte, error) {
+		logger.Error("viewing key access denied",
+			"reason", msg,
+			"contract", contract.Hex(),
+			"auditor", auditor.Hex(),
+			"handle", handle.Hex(),
+			"blockNumber", environment.BlockNumber(),
+		)
+		return nil, errors.New("auditorReencrypt " + msg)
+	}
+	if publicKey == (common.Hash{}) || getViewingKey(environment, contract, auditor) != publicKey {
+		return denied("signer is not a registered auditor with this public key")
+	}
+	ct, err := getAuditableCiphertext(environment, contract, handle)
+	if err != nil {
+		return denied("failed to load the ciphertext: " + err.Error())
+	}
+	if ct == nil {
+		return denied("ciphertext handle is not owned by the contract")
+	}
+	otelDescribeOperandsFheTypes(runSpan, ct.Type())
+	if err := verifyUnlocked(environment, handle); err != nil {
+		return denied(err.Error())
+	}
+
+	reencryptedValue, err := getDecryptionBackend(environment).Reencrypt(decryptionContext(environment), environment, contract, ct, publicKey.Bytes())
+	if err != nil {
+		logger.Error("auditorReencrypt failed", "err", err)
+		return nil, err
+	}
+
+	logger.Info("viewing key access",
+		"contract", contract.Hex(),
+		"auditor", auditor.Hex(),
+		"handle", handle.Hex(),
+		"publicKey", publicKey.Hex(),
+		"blockNumber", environment.BlockNumber(),
+	)
+	return encodeReencryptedValue(reencryptedValue), nil
+}
@@ -0,0 +1,26 @@
+package fhevm
+
+func registerViewingKeyRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
+	return environment.FhevmParams().GasCosts.RegisterViewingKey
+}
+
+func revokeViewingKeyRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
+	return environment.FhevmParams().GasCosts.RevokeViewingKey
+}
+
+func getViewingKeyRequiredGas(environment EVMEnvironment, suppliedGas uint64, input []byte) uint64 {
+	return environment.FhevmParams().GasCosts.GetViewingKey
+}
+
+func auditorReencryptRequiredGas(environment EVMEnvironment, suppliedGas uint64, args abiArgs) uint64 {
+	handle := args.word(0)
+	if ct := getVerifiedCiphertextFromEVM(environment, handle); ct != nil {
+		return environment.FhevmParams().GasCosts.FheReencrypt[ct.fheUintType()]
+	}
+	metadata := getCiphertextMetadataFromProtectedStorage(environment, args.address(1), handle)
+	if metadata == nil {
+		environment.GetLogger().Error("auditorReencrypt RequiredGas() input doesn't point to a ciphertext", "handle", handle.Hex())
+		return 0
+	}
+	return environment.FhevmParams().GasCosts.FheReencrypt[metadata.fheUintType]
+}
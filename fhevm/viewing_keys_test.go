@@ -0,0 +1,109 @@
+package fhevm
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	fhevm_crypto "github.com/zama-ai/fhevm-go/fhevm/crypto"
+	"github.com/zama-ai/fhevm-go/fhevm/tfhe"
+	"golang.org/x/crypto/nacl/box"
+)
+
+func toViewingKeyInput(method string, args ...common.Hash) []byte {
+	input := crypto.Keccak256([]byte(method))[0:4]
+	for _, arg := range args {
+		input = append(input, arg.Bytes()...)
+	}
+	return input
+}
+
+func TestAuditorReencrypt(t *testing.T) {
+	auditor, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	auditorAddr := crypto.PubkeyToAddress(auditor.PublicKey)
+	boxPublicKey, boxPrivateKey, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	publicKey := common.Hash(*boxPublicKey)
+	contract := common.HexToAddress("0x1000")
+	precompile := common.HexToAddress("0x5d")
+
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	handle := verifyCiphertextInTestMemory(environment, 42, environment.depth, tfhe.FheUint8).GetHash()
+	persistIfVerifiedCiphertext(crypto.Keccak256Hash([]byte{1}), handle, fhevm_crypto.CreateProtectedStorageContractAddress(contract), environment)
+	other := verifyCiphertextInTestMemory(environment, 7, environment.depth, tfhe.FheUint8).GetHash()
+
+	if _, err := FheLibRun(environment, contract, precompile, toViewingKeyInput("registerViewingKey(address,bytes32)", common.BytesToHash(auditorAddr.Bytes()), publicKey), false); err != nil {
+		t.Fatalf(err.Error())
+	}
+	out, err := FheLibRun(environment, contract, precompile, toViewingKeyInput("getViewingKey(address,address)", common.BytesToHash(contract.Bytes()), common.BytesToHash(auditorAddr.Bytes())), false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if common.BytesToHash(out) != publicKey {
+		t.Fatalf("expected the registered viewing key, got %x", out)
+	}
+
+	// The auditor calls the precompile directly, with nothing verified in memory.
+	ethCall := newEthCallOnSameState(environment)
+	auditorReencrypt := func(handle common.Hash, publicKey common.Hash) ([]byte, error) {
+		input := toViewingKeyInput("auditorReencrypt(uint256,address,bytes32)", handle, common.BytesToHash(contract.Bytes()), publicKey)
+		authorization := signAuthorization(t, environment.ChainID(), auditor, precompile, handle, contract, publicKey, int64(testBlockTimestamp)+3600)
+		return FheLibRun(ethCall, auditorAddr, precompile, append(input, authorization...), false)
+	}
+	out, err = auditorReencrypt(handle, publicKey)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	length := new(big.Int).SetBytes(out[32:64]).Uint64()
+	plaintext, ok := box.OpenAnonymous(nil, out[64:64+length], boxPublicKey, boxPrivateKey)
+	if !ok {
+		t.Fatalf("failed to open the re-encrypted value")
+	}
+	if new(big.Int).SetBytes(plaintext).Uint64() != 42 {
+		t.Fatalf("auditorReencrypt result not equal to value, result %v != value %v", new(big.Int).SetBytes(plaintext).Uint64(), 42)
+	}
+
+	if _, err := auditorReencrypt(other, publicKey); err == nil {
+		t.Fatalf("expected auditorReencrypt to fail for a handle the contract doesn't own")
+	}
+	if _, err := auditorReencrypt(handle, common.HexToHash("0x1234")); err == nil {
+		t.Fatalf("expected auditorReencrypt to fail for another public key")
+	}
+
+	if _, err := FheLibRun(environment, contract, precompile, toViewingKeyInput("revokeViewingKey(address)", common.BytesToHash(auditorAddr.Bytes())), false); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if _, err := auditorReencrypt(handle, publicKey); err == nil {
+		t.Fatalf("expected auditorReencrypt to fail after the viewing key is revoked")
+	}
+}
+
+func TestAuditorReencryptUnregistered(t *testing.T) {
+	auditor, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	contract := common.HexToAddress("0x1000")
+	precompile := common.HexToAddress("0x5d")
+	publicKey := common.HexToHash("0x1234")
+
+	environment := newTestEVMEnvironment()
+	environment.depth = 1
+	environment.ethCall = true
+	handle := verifyCiphertextInTestMemory(environment, 42, environment.depth, tfhe.FheUint8).GetHash()
+	allowHandle(environment, handle, contract)
+
+	input := toViewingKeyInput("auditorReencrypt(uint256,address,bytes32)", handle, common.BytesToHash(contract.Bytes()), publicKey)
+	authorization := signAuthorization(t, environment.ChainID(), auditor, precompile, handle, contract, publicKey, int64(testBlockTimestamp)+3600)
+	if _, err := FheLibRun(environment, contract, precompile, append(input, authorization...), false); err == nil {
+		t.Fatalf("expected auditorReencrypt to fail for an auditor that isn't registered")
+	}
+}
@@ -13,8 +13,10 @@ require (
 )
 
 require (
+	github.com/deckarep/golang-set/v2 v2.1.0 // indirect
 	github.com/go-logr/logr v1.4.1 // indirect
 	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/gorilla/websocket v1.4.2 // indirect
 	github.com/naoina/go-stringutil v0.1.0 // indirect
 	go.opentelemetry.io/otel/metric v1.23.1 // indirect
 	golang.org/x/net v0.14.0 // indirect
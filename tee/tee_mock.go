@@ -2,9 +2,12 @@ package tee
 
 import (
 	"crypto/rand"
-	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
+	"slices"
+	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -42,79 +45,172 @@ func NewTeePlaintext(plaintext []byte, fheType tfhe.FheUintType, address common.
 	}
 }
 
-// AsUint8 returns the plaintext as a uint8.
-func (sp TeePlaintext) AsUint8() uint8 {
-	if sp.FheUintType != tfhe.FheUint4 && sp.FheUintType != tfhe.FheUint8 {
-		panic(fmt.Sprintf("Expected FheUint4 or FheUint8, got %s", sp.FheUintType))
+// ErrPlaintextType is returned when a plaintext is read as a type it doesn't have.
+var ErrPlaintextType = errors.New("unexpected plaintext type")
+
+// Returns the value of the plaintext if it has one of the given types and fits in bits.
+func (sp TeePlaintext) asUint(bits int, types ...tfhe.FheUintType) (uint64, error) {
+	if !slices.Contains(types, sp.FheUintType) {
+		return 0, fmt.Errorf("%w: expected %v, got %s", ErrPlaintextType, types, sp.FheUintType)
+	}
+	value := new(big.Int).SetBytes(sp.Value)
+	if value.BitLen() > bits {
+		return 0, fmt.Errorf("%w: %s value overflows uint%d", ErrPlaintextType, sp.FheUintType, bits)
 	}
+	return value.Uint64(), nil
+}
 
-	return sp.Value[0]
+// AsUint8 returns the plaintext as a uint8.
+func (sp TeePlaintext) AsUint8() (uint8, error) {
+	value, err := sp.asUint(8, tfhe.FheUint4, tfhe.FheUint8)
+	return uint8(value), err
 }
 
 // AsUint16 returns the plaintext as a uint16.
-func (sp TeePlaintext) AsUint16() uint16 {
-	if sp.FheUintType != tfhe.FheUint16 {
-		panic(fmt.Sprintf("Expected FheUint16, got %s", sp.FheUintType))
-	}
-
-	return binary.BigEndian.Uint16(sp.Value)
+func (sp TeePlaintext) AsUint16() (uint16, error) {
+	value, err := sp.asUint(16, tfhe.FheUint16)
+	return uint16(value), err
 }
 
 // AsUint32 returns the plaintext as a uint32.
-func (sp TeePlaintext) AsUint32() uint32 {
-	if sp.FheUintType != tfhe.FheUint32 {
-		panic(fmt.Sprintf("Expected FheUint32, got %s", sp.FheUintType))
-	}
-
-	return binary.BigEndian.Uint32(sp.Value)
+func (sp TeePlaintext) AsUint32() (uint32, error) {
+	value, err := sp.asUint(32, tfhe.FheUint32)
+	return uint32(value), err
 }
 
 // AsUint64 returns the plaintext as a uint64.
-func (sp TeePlaintext) AsUint64() uint64 {
-	if sp.FheUintType != tfhe.FheUint64 {
-		panic(fmt.Sprintf("expected FheUint64, got %s", sp.FheUintType))
-	}
+func (sp TeePlaintext) AsUint64() (uint64, error) {
+	return sp.asUint(64, tfhe.FheUint64)
+}
 
-	return binary.BigEndian.Uint64(sp.Value)
+// The TEE is reached through a single entry point, handleEnclaveRequest. Each call is one round trip: the
+// request and the response are serialized, as they would be to cross the enclave boundary, so that batches
+// of plaintexts and ciphertexts cost a single round trip.
+type enclaveRequest struct {
+	Plaintexts  []TeePlaintext
+	Ciphertexts [][]byte
 }
 
-func Encrypt(teeCt TeePlaintext) (tfhe.TfheCiphertext, error) {
-	// Encode the TeePlaintext struct as a byte array using JSON.
-	// This will be used as the plaintext for the ECIES encryption.
-	//
-	// We only require that the implementation of this JSON is deterministic,
-	// which is the case for golang's standard library.
-	bz, err := json.Marshal(teeCt)
+type enclaveResponse struct {
+	Ciphertexts [][]byte
+	Plaintexts  []TeePlaintext
+}
+
+var roundTrips atomic.Uint64
+
+// RoundTrips returns the number of round trips made to the TEE since the process started.
+func RoundTrips() uint64 {
+	return roundTrips.Load()
+}
+
+func roundTrip(request enclaveRequest) (enclaveResponse, error) {
+	roundTrips.Add(1)
+	requestBz, err := json.Marshal(request)
 	if err != nil {
-		return tfhe.TfheCiphertext{}, err
+		return enclaveResponse{}, err
 	}
+	responseBz, err := handleEnclaveRequest(requestBz)
+	if err != nil {
+		return enclaveResponse{}, err
+	}
+	var response enclaveResponse
+	if err := json.Unmarshal(responseBz, &response); err != nil {
+		return enclaveResponse{}, err
+	}
+	if len(response.Ciphertexts) != len(request.Plaintexts) || len(response.Plaintexts) != len(request.Ciphertexts) {
+		return enclaveResponse{}, errors.New("unexpected TEE response length")
+	}
+	return response, nil
+}
+
+// Runs in the TEE: encrypts the plaintexts and decrypts the ciphertexts of a request.
+func handleEnclaveRequest(requestBz []byte) ([]byte, error) {
+	var request enclaveRequest
+	if err := json.Unmarshal(requestBz, &request); err != nil {
+		return nil, err
+	}
+	response := enclaveResponse{
+		Ciphertexts: make([][]byte, 0, len(request.Plaintexts)),
+		Plaintexts:  make([]TeePlaintext, 0, len(request.Ciphertexts)),
+	}
+	for _, plaintext := range request.Plaintexts {
+		// Encode the TeePlaintext struct as a byte array using JSON.
+		// This will be used as the plaintext for the ECIES encryption.
+		//
+		// We only require that the implementation of this JSON is deterministic,
+		// which is the case for golang's standard library.
+		bz, err := json.Marshal(plaintext)
+		if err != nil {
+			return nil, err
+		}
+
+		// Encrypt the plaintext using the public key.
+		ciphertext, err := ecies.Encrypt(rand.Reader, &key.PublicKey, bz, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		response.Ciphertexts = append(response.Ciphertexts, ciphertext)
+	}
+	for _, ciphertext := range request.Ciphertexts {
+		// Decrypt the ciphertext using the private key.
+		plaintextBz, err := key.Decrypt(ciphertext, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		// Decode the plaintext bytes into a TeePlaintext struct.
+		var plaintext TeePlaintext
+		if err := json.Unmarshal(plaintextBz, &plaintext); err != nil {
+			return nil, err
+		}
+		response.Plaintexts = append(response.Plaintexts, plaintext)
+	}
+	return json.Marshal(response)
+}
 
-	// Encrypt the plaintext using the public key.
-	ciphertext, err := ecies.Encrypt(rand.Reader, &key.PublicKey, bz, nil, nil)
+func Encrypt(teeCt TeePlaintext) (tfhe.TfheCiphertext, error) {
+	cts, err := EncryptBatch([]TeePlaintext{teeCt})
 	if err != nil {
 		return tfhe.TfheCiphertext{}, err
 	}
-	hash := common.BytesToHash(crypto.Keccak256(ciphertext))
-	return tfhe.TfheCiphertext{
-		FheUintType:   teeCt.FheUintType,
-		Serialization: ciphertext,
-		Hash:          &hash,
-	}, nil
+	return cts[0], nil
 }
 
 func Decrypt(ct *tfhe.TfheCiphertext) (TeePlaintext, error) {
-	// Decrypt the ciphertext using the private key.
-	plaintextBz, err := key.Decrypt(ct.Serialization, nil, nil)
+	plaintexts, err := DecryptBatch([]*tfhe.TfheCiphertext{ct})
 	if err != nil {
 		return TeePlaintext{}, err
 	}
+	return plaintexts[0], nil
+}
 
-	// Decode the plaintext bytes into a TeePlaintext struct.
-	var plaintext TeePlaintext
-	err = json.Unmarshal(plaintextBz, &plaintext)
+// EncryptBatch encrypts several plaintexts in a single round trip to the TEE.
+func EncryptBatch(teeCts []TeePlaintext) ([]tfhe.TfheCiphertext, error) {
+	response, err := roundTrip(enclaveRequest{Plaintexts: teeCts})
 	if err != nil {
-		return TeePlaintext{}, err
+		return nil, err
+	}
+	cts := make([]tfhe.TfheCiphertext, 0, len(teeCts))
+	for i, ciphertext := range response.Ciphertexts {
+		hash := common.BytesToHash(crypto.Keccak256(ciphertext))
+		cts = append(cts, tfhe.TfheCiphertext{
+			FheUintType:   teeCts[i].FheUintType,
+			Serialization: ciphertext,
+			Hash:          &hash,
+		})
 	}
+	return cts, nil
+}
 
-	return plaintext, nil
+// DecryptBatch decrypts several ciphertexts in a single round trip to the TEE.
+func DecryptBatch(cts []*tfhe.TfheCiphertext) ([]TeePlaintext, error) {
+	ciphertexts := make([][]byte, 0, len(cts))
+	for _, ct := range cts {
+		ciphertexts = append(ciphertexts, ct.Serialization)
+	}
+	response, err := roundTrip(enclaveRequest{Ciphertexts: ciphertexts})
+	if err != nil {
+		return nil, err
+	}
+	return response.Plaintexts, nil
 }
@@ -2,6 +2,7 @@ package tee_test
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -65,3 +66,71 @@ func TestUniqueCiphertexts(t *testing.T) {
 		}
 	})
 }
+
+func TestBatchRoundTrip(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		a := rapid.SliceOf(teePlaintextGen).Draw(t, "a")
+
+		roundTrips := tee.RoundTrips()
+		b, err := tee.EncryptBatch(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cts := make([]*tfhe.TfheCiphertext, 0, len(b))
+		for i := range b {
+			cts = append(cts, &b[i])
+		}
+		c, err := tee.DecryptBatch(cts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := tee.RoundTrips() - roundTrips; n != 2 {
+			t.Fatalf("expected 2 round trips to the TEE, got %d", n)
+		}
+		if len(a) != len(c) {
+			t.Fatalf("expected %d plaintexts, got %d", len(a), len(c))
+		}
+		for i := range a {
+			if !compareTeePlaintexts(a[i], c[i]) {
+				t.Fatalf("expected %v, got %v", a[i], c[i])
+			}
+		}
+	})
+}
+
+func TestAsUintNeverPanics(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		a := teePlaintextGen.Draw(t, "a")
+
+		// Reading a plaintext as any type either succeeds or fails with ErrPlaintextType.
+		for _, as := range []func() error{
+			func() error { _, err := a.AsUint8(); return err },
+			func() error { _, err := a.AsUint16(); return err },
+			func() error { _, err := a.AsUint32(); return err },
+			func() error { _, err := a.AsUint64(); return err },
+		} {
+			if err := as(); err != nil && !errors.Is(err, tee.ErrPlaintextType) {
+				t.Fatalf("expected ErrPlaintextType, got %v", err)
+			}
+		}
+	})
+}
+
+func TestAsUint(t *testing.T) {
+	value, err := tee.NewTeePlaintext([]byte{0x12, 0x34}, tfhe.FheUint16, common.Address{}).AsUint16()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != 0x1234 {
+		t.Fatalf("expected 0x1234, got %#x", value)
+	}
+	if _, err := tee.NewTeePlaintext([]byte{0x12, 0x34}, tfhe.FheUint16, common.Address{}).AsUint8(); !errors.Is(err, tee.ErrPlaintextType) {
+		t.Fatalf("expected a type mismatch to fail with ErrPlaintextType, got %v", err)
+	}
+	if _, err := tee.NewTeePlaintext([]byte{0x01, 0x00}, tfhe.FheUint8, common.Address{}).AsUint8(); !errors.Is(err, tee.ErrPlaintextType) {
+		t.Fatalf("expected an overflowing value to fail with ErrPlaintextType, got %v", err)
+	}
+	if _, err := tee.NewTeePlaintext(nil, tfhe.FheUint64, common.Address{}).AsUint64(); err != nil {
+		t.Fatalf("expected an empty value to be zero, got %v", err)
+	}
+}